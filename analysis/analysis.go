@@ -0,0 +1,328 @@
+// Package analysis reports what a program could do without running it, so
+// embedders can refuse untrusted scripts that need capabilities they do not
+// want to grant.
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+// Report describes the builtins a program refers to and what they need. It
+// errs on the side of listing too much: a name counts as the builtin unless
+// an enclosing parameter or pattern, or an earlier let in an enclosing
+// block, certainly binds it, and a module referred to other than by one of
+// its members counts as all of them.
+type Report struct {
+	// Builtins are the names of the builtins the program refers to, sorted.
+	// The members of a standard library module are listed by the builtin
+	// they are, such as shell for proc.shell, and those of a builtin pack
+	// by the pack and member names, such as aws.upload.
+	Builtins []string
+
+	// Capabilities are the capabilities those builtins need, sorted. A
+	// program with evaluator.Dynamic may call any builtin at all.
+	Capabilities []evaluator.Capability
+
+	// needs are the capabilities builtins need besides their own: those of
+	// packs, and those import needs for the paths it is given.
+	needs map[string][]evaluator.Capability
+}
+
+// Uses reports whether the program needs capability.
+func (r *Report) Uses(capability evaluator.Capability) bool {
+	return contains(r.Capabilities, capability)
+}
+
+// Forbidden returns the builtins the program refers to that need a
+// capability other than the allowed ones.
+func (r *Report) Forbidden(allowed ...evaluator.Capability) []string {
+	var forbidden []string
+	for _, name := range r.Builtins {
+		for _, capability := range r.capabilities(name) {
+			if !contains(allowed, capability) {
+				forbidden = append(forbidden, name)
+				break
+			}
+		}
+	}
+	return forbidden
+}
+
+// capabilities returns what the builtin name needs in the program.
+func (r *Report) capabilities(name string) []evaluator.Capability {
+	needs := r.needs[name]
+	if capability, ok := evaluator.BuiltinCapability(name); ok {
+		needs = append([]evaluator.Capability{capability}, needs...)
+	}
+	return needs
+}
+
+func contains(capabilities []evaluator.Capability, capability evaluator.Capability) bool {
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+var builtins = make(map[string]bool)
+
+// preloaded maps the names the standard library modules are preloaded
+// under to their paths.
+var preloaded = make(map[string]string)
+
+func init() {
+	for _, name := range evaluator.Builtins() {
+		builtins[name] = true
+	}
+	for _, path := range evaluator.Modules() {
+		preloaded[path[strings.LastIndex(path, "/")+1:]] = path
+	}
+}
+
+// Analyze reports the builtins program refers to.
+func Analyze(program *ast.Program) *Report {
+	a := &analyzer{
+		scope: &scope{},
+		used:  make(map[string]bool),
+		needs: make(map[string][]evaluator.Capability),
+	}
+	a.statements(program.Statements)
+
+	report := &Report{needs: a.needs}
+	seen := make(map[evaluator.Capability]bool)
+	for name := range a.used {
+		report.Builtins = append(report.Builtins, name)
+		for _, capability := range report.capabilities(name) {
+			if !seen[capability] {
+				seen[capability] = true
+				report.Capabilities = append(report.Capabilities, capability)
+			}
+		}
+	}
+	sort.Strings(report.Builtins)
+	sort.Slice(report.Capabilities, func(i, j int) bool {
+		return report.Capabilities[i] < report.Capabilities[j]
+	})
+	return report
+}
+
+type scope struct {
+	outer *scope
+	names map[string]bool
+}
+
+func (s *scope) binds(name string) bool {
+	for ; s != nil; s = s.outer {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+type analyzer struct {
+	scope *scope
+	used  map[string]bool
+	needs map[string][]evaluator.Capability
+}
+
+// need records that the builtin name is used and needs capability.
+func (a *analyzer) need(name string, capability evaluator.Capability) {
+	a.used[name] = true
+	if !contains(a.needs[name], capability) {
+		a.needs[name] = append(a.needs[name], capability)
+	}
+}
+
+// a module is what an expression referring to one stands for: the
+// builtins its members are, by their names, and the capability the
+// members need besides their own, if any.
+type module struct {
+	members    map[string]string
+	capability evaluator.Capability
+}
+
+// module returns the module exp refers to, if it is the name of a
+// preloaded one or a call of import with a literal path. The call counts as
+// a use of import, needing evaluator.Dynamic for a path in the standard
+// library's namespace that no module has, since the evaluator it runs in
+// may know it, and evaluator.Packs for any other path, which names a pack
+// whose members are known if it is registered here too.
+func (a *analyzer) module(exp ast.Expression) (*module, bool) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		path, ok := preloaded[exp.Value]
+		if !ok || a.scope.binds(exp.Value) {
+			return nil, false
+		}
+		members, _ := evaluator.ModuleMembers(path)
+		return &module{members: members}, true
+
+	case *ast.CallExpression:
+		ident, ok := exp.Function.(*ast.Identifier)
+		if !ok || ident.Value != "import" || !builtins["import"] || a.scope.binds("import") || len(exp.Arguments) != 1 {
+			return nil, false
+		}
+		path, ok := exp.Arguments[0].(*ast.StringLiteral)
+		if !ok {
+			return nil, false
+		}
+		a.used["import"] = true
+		if members, ok := evaluator.ModuleMembers(path.Value); ok {
+			return &module{members: members}, true
+		}
+		if strings.HasPrefix(path.Value, "std/") {
+			a.need("import", evaluator.Dynamic)
+			return &module{}, true
+		}
+		a.need("import", evaluator.Packs)
+		m := &module{members: map[string]string{}, capability: evaluator.Packs}
+		names, _ := evaluator.BuiltinPackMembers(path.Value)
+		for _, name := range names {
+			m.members[name] = path.Value + "." + name
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// use records that the program uses the member name of m, or all of them
+// when name is empty.
+func (a *analyzer) use(m *module, name string) {
+	for member, builtin := range m.members {
+		if name != "" && member != name {
+			continue
+		}
+		if m.capability != "" {
+			a.need(builtin, m.capability)
+		} else {
+			a.used[builtin] = true
+		}
+	}
+}
+
+func (a *analyzer) withScope(f func()) {
+	a.scope = &scope{outer: a.scope}
+	f()
+	a.scope = a.scope.outer
+}
+
+// declare records that every identifier in node is bound from now on.
+func (a *analyzer) declare(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if a.scope.names == nil {
+				a.scope.names = make(map[string]bool)
+			}
+			a.scope.names[ident.Value] = true
+		}
+		return true
+	})
+}
+
+func (a *analyzer) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		a.walk(stmt)
+	}
+}
+
+func (a *analyzer) walk(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if !builtins[node.Value] || a.scope.binds(node.Value) {
+				if m, ok := a.module(node); ok {
+					a.use(m, "")
+				}
+			} else if node.Value == "import" {
+				// Any use of import but a call with a literal path, which
+				// module resolves, may import anything.
+				a.need("import", evaluator.Dynamic)
+			} else {
+				a.used[node.Value] = true
+			}
+
+		case *ast.CallExpression:
+			if m, ok := a.module(node); ok {
+				a.use(m, "")
+				return false
+			}
+
+		case *ast.FieldExpression:
+			if m, ok := a.module(node.Left); ok {
+				a.use(m, node.Name)
+				return false
+			}
+
+		case *ast.IndexExpression:
+			if m, ok := a.module(node.Left); ok {
+				if index, ok := node.Index.(*ast.StringLiteral); ok {
+					a.use(m, index.Value)
+				} else {
+					a.walk(node.Index)
+					a.use(m, "")
+				}
+				return false
+			}
+
+		case *ast.LetStatement:
+			a.walk(node.Value)
+			if node.Pattern != nil {
+				a.declare(node.Pattern)
+			} else {
+				a.declare(node.Name)
+			}
+			return false
+
+		case *ast.BlockStatement:
+			a.withScope(func() { a.statements(node.Statements) })
+			return false
+
+		case *ast.FunctionLiteral:
+			a.withScope(func() {
+				for _, param := range node.Parameters {
+					a.declare(param)
+				}
+				a.walk(node.Body)
+			})
+			return false
+
+		case *ast.MatchExpression:
+			a.walk(node.Subject)
+			for _, arm := range node.Arms {
+				a.withScope(func() {
+					a.declare(arm.Pattern)
+					a.walk(arm.Guard)
+					a.walk(arm.Body)
+				})
+			}
+			return false
+
+		case *ast.ArrayComprehension:
+			a.comprehension(node.Clause, node.Element)
+			return false
+
+		case *ast.HashComprehension:
+			a.comprehension(node.Clause, node.Key, node.Value)
+			return false
+		}
+		return true
+	})
+}
+
+func (a *analyzer) comprehension(clause *ast.ComprehensionClause, body ...ast.Expression) {
+	a.walk(clause.Iterable)
+	a.withScope(func() {
+		a.declare(clause.Pattern)
+		a.walk(clause.Condition)
+		for _, exp := range body {
+			a.walk(exp)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func init() {
+	evaluator.RegisterBuiltinPack("test/analysis", map[string]*object.Builtin{
+		"greet": {Fn: func(args ...object.Object) object.Object { return args[0] }},
+		"wave":  {Fn: func(args ...object.Object) object.Object { return args[0] }},
+	})
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		input        string
+		builtins     []string
+		capabilities []evaluator.Capability
+	}{
+		{"1 + 2", nil, nil},
+		{`len("abc")`, []string{"len"}, nil},
+		{`puts(len([1]))`, []string{"len", "puts"}, []evaluator.Capability{evaluator.Output}},
+		{"let p = puts; p(1)", []string{"puts"}, []evaluator.Capability{evaluator.Output}},
+		{`eval("1")`, []string{"eval"}, []evaluator.Capability{evaluator.Dynamic}},
+		{"pmap([1], fn(x) { puts(x) })", []string{"pmap", "puts"},
+			[]evaluator.Capability{evaluator.Concurrency, evaluator.Output}},
+		{"let puts = fn(x) { x }; puts(1)", nil, nil},
+		{"let f = fn(puts) { puts(1) }", nil, nil},
+		{"let f = fn() { puts(1) }; let puts = 1", []string{"puts"}, []evaluator.Capability{evaluator.Output}},
+		{"if (true) { let puts = 1 } puts(2)", []string{"puts"}, []evaluator.Capability{evaluator.Output}},
+		{"h.puts; {}.len", nil, nil},
+		{"[puts for puts in xs]; match x { [len] => len }", nil, nil},
+		{"[x for x in xs if first(x)]; match x { _ if last(x) => rest(x) }", []string{"first", "last", "rest"}, nil},
+		{`proc.shell("echo pwned")`, []string{"shell"}, []evaluator.Capability{evaluator.Proc}},
+		{`fs.readFile("secrets")`, []string{"fs.readFile"}, []evaluator.Capability{evaluator.FS}},
+		{`math["sqrt"](4)`, []string{"math.sqrt"}, nil},
+		{`let p = proc; p.exec("ls")`, []string{"exec", "shell"}, []evaluator.Capability{evaluator.Proc}},
+		{"let proc = {}; proc.shell", nil, nil},
+		{`let f = fn(fs) { fs.readFile("x") }`, nil, nil},
+		{`import("std/net").connect("localhost", 80)`, []string{"import", "tcpConnect"}, []evaluator.Capability{evaluator.Net}},
+		{`let m = import("std/math"); m.sqrt(4)`, []string{"import", "math.abs", "math.max", "math.min", "math.pow", "math.sqrt"}, nil},
+		{`import("std/nope")`, []string{"import"}, []evaluator.Capability{evaluator.Dynamic}},
+		{`import("aws")`, []string{"import"}, []evaluator.Capability{evaluator.Packs}},
+		{`import("test/analysis").greet("you")`, []string{"import", "test/analysis.greet"}, []evaluator.Capability{evaluator.Packs}},
+		{`let pack = import("test/analysis")`, []string{"import", "test/analysis.greet", "test/analysis.wave"}, []evaluator.Capability{evaluator.Packs}},
+		{`import(name)`, []string{"import"}, []evaluator.Capability{evaluator.Dynamic}},
+		{`let i = import; i("std/proc")`, []string{"import"}, []evaluator.Capability{evaluator.Dynamic}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		report := Analyze(program)
+		if !reflect.DeepEqual(report.Builtins, tt.builtins) {
+			t.Errorf("wrong builtins for %q. expected=%q, got=%q", tt.input, tt.builtins, report.Builtins)
+		}
+		if !reflect.DeepEqual(report.Capabilities, tt.capabilities) {
+			t.Errorf("wrong capabilities for %q. expected=%q, got=%q", tt.input, tt.capabilities, report.Capabilities)
+		}
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	p := parser.New(lexer.New(`puts(eval("1"), len("a"), async(fn() { 1 }))`))
+	report := Analyze(p.ParseProgram())
+
+	if !report.Uses(evaluator.Output) || report.Uses("net") {
+		t.Errorf("wrong capabilities. got=%q", report.Capabilities)
+	}
+
+	forbidden := report.Forbidden(evaluator.Output)
+	expected := []string{"async", "eval"}
+	if !reflect.DeepEqual(forbidden, expected) {
+		t.Errorf("wrong forbidden builtins. expected=%q, got=%q", expected, forbidden)
+	}
+	if forbidden := report.Forbidden(evaluator.Output, evaluator.Dynamic, evaluator.Concurrency); forbidden != nil {
+		t.Errorf("expected nothing forbidden, got=%q", forbidden)
+	}
+
+	p = parser.New(lexer.New(`import(name); proc.shell("ls"); import("test/analysis").wave()`))
+	forbidden = Analyze(p.ParseProgram()).Forbidden(evaluator.Output)
+	expected = []string{"import", "shell", "test/analysis.wave"}
+	if !reflect.DeepEqual(forbidden, expected) {
+		t.Errorf("wrong forbidden builtins. expected=%q, got=%q", expected, forbidden)
+	}
+}
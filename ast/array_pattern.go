@@ -0,0 +1,38 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// ArrayPattern destructures an array element by element, as in
+// `let [a, b, ...rest] = arr;`. Rest, when present, receives the elements
+// left over after the others.
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Pattern
+	Rest     *Identifier
+}
+
+var _ Pattern = &ArrayPattern{}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
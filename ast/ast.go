@@ -14,3 +14,11 @@ type Expression interface {
 	Node
 	expressionNode()
 }
+
+// Pattern is the target of a binding, such as the left side of a let. It is
+// either an identifier, a pattern destructuring an array, a tuple or a hash,
+// or a literal or wildcard that checks a value without binding it.
+type Pattern interface {
+	Node
+	patternNode()
+}
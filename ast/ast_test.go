@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/fcidade/monkey-lang/token"
@@ -25,3 +26,118 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&LetStatement{
+			Name: &Identifier{Value: "f"},
+			Value: &FunctionLiteral{
+				Parameters: []*Identifier{{Value: "x"}},
+				Body: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &Identifier{Value: "x"}},
+				}},
+			},
+		},
+		(*LetStatement)(nil),
+	}}
+
+	names := []string{}
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+
+	if strings.Join(names, " ") != "f x x" {
+		t.Errorf("wrong identifiers visited. got=%q", names)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	block := func() *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}
+	}
+	clause := func() *ComprehensionClause {
+		return &ComprehensionClause{Pattern: &Identifier{Value: "x"}, Iterable: one(), Condition: one()}
+	}
+
+	turnOneIntoTwo := func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok && integer.Value == 1 {
+			return two()
+		}
+		return node
+	}
+
+	tests := []Node{
+		one(),
+		&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+		&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+		&LetStatement{Pattern: &ArrayPattern{Elements: []Pattern{&LiteralPattern{Value: one()}}, Rest: &Identifier{Value: "r"}}, Value: one()},
+		&LetStatement{Pattern: &TuplePattern{Elements: []Pattern{&LiteralPattern{Value: one()}}}, Value: one()},
+		&LetStatement{Pattern: &HashPattern{Pairs: []HashPatternPair{{Key: "k", Value: &LiteralPattern{Value: one()}}}}, Value: one()},
+		&ReturnStatement{ReturnValue: one()},
+		&PrefixExpression{Operator: "-", Right: one()},
+		&InfixExpression{Left: one(), Operator: "+", Right: one()},
+		&ComparisonChain{Operands: []Expression{one(), one(), one()}, Operators: []string{"<", "<"}},
+		&IfExpression{Condition: one(), Consequence: block(), Alternative: block()},
+		&FunctionLiteral{Parameters: []*Identifier{{Value: "x"}}, Body: block()},
+		&CallExpression{Function: one(), Arguments: []Expression{one(), one()}},
+		&ArrayLiteral{Elements: []Expression{one(), one()}},
+		&TupleLiteral{Elements: []Expression{one(), one()}},
+		&HashLiteral{Pairs: map[Expression]Expression{one(): one()}},
+		&ArrayComprehension{Element: one(), Clause: clause()},
+		&HashComprehension{Key: one(), Value: one(), Clause: clause()},
+		&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: &LiteralPattern{Value: one()}, Guard: one(), Body: block()}}},
+		&FieldExpression{Left: one(), Name: "f"},
+		&IndexExpression{Left: one(), Index: one()},
+	}
+
+	for _, tt := range tests {
+		rewritten := Rewrite(tt, turnOneIntoTwo)
+		ones, twos := 0, 0
+		Inspect(rewritten, func(node Node) bool {
+			if integer, ok := node.(*IntegerLiteral); ok {
+				if integer.Value == 1 {
+					ones++
+				} else {
+					twos++
+				}
+			}
+			return true
+		})
+		if ones != 0 || twos == 0 {
+			t.Errorf("%T: expected every 1 turned into 2, got %d ones and %d twos", tt, ones, twos)
+		}
+	}
+}
+
+func TestRewriteMisfits(t *testing.T) {
+	// Identifiers in expressions can become literals, but the one a let binds
+	// cannot.
+	let := &LetStatement{Name: &Identifier{Value: "x"}, Value: &Identifier{Value: "y"}}
+	Rewrite(let, func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &IntegerLiteral{Value: 1}
+		}
+		return node
+	})
+
+	if let.Name.Value != "x" {
+		t.Errorf("let name rewritten into %q", let.Name.String())
+	}
+	if _, ok := let.Value.(*IntegerLiteral); !ok {
+		t.Errorf("let value not rewritten, got %T", let.Value)
+	}
+
+	// Nor do the nil nodes of what did not parse reach f.
+	broken := &LetStatement{Pattern: &WildcardPattern{}, Value: (*IntegerLiteral)(nil)}
+	Rewrite(&Program{Statements: []Statement{broken, (*LetStatement)(nil)}}, func(node Node) Node {
+		if isNil(node) {
+			t.Errorf("nil %T passed to f", node)
+		}
+		return node
+	})
+}
@@ -9,6 +9,10 @@ import (
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+
+	// End are the comments after the last statement, before the closing
+	// brace.
+	End *Comments
 }
 
 var _ Statement = &BlockStatement{}
@@ -0,0 +1,15 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+type BytesLiteral struct {
+	Token token.Token
+	Value string
+}
+
+var _ Expression = &BytesLiteral{}
+
+func (b *BytesLiteral) expressionNode() {}
+
+func (b *BytesLiteral) TokenLiteral() string { return b.Token.Literal }
+func (b *BytesLiteral) String() string       { return `b"` + b.Value + `"` }
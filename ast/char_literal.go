@@ -0,0 +1,16 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+// CharLiteral is a single character between single quotes, as in 'a'.
+type CharLiteral struct {
+	Token token.Token
+	Value rune
+}
+
+var _ Expression = &CharLiteral{}
+
+func (c *CharLiteral) expressionNode() {}
+
+func (c *CharLiteral) TokenLiteral() string { return c.Token.Literal }
+func (c *CharLiteral) String() string       { return "'" + string(c.Value) + "'" }
@@ -0,0 +1,35 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+// Comments are the comments a parser found with a statement, or at the end
+// of a block or program, when its tokens came with trivia.
+type Comments struct {
+	// Leading are the comments on the lines before the statement, and Blank
+	// counts the blank lines between the last of them and the statement.
+	Leading []token.Comment
+	Blank   int
+
+	// Trailing are the comments after the statement on its last line, along
+	// with those inside it that none of the statements nested in it has.
+	Trailing []string
+}
+
+// CommentsOf returns the comments of stmt, or nil when it has none.
+func CommentsOf(stmt Statement) *Comments {
+	switch stmt := stmt.(type) {
+	case *LetStatement:
+		if stmt != nil {
+			return stmt.Comments
+		}
+	case *ReturnStatement:
+		if stmt != nil {
+			return stmt.Comments
+		}
+	case *ExpressionStatement:
+		if stmt != nil {
+			return stmt.Comments
+		}
+	}
+	return nil
+}
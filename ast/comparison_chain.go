@@ -0,0 +1,35 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// ComparisonChain is a run of ordering comparisons such as `a < b < c`,
+// which holds when every adjacent pair compares true. Operators[i] sits
+// between Operands[i] and Operands[i+1].
+type ComparisonChain struct {
+	Token     token.Token
+	Operands  []Expression
+	Operators []string
+}
+
+var _ Expression = &ComparisonChain{}
+
+func (cc *ComparisonChain) expressionNode()      {}
+func (cc *ComparisonChain) TokenLiteral() string { return cc.Token.Literal }
+func (cc *ComparisonChain) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	for i, operand := range cc.Operands {
+		if i > 0 {
+			out.WriteString(" " + cc.Operators[i-1] + " ")
+		}
+		out.WriteString(operand.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
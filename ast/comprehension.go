@@ -0,0 +1,66 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// ComprehensionClause is the `for pattern in iterable if condition` part of
+// a comprehension; Condition is nil when there is no `if`. The pattern is
+// bound in a scope of its own for every item, and the resolver names that
+// scope's slots in Locals.
+type ComprehensionClause struct {
+	Token     token.Token
+	Pattern   Pattern
+	Iterable  Expression
+	Condition Expression
+
+	Locals []string
+}
+
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	out.WriteString(cc.Pattern.String())
+	out.WriteString(" in ")
+	out.WriteString(cc.Iterable.String())
+	if cc.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Condition.String())
+	}
+
+	return out.String()
+}
+
+// ArrayComprehension builds an array, as in `[x * x for x in xs if x > 0]`.
+type ArrayComprehension struct {
+	Token   token.Token
+	Element Expression
+	Clause  *ComprehensionClause
+}
+
+var _ Expression = &ArrayComprehension{}
+
+func (ac *ArrayComprehension) expressionNode()      {}
+func (ac *ArrayComprehension) TokenLiteral() string { return ac.Token.Literal }
+func (ac *ArrayComprehension) String() string {
+	return "[" + ac.Element.String() + " " + ac.Clause.String() + "]"
+}
+
+// HashComprehension builds a hash, as in `{k: v * 2 for k, v in h}`.
+type HashComprehension struct {
+	Token  token.Token
+	Key    Expression
+	Value  Expression
+	Clause *ComprehensionClause
+}
+
+var _ Expression = &HashComprehension{}
+
+func (hc *HashComprehension) expressionNode()      {}
+func (hc *HashComprehension) TokenLiteral() string { return hc.Token.Literal }
+func (hc *HashComprehension) String() string {
+	return "{" + hc.Key.String() + ": " + hc.Value.String() + " " + hc.Clause.String() + "}"
+}
@@ -5,6 +5,8 @@ import "github.com/fcidade/monkey-lang/token"
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
+
+	Comments *Comments
 }
 
 var _ Statement = &ExpressionStatement{}
@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// FieldExpression is `left.name`, a shorter way of writing `left["name"]`
+// for hashes. With `left?.name`, Optional is set and a null left yields null
+// instead of an error.
+type FieldExpression struct {
+	Token    token.Token
+	Left     Expression
+	Name     string
+	Optional bool
+}
+
+var _ Expression = &FieldExpression{}
+
+func (fe *FieldExpression) expressionNode()      {}
+func (fe *FieldExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *FieldExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(fe.Left.String())
+	if fe.Optional {
+		out.WriteString("?.")
+	} else {
+		out.WriteString(".")
+	}
+	out.WriteString(fe.Name)
+	out.WriteString(")")
+
+	return out.String()
+}
@@ -10,7 +10,12 @@ import (
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	ReturnType *TypeAnnotation
 	Body       *BlockStatement
+
+	// Locals names the slots of the function's scope, parameters first. It is
+	// nil until the literal has been resolved.
+	Locals []string
 }
 
 var _ Expression = &FunctionLiteral{}
@@ -26,11 +31,14 @@ func (fl *FunctionLiteral) String() string {
 
 	params := []string{}
 	for _, param := range fl.Parameters {
-		params = append(params, param.String())
+		params = append(params, param.annotated())
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String() + " ")
+	}
 
 	out.WriteString("{ ")
 	out.WriteString(fl.Body.String())
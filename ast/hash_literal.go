@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"sort"
 	"strings"
 
 	"github.com/fcidade/monkey-lang/token"
@@ -24,6 +25,9 @@ func (hl *HashLiteral) String() string {
 	for key, value := range hl.Pairs {
 		pairs = append(pairs, key.String()+":"+value.String())
 	}
+	// Pairs are kept in a map, so they are sorted to print the same way
+	// every time.
+	sort.Strings(pairs)
 
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -0,0 +1,63 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// HashPattern destructures a hash by its string keys, as in
+// `let {name, age: years} = person;`. A key written on its own binds the
+// value to an identifier of the same name; other keys may be quoted, as in
+// `{"type": "circle"}`.
+type HashPattern struct {
+	Token token.Token
+	Pairs []HashPatternPair
+}
+
+type HashPatternPair struct {
+	Key   string
+	Value Pattern
+}
+
+var _ Pattern = &HashPattern{}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		key := pair.Key
+		if !isIdentifierName(key) {
+			key = Quote(key)
+		} else if ident, ok := pair.Value.(*Identifier); ok && ident.Value == pair.Key {
+			pairs = append(pairs, key)
+			continue
+		}
+		pairs = append(pairs, key+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// isIdentifierName reports whether name can be written as a bare key, which
+// it can when it lexes as an identifier.
+func isIdentifierName(name string) bool {
+	if name == "" || token.LookupIdentifier(name) != token.IDENTIFIER {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		letter := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+		if !letter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -5,11 +5,31 @@ import "github.com/fcidade/monkey-lang/token"
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// Resolved is set when the identifier names a local of an enclosing
+	// function. Depth counts the function scopes between the identifier and
+	// that function, and Index is the local's slot in it.
+	Resolved bool
+	Depth    int
+	Index    int
+
+	// Type is the annotation of a let binding or a parameter, if it has one.
+	Type *TypeAnnotation
 }
 
 var _ Expression = &Identifier{}
+var _ Pattern = &Identifier{}
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
+
+// annotated is the identifier followed by its type annotation, if any.
+func (i *Identifier) annotated() string {
+	if i.Type == nil {
+		return i.Value
+	}
+	return i.Value + ": " + i.Type.String()
+}
@@ -6,10 +6,13 @@ import (
 	"github.com/fcidade/monkey-lang/token"
 )
 
+// IndexExpression is `left[index]`, or `left?.[index]` when Optional is set,
+// which yields null instead of failing when left is null.
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool
 }
 
 var _ Expression = &IndexExpression{}
@@ -21,6 +24,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -0,0 +1,118 @@
+package ast
+
+import "reflect"
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for each node. When f returns false the children of that node are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *LetStatement:
+		Inspect(node.Name, f)
+		Inspect(node.Pattern, f)
+		Inspect(node.Value, f)
+	case *ArrayPattern:
+		for _, element := range node.Elements {
+			Inspect(element, f)
+		}
+		Inspect(node.Rest, f)
+	case *TuplePattern:
+		for _, element := range node.Elements {
+			Inspect(element, f)
+		}
+	case *HashPattern:
+		for _, pair := range node.Pairs {
+			Inspect(pair.Value, f)
+		}
+	case *LiteralPattern:
+		Inspect(node.Value, f)
+	case *MatchExpression:
+		Inspect(node.Subject, f)
+		for _, arm := range node.Arms {
+			Inspect(arm.Pattern, f)
+			Inspect(arm.Guard, f)
+			Inspect(arm.Body, f)
+		}
+	case *ReturnStatement:
+		Inspect(node.ReturnValue, f)
+	case *ExpressionStatement:
+		Inspect(node.Expression, f)
+	case *PrefixExpression:
+		Inspect(node.Right, f)
+	case *InfixExpression:
+		Inspect(node.Left, f)
+		Inspect(node.Right, f)
+	case *ComparisonChain:
+		for _, operand := range node.Operands {
+			Inspect(operand, f)
+		}
+	case *IfExpression:
+		Inspect(node.Condition, f)
+		Inspect(node.Consequence, f)
+		Inspect(node.Alternative, f)
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, f)
+		}
+		Inspect(node.Body, f)
+	case *CallExpression:
+		Inspect(node.Function, f)
+		for _, arg := range node.Arguments {
+			Inspect(arg, f)
+		}
+	case *ArrayLiteral:
+		for _, element := range node.Elements {
+			Inspect(element, f)
+		}
+	case *TupleLiteral:
+		for _, element := range node.Elements {
+			Inspect(element, f)
+		}
+	case *ArrayComprehension:
+		Inspect(node.Element, f)
+		inspectClause(node.Clause, f)
+	case *HashComprehension:
+		Inspect(node.Key, f)
+		Inspect(node.Value, f)
+		inspectClause(node.Clause, f)
+	case *FieldExpression:
+		Inspect(node.Left, f)
+	case *IndexExpression:
+		Inspect(node.Left, f)
+		Inspect(node.Index, f)
+	case *HashLiteral:
+		for key, value := range node.Pairs {
+			Inspect(key, f)
+			Inspect(value, f)
+		}
+	}
+}
+
+func inspectClause(clause *ComprehensionClause, f func(Node) bool) {
+	if clause != nil {
+		Inspect(clause.Pattern, f)
+		Inspect(clause.Iterable, f)
+		Inspect(clause.Condition, f)
+	}
+}
+
+// isNil reports whether node is nil or a nil pointer, which the parser leaves
+// behind for statements it could not parse.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
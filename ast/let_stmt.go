@@ -6,10 +6,15 @@ import (
 	"github.com/fcidade/monkey-lang/token"
 )
 
+// LetStatement binds Value to Name or, when destructuring, to the
+// identifiers in Pattern, in which case Name is nil.
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Pattern
+	Value   Expression
+
+	Comments *Comments
 }
 
 var _ Statement = &LetStatement{}
@@ -21,7 +26,11 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.annotated())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -0,0 +1,21 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+// LiteralPattern only matches values equal to its literal, an integer, a
+// string, a character or a boolean, as in the `0` of `match n { 0 => "none", _ => "some" }`.
+type LiteralPattern struct {
+	Token token.Token
+	Value Expression
+}
+
+var _ Pattern = &LiteralPattern{}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) String() string {
+	if str, ok := lp.Value.(*StringLiteral); ok {
+		return Quote(str.Value)
+	}
+	return lp.Value.String()
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// MatchExpression evaluates the body of the first arm whose pattern matches
+// the subject, as in `match shape { {"r": r} => r * r * 3, _ => 0 }`.
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm is `pattern if guard => body`; Guard is nil when there is no
+// `if`. The pattern is bound in a scope of its own, whose slots the resolver
+// names in Locals.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression
+	Body    *BlockStatement
+
+	Locals []string
+}
+
+var _ Expression = &MatchExpression{}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
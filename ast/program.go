@@ -4,6 +4,9 @@ import "bytes"
 
 type Program struct {
 	Statements []Statement
+
+	// End are the comments after the last statement.
+	End *Comments
 }
 
 var _ Node = &Program{}
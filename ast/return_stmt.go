@@ -9,6 +9,8 @@ import (
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
+
+	Comments *Comments
 }
 
 var _ Statement = &ReturnStatement{}
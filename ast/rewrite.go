@@ -0,0 +1,175 @@
+package ast
+
+// Rewrite rewrites the tree rooted at node bottom-up: the children of every
+// node are rewritten first and the node is then replaced by what f returns
+// for it, which is node itself to keep it. Nodes are changed in place, and
+// Rewrite returns what f returned for node.
+//
+// A node f returns that cannot go where the one it was given was, such as a
+// literal for the name a let binds, or nil, is dropped and the node it was
+// given kept. Nil nodes, which the parser leaves for what it could not parse, are
+// not passed to f.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		rewriteStatements(node.Statements, f)
+	case *BlockStatement:
+		rewriteStatements(node.Statements, f)
+	case *LetStatement:
+		node.Name = rewriteIdentifier(node.Name, f)
+		node.Pattern = rewritePattern(node.Pattern, f)
+		node.Value = rewriteExpression(node.Value, f)
+	case *ReturnStatement:
+		node.ReturnValue = rewriteExpression(node.ReturnValue, f)
+	case *ExpressionStatement:
+		node.Expression = rewriteExpression(node.Expression, f)
+
+	case *Identifier:
+		node.Type = rewriteAnnotation(node.Type, f)
+	case *ArrayPattern:
+		for i, element := range node.Elements {
+			node.Elements[i] = rewritePattern(element, f)
+		}
+		node.Rest = rewriteIdentifier(node.Rest, f)
+	case *TuplePattern:
+		for i, element := range node.Elements {
+			node.Elements[i] = rewritePattern(element, f)
+		}
+	case *HashPattern:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Value = rewritePattern(pair.Value, f)
+		}
+	case *LiteralPattern:
+		node.Value = rewriteExpression(node.Value, f)
+
+	case *PrefixExpression:
+		node.Right = rewriteExpression(node.Right, f)
+	case *InfixExpression:
+		node.Left = rewriteExpression(node.Left, f)
+		node.Right = rewriteExpression(node.Right, f)
+	case *ComparisonChain:
+		rewriteExpressions(node.Operands, f)
+	case *IfExpression:
+		node.Condition = rewriteExpression(node.Condition, f)
+		node.Consequence = rewriteBlock(node.Consequence, f)
+		node.Alternative = rewriteBlock(node.Alternative, f)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = rewriteIdentifier(param, f)
+		}
+		node.ReturnType = rewriteAnnotation(node.ReturnType, f)
+		node.Body = rewriteBlock(node.Body, f)
+	case *CallExpression:
+		node.Function = rewriteExpression(node.Function, f)
+		rewriteExpressions(node.Arguments, f)
+	case *ArrayLiteral:
+		rewriteExpressions(node.Elements, f)
+	case *TupleLiteral:
+		rewriteExpressions(node.Elements, f)
+	case *HashLiteral:
+		pairs := make(map[Expression]Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			pairs[rewriteExpression(key, f)] = rewriteExpression(value, f)
+		}
+		node.Pairs = pairs
+	case *ArrayComprehension:
+		node.Element = rewriteExpression(node.Element, f)
+		rewriteClause(node.Clause, f)
+	case *HashComprehension:
+		node.Key = rewriteExpression(node.Key, f)
+		node.Value = rewriteExpression(node.Value, f)
+		rewriteClause(node.Clause, f)
+	case *MatchExpression:
+		node.Subject = rewriteExpression(node.Subject, f)
+		for _, arm := range node.Arms {
+			arm.Pattern = rewritePattern(arm.Pattern, f)
+			arm.Guard = rewriteExpression(arm.Guard, f)
+			arm.Body = rewriteBlock(arm.Body, f)
+		}
+	case *FieldExpression:
+		node.Left = rewriteExpression(node.Left, f)
+	case *IndexExpression:
+		node.Left = rewriteExpression(node.Left, f)
+		node.Index = rewriteExpression(node.Index, f)
+	}
+
+	return f(node)
+}
+
+func rewriteClause(clause *ComprehensionClause, f func(Node) Node) {
+	if clause != nil {
+		clause.Pattern = rewritePattern(clause.Pattern, f)
+		clause.Iterable = rewriteExpression(clause.Iterable, f)
+		clause.Condition = rewriteExpression(clause.Condition, f)
+	}
+}
+
+func rewriteStatements(stmts []Statement, f func(Node) Node) {
+	for i, stmt := range stmts {
+		if !isNil(stmt) {
+			if rewritten, ok := Rewrite(stmt, f).(Statement); ok && !isNil(rewritten) {
+				stmts[i] = rewritten
+			}
+		}
+	}
+}
+
+func rewriteExpressions(exprs []Expression, f func(Node) Node) {
+	for i, expr := range exprs {
+		exprs[i] = rewriteExpression(expr, f)
+	}
+}
+
+func rewriteExpression(expr Expression, f func(Node) Node) Expression {
+	if isNil(expr) {
+		return expr
+	}
+	if rewritten, ok := Rewrite(expr, f).(Expression); ok && !isNil(rewritten) {
+		return rewritten
+	}
+	return expr
+}
+
+func rewritePattern(pattern Pattern, f func(Node) Node) Pattern {
+	if isNil(pattern) {
+		return pattern
+	}
+	if rewritten, ok := Rewrite(pattern, f).(Pattern); ok && !isNil(rewritten) {
+		return rewritten
+	}
+	return pattern
+}
+
+func rewriteIdentifier(ident *Identifier, f func(Node) Node) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(ident, f).(*Identifier); ok && !isNil(rewritten) {
+		return rewritten
+	}
+	return ident
+}
+
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(block, f).(*BlockStatement); ok && !isNil(rewritten) {
+		return rewritten
+	}
+	return block
+}
+
+func rewriteAnnotation(annotation *TypeAnnotation, f func(Node) Node) *TypeAnnotation {
+	if annotation == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(annotation, f).(*TypeAnnotation); ok && !isNil(rewritten) {
+		return rewritten
+	}
+	return annotation
+}
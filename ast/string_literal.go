@@ -1,6 +1,10 @@
 package ast
 
-import "github.com/fcidade/monkey-lang/token"
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
 
 type StringLiteral struct {
 	Token token.Token
@@ -13,3 +17,13 @@ func (s *StringLiteral) expressionNode() {}
 
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
 func (s *StringLiteral) String() string       { return s.Value }
+
+// Quote writes s as a string literal, in backticks when it contains a double
+// quote. Literals have no escapes, so one with both kinds of quote cannot be
+// written at all and is left in double quotes.
+func Quote(s string) string {
+	if strings.Contains(s, `"`) && !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return `"` + s + `"`
+}
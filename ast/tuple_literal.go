@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// TupleLiteral is a parenthesized list of expressions separated by commas,
+// such as `(1, "a")`. A single element needs a trailing comma, `(1,)`, to
+// tell it apart from a grouped expression.
+type TupleLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+var _ Expression = &TupleLiteral{}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+	return out.String()
+}
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// TuplePattern destructures a tuple, as in `let (min, max) = minmax(arr);`
+// or its shorter form `let min, max = minmax(arr);`.
+type TuplePattern struct {
+	Token    token.Token
+	Elements []Pattern
+}
+
+var _ Pattern = &TuplePattern{}
+
+func (tp *TuplePattern) patternNode()         {}
+func (tp *TuplePattern) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TuplePattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range tp.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+	return out.String()
+}
@@ -0,0 +1,14 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+// TypeAnnotation is the declared type of a let binding, a parameter or a
+// function's result, such as the `int` of `let x: int = 5;`. Annotations do
+// not change how a program runs; the typecheck package checks them.
+type TypeAnnotation struct {
+	Token token.Token
+	Name  string
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
@@ -0,0 +1,14 @@
+package ast
+
+import "github.com/fcidade/monkey-lang/token"
+
+// WildcardPattern is `_`, which matches any value without binding it.
+type WildcardPattern struct {
+	Token token.Token
+}
+
+var _ Pattern = &WildcardPattern{}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
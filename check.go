@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/repl"
+	"github.com/fcidade/monkey-lang/typecheck"
+)
+
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	jsonDiagnostics := flags.Bool("json-diagnostics", false, "write errors to stdout as a JSON array of diagnostics with stable codes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey check <script.mk>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	check := checkFile
+	if *jsonDiagnostics {
+		check = checkFileDiagnostics
+	}
+	if !check(flags.Arg(0), os.Stdout) {
+		return 1
+	}
+	return 0
+}
+
+// checkFile parses the script at path and checks its type annotations
+// without running it, writing every error to out. It reports whether the
+// script passed.
+func checkFile(path string, out io.Writer) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
+	}
+
+	errors := typecheck.Check(program)
+	for _, err := range errors {
+		fmt.Fprintf(out, "%s:%d:%d: %s\n", path, err.Line, err.Column, err.Message)
+	}
+	return len(errors) == 0
+}
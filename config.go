@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+const (
+	// settingsFile holds the REPL settings. It is looked for in the working
+	// directory and then in the home directory.
+	settingsFile = "monkey.toml"
+
+	// rcFile, in the home directory, is evaluated when the REPL starts,
+	// after the files settingsFile preloads.
+	rcFile = ".monkeyrc"
+)
+
+// settings is what settingsFile may set. Settings it leaves out keep the
+// defaults, and the environment and flags override it in turn.
+type settings struct {
+	Prompt             string   `toml:"prompt"`
+	ContinuationPrompt string   `toml:"continuation_prompt"`
+	Banner             string   `toml:"banner"`
+	History            string   `toml:"history"`
+	Color              bool     `toml:"color"`
+	MaxCallDepth       int      `toml:"max_call_depth"`
+	Strict             bool     `toml:"strict"`
+	Preload            []string `toml:"preload"`
+}
+
+// loadSettings applies the first settingsFile found in dirs to config and
+// adds the rcFile in home, when there is one, to the files it preloads.
+// Preloaded paths are relative to the settings file they are listed in.
+func loadSettings(config *repl.Config, home string, dirs ...string) error {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, settingsFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		s := settings{
+			Prompt:             config.Prompt,
+			ContinuationPrompt: config.ContinuationPrompt,
+			Banner:             config.Banner,
+			History:            config.HistoryFile,
+			Color:              config.Color,
+			MaxCallDepth:       config.Evaluator.MaxCallDepth,
+			Strict:             config.Evaluator.StrictIndexing,
+		}
+		if _, err := toml.DecodeFile(path, &s); err != nil {
+			return fmt.Errorf("could not read %s: %s", path, err)
+		}
+
+		config.Prompt = s.Prompt
+		config.ContinuationPrompt = s.ContinuationPrompt
+		config.Banner = s.Banner
+		config.HistoryFile = s.History
+		config.Color = s.Color
+		config.Evaluator.MaxCallDepth = s.MaxCallDepth
+		config.Evaluator.StrictIndexing = s.Strict
+		for _, preload := range s.Preload {
+			if !filepath.IsAbs(preload) {
+				preload = filepath.Join(dir, preload)
+			}
+			config.Preload = append(config.Preload, preload)
+		}
+		break
+	}
+
+	if home != "" {
+		rc := filepath.Join(home, rcFile)
+		if _, err := os.Stat(rc); err == nil {
+			config.Preload = append(config.Preload, rc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+func TestLoadSettings(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+
+	settings := `prompt = "monkey> "
+color = true
+max_call_depth = 50
+preload = ["lib/helpers.mk", "/abs/other.mk"]
+`
+	if err := os.WriteFile(filepath.Join(project, "monkey.toml"), []byte(settings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "monkey.toml"), []byte(`banner = "ignored"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".monkeyrc"), []byte("let x = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := repl.DefaultConfig()
+	config.Banner = "hi\n"
+	config.Evaluator.MaxCallDepth = evaluator.DefaultMaxCallDepth
+	if err := loadSettings(&config, home, t.TempDir(), project, home); err != nil {
+		t.Fatalf("loadSettings failed: %s", err)
+	}
+
+	if config.Prompt != "monkey> " || config.Banner != "hi\n" || config.ContinuationPrompt != repl.CONTINUATION_PROMPT {
+		t.Errorf("wrong prompts. got prompt=%q banner=%q continuation=%q",
+			config.Prompt, config.Banner, config.ContinuationPrompt)
+	}
+	if !config.Color || config.Evaluator.MaxCallDepth != 50 || config.Evaluator.StrictIndexing {
+		t.Errorf("wrong settings. got color=%t max-call-depth=%d strict=%t",
+			config.Color, config.Evaluator.MaxCallDepth, config.Evaluator.StrictIndexing)
+	}
+	expected := []string{
+		filepath.Join(project, "lib", "helpers.mk"),
+		"/abs/other.mk",
+		filepath.Join(home, ".monkeyrc"),
+	}
+	if !reflect.DeepEqual(config.Preload, expected) {
+		t.Errorf("wrong preload. expected=%q, got=%q", expected, config.Preload)
+	}
+}
+
+func TestLoadSettingsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "monkey.toml"), []byte("prompt = "), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := repl.DefaultConfig()
+	if err := loadSettings(&config, "", dir); err == nil {
+		t.Errorf("expected an error for an invalid settings file")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/fcidade/monkey-lang/daemon"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/sessions"
+)
+
+func daemonCommand(args []string) int {
+	config := sessions.Config{Evaluator: evaluator.Config{MaxCallDepth: evaluator.DefaultMaxCallDepth}}
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	flags.DurationVar(&config.Limits.Timeout, "timeout", 0, "how long an evaluation may run, without limit when 0")
+	flags.IntVar(&config.Limits.MaxOutput, "max-output", 0, "how many bytes of what an evaluation puts are kept, without limit when 0")
+	flags.DurationVar(&config.Limits.IdleTimeout, "idle-timeout", time.Hour, "how long a session may go unused before it is dropped, never when 0")
+	flags.IntVar(&config.Limits.MaxSessions, "max-sessions", 0, "how many sessions may be open at once, without limit when 0")
+	flags.IntVar(&config.Evaluator.MaxCallDepth, "max-call-depth", config.Evaluator.MaxCallDepth, "how deeply functions may recurse")
+	unsandboxed := flags.Bool("unsandboxed", false, "enable every builtin, which gives whoever can connect the files, network and processes of the daemon")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey daemon [flags] [address]")
+		fmt.Fprintln(flags.Output(), "serves Monkey.Evaluate, Monkey.CreateSession, Monkey.DestroySession and")
+		fmt.Fprintln(flags.Output(), "Monkey.Bindings over JSON-RPC at address, localhost:7377 by default;")
+		fmt.Fprintln(flags.Output(), "connections are not authenticated, so code is sandboxed to puts unless -unsandboxed")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if !*unsandboxed {
+		config.Evaluator.Sandboxed = true
+		config.Evaluator.Capabilities = []evaluator.Capability{evaluator.Output}
+	}
+
+	address := "localhost:7377"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		address = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "serving JSON-RPC on %s\n", listener.Addr())
+	if err := daemon.Serve(listener, daemon.NewService(config)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
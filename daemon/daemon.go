@@ -0,0 +1,151 @@
+// Package daemon serves Monkey evaluation to other programs over JSON-RPC,
+// so they can hand scripts to one long-lived process instead of starting
+// the interpreter for each. Code runs either on its own or in a session,
+// which keeps its bindings from one evaluation to the next and shares none
+// with the other sessions.
+//
+// The service is registered with net/rpc as Monkey and speaks the JSON-RPC
+// of net/rpc/jsonrpc, one request an object such as
+//
+//	{"method": "Monkey.Evaluate", "params": [{"session": "...", "code": "1 + 2"}], "id": 1}
+package daemon
+
+import (
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/sessions"
+)
+
+// Service evaluates code for the RPC methods, in the sessions it manages.
+type Service struct {
+	sessions *sessions.Manager
+}
+
+// NewService returns a service whose sessions are configured and bounded
+// by config. Callers are not authenticated, so unless only trusted programs
+// can connect, config.Evaluator should be sandboxed, as `monkey daemon`
+// does unless it is run with -unsandboxed.
+func NewService(config sessions.Config) *Service {
+	return &Service{sessions: sessions.NewManager(config)}
+}
+
+// EvaluateArgs are the arguments of Monkey.Evaluate. Code runs in Session,
+// or in a session of its own that is dropped after it when Session is
+// empty.
+type EvaluateArgs struct {
+	Session string `json:"session"`
+	Code    string `json:"code"`
+}
+
+// EvaluateReply is what Monkey.Evaluate answers. Result is the Inspect of
+// the value of the code and Name what the session bound it to, such as _1;
+// both are empty when it has no value or failed.
+type EvaluateReply struct {
+	Stdout      string                  `json:"stdout"`
+	Truncated   bool                    `json:"truncated,omitempty"`
+	Result      string                  `json:"result,omitempty"`
+	Name        string                  `json:"name,omitempty"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// Evaluate evaluates the code of args. Code that fails to parse or run
+// still succeeds as a call, with the errors in the diagnostics of reply.
+func (s *Service) Evaluate(args EvaluateArgs, reply *EvaluateReply) error {
+	var result sessions.Result
+	if args.Session == "" {
+		result = s.sessions.Run(args.Code)
+	} else {
+		var err error
+		if result, err = s.sessions.Eval(args.Session, args.Code); err != nil {
+			return err
+		}
+	}
+
+	reply.Stdout, reply.Truncated, reply.Name = result.Output, result.Truncated, result.Name
+	if result.Value != nil {
+		reply.Result = result.Value.Inspect()
+	}
+	reply.Diagnostics = result.Diagnostics
+	if reply.Diagnostics == nil {
+		reply.Diagnostics = []diagnostic.Diagnostic{}
+	}
+	return nil
+}
+
+// CreateSessionArgs are the arguments of Monkey.CreateSession, which needs
+// none.
+type CreateSessionArgs struct{}
+
+// CreateSessionReply names the session Monkey.CreateSession created. The
+// name is random, so a caller can only reach the sessions it was given.
+type CreateSessionReply struct {
+	Session string `json:"session"`
+}
+
+// CreateSession creates a session with nothing bound but the prelude.
+func (s *Service) CreateSession(args CreateSessionArgs, reply *CreateSessionReply) error {
+	id, err := s.sessions.Create()
+	reply.Session = id
+	return err
+}
+
+// SessionArgs name the session of Monkey.DestroySession and Monkey.Bindings.
+type SessionArgs struct {
+	Session string `json:"session"`
+}
+
+// DestroySessionReply is what Monkey.DestroySession answers, which is
+// nothing.
+type DestroySessionReply struct{}
+
+// DestroySession drops a session, so its name no longer works. An
+// evaluation running in it finishes first.
+func (s *Service) DestroySession(args SessionArgs, reply *DestroySessionReply) error {
+	return s.sessions.Destroy(args.Session)
+}
+
+// Binding is a name a session bound, with the type and Inspect of its
+// value.
+type Binding struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// BindingsReply lists what Monkey.Bindings found, sorted by name.
+type BindingsReply struct {
+	Bindings []Binding `json:"bindings"`
+}
+
+// Bindings lists what the evaluations in a session bound, including the
+// names of their results such as _1.
+func (s *Service) Bindings(args SessionArgs, reply *BindingsReply) error {
+	bindings, err := s.sessions.Bindings(args.Session)
+	if err != nil {
+		return err
+	}
+	reply.Bindings = []Binding{}
+	for _, b := range bindings {
+		reply.Bindings = append(reply.Bindings, Binding{Name: b.Name, Type: string(b.Value.Type()), Value: b.Value.Inspect()})
+	}
+	return nil
+}
+
+// Serve answers the JSON-RPC calls of every connection listener accepts
+// with service, until accepting fails.
+func Serve(listener net.Listener, service *Service) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Monkey", service); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/sessions"
+)
+
+func dial(t *testing.T, service *Service) *rpc.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go Serve(listener, service)
+
+	client, err := jsonrpc.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestSessions(t *testing.T) {
+	client := dial(t, NewService(sessions.Config{}))
+
+	var a, b CreateSessionReply
+	if err := client.Call("Monkey.CreateSession", CreateSessionArgs{}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call("Monkey.CreateSession", CreateSessionArgs{}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Session == "" || a.Session == b.Session {
+		t.Fatalf("expected two sessions, got %q and %q", a.Session, b.Session)
+	}
+
+	var reply EvaluateReply
+	if err := client.Call("Monkey.Evaluate", EvaluateArgs{Session: a.Session, Code: "let x = 2; puts(x); x * 3"}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Stdout != "2\n" || reply.Result != "6" || reply.Name != "_1" || len(reply.Diagnostics) != 0 {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	// The sessions share nothing, and neither do evaluations outside them.
+	for _, session := range []string{b.Session, ""} {
+		reply = EvaluateReply{}
+		if err := client.Call("Monkey.Evaluate", EvaluateArgs{Session: session, Code: "x"}, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if len(reply.Diagnostics) != 1 || reply.Diagnostics[0].Code != diagnostic.IdentifierNotFound {
+			t.Errorf("expected x to be unbound in %q, got %+v", session, reply)
+		}
+	}
+
+	var bindings BindingsReply
+	if err := client.Call("Monkey.Bindings", SessionArgs{Session: a.Session}, &bindings); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Binding{{"_", "INTEGER", "6"}, {"_1", "INTEGER", "6"}, {"x", "INTEGER", "2"}}
+	if !reflect.DeepEqual(bindings.Bindings, expected) {
+		t.Errorf("expected bindings %+v, got %+v", expected, bindings.Bindings)
+	}
+
+	if err := client.Call("Monkey.DestroySession", SessionArgs{Session: a.Session}, &DestroySessionReply{}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call("Monkey.Evaluate", EvaluateArgs{Session: a.Session, Code: "x"}, &EvaluateReply{})
+	if err == nil || !strings.Contains(err.Error(), "there is no session") {
+		t.Errorf("expected the destroyed session to be gone, got %v", err)
+	}
+}
+
+func TestEvaluateTimeout(t *testing.T) {
+	client := dial(t, NewService(sessions.Config{Limits: sessions.Limits{Timeout: 50 * time.Millisecond}}))
+
+	var reply EvaluateReply
+	code := "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(50)"
+	if err := client.Call("Monkey.Evaluate", EvaluateArgs{Code: code}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Diagnostics) != 1 || reply.Diagnostics[0].Code != diagnostic.Interrupted {
+		t.Errorf("expected the evaluation to be interrupted, got %+v", reply)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/symbols"
+)
+
+func defsCommand(args []string) int {
+	flags := flag.NewFlagSet("defs", flag.ExitOnError)
+	at := flags.String("at", "", "print the definition of the identifier at `script.mk:line:column` instead")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey defs [flags] <dir> [name]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+
+	ix := symbols.New()
+	if err := ix.Load(flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "could not index %s: %s\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *at != "" {
+		if !printDefinition(ix, *at, os.Stdout) {
+			return 1
+		}
+		return 0
+	}
+
+	defs := ix.Symbols()
+	if flags.NArg() == 2 {
+		defs = ix.Lookup(flags.Arg(1))
+		if len(defs) == 0 {
+			fmt.Fprintf(os.Stderr, "%s is not defined in %s\n", flags.Arg(1), flags.Arg(0))
+			return 1
+		}
+	}
+	for _, def := range defs {
+		fmt.Println(def)
+	}
+	return 0
+}
+
+// printDefinition writes to out the definition of the identifier at, a
+// position written script.mk:line:column, and reports whether it has one.
+func printDefinition(ix *symbols.Index, at string, out io.Writer) bool {
+	parts := strings.Split(at, ":")
+	if len(parts) < 3 {
+		fmt.Fprintf(out, "invalid position %q: expected script.mk:line:column\n", at)
+		return false
+	}
+	n := len(parts)
+	line, err := strconv.Atoi(parts[n-2])
+	if err != nil {
+		fmt.Fprintf(out, "invalid position %q: expected script.mk:line:column\n", at)
+		return false
+	}
+	column, err := strconv.Atoi(parts[n-1])
+	if err != nil {
+		fmt.Fprintf(out, "invalid position %q: expected script.mk:line:column\n", at)
+		return false
+	}
+
+	def, ok := ix.Definition(filepath.Clean(strings.Join(parts[:n-2], ":")), line, column)
+	if !ok {
+		fmt.Fprintf(out, "no definition for %s\n", at)
+		return false
+	}
+	fmt.Fprintln(out, def)
+	return true
+}
@@ -0,0 +1,87 @@
+// Package diagnostic defines the stable codes Monkey gives its errors, so
+// editors and other tools can recognize a kind of error without matching
+// its message, and the form diagnostics take when written for them.
+package diagnostic
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Code identifies a kind of error. Codes never change meaning once
+// released; a kind of error that goes away leaves its code unused.
+type Code string
+
+// Runtime errors.
+const (
+	UnknownOperator         Code = "E0001"
+	IdentifierNotFound      Code = "E0002"
+	TypeMismatch            Code = "E0003"
+	DivisionByZero          Code = "E0004"
+	NotAFunction            Code = "E0005"
+	WrongArgumentCount      Code = "E0006"
+	IndexNotSupported       Code = "E0007"
+	UnusableHashKey         Code = "E0008"
+	FieldAccessNotSupported Code = "E0009"
+	CallDepthExceeded       Code = "E0010"
+	NotIterable             Code = "E0011"
+	RangeTooLong            Code = "E0012"
+	InvalidRepeat           Code = "E0013"
+	NoMatchingArm           Code = "E0014"
+	PatternMismatch         Code = "E0015"
+	FrozenValue             Code = "E0016"
+	InvalidArgument         Code = "E0017"
+	BuiltinFailed           Code = "E0018"
+	CapabilityDenied        Code = "E0019"
+	EvalParseError          Code = "E0020"
+	IndexOutOfRange         Code = "E0021"
+	KeyNotFound             Code = "E0022"
+	Interrupted             Code = "E0023"
+	TooManyElements         Code = "E0024"
+)
+
+// Parser errors.
+const (
+	UnexpectedToken Code = "E0101"
+	NoPrefixParse   Code = "E0102"
+	InvalidInteger  Code = "E0103"
+	InvalidDuration Code = "E0104"
+	ExpectedType    Code = "E0105"
+	ExpectedPattern Code = "E0106"
+	IllegalToken    Code = "E0107"
+	ReservedWord    Code = "E0108"
+	FeatureDisabled Code = "E0109"
+)
+
+// Type errors reported by the typecheck package.
+const (
+	TypeError Code = "E0201"
+)
+
+// Errors of the REPL's own commands, such as :save.
+const (
+	CommandFailed Code = "E0301"
+)
+
+// Diagnostic is an error as reported to tools.
+type Diagnostic struct {
+	// File is the script the error is in, empty when it did not come from
+	// a file.
+	File string `json:"file,omitempty"`
+	// Line and Column are where the error is, zero when it has no position.
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteJSON writes diagnostics to out as a JSON array, which is empty
+// rather than null when there are none.
+func WriteJSON(out io.Writer, diagnostics []Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diagnostics)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/optimize"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/typecheck"
+)
+
+// parseFileDiagnostics parses the script at path, returning the diagnostics
+// for its parser errors instead of a program when there are any.
+func parseFileDiagnostics(path string, language parser.Config) (*ast.Program, []diagnostic.Diagnostic, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := parser.NewWithConfig(lexer.New(string(content)), language)
+	program := p.ParseProgram()
+	diagnostics := p.Diagnostics()
+	for i := range diagnostics {
+		diagnostics[i].File = path
+	}
+	if len(diagnostics) != 0 {
+		return nil, diagnostics, nil
+	}
+	return program, nil, nil
+}
+
+// runFileDiagnostics is runFile for tools: it writes the errors of the
+// script as a JSON array of diagnostics to out, and anything else wrong to
+// stderr.
+func runFileDiagnostics(path string, ev *evaluator.Evaluator, env *object.Environment, level int, out io.Writer) bool {
+	program, diagnostics, err := parseFileDiagnostics(path, ev.Language())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	if program != nil {
+		evaluated := ev.Eval(optimize.Program(program, level), env)
+		if err, ok := evaluated.(*object.Error); ok {
+			diagnostics = append(diagnostics, diagnostic.Diagnostic{File: path, Line: err.Line, Column: err.Column, Code: err.Code, Message: err.Message})
+		}
+	}
+	diagnostic.WriteJSON(out, diagnostics)
+	return len(diagnostics) == 0
+}
+
+// checkFileDiagnostics is checkFile writing a JSON array of diagnostics.
+func checkFileDiagnostics(path string, out io.Writer) bool {
+	program, diagnostics, err := parseFileDiagnostics(path, parser.Config{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	if program != nil {
+		for _, d := range typecheck.Check(program) {
+			d.File = path
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	diagnostic.WriteJSON(out, diagnostics)
+	return len(diagnostics) == 0
+}
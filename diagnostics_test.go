@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/optimize"
+)
+
+func TestJSONDiagnostics(t *testing.T) {
+	tests := []struct {
+		script   string
+		check    bool
+		expected []diagnostic.Code
+	}{
+		{"let x = 5; x", false, []diagnostic.Code{}},
+		{"1 + true", false, []diagnostic.Code{diagnostic.TypeMismatch}},
+		{"let = 5;", false, []diagnostic.Code{diagnostic.UnexpectedToken, diagnostic.NoPrefixParse}},
+		{`let x: int = "a"; let y: bool = 1;`, true, []diagnostic.Code{diagnostic.TypeError, diagnostic.TypeError}},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "script.mk")
+		if err := os.WriteFile(path, []byte(tt.script), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		var ok bool
+		if tt.check {
+			ok = checkFileDiagnostics(path, &out)
+		} else {
+			ok = runFileDiagnostics(path, evaluator.New(evaluator.Config{}), object.NewEnvironment(), optimize.None, &out)
+		}
+
+		var diagnostics []diagnostic.Diagnostic
+		if err := json.Unmarshal(out.Bytes(), &diagnostics); err != nil {
+			t.Fatalf("%q: output is not JSON: %s\n%s", tt.script, err, out.String())
+		}
+		if ok != (len(tt.expected) == 0) {
+			t.Errorf("%q: wrong result %t", tt.script, ok)
+		}
+		if len(diagnostics) != len(tt.expected) {
+			t.Fatalf("%q: expected %d diagnostics, got %+v", tt.script, len(tt.expected), diagnostics)
+		}
+		for i, code := range tt.expected {
+			if diagnostics[i].Code != code || diagnostics[i].File != path || diagnostics[i].Message == "" {
+				t.Errorf("%q: wrong diagnostic %d. expected code %s, got %+v", tt.script, i, code, diagnostics[i])
+			}
+		}
+	}
+}
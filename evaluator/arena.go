@@ -0,0 +1,48 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+// arenaChunk is how many integers an arena allocates at once.
+const arenaChunk = 256
+
+// arena hands out integers from chunks allocated for many of them at once.
+// Integers are never modified, and a chunk is only appended to within its
+// capacity, so the integers handed out stay valid however long they live.
+// Releasing the arena only stops it handing out the rest of its chunk, which
+// is then collected once none of its integers are left in use.
+type arena struct {
+	integers []object.Integer
+}
+
+func (a *arena) integer(value int64) *object.Integer {
+	if value >= smallIntegerMin && value <= smallIntegerMax {
+		return smallIntegers[value-smallIntegerMin]
+	}
+	if len(a.integers) == cap(a.integers) {
+		a.integers = make([]object.Integer, 0, arenaChunk)
+	}
+	a.integers = append(a.integers, object.Integer{Value: value})
+	return &a.integers[len(a.integers)-1]
+}
+
+// arithmetic applies the arithmetic operator to left and right when they are
+// both integers, allocating the result in a, and returns nil otherwise.
+func (a *arena) arithmetic(left object.Object, operator string, right object.Object) object.Object {
+	l, ok := left.(*object.Integer)
+	if !ok {
+		return nil
+	}
+	r, ok := right.(*object.Integer)
+	if !ok {
+		return nil
+	}
+	if value, ok := integerArithmetic(l.Value, operator, r.Value); ok {
+		return a.integer(value)
+	}
+	return nil
+}
+
+// release starts a new chunk for the next integer.
+func (a *arena) release() {
+	a.integers = nil
+}
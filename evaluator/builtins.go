@@ -1,38 +1,96 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
+	"github.com/fcidade/monkey-lang/message"
 	"github.com/fcidade/monkey-lang/object"
 )
 
+// signatures shows how each builtin is called, for the errors reporting bad
+// arguments to it. Parameters without a type take any value; a trailing `?`
+// marks an optional parameter and `...` a variadic one.
+var signatures = map[string]string{
+	"len":      "len(value: STRING|ARRAY|TUPLE|BYTES)",
+	"first":    "first(array: ARRAY)",
+	"last":     "last(array: ARRAY)",
+	"rest":     "rest(array: ARRAY)",
+	"push":     "push(array: ARRAY, value)",
+	"pushMut":  "pushMut(array: ARRAY, value)",
+	"builder":  "builder(initial?: STRING)",
+	"append":   "append(builder: STRING_BUILDER, values...)",
+	"toString": "toString(value, base?: INTEGER)",
+	"puts":     "puts(values...)",
+}
+
+// argumentError reports that the builtin name cannot be called with args.
+// Every builtin reports bad arguments through it, so the message always
+// starts with the builtin's signature and ends with the types it got:
+//
+//	first(array: ARRAY): argument 1 must be ARRAY; called with (INTEGER)
+func argumentError(name string, args []object.Object, id message.ID, a ...interface{}) *object.Error {
+	signature, ok := signatures[name]
+	if !ok {
+		signature = name
+	}
+
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = string(arg.Type())
+	}
+	return newError(message.BuiltinArgument,
+		signature, message.Format(id, a...), strings.Join(types, ", "))
+}
+
+// checkArgumentCount fails unless the builtin name got exactly want args.
+func checkArgumentCount(name string, args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return argumentError(name, args, message.ArgumentCount, want)
+	}
+	return nil
+}
+
+// argumentTypeError reports that args[i] is not what the builtin name
+// expects there, which want describes.
+func argumentTypeError(name string, args []object.Object, i int, want string) *object.Error {
+	return argumentError(name, args, message.ArgumentType, i+1, want)
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArgumentCount("len", args, 1); err != nil {
+				return err
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{
-					Value: int64(len(arg.Value)),
-				}
+				// Strings are indexed by character, so they are measured
+				// in characters rather than bytes.
+				return integer(int64(utf8.RuneCountInString(arg.Value)))
 			case *object.Array:
-				return &object.Integer{
-					Value: int64(len(arg.Elements)),
-				}
+				return integer(int64(len(arg.Elements)))
+			case *object.Tuple:
+				return integer(int64(len(arg.Elements)))
+			case *object.Bytes:
+				return integer(int64(len(arg.Value)))
 			default:
-				return newError("argument to `len` not supported, got %s", arg.Type())
+				return argumentTypeError("len", args, 0, "STRING, ARRAY, TUPLE or BYTES")
 			}
 		},
 	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArgumentCount("first", args, 1); err != nil {
+				return err
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY got=%s", args[0].Type())
+				return argumentTypeError("first", args, 0, "ARRAY")
 			}
 
 			arr := args[0].(*object.Array)
@@ -45,12 +103,12 @@ var builtins = map[string]*object.Builtin{
 	},
 	"last": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArgumentCount("last", args, 1); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `last` must be ARRAY got=%s", args[0].Type())
+				return argumentTypeError("last", args, 0, "ARRAY")
 			}
 
 			arr := args[0].(*object.Array)
@@ -64,12 +122,12 @@ var builtins = map[string]*object.Builtin{
 	},
 	"rest": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArgumentCount("rest", args, 1); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY got=%s", args[0].Type())
+				return argumentTypeError("rest", args, 0, "ARRAY")
 			}
 
 			arr := args[0].(*object.Array)
@@ -83,14 +141,16 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	// push copies the array, so building an array of n elements with it
+	// takes O(n²) time. pushMut appends in place instead.
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			if err := checkArgumentCount("push", args, 2); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY got=%s", args[0].Type())
+				return argumentTypeError("push", args, 0, "ARRAY")
 			}
 
 			arr := args[0].(*object.Array)
@@ -101,12 +161,215 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
-	"puts": {
+	"pushMut": {
 		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+			if err := checkArgumentCount("pushMut", args, 2); err != nil {
+				return err
 			}
-			return NULL
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return argumentTypeError("pushMut", args, 0, "ARRAY")
+			}
+
+			arr := args[0].(*object.Array)
+			if arr.Frozen {
+				return newError(message.Frozen, object.ARRAY_OBJ)
+			}
+			arr.Elements = append(arr.Elements, args[1])
+			return arr
+		},
+	},
+	"builder": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return argumentError("builder", args, message.ArgumentCountEither, 0, 1)
+			}
+
+			sb := &object.StringBuilder{}
+			if len(args) == 1 {
+				if args[0].Type() != object.STRING_OBJ {
+					return argumentTypeError("builder", args, 0, "STRING")
+				}
+				sb.WriteString(args[0].(*object.String).Value)
+			}
+			return sb
+		},
+	},
+	"toString": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 2 {
+				n, ok := args[0].(*object.Integer)
+				if !ok {
+					return argumentTypeError("toString", args, 0, "INTEGER")
+				}
+				base, err := baseArgument("toString", args, 1)
+				if err != nil {
+					return err
+				}
+				return &object.String{Value: strconv.FormatInt(n.Value, base)}
+			}
+			if len(args) != 1 {
+				return argumentError("toString", args, message.ArgumentCountEither, 1, 2)
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+			return &object.String{Value: stringValue(args[0])}
 		},
 	},
 }
+
+// outputMu keeps the lines of evaluators writing to the same Output
+// concurrently from interleaving.
+var outputMu sync.Mutex
+
+// putsBuiltin writes each argument on a line of its own to the configured
+// Output.
+func (e *Evaluator) putsBuiltin(args ...object.Object) object.Object {
+	out := e.config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for _, arg := range args {
+		fmt.Fprintln(out, arg.Inspect())
+	}
+	return NULL
+}
+
+// evaluatorBuiltins returns the builtins that call back into e, for example to
+// apply a user function.
+func (e *Evaluator) evaluatorBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"puts":       {Fn: e.putsBuiltin},
+		"import":     {Fn: e.importBuiltin},
+		"version":    {Fn: e.versionBuiltin},
+		"printTable": {Fn: e.printTableBuiltin},
+		"dumpHeap":   {Fn: e.dumpHeapBuiltin},
+		"eval":       {Fn: e.evalBuiltin},
+		"sortBy":     {Fn: e.sortByBuiltin},
+		"pmap":       {Fn: e.pmapBuiltin},
+		"pfilter":    {Fn: e.pfilterBuiltin},
+		"async":      {Fn: e.asyncBuiltin},
+		"actor":      {Fn: e.actorBuiltin},
+		"ask":        {Fn: e.askBuiltin},
+
+		// These grow strings and arrays, up to MaxElements.
+		"repeat":          {Fn: e.repeatBuiltin},
+		"append":          {Fn: e.appendBuiltin},
+		"template":        {Fn: e.templateBuiltin},
+		"csvEncode":       {Fn: e.csvEncodeBuiltin},
+		"strings.join":    {Fn: e.joinBuiltin},
+		"strings.replace": {Fn: e.replaceBuiltin},
+		"padLeft":         {Fn: e.padBuiltin("padLeft")},
+		"padRight":        {Fn: e.padBuiltin("padRight")},
+		"center":          {Fn: e.padBuiltin("center")},
+
+		"random":     {Fn: e.randomBuiltin},
+		"seedRandom": {Fn: e.seedRandomBuiltin},
+
+		"logDebug": {Fn: e.logBuiltin("logDebug", LogDebug)},
+		"logInfo":  {Fn: e.logBuiltin("logInfo", LogInfo)},
+		"logWarn":  {Fn: e.logBuiltin("logWarn", LogWarn)},
+		"logError": {Fn: e.logBuiltin("logError", LogError)},
+	}
+}
+
+// errOutput is what writes to an output fail with once it has an error.
+var errOutput = errors.New("the output is stopped")
+
+// output is where the builtins whose result is only known to fit once it is
+// written build it. Writes that would take it past MaxElements bytes, or made
+// once e is interrupted, fail, leaving the error the builtin is to return in
+// err.
+type output struct {
+	strings.Builder
+	e   *Evaluator
+	err *object.Error
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if !o.grow(len(p)) {
+		return 0, errOutput
+	}
+	return o.Builder.Write(p)
+}
+
+func (o *output) WriteString(s string) (int, error) {
+	if !o.grow(len(s)) {
+		return 0, errOutput
+	}
+	return o.Builder.WriteString(s)
+}
+
+// grow reports whether o can take n more bytes.
+func (o *output) grow(n int) bool {
+	if o.err == nil {
+		if err := o.e.checkElements(object.STRING_OBJ, o.Len(), n); err != nil {
+			o.err = err
+		} else if o.e.isInterrupted() {
+			o.err = newError(message.Interrupted)
+		}
+	}
+	return o.err == nil
+}
+
+// appendBuiltin writes each of values to a builder as Inspect shows them.
+func (e *Evaluator) appendBuiltin(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return argumentError("append", args, message.ArgumentCountAtLeast, 1)
+	}
+
+	if args[0].Type() != object.STRING_BUILDER_OBJ {
+		return argumentTypeError("append", args, 0, "STRING_BUILDER")
+	}
+
+	sb := args[0].(*object.StringBuilder)
+	lengths := []int{sb.Len()}
+	values := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		values[i] = arg.Inspect()
+		lengths = append(lengths, len(values[i]))
+	}
+	if err := e.checkElements(object.STRING_BUILDER_OBJ, lengths...); err != nil {
+		return err
+	}
+	for _, value := range values {
+		sb.WriteString(value)
+	}
+	return sb
+}
+
+// hashOf returns a hash with a string key for each of pairs, for builtins
+// that return records.
+func hashOf(pairs map[string]object.Object) *object.Hash {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(pairs))}
+	for key, value := range pairs {
+		k := &object.String{Value: key}
+		hash.Pairs[k.HashKey()] = object.HashPair{Key: k, Value: value}
+	}
+	return hash
+}
+
+// stringValue is the text toString turns obj into: the contents of strings,
+// string builders and bytes, and what Inspect shows for anything else.
+func stringValue(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value
+	case *object.StringBuilder:
+		return obj.String()
+	case *object.Bytes:
+		return string(obj.Value)
+	default:
+		return obj.Inspect()
+	}
+}
+
+// hashValue returns the value hash has for the string key.
+func hashValue(hash *object.Hash, key string) (object.Object, bool) {
+	pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+	return pair.Value, ok
+}
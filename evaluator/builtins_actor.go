@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// mailboxSize is how many messages an actor can have queued before tell and
+// ask block.
+const mailboxSize = 64
+
+func init() {
+	builtins["tell"] = &object.Builtin{Fn: tellBuiltin}
+
+	signatures["actor"] = "actor(fn: FUNCTION)"
+	signatures["tell"] = "tell(actor: ACTOR, message)"
+	signatures["ask"] = "ask(actor: ACTOR, message)"
+}
+
+// actorBuiltin starts an actor calling fn with each message it receives, in
+// the order they arrive, on an Evaluator of its own. Messages and replies are
+// deep copied, so the actor and its senders never share mutable values. The
+// actor is a resource: it stops when closed, when the evaluation that
+// started it is torn down or when it is collected.
+func (e *Evaluator) actorBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("actor", args, 1); err != nil {
+		return err
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if len(fn.Parameters) != 1 {
+			return argumentTypeError("actor", args, 0, "a FUNCTION taking 1 parameter")
+		}
+	case *object.Builtin:
+	default:
+		return argumentTypeError("actor", args, 0, "FUNCTION")
+	}
+
+	fn := args[0]
+	shareEnvironment(fn)
+	actor := object.NewActor(mailboxSize)
+	worker := New(e.config)
+	worker.resources = e.resources
+	worker.mailbox = actor.Mailbox
+	// The goroutine holds the mailbox rather than the actor, so an actor
+	// the program drops can be collected and stop it.
+	mailbox, done := actor.Mailbox, actor.Done()
+	go func() {
+		for {
+			select {
+			case msg := <-mailbox:
+				result := worker.applyFunction(fn, []object.Object{msg.Value})
+				if msg.Reply != nil {
+					msg.Reply <- deepCopy(result, map[object.Object]object.Object{})
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return actor
+}
+
+// tellBuiltin sends a message without waiting for it to be handled. The
+// result of handling it, including any error, is dropped.
+func tellBuiltin(args ...object.Object) object.Object {
+	actor, err := actorArgs("tell", args)
+	if err != nil {
+		return err
+	}
+	if err := send("tell", actor, args, object.Message{Value: deepCopy(args[1], map[object.Object]object.Object{})}); err != nil {
+		return err
+	}
+	return NULL
+}
+
+// askBuiltin sends a message and waits for the actor's reply. An error while
+// handling the message is returned to the caller. An actor asking itself
+// would wait for a reply only it can give, so that fails instead.
+func (e *Evaluator) askBuiltin(args ...object.Object) object.Object {
+	actor, err := actorArgs("ask", args)
+	if err != nil {
+		return err
+	}
+	if e.mailbox != nil && e.mailbox == actor.Mailbox {
+		return argumentError("ask", args, message.ActorAsksItself)
+	}
+	reply := make(chan object.Object, 1)
+	msg := object.Message{Value: deepCopy(args[1], map[object.Object]object.Object{}), Reply: reply}
+	if err := send("ask", actor, args, msg); err != nil {
+		return err
+	}
+	select {
+	case result := <-reply:
+		return result
+	case <-actor.Done():
+		return argumentError("ask", args, message.ActorStopped)
+	}
+}
+
+// send puts msg in the mailbox of actor for the builtin name, failing if
+// the actor is stopped.
+func send(name string, actor *object.Actor, args []object.Object, msg object.Message) *object.Error {
+	if actor.Closed() {
+		return argumentError(name, args, message.ActorStopped)
+	}
+	select {
+	case actor.Mailbox <- msg:
+		return nil
+	case <-actor.Done():
+		return argumentError(name, args, message.ActorStopped)
+	}
+}
+
+func actorArgs(name string, args []object.Object) (*object.Actor, *object.Error) {
+	if err := checkArgumentCount(name, args, 2); err != nil {
+		return nil, err
+	}
+	actor, ok := args[0].(*object.Actor)
+	if !ok {
+		return nil, argumentTypeError(name, args, 0, "ACTOR")
+	}
+	return actor, nil
+}
@@ -0,0 +1,50 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+func init() {
+	builtins["await"] = &object.Builtin{Fn: awaitBuiltin}
+
+	signatures["async"] = "async(fn: FUNCTION)"
+	signatures["await"] = "await(future: FUTURE)"
+}
+
+// asyncBuiltin starts calling a function without arguments on its own
+// goroutine and returns a future for its result.
+func (e *Evaluator) asyncBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("async", args, 1); err != nil {
+		return err
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if len(fn.Parameters) != 0 {
+			return argumentTypeError("async", args, 0, "a FUNCTION taking 0 parameters")
+		}
+	case *object.Builtin:
+	default:
+		return argumentTypeError("async", args, 0, "FUNCTION")
+	}
+
+	fn := args[0]
+	shareEnvironment(fn)
+	future := object.NewFuture()
+	worker := e.fork()
+	go func() {
+		future.Resolve(worker.applyFunction(fn, nil))
+	}()
+	return future
+}
+
+// awaitBuiltin blocks until the future is resolved. An error from the
+// function is returned as is, so it propagates from the await call.
+func awaitBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("await", args, 1); err != nil {
+		return err
+	}
+	future, ok := args[0].(*object.Future)
+	if !ok {
+		return argumentTypeError("await", args, 0, "FUTURE")
+	}
+	return future.Wait()
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"encoding/hex"
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["bytes"] = &object.Builtin{Fn: bytesBuiltin}
+	builtins["slice"] = &object.Builtin{Fn: sliceBuiltin}
+	builtins["hexEncode"] = &object.Builtin{Fn: hexEncodeBuiltin}
+	builtins["hexDecode"] = &object.Builtin{Fn: hexDecodeBuiltin}
+
+	signatures["bytes"] = "bytes(value: STRING|BYTES|ARRAY)"
+	signatures["slice"] = "slice(value: ARRAY|BYTES|STRING, start: INTEGER, end?: INTEGER)"
+	signatures["hexEncode"] = "hexEncode(data: STRING|BYTES)"
+	signatures["hexDecode"] = "hexDecode(hex: STRING)"
+}
+
+func bytesBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("bytes", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Bytes{Value: []byte(arg.Value)}
+	case *object.Bytes:
+		value := make([]byte, len(arg.Value))
+		copy(value, arg.Value)
+		return &object.Bytes{Value: value}
+	case *object.Array:
+		value := make([]byte, len(arg.Elements))
+		for i, el := range arg.Elements {
+			n, ok := el.(*object.Integer)
+			if !ok || n.Value < 0 || n.Value > 255 {
+				return argumentError("bytes", args, message.ArgumentByteRange, 1, el.Inspect())
+			}
+			value[i] = byte(n.Value)
+		}
+		return &object.Bytes{Value: value}
+	default:
+		return argumentTypeError("bytes", args, 0, "STRING, BYTES or ARRAY")
+	}
+}
+
+// sliceBuiltin implements slice(x, start[, end]) for arrays, bytes and
+// strings. Strings are sliced by character rather than by byte.
+func sliceBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return argumentError("slice", args, message.ArgumentCountEither, 2, 3)
+	}
+
+	var length int
+	switch arg := args[0].(type) {
+	case *object.Array:
+		length = len(arg.Elements)
+	case *object.Bytes:
+		length = len(arg.Value)
+	case *object.String:
+		length = utf8.RuneCountInString(arg.Value)
+	default:
+		return argumentTypeError("slice", args, 0, "ARRAY, BYTES or STRING")
+	}
+
+	bounds := []int{0, length}
+	for i, arg := range args[1:] {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return argumentTypeError("slice", args, i+1, "INTEGER")
+		}
+		bounds[i] = int(n.Value)
+	}
+	start, end := bounds[0], bounds[1]
+	if start < 0 || end < start || end > length {
+		return argumentError("slice", args, message.SliceBounds, start, end, length)
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		elements := make([]object.Object, end-start)
+		copy(elements, arg.Elements[start:end])
+		return &object.Array{Elements: elements}
+	case *object.Bytes:
+		value := make([]byte, end-start)
+		copy(value, arg.Value[start:end])
+		return &object.Bytes{Value: value}
+	default:
+		runes := []rune(arg.(*object.String).Value)
+		return &object.String{Value: string(runes[start:end])}
+	}
+}
+
+func hexEncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("hexEncode", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("hexEncode", args)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: hex.EncodeToString(data)}
+}
+
+// binaryArg returns the raw contents of the first argument to the builtin
+// name, which must be a STRING or BYTES.
+func binaryArg(name string, args []object.Object) ([]byte, *object.Error) {
+	switch arg := args[0].(type) {
+	case *object.String:
+		return []byte(arg.Value), nil
+	case *object.Bytes:
+		return arg.Value, nil
+	default:
+		return nil, argumentTypeError(name, args, 0, "STRING or BYTES")
+	}
+}
+
+func hexDecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("hexDecode", args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() != object.STRING_OBJ {
+		return argumentTypeError("hexDecode", args, 0, "STRING")
+	}
+
+	value, err := hex.DecodeString(args[0].(*object.String).Value)
+	if err != nil {
+		return argumentError("hexDecode", args, message.DecodeFailed, "hex", err)
+	}
+	return &object.Bytes{Value: value}
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["ord"] = &object.Builtin{Fn: ordBuiltin}
+	builtins["chr"] = &object.Builtin{Fn: chrBuiltin}
+
+	signatures["ord"] = "ord(char: CHAR)"
+	signatures["chr"] = "chr(codePoint: INTEGER)"
+}
+
+// ordBuiltin returns the code point of a character.
+func ordBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("ord", args, 1); err != nil {
+		return err
+	}
+	ch, ok := args[0].(*object.Char)
+	if !ok {
+		return argumentTypeError("ord", args, 0, "CHAR")
+	}
+	return integer(int64(ch.Value))
+}
+
+// chrBuiltin returns the character with a code point, the reverse of ord.
+func chrBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("chr", args, 1); err != nil {
+		return err
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("chr", args, 0, "INTEGER")
+	}
+	if n.Value < 0 || n.Value > utf8.MaxRune || !utf8.ValidRune(rune(n.Value)) {
+		return argumentError("chr", args, message.NotCodePoint, n.Value)
+	}
+	return &object.Char{Value: rune(n.Value)}
+}
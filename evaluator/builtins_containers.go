@@ -0,0 +1,176 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["clone"] = &object.Builtin{Fn: cloneBuiltin}
+	builtins["freeze"] = &object.Builtin{Fn: freezeBuiltin}
+	builtins["isFrozen"] = &object.Builtin{Fn: isFrozenBuiltin}
+	builtins["get"] = &object.Builtin{Fn: getBuiltin}
+	builtins["fetch"] = &object.Builtin{Fn: fetchBuiltin}
+
+	signatures["clone"] = "clone(value)"
+	signatures["freeze"] = "freeze(value)"
+	signatures["isFrozen"] = "isFrozen(value)"
+	signatures["get"] = "get(hash: HASH, key, default)"
+	signatures["fetch"] = "fetch(array: ARRAY|TUPLE, index: INTEGER, default)"
+}
+
+// getBuiltin returns the value hash has for key, or default when it has
+// none. Unlike indexing, it tells a missing key from one holding null.
+func getBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("get", args, 3); err != nil {
+		return err
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return argumentTypeError("get", args, 0, "HASH")
+	}
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return newError(message.UnusableHashKey, args[1].Type())
+	}
+
+	if pair, ok := hash.Pairs[key.HashKey()]; ok {
+		return pair.Value
+	}
+	return args[2]
+}
+
+// fetchBuiltin returns the element at index, or default when index is out
+// of range.
+func fetchBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("fetch", args, 3); err != nil {
+		return err
+	}
+	var elements []object.Object
+	switch arg := args[0].(type) {
+	case *object.Array:
+		elements = arg.Elements
+	case *object.Tuple:
+		elements = arg.Elements
+	default:
+		return argumentTypeError("fetch", args, 0, "ARRAY|TUPLE")
+	}
+	index, ok := args[1].(*object.Integer)
+	if !ok {
+		return argumentTypeError("fetch", args, 1, "INTEGER")
+	}
+
+	if index.Value < 0 || index.Value >= int64(len(elements)) {
+		return args[2]
+	}
+	return elements[index.Value]
+}
+
+func cloneBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("clone", args, 1); err != nil {
+		return err
+	}
+	return deepCopy(args[0], map[object.Object]object.Object{})
+}
+
+func freezeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("freeze", args, 1); err != nil {
+		return err
+	}
+	deepFreeze(args[0])
+	return args[0]
+}
+
+func isFrozenBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("isFrozen", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		return boolean(arg.Frozen)
+	case *object.Hash:
+		return boolean(arg.Frozen)
+	default:
+		return FALSE
+	}
+}
+
+// deepCopy returns an unfrozen copy of obj where every array, hash, builder
+// and byte string reachable from it is copied as well. copies maps the
+// containers already copied to their copy so that shared and self-referencing
+// values keep their shape.
+func deepCopy(obj object.Object, copies map[object.Object]object.Object) object.Object {
+	if c, ok := copies[obj]; ok {
+		return c
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		c := &object.Array{Elements: make([]object.Object, len(obj.Elements))}
+		copies[obj] = c
+		for i, el := range obj.Elements {
+			c.Elements[i] = deepCopy(el, copies)
+		}
+		return c
+
+	case *object.Hash:
+		c := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(obj.Pairs))}
+		copies[obj] = c
+		for key, pair := range obj.Pairs {
+			c.Pairs[key] = object.HashPair{
+				Key:   deepCopy(pair.Key, copies),
+				Value: deepCopy(pair.Value, copies),
+			}
+		}
+		return c
+
+	case *object.Tuple:
+		c := &object.Tuple{Elements: make([]object.Object, len(obj.Elements))}
+		copies[obj] = c
+		for i, el := range obj.Elements {
+			c.Elements[i] = deepCopy(el, copies)
+		}
+		return c
+
+	case *object.StringBuilder:
+		c := &object.StringBuilder{}
+		c.WriteString(obj.String())
+		return c
+
+	case *object.Bytes:
+		value := make([]byte, len(obj.Value))
+		copy(value, obj.Value)
+		return &object.Bytes{Value: value}
+
+	default:
+		return obj
+	}
+}
+
+func deepFreeze(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, el := range obj.Elements {
+			deepFreeze(el)
+		}
+
+	case *object.Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, pair := range obj.Pairs {
+			deepFreeze(pair.Value)
+		}
+
+	case *object.Tuple:
+		for _, el := range obj.Elements {
+			deepFreeze(el)
+		}
+	}
+}
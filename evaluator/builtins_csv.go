@@ -0,0 +1,176 @@
+package evaluator
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["csvParse"] = &object.Builtin{Fn: csvParseBuiltin}
+
+	signatures["csvParse"] = "csvParse(text: STRING, options?: HASH)"
+	signatures["csvEncode"] = "csvEncode(rows: ARRAY, options?: HASH)"
+}
+
+// csvOptions are the options csvParse and csvEncode take in a hash:
+// "separator", a one character string such as "\t" for TSV, and "header".
+type csvOptions struct {
+	separator rune
+	header    object.Object
+}
+
+// csvArgs checks the arguments of the builtin name, whose first argument
+// must be of type want, and returns its options.
+func csvArgs(name string, args []object.Object, want object.ObjectType) (csvOptions, *object.Error) {
+	options := csvOptions{separator: ','}
+	if len(args) != 1 && len(args) != 2 {
+		return options, argumentError(name, args, message.ArgumentCountEither, 1, 2)
+	}
+	if args[0].Type() != want {
+		return options, argumentTypeError(name, args, 0, string(want))
+	}
+	if len(args) == 1 {
+		return options, nil
+	}
+
+	hash, ok := args[1].(*object.Hash)
+	if !ok {
+		return options, argumentTypeError(name, args, 1, "HASH")
+	}
+	for _, pair := range hash.Pairs {
+		key, _ := pair.Key.(*object.String)
+		switch {
+		case key != nil && key.Value == "separator":
+			separator, ok := pair.Value.(*object.String)
+			if !ok || utf8.RuneCountInString(separator.Value) != 1 {
+				return options, argumentError(name, args, message.SingleCharacter, "separator")
+			}
+			options.separator, _ = utf8.DecodeRuneInString(separator.Value)
+		case key != nil && key.Value == "header":
+			options.header = pair.Value
+		default:
+			return options, argumentError(name, args, message.UnknownOption, pair.Key.Inspect())
+		}
+	}
+	return options, nil
+}
+
+// csvParseBuiltin returns the records of text as arrays of strings. With the
+// header option set to true the first record names the fields, and each of
+// the other records becomes a hash from those names to its fields.
+func csvParseBuiltin(args ...object.Object) object.Object {
+	options, err := csvArgs("csvParse", args, object.STRING_OBJ)
+	if err != nil {
+		return err
+	}
+	header := false
+	if options.header != nil {
+		b, ok := options.header.(*object.Boolean)
+		if !ok {
+			return argumentError("csvParse", args, message.OptionType, "header", "a BOOLEAN")
+		}
+		header = b.Value
+	}
+
+	r := csv.NewReader(strings.NewReader(args[0].(*object.String).Value))
+	r.Comma = options.separator
+
+	var names []string
+	rows := []object.Object{}
+	for {
+		record, readErr := r.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return newError(message.BuiltinFailed, "csvParse", readErr)
+		}
+
+		switch {
+		case header && names == nil:
+			names = record
+		case header:
+			row := make(map[string]object.Object, len(record))
+			for i, field := range record {
+				row[names[i]] = &object.String{Value: field}
+			}
+			rows = append(rows, hashOf(row))
+		default:
+			fields := make([]object.Object, len(record))
+			for i, field := range record {
+				fields[i] = &object.String{Value: field}
+			}
+			rows = append(rows, &object.Array{Elements: fields})
+		}
+	}
+	return &object.Array{Elements: rows}
+}
+
+// csvEncodeBuiltin writes rows as CSV, quoting fields where needed. Rows are
+// arrays of values, which are written like toString shows them. When the
+// header option is an array of names it is written first, and rows may also
+// be hashes, whose values are written in the order of the names.
+func (e *Evaluator) csvEncodeBuiltin(args ...object.Object) object.Object {
+	options, err := csvArgs("csvEncode", args, object.ARRAY_OBJ)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if options.header != nil {
+		header, ok := options.header.(*object.Array)
+		if !ok {
+			return argumentError("csvEncode", args, message.OptionType, "header", "an ARRAY")
+		}
+		names = make([]string, len(header.Elements))
+		for i, name := range header.Elements {
+			names[i] = stringValue(name)
+		}
+	}
+
+	out := &output{e: e}
+	w := csv.NewWriter(out)
+	w.Comma = options.separator
+	if names != nil {
+		w.Write(names)
+	}
+
+	for i, row := range args[0].(*object.Array).Elements {
+		var record []string
+		switch row := row.(type) {
+		case *object.Array:
+			record = make([]string, len(row.Elements))
+			for j, value := range row.Elements {
+				record[j] = stringValue(value)
+			}
+		case *object.Hash:
+			if names == nil {
+				return argumentError("csvEncode", args, message.RowNeedsHeader, i)
+			}
+			record = make([]string, len(names))
+			for j, name := range names {
+				if value, ok := hashValue(row, name); ok {
+					record[j] = stringValue(value)
+				}
+			}
+		default:
+			return argumentError("csvEncode", args, message.RowType, i, row.Type())
+		}
+		w.Write(record)
+	}
+
+	w.Flush()
+	if out.err != nil {
+		return out.err
+	}
+	if flushErr := w.Error(); flushErr != nil {
+		return newError(message.BuiltinFailed, "csvEncode", flushErr)
+	}
+	return &object.String{Value: out.String()}
+}
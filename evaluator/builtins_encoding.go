@@ -0,0 +1,106 @@
+package evaluator
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["sha256"] = &object.Builtin{Fn: sha256Builtin}
+	builtins["md5"] = &object.Builtin{Fn: md5Builtin}
+	builtins["base64Encode"] = &object.Builtin{Fn: base64EncodeBuiltin}
+	builtins["base64Decode"] = &object.Builtin{Fn: base64DecodeBuiltin}
+	builtins["urlEncode"] = &object.Builtin{Fn: urlEncodeBuiltin}
+	builtins["urlDecode"] = &object.Builtin{Fn: urlDecodeBuiltin}
+
+	signatures["sha256"] = "sha256(data: STRING|BYTES)"
+	signatures["md5"] = "md5(data: STRING|BYTES)"
+	signatures["base64Encode"] = "base64Encode(data: STRING|BYTES)"
+	signatures["base64Decode"] = "base64Decode(data: STRING|BYTES)"
+	signatures["urlEncode"] = "urlEncode(data: STRING|BYTES)"
+	signatures["urlDecode"] = "urlDecode(data: STRING|BYTES)"
+}
+
+func sha256Builtin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("sha256", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("sha256", args)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	return &object.Bytes{Value: sum[:]}
+}
+
+func md5Builtin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("md5", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("md5", args)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	return &object.Bytes{Value: sum[:]}
+}
+
+func base64EncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("base64Encode", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("base64Encode", args)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: base64.StdEncoding.EncodeToString(data)}
+}
+
+func base64DecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("base64Decode", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("base64Decode", args)
+	if err != nil {
+		return err
+	}
+
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, decodeErr := base64.StdEncoding.Decode(decoded, data)
+	if decodeErr != nil {
+		return argumentError("base64Decode", args, message.DecodeFailed, "base64", decodeErr)
+	}
+	return &object.Bytes{Value: decoded[:n]}
+}
+
+func urlEncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("urlEncode", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("urlEncode", args)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: url.QueryEscape(string(data))}
+}
+
+func urlDecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("urlDecode", args, 1); err != nil {
+		return err
+	}
+	data, err := binaryArg("urlDecode", args)
+	if err != nil {
+		return err
+	}
+
+	decoded, decodeErr := url.QueryUnescape(string(data))
+	if decodeErr != nil {
+		return argumentError("urlDecode", args, message.DecodeFailed, "url", decodeErr)
+	}
+	return &object.String{Value: decoded}
+}
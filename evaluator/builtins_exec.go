@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["exec"] = &object.Builtin{Fn: execBuiltin}
+	builtins["shell"] = &object.Builtin{Fn: shellBuiltin}
+
+	signatures["exec"] = "exec(command: STRING, args...: STRING)"
+	signatures["shell"] = "shell(command: STRING)"
+
+	capabilities["exec"] = Proc
+	capabilities["shell"] = Proc
+}
+
+// execBuiltin runs a program and waits for it, returning a hash with what it
+// wrote to stdout and stderr and its exitCode. A program that fails still
+// returns the hash; only one that cannot be started is an error. Given a
+// database instead of a command, it runs a statement in the database.
+func execBuiltin(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return argumentError("exec", args, message.ArgumentCountAtLeast, 1)
+	}
+	if args[0].Type() == object.DATABASE_OBJ {
+		return execDatabase(args)
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return argumentTypeError("exec", args, i, "STRING")
+		}
+		strs[i] = str.Value
+	}
+	return runProcess("exec", exec.Command(strs[0], strs[1:]...))
+}
+
+// shellBuiltin runs command with sh, so it may use pipes and redirections.
+func shellBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("shell", args, 1); err != nil {
+		return err
+	}
+	command, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("shell", args, 0, "STRING")
+	}
+	return runProcess("shell", exec.Command("sh", "-c", command.Value))
+}
+
+func runProcess(name string, cmd *exec.Cmd) object.Object {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return newError(message.BuiltinFailed, name, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return hashOf(map[string]object.Object{
+		"stdout":   &object.String{Value: stdout.String()},
+		"stderr":   &object.String{Value: stderr.String()},
+		"exitCode": integer(int64(exitCode)),
+	})
+}
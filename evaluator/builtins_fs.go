@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"os"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["fs.readFile"] = &object.Builtin{Fn: readFileBuiltin}
+	builtins["fs.writeFile"] = &object.Builtin{Fn: writeFileBuiltin}
+
+	signatures["fs.readFile"] = "fs.readFile(path: STRING, as?: STRING)"
+	signatures["fs.writeFile"] = "fs.writeFile(path: STRING, data: STRING|BYTES)"
+
+	capabilities["fs.readFile"] = FS
+	capabilities["fs.writeFile"] = FS
+}
+
+// readFileBuiltin returns the content of the file at path as a STRING, or
+// as BYTES when as is "bytes", for files that are not text. The STRING holds
+// the content as it is, even when it is not valid UTF-8.
+func readFileBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("fs.readFile", args, message.ArgumentCountEither, 1, 2)
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("fs.readFile", args, 0, "STRING")
+	}
+	asBytes := false
+	if len(args) == 2 {
+		as, ok := args[1].(*object.String)
+		if !ok {
+			return argumentTypeError("fs.readFile", args, 1, "STRING")
+		}
+		switch as.Value {
+		case "string":
+		case "bytes":
+			asBytes = true
+		default:
+			return argumentError("fs.readFile", args, message.OptionType, "as", `"string" or "bytes"`)
+		}
+	}
+
+	content, err := os.ReadFile(path.Value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "fs.readFile", err)
+	}
+	if asBytes {
+		return &object.Bytes{Value: content}
+	}
+	return &object.String{Value: string(content)}
+}
+
+// writeFileBuiltin replaces the content of the file at path with data,
+// creating the file when it does not exist.
+func writeFileBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("fs.writeFile", args, 2); err != nil {
+		return err
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("fs.writeFile", args, 0, "STRING")
+	}
+
+	var data []byte
+	switch arg := args[1].(type) {
+	case *object.String:
+		data = []byte(arg.Value)
+	case *object.Bytes:
+		data = arg.Value
+	default:
+		return argumentTypeError("fs.writeFile", args, 1, "STRING|BYTES")
+	}
+
+	if err := os.WriteFile(path.Value, data, 0o644); err != nil {
+		return newError(message.BuiltinFailed, "fs.writeFile", err)
+	}
+	return NULL
+}
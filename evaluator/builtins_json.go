@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["json.decode"] = &object.Builtin{Fn: jsonDecodeBuiltin}
+	builtins["json.encode"] = &object.Builtin{Fn: jsonEncodeBuiltin}
+
+	signatures["json.decode"] = "json.decode(text: STRING)"
+	signatures["json.encode"] = "json.encode(value)"
+}
+
+// jsonDecodeBuiltin returns the value the JSON document in text holds.
+// Objects become hashes and arrays arrays; Monkey has no floats, so only
+// whole numbers are allowed.
+func jsonDecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("json.decode", args, 1); err != nil {
+		return err
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("json.decode", args, 0, "STRING")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text.Value))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return newError(message.BuiltinFailed, "json.decode", err)
+	}
+	value, err := jsonIntegers(value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "json.decode", err)
+	}
+	obj, err := fromGo(value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "json.decode", err)
+	}
+	return obj
+}
+
+// jsonIntegers replaces the numbers in value with int64s, failing for the
+// ones that are not whole.
+func jsonIntegers(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case json.Number:
+		n, err := value.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an integer", value)
+		}
+		return n, nil
+	case []interface{}:
+		for i, element := range value {
+			element, err := jsonIntegers(element)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = element
+		}
+	case map[string]interface{}:
+		for key, element := range value {
+			element, err := jsonIntegers(element)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = element
+		}
+	}
+	return value, nil
+}
+
+// jsonEncodeBuiltin writes value as compact JSON, with the keys of objects
+// sorted. Hashes need string keys, and bytes are written in base64.
+func jsonEncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("json.encode", args, 1); err != nil {
+		return err
+	}
+
+	value, err := toGo(args[0])
+	if err != nil {
+		return newError(message.BuiltinFailed, "json.encode", err)
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return newError(message.BuiltinFailed, "json.encode", err)
+	}
+	return &object.String{Value: strings.TrimSuffix(out.String(), "\n")}
+}
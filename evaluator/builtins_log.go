@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// LogLevel is how important a log message is. The zero value is LogInfo.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota - 1
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogDebug: "debug",
+	LogInfo:  "info",
+	LogWarn:  "warn",
+	LogError: "error",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel returns the level called name, such as "warn".
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, levelName := range logLevelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// logMu keeps the lines of evaluators logging concurrently, such as those
+// forked by parallel builtins, from interleaving.
+var logMu sync.Mutex
+
+func init() {
+	for _, name := range []string{"logDebug", "logInfo", "logWarn", "logError"} {
+		signatures[name] = name + "(message, fields?: HASH)"
+	}
+}
+
+// logBuiltin returns a builtin writing a message at level, followed by the
+// pairs of an optional hash of fields, to the configured LogOutput. Messages
+// below the configured LogLevel are dropped.
+func (e *Evaluator) logBuiltin(name string, level LogLevel) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return argumentError(name, args, message.ArgumentCountEither, 1, 2)
+		}
+		var fields *object.Hash
+		if len(args) == 2 {
+			hash, ok := args[1].(*object.Hash)
+			if !ok {
+				return argumentTypeError(name, args, 1, "HASH")
+			}
+			fields = hash
+		}
+		if level < e.config.LogLevel {
+			return NULL
+		}
+
+		var line string
+		if e.config.LogJSON {
+			line = jsonLogLine(level, args[0], fields)
+		} else {
+			line = textLogLine(level, args[0], fields)
+		}
+
+		out := e.config.LogOutput
+		if out == nil {
+			out = os.Stderr
+		}
+		logMu.Lock()
+		defer logMu.Unlock()
+		fmt.Fprintln(out, line)
+		return NULL
+	}
+}
+
+// sortedFields returns the pairs of fields ordered by how their keys show.
+func sortedFields(fields *object.Hash) []object.HashPair {
+	if fields == nil {
+		return nil
+	}
+	pairs := make([]object.HashPair, 0, len(fields.Pairs))
+	for _, pair := range fields.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return stringValue(pairs[i].Key) < stringValue(pairs[j].Key)
+	})
+	return pairs
+}
+
+// textLogLine formats a message as `WARN disk almost full free=3 path=/`,
+// quoting values that would otherwise be ambiguous.
+func textLogLine(level LogLevel, message object.Object, fields *object.Hash) string {
+	var out strings.Builder
+	out.WriteString(strings.ToUpper(level.String()))
+	out.WriteString(" ")
+	out.WriteString(stringValue(message))
+	for _, pair := range sortedFields(fields) {
+		value := stringValue(pair.Value)
+		if value == "" || strings.ContainsAny(value, " =\"\n") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&out, " %s=%s", stringValue(pair.Key), value)
+	}
+	return out.String()
+}
+
+// jsonLogLine formats a message as a JSON object with the level, the message
+// as msg and the fields. Values JSON cannot represent are written as toString
+// shows them.
+func jsonLogLine(level LogLevel, message object.Object, fields *object.Hash) string {
+	var out strings.Builder
+	msg, _ := json.Marshal(stringValue(message))
+	fmt.Fprintf(&out, `{"level":%q,"msg":%s`, level, msg)
+	for _, pair := range sortedFields(fields) {
+		key, _ := json.Marshal(stringValue(pair.Key))
+		fmt.Fprintf(&out, ",%s:%s", key, jsonValue(pair.Value))
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func jsonValue(obj object.Object) string {
+	if value, err := toGo(obj); err == nil {
+		if encoded, err := json.Marshal(value); err == nil {
+			return string(encoded)
+		}
+	}
+	encoded, _ := json.Marshal(stringValue(obj))
+	return string(encoded)
+}
@@ -0,0 +1,227 @@
+package evaluator
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// defaultReadSize is how many bytes read returns at most when not told.
+const defaultReadSize = 4096
+
+func init() {
+	builtins["tcpConnect"] = &object.Builtin{Fn: tcpConnectBuiltin}
+	builtins["tcpListen"] = &object.Builtin{Fn: tcpListenBuiltin}
+	builtins["accept"] = &object.Builtin{Fn: acceptBuiltin}
+	builtins["port"] = &object.Builtin{Fn: portBuiltin}
+	builtins["read"] = &object.Builtin{Fn: readBuiltin}
+	builtins["readLine"] = &object.Builtin{Fn: readLineBuiltin}
+	builtins["write"] = &object.Builtin{Fn: writeBuiltin}
+	builtins["close"] = &object.Builtin{Fn: closeBuiltin}
+
+	signatures["tcpConnect"] = "tcpConnect(host: STRING, port: INTEGER)"
+	signatures["tcpListen"] = "tcpListen(port: INTEGER)"
+	signatures["accept"] = "accept(listener: LISTENER)"
+	signatures["port"] = "port(listener: LISTENER)"
+	signatures["read"] = "read(connection: CONNECTION, max?: INTEGER)"
+	signatures["readLine"] = "readLine(connection: CONNECTION)"
+	signatures["write"] = "write(connection: CONNECTION, data: STRING|BYTES)"
+	signatures["close"] = "close(value: CONNECTION|LISTENER|DATABASE|ACTOR)"
+
+	// The other builtins need a connection or listener made by these first.
+	capabilities["tcpConnect"] = Net
+	capabilities["tcpListen"] = Net
+}
+
+// portArg returns args[i] if it is a valid port number.
+func portArg(name string, args []object.Object, i int) (int, *object.Error) {
+	port, ok := args[i].(*object.Integer)
+	if !ok {
+		return 0, argumentTypeError(name, args, i, "INTEGER")
+	}
+	if port.Value < 0 || port.Value > 65535 {
+		return 0, argumentError(name, args, message.OutOfRange, "port", 0, 65535, port.Value)
+	}
+	return int(port.Value), nil
+}
+
+func tcpConnectBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("tcpConnect", args, 2); err != nil {
+		return err
+	}
+	host, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("tcpConnect", args, 0, "STRING")
+	}
+	port, err := portArg("tcpConnect", args, 1)
+	if err != nil {
+		return err
+	}
+
+	conn, dialErr := net.Dial("tcp", net.JoinHostPort(host.Value, strconv.Itoa(port)))
+	if dialErr != nil {
+		return newError(message.BuiltinFailed, "tcpConnect", dialErr)
+	}
+	return object.NewConnection(conn)
+}
+
+// tcpListenBuiltin listens on port on every interface. Port 0 picks a free
+// port, which port tells.
+func tcpListenBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("tcpListen", args, 1); err != nil {
+		return err
+	}
+	port, err := portArg("tcpListen", args, 0)
+	if err != nil {
+		return err
+	}
+
+	listener, listenErr := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if listenErr != nil {
+		return newError(message.BuiltinFailed, "tcpListen", listenErr)
+	}
+	return object.NewListener(listener)
+}
+
+// listenerArg returns the only argument of the builtin name, which must be a
+// listener.
+func listenerArg(name string, args []object.Object) (*object.Listener, *object.Error) {
+	if err := checkArgumentCount(name, args, 1); err != nil {
+		return nil, err
+	}
+	listener, ok := args[0].(*object.Listener)
+	if !ok {
+		return nil, argumentTypeError(name, args, 0, "LISTENER")
+	}
+	return listener, nil
+}
+
+// acceptBuiltin waits for the next connection to listener.
+func acceptBuiltin(args ...object.Object) object.Object {
+	listener, err := listenerArg("accept", args)
+	if err != nil {
+		return err
+	}
+	conn, acceptErr := listener.Listener.Accept()
+	if acceptErr != nil {
+		return newError(message.BuiltinFailed, "accept", acceptErr)
+	}
+	return object.NewConnection(conn)
+}
+
+func portBuiltin(args ...object.Object) object.Object {
+	listener, err := listenerArg("port", args)
+	if err != nil {
+		return err
+	}
+	return integer(int64(listener.Listener.Addr().(*net.TCPAddr).Port))
+}
+
+// readBuiltin waits for data on a connection and returns up to max bytes of
+// it as a string, or null once the other side has closed the connection.
+func readBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("read", args, message.ArgumentCountEither, 1, 2)
+	}
+	conn, ok := args[0].(*object.Connection)
+	if !ok {
+		return argumentTypeError("read", args, 0, "CONNECTION")
+	}
+	max := int64(defaultReadSize)
+	if len(args) == 2 {
+		n, ok := args[1].(*object.Integer)
+		if !ok {
+			return argumentTypeError("read", args, 1, "INTEGER")
+		}
+		if n.Value <= 0 || n.Value > maxRepeatLength {
+			return argumentError("read", args, message.OutOfRange, "max", 1, maxRepeatLength, n.Value)
+		}
+		max = n.Value
+	}
+
+	buf := make([]byte, max)
+	n, readErr := conn.Reader.Read(buf)
+	if errors.Is(readErr, io.EOF) {
+		return NULL
+	}
+	if readErr != nil {
+		return newError(message.BuiltinFailed, "read", readErr)
+	}
+	return &object.String{Value: string(buf[:n])}
+}
+
+// readLineBuiltin returns the next line from a connection without its line
+// ending, or null once the other side has closed the connection. A last line
+// without a line ending is returned as it is.
+func readLineBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("readLine", args, 1); err != nil {
+		return err
+	}
+	conn, ok := args[0].(*object.Connection)
+	if !ok {
+		return argumentTypeError("readLine", args, 0, "CONNECTION")
+	}
+
+	line, readErr := conn.Reader.ReadString('\n')
+	if errors.Is(readErr, io.EOF) && line == "" {
+		return NULL
+	}
+	if readErr != nil && !errors.Is(readErr, io.EOF) {
+		return newError(message.BuiltinFailed, "readLine", readErr)
+	}
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return &object.String{Value: line}
+}
+
+// writeBuiltin sends data over a connection and returns how many bytes it
+// sent.
+func writeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("write", args, 2); err != nil {
+		return err
+	}
+	conn, ok := args[0].(*object.Connection)
+	if !ok {
+		return argumentTypeError("write", args, 0, "CONNECTION")
+	}
+
+	var data []byte
+	switch arg := args[1].(type) {
+	case *object.String:
+		data = []byte(arg.Value)
+	case *object.Bytes:
+		data = arg.Value
+	default:
+		return argumentTypeError("write", args, 1, "STRING or BYTES")
+	}
+
+	n, writeErr := conn.Conn.Write(data)
+	if writeErr != nil {
+		return newError(message.BuiltinFailed, "write", writeErr)
+	}
+	return integer(int64(n))
+}
+
+// closeBuiltin closes a resource, which the evaluation that opened it would
+// otherwise close once torn down.
+func closeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("close", args, 1); err != nil {
+		return err
+	}
+	resource, ok := args[0].(object.Resource)
+	if !ok {
+		return argumentTypeError("close", args, 0, "CONNECTION, LISTENER, DATABASE or ACTOR")
+	}
+	if err := resource.Close(); err != nil {
+		return newError(message.BuiltinFailed, "close", err)
+	}
+	return NULL
+}
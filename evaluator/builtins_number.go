@@ -0,0 +1,186 @@
+package evaluator
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["parseInt"] = &object.Builtin{Fn: parseIntBuiltin}
+
+	builtins["math.abs"] = &object.Builtin{Fn: absBuiltin}
+	builtins["math.min"] = &object.Builtin{Fn: extremumBuiltin("math.min", -1)}
+	builtins["math.max"] = &object.Builtin{Fn: extremumBuiltin("math.max", 1)}
+	builtins["math.pow"] = &object.Builtin{Fn: powBuiltin}
+	builtins["math.sqrt"] = &object.Builtin{Fn: sqrtBuiltin}
+
+	signatures["parseInt"] = "parseInt(text: STRING, base?: INTEGER)"
+	signatures["math.abs"] = "math.abs(n: INTEGER)"
+	signatures["math.min"] = "math.min(values...: INTEGER)"
+	signatures["math.max"] = "math.max(values...: INTEGER)"
+	signatures["math.pow"] = "math.pow(base: INTEGER, exponent: INTEGER)"
+	signatures["math.sqrt"] = "math.sqrt(n: INTEGER)"
+}
+
+// parseIntBuiltin reads the integer text writes in base, 10 unless given.
+// There may be a leading sign but nothing else around the digits.
+func parseIntBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("parseInt", args, message.ArgumentCountEither, 1, 2)
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("parseInt", args, 0, "STRING")
+	}
+	base, err := baseArgument("parseInt", args, 1)
+	if err != nil {
+		return err
+	}
+
+	n, parseErr := strconv.ParseInt(text.Value, base, 64)
+	if errors.Is(parseErr, strconv.ErrRange) {
+		return argumentError("parseInt", args, message.IntegerTooLarge, text.Value)
+	}
+	if parseErr != nil {
+		return argumentError("parseInt", args, message.NotAnInteger, text.Value, base)
+	}
+	return integer(n)
+}
+
+// baseArgument returns args[i] as a base between 2 and 36, or 10 when the
+// builtin name was called without it.
+func baseArgument(name string, args []object.Object, i int) (int, *object.Error) {
+	if len(args) <= i {
+		return 10, nil
+	}
+	base, ok := args[i].(*object.Integer)
+	if !ok {
+		return 0, argumentTypeError(name, args, i, "INTEGER")
+	}
+	if base.Value < 2 || base.Value > 36 {
+		return 0, argumentError(name, args, message.OutOfRange, "base", 2, 36, base.Value)
+	}
+	return int(base.Value), nil
+}
+
+// integerArguments returns the values of args, which must all be integers.
+func integerArguments(name string, args []object.Object) ([]int64, *object.Error) {
+	values := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return nil, argumentTypeError(name, args, i, "INTEGER")
+		}
+		values[i] = n.Value
+	}
+	return values, nil
+}
+
+func absBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("math.abs", args, 1); err != nil {
+		return err
+	}
+	values, err := integerArguments("math.abs", args)
+	if err != nil {
+		return err
+	}
+
+	n := values[0]
+	if n == math.MinInt64 {
+		return argumentError("math.abs", args, message.Overflow)
+	}
+	if n < 0 {
+		n = -n
+	}
+	return integer(n)
+}
+
+// extremumBuiltin returns math.min when sign is -1 and math.max when it is
+// 1: the value that compares to every other one with that sign.
+func extremumBuiltin(name string, sign int) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) == 0 {
+			return argumentError(name, args, message.ArgumentCountAtLeast, 1)
+		}
+		values, err := integerArguments(name, args)
+		if err != nil {
+			return err
+		}
+
+		best := values[0]
+		for _, n := range values[1:] {
+			if (sign < 0 && n < best) || (sign > 0 && n > best) {
+				best = n
+			}
+		}
+		return integer(best)
+	}
+}
+
+// powBuiltin raises base to a non-negative exponent, failing rather than
+// wrapping around when the result is too large.
+func powBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("math.pow", args, 2); err != nil {
+		return err
+	}
+	values, err := integerArguments("math.pow", args)
+	if err != nil {
+		return err
+	}
+	base, exponent := values[0], values[1]
+	if exponent < 0 {
+		return argumentError("math.pow", args, message.Negative, "exponent", exponent)
+	}
+
+	result := int64(1)
+	for ; exponent > 0; exponent-- {
+		hi, lo := bits.Mul64(uint64(absInt(result)), uint64(absInt(base)))
+		if hi != 0 || lo > math.MaxInt64 {
+			return argumentError("math.pow", args, message.Overflow)
+		}
+		result *= base
+		if result == 0 {
+			break
+		}
+	}
+	return integer(result)
+}
+
+func absInt(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// sqrtBuiltin returns the integer square root of n, the largest integer
+// whose square is at most n.
+func sqrtBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("math.sqrt", args, 1); err != nil {
+		return err
+	}
+	values, err := integerArguments("math.sqrt", args)
+	if err != nil {
+		return err
+	}
+	n := values[0]
+	if n < 0 {
+		return argumentError("math.sqrt", args, message.Negative, "n", n)
+	}
+
+	// The float estimate can be off by one for large n. Comparing with
+	// divisions instead of squares keeps the correction from overflowing.
+	root := int64(math.Sqrt(float64(n)))
+	for root > 0 && root > n/root {
+		root--
+	}
+	for root+1 <= n/(root+1) {
+		root++
+	}
+	return integer(root)
+}
@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	signatures["pmap"] = "pmap(array: ARRAY, fn: FUNCTION)"
+	signatures["pfilter"] = "pfilter(array: ARRAY, fn: FUNCTION)"
+}
+
+// pmapBuiltin is like mapping fn over an array, but the calls are spread over
+// a bounded pool of goroutines. The results keep the order of the elements.
+// Functions run concurrently share the objects they close over, so they
+// should not modify them.
+func (e *Evaluator) pmapBuiltin(args ...object.Object) object.Object {
+	arr, fn, err := parallelArgs("pmap", args)
+	if err != nil {
+		return err
+	}
+
+	results, failure := e.parallelApply(fn, arr.Elements)
+	if failure != nil {
+		return failure
+	}
+	return &object.Array{Elements: results}
+}
+
+// pfilterBuiltin keeps the elements for which fn returns a truthy value,
+// calling fn concurrently like pmap does.
+func (e *Evaluator) pfilterBuiltin(args ...object.Object) object.Object {
+	arr, fn, err := parallelArgs("pfilter", args)
+	if err != nil {
+		return err
+	}
+
+	results, failure := e.parallelApply(fn, arr.Elements)
+	if failure != nil {
+		return failure
+	}
+
+	kept := []object.Object{}
+	for i, result := range results {
+		if isTruthy(result) {
+			kept = append(kept, arr.Elements[i])
+		}
+	}
+	return &object.Array{Elements: kept}
+}
+
+// shareEnvironment prepares the environment fn closes over for use from
+// other goroutines.
+func shareEnvironment(fn object.Object) {
+	if fn, ok := fn.(*object.Function); ok {
+		fn.Env.Share()
+	}
+}
+
+func parallelArgs(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if err := checkArgumentCount(name, args, 2); err != nil {
+		return nil, nil, err
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, argumentTypeError(name, args, 0, "ARRAY")
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return nil, nil, argumentTypeError(name, args, 1, "FUNCTION")
+	}
+	return arr, args[1], nil
+}
+
+// parallelApply calls fn with each of elements on up to MaxWorkers
+// goroutines, each using its own fork of e. If any call fails, the error for
+// the earliest element is returned, so the outcome does not depend on
+// scheduling; later elements are skipped once an earlier one has failed.
+func (e *Evaluator) parallelApply(fn object.Object, elements []object.Object) ([]object.Object, object.Object) {
+	results := make([]object.Object, len(elements))
+
+	shareEnvironment(fn)
+
+	workers := e.config.MaxWorkers
+	if workers > len(elements) {
+		workers = len(elements)
+	}
+
+	// With deterministic random numbers every element gets a generator of its
+	// own, so what it draws does not depend on which worker takes it.
+	var seeds []int64
+	if e.config.DeterministicRandom {
+		seeds = make([]int64, len(elements))
+		for i := range seeds {
+			seeds[i] = e.rng().Int63()
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		next     int
+		failedAt = len(elements)
+		wg       sync.WaitGroup
+	)
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= failedAt {
+			return 0, false
+		}
+		i := next
+		next++
+		return i, true
+	}
+	fail := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if i < failedAt {
+			failedAt = i
+		}
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker *Evaluator) {
+			defer wg.Done()
+			for {
+				i, ok := take()
+				if !ok {
+					return
+				}
+				if seeds != nil {
+					worker.random = rand.New(rand.NewSource(seeds[i]))
+				}
+				results[i] = worker.applyFunction(fn, []object.Object{elements[i]})
+				if isError(results[i]) {
+					fail(i)
+				}
+			}
+		}(e.fork())
+	}
+	wg.Wait()
+
+	if failedAt < len(elements) {
+		return nil, results[failedAt]
+	}
+	return results, nil
+}
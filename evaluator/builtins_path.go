@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"path/filepath"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["joinPath"] = &object.Builtin{Fn: joinPathBuiltin}
+	builtins["basename"] = pathBuiltin("basename", filepath.Base)
+	builtins["dirname"] = pathBuiltin("dirname", filepath.Dir)
+	builtins["ext"] = pathBuiltin("ext", filepath.Ext)
+	builtins["absPath"] = &object.Builtin{Fn: absPathBuiltin}
+	builtins["glob"] = &object.Builtin{Fn: globBuiltin}
+
+	signatures["joinPath"] = "joinPath(parts...: STRING)"
+	signatures["basename"] = "basename(path: STRING)"
+	signatures["dirname"] = "dirname(path: STRING)"
+	signatures["ext"] = "ext(path: STRING)"
+	signatures["absPath"] = "absPath(path: STRING)"
+	signatures["glob"] = "glob(pattern: STRING)"
+
+	// absPath resolves relative paths against the working directory.
+	capabilities["absPath"] = Proc
+	capabilities["glob"] = FS
+}
+
+// pathArg returns the only argument of the builtin name, which must be a
+// string.
+func pathArg(name string, args []object.Object) (string, *object.Error) {
+	if err := checkArgumentCount(name, args, 1); err != nil {
+		return "", err
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return "", argumentTypeError(name, args, 0, "STRING")
+	}
+	return path.Value, nil
+}
+
+// pathBuiltin returns a builtin applying f to its only argument.
+func pathBuiltin(name string, f func(string) string) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		path, err := pathArg(name, args)
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: f(path)}
+	}}
+}
+
+func joinPathBuiltin(args ...object.Object) object.Object {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		part, ok := arg.(*object.String)
+		if !ok {
+			return argumentTypeError("joinPath", args, i, "STRING")
+		}
+		parts[i] = part.Value
+	}
+	return &object.String{Value: filepath.Join(parts...)}
+}
+
+func absPathBuiltin(args ...object.Object) object.Object {
+	path, err := pathArg("absPath", args)
+	if err != nil {
+		return err
+	}
+	abs, absErr := filepath.Abs(path)
+	if absErr != nil {
+		return newError(message.BuiltinFailed, "absPath", absErr)
+	}
+	return &object.String{Value: abs}
+}
+
+// globBuiltin returns the paths matching pattern, sorted.
+func globBuiltin(args ...object.Object) object.Object {
+	pattern, err := pathArg("glob", args)
+	if err != nil {
+		return err
+	}
+	matches, globErr := filepath.Glob(pattern)
+	if globErr != nil {
+		return argumentError("glob", args, message.InvalidGlob, globErr)
+	}
+
+	elements := make([]object.Object, len(matches))
+	for i, match := range matches {
+		elements[i] = &object.String{Value: match}
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	cryptorand "crypto/rand"
+	"math/rand"
+	"time"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["randomBytes"] = &object.Builtin{Fn: randomBytesBuiltin}
+
+	signatures["random"] = "random(n: INTEGER)"
+	signatures["seedRandom"] = "seedRandom(seed: INTEGER)"
+	signatures["randomBytes"] = "randomBytes(n: INTEGER)"
+}
+
+// rng returns the generator behind random, creating it on first use so
+// evaluators that never need one do not pay for it.
+func (e *Evaluator) rng() *rand.Rand {
+	if e.random == nil {
+		seed := time.Now().UnixNano()
+		if e.config.DeterministicRandom {
+			seed = e.config.RandomSeed
+		}
+		e.random = rand.New(rand.NewSource(seed))
+	}
+	return e.random
+}
+
+// randomBuiltin returns a pseudo-random integer from 0 up to but not
+// including n.
+func (e *Evaluator) randomBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("random", args, 1); err != nil {
+		return err
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("random", args, 0, "INTEGER")
+	}
+	if n.Value <= 0 {
+		return argumentError("random", args, message.NotPositive, "n", n.Value)
+	}
+	return integer(e.rng().Int63n(n.Value))
+}
+
+// seedRandomBuiltin restarts the sequence random returns from seed, so the
+// rest of the script is reproducible.
+func (e *Evaluator) seedRandomBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("seedRandom", args, 1); err != nil {
+		return err
+	}
+	seed, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("seedRandom", args, 0, "INTEGER")
+	}
+	e.random = rand.New(rand.NewSource(seed.Value))
+	return NULL
+}
+
+// randomBytesBuiltin returns n cryptographically secure random bytes. Unlike
+// random it is never deterministic, so it is fit for keys and tokens.
+func randomBytesBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("randomBytes", args, 1); err != nil {
+		return err
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("randomBytes", args, 0, "INTEGER")
+	}
+	if n.Value < 0 || n.Value > maxRepeatLength {
+		return argumentError("randomBytes", args, message.OutOfRange, "n", 0, maxRepeatLength, n.Value)
+	}
+
+	b := make([]byte, n.Value)
+	if _, err := cryptorand.Read(b); err != nil {
+		return newError(message.BuiltinFailed, "randomBytes", err)
+	}
+	return &object.Bytes{Value: b}
+}
@@ -0,0 +1,24 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+func init() {
+	signatures["repeat"] = "repeat(value, count: INTEGER)"
+}
+
+// repeatBuiltin repeats a string or an array like `*` does. Any other value
+// is repeated into an array, so `repeat(0, 3)` is `[0, 0, 0]`.
+func (e *Evaluator) repeatBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("repeat", args, 2); err != nil {
+		return err
+	}
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return argumentTypeError("repeat", args, 1, "INTEGER")
+	}
+
+	if isRepeatable(args[0]) {
+		return e.repeat(args[0], count.Value)
+	}
+	return e.repeat(&object.Array{Elements: []object.Object{args[0]}}, count.Value)
+}
@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["sort"] = &object.Builtin{Fn: sortBuiltin}
+
+	signatures["sort"] = "sort(array: ARRAY)"
+	signatures["sortBy"] = "sortBy(array: ARRAY, fn: FUNCTION)"
+}
+
+func sortBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("sort", args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return argumentTypeError("sort", args, 0, "ARRAY")
+	}
+
+	elements := copyElements(args[0].(*object.Array))
+	if err := checkSortKeys("sort", args, elements); err != nil {
+		return err
+	}
+
+	sort.SliceStable(elements, func(i, j int) bool {
+		return lessSortKey(elements[i], elements[j])
+	})
+	return &object.Array{Elements: elements}
+}
+
+// sortByBuiltin sorts with a user function. A function taking one parameter
+// is a key function whose results are compared like `sort` does; one taking
+// two parameters is a comparator returning whether its first argument goes
+// first, either as a boolean or as a negative integer.
+func (e *Evaluator) sortByBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("sortBy", args, 2); err != nil {
+		return err
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return argumentTypeError("sortBy", args, 0, "ARRAY")
+	}
+
+	elements := copyElements(args[0].(*object.Array))
+	fn := args[1]
+
+	switch fn := fn.(type) {
+	case *object.Function:
+		if len(fn.Parameters) == 2 {
+			return e.sortWithComparator(args, elements, fn)
+		}
+		if len(fn.Parameters) != 1 {
+			return argumentTypeError("sortBy", args, 1, "a FUNCTION taking 1 or 2 parameters")
+		}
+	case *object.Builtin:
+	default:
+		return argumentTypeError("sortBy", args, 1, "FUNCTION")
+	}
+
+	keys := make([]object.Object, len(elements))
+	for i, el := range elements {
+		key := e.applyFunction(fn, []object.Object{el})
+		if isError(key) {
+			return key
+		}
+		keys[i] = key
+	}
+	if err := checkSortKeys("sortBy", args, keys); err != nil {
+		return err
+	}
+
+	indexes := make([]int, len(elements))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return lessSortKey(keys[indexes[i]], keys[indexes[j]])
+	})
+
+	sorted := make([]object.Object, len(elements))
+	for i, idx := range indexes {
+		sorted[i] = elements[idx]
+	}
+	return &object.Array{Elements: sorted}
+}
+
+func (e *Evaluator) sortWithComparator(args, elements []object.Object, fn *object.Function) object.Object {
+	var err object.Object
+
+	sort.SliceStable(elements, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+
+		result := e.applyFunction(fn, []object.Object{elements[i], elements[j]})
+		switch result := result.(type) {
+		case *object.Boolean:
+			return result.Value
+		case *object.Integer:
+			return result.Value < 0
+		case *object.Error:
+			err = result
+		default:
+			err = argumentError("sortBy", args, message.SortComparator, result.Type())
+		}
+		return false
+	})
+
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+func copyElements(arr *object.Array) []object.Object {
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+	return elements
+}
+
+// checkSortKeys fails unless keys, what the builtin name called with args
+// sorts by, can all be compared with each other.
+func checkSortKeys(name string, args, keys []object.Object) *object.Error {
+	for _, key := range keys {
+		if key.Type() != object.INTEGER_OBJ && key.Type() != object.STRING_OBJ {
+			return argumentError(name, args, message.SortUnorderable, key.Type())
+		}
+		if key.Type() != keys[0].Type() {
+			return argumentError(name, args, message.SortIncomparable, keys[0].Type(), key.Type())
+		}
+	}
+	return nil
+}
+
+func lessSortKey(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value < b.(*object.Integer).Value
+	case *object.String:
+		return a.Value < b.(*object.String).Value
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package evaluator
+
+import (
+	"database/sql"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	builtins["sqliteOpen"] = &object.Builtin{Fn: sqliteOpenBuiltin}
+	builtins["query"] = &object.Builtin{Fn: queryBuiltin}
+
+	signatures["sqliteOpen"] = "sqliteOpen(path: STRING)"
+	signatures["query"] = "query(db: DATABASE, sql: STRING, params?: ARRAY)"
+	// exec runs statements when given a database instead of a command.
+	signatures["execDatabase"] = "exec(db: DATABASE, sql: STRING, params?: ARRAY)"
+
+	capabilities["sqliteOpen"] = FS
+}
+
+// sqliteOpenBuiltin opens the SQLite database at path, creating it if it
+// does not exist. The path ":memory:" opens a database held in memory.
+func sqliteOpenBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("sqliteOpen", args, 1); err != nil {
+		return err
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("sqliteOpen", args, 0, "STRING")
+	}
+
+	db, err := sql.Open("sqlite", path.Value)
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		return newError(message.BuiltinFailed, "sqliteOpen", err)
+	}
+	if path.Value == ":memory:" {
+		// Every connection to ":memory:" gets a database of its own.
+		db.SetMaxOpenConns(1)
+	}
+	return object.NewDatabase(db, path.Value)
+}
+
+// sqlArgs checks the arguments of the builtin name: a database, a statement
+// and optionally an array with a value for each placeholder in it.
+func sqlArgs(name string, args []object.Object) (*object.Database, string, []interface{}, *object.Error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, "", nil, argumentError(name, args, message.ArgumentCountEither, 2, 3)
+	}
+	db, ok := args[0].(*object.Database)
+	if !ok {
+		return nil, "", nil, argumentTypeError(name, args, 0, "DATABASE")
+	}
+	statement, ok := args[1].(*object.String)
+	if !ok {
+		return nil, "", nil, argumentTypeError(name, args, 1, "STRING")
+	}
+	if len(args) == 2 {
+		return db, statement.Value, nil, nil
+	}
+
+	params, ok := args[2].(*object.Array)
+	if !ok {
+		return nil, "", nil, argumentTypeError(name, args, 2, "ARRAY")
+	}
+	values := make([]interface{}, len(params.Elements))
+	for i, param := range params.Elements {
+		switch param.(type) {
+		case *object.Integer, *object.String, *object.Boolean, *object.Bytes, *object.Null:
+			values[i], _ = toGo(param)
+		default:
+			return nil, "", nil, argumentError(name, args, message.CannotBind, i+1, param.Type())
+		}
+	}
+	return db, statement.Value, values, nil
+}
+
+// queryBuiltin runs a query and returns its rows as hashes from column names
+// to values.
+func queryBuiltin(args ...object.Object) object.Object {
+	db, statement, params, err := sqlArgs("query", args)
+	if err != nil {
+		return err
+	}
+
+	rows, queryErr := db.DB.Query(statement, params...)
+	if queryErr != nil {
+		return newError(message.BuiltinFailed, "query", queryErr)
+	}
+	defer rows.Close()
+
+	columns, queryErr := rows.Columns()
+	if queryErr != nil {
+		return newError(message.BuiltinFailed, "query", queryErr)
+	}
+
+	result := []object.Object{}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if scanErr := rows.Scan(pointers...); scanErr != nil {
+			return newError(message.BuiltinFailed, "query", scanErr)
+		}
+		row := make(map[string]object.Object, len(columns))
+		for i, column := range columns {
+			value, convertErr := fromGo(values[i])
+			if convertErr != nil {
+				return newError(message.ColumnFailed, "query", column, convertErr)
+			}
+			row[column] = value
+		}
+		result = append(result, hashOf(row))
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return newError(message.BuiltinFailed, "query", rowsErr)
+	}
+	return &object.Array{Elements: result}
+}
+
+// execDatabase runs a statement that returns no rows, returning a hash with
+// the number of rowsAffected and the lastInsertId.
+func execDatabase(args []object.Object) object.Object {
+	db, statement, params, err := sqlArgs("execDatabase", args)
+	if err != nil {
+		return err
+	}
+
+	result, execErr := db.DB.Exec(statement, params...)
+	if execErr != nil {
+		return newError(message.BuiltinFailed, "exec", execErr)
+	}
+	affected, _ := result.RowsAffected()
+	lastID, _ := result.LastInsertId()
+	return hashOf(map[string]object.Object{
+		"rowsAffected": integer(affected),
+		"lastInsertId": integer(lastID),
+	})
+}
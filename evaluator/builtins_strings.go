@@ -0,0 +1,164 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["strings.split"] = &object.Builtin{Fn: splitBuiltin}
+	builtins["strings.trim"] = &object.Builtin{Fn: trimBuiltin}
+	builtins["strings.upper"] = &object.Builtin{Fn: stringFunction("strings.upper", strings.ToUpper)}
+	builtins["strings.lower"] = &object.Builtin{Fn: stringFunction("strings.lower", strings.ToLower)}
+	builtins["strings.contains"] = &object.Builtin{Fn: stringPredicate("strings.contains", strings.Contains)}
+	builtins["strings.startsWith"] = &object.Builtin{Fn: stringPredicate("strings.startsWith", strings.HasPrefix)}
+	builtins["strings.endsWith"] = &object.Builtin{Fn: stringPredicate("strings.endsWith", strings.HasSuffix)}
+
+	signatures["strings.split"] = "strings.split(text: STRING, separator?: STRING)"
+	signatures["strings.join"] = "strings.join(values: ARRAY, separator?: STRING)"
+	signatures["strings.trim"] = "strings.trim(text: STRING, cutset?: STRING)"
+	signatures["strings.upper"] = "strings.upper(text: STRING)"
+	signatures["strings.lower"] = "strings.lower(text: STRING)"
+	signatures["strings.contains"] = "strings.contains(text: STRING, part: STRING)"
+	signatures["strings.startsWith"] = "strings.startsWith(text: STRING, prefix: STRING)"
+	signatures["strings.endsWith"] = "strings.endsWith(text: STRING, suffix: STRING)"
+	signatures["strings.replace"] = "strings.replace(text: STRING, old: STRING, new: STRING)"
+}
+
+// stringArguments returns the values of args, which must all be strings.
+func stringArguments(name string, args []object.Object) ([]string, *object.Error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(*object.String)
+		if !ok {
+			return nil, argumentTypeError(name, args, i, "STRING")
+		}
+		values[i] = s.Value
+	}
+	return values, nil
+}
+
+// splitBuiltin splits text around each separator, or around runs of white
+// space when there is none.
+func splitBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("strings.split", args, message.ArgumentCountEither, 1, 2)
+	}
+	values, err := stringArguments("strings.split", args)
+	if err != nil {
+		return err
+	}
+
+	var parts []string
+	if len(values) == 1 {
+		parts = strings.Fields(values[0])
+	} else {
+		parts = strings.Split(values[0], values[1])
+	}
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// joinBuiltin writes each value as toString would, with separator, nothing
+// unless given, between them.
+func (e *Evaluator) joinBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("strings.join", args, message.ArgumentCountEither, 1, 2)
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argumentTypeError("strings.join", args, 0, "ARRAY")
+	}
+	separator := ""
+	if len(args) == 2 {
+		s, ok := args[1].(*object.String)
+		if !ok {
+			return argumentTypeError("strings.join", args, 1, "STRING")
+		}
+		separator = s.Value
+	}
+
+	parts := make([]string, len(array.Elements))
+	lengths := make([]int, 0, len(parts)+1)
+	for i, element := range array.Elements {
+		if i%interruptEvery == 0 && e.isInterrupted() {
+			return newError(message.Interrupted)
+		}
+		parts[i] = stringValue(element)
+		lengths = append(lengths, len(parts[i]))
+	}
+	if len(parts) > 1 {
+		lengths = append(lengths, len(separator)*(len(parts)-1))
+	}
+	if err := e.checkElements(object.STRING_OBJ, lengths...); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.Join(parts, separator)}
+}
+
+// trimBuiltin removes white space, or the characters in cutset when given,
+// from both ends of text.
+func trimBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("strings.trim", args, message.ArgumentCountEither, 1, 2)
+	}
+	values, err := stringArguments("strings.trim", args)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 1 {
+		return &object.String{Value: strings.TrimSpace(values[0])}
+	}
+	return &object.String{Value: strings.Trim(values[0], values[1])}
+}
+
+// replaceBuiltin replaces every old in text with new.
+func (e *Evaluator) replaceBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("strings.replace", args, 3); err != nil {
+		return err
+	}
+	values, err := stringArguments("strings.replace", args)
+	if err != nil {
+		return err
+	}
+	if growth := len(values[2]) - len(values[1]); growth > 0 {
+		if err := e.checkElements(object.STRING_OBJ, len(values[0]), strings.Count(values[0], values[1])*growth); err != nil {
+			return err
+		}
+	}
+	return &object.String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+}
+
+// stringFunction returns the builtin name, which maps a string with f.
+func stringFunction(name string, f func(string) string) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(name, args, 1); err != nil {
+			return err
+		}
+		values, err := stringArguments(name, args)
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: f(values[0])}
+	}
+}
+
+// stringPredicate returns the builtin name, which tests two strings with f.
+func stringPredicate(name string, f func(string, string) bool) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(name, args, 2); err != nil {
+			return err
+		}
+		values, err := stringArguments(name, args)
+		if err != nil {
+			return err
+		}
+		return boolean(f(values[0], values[1]))
+	}
+}
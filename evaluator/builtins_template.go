@@ -0,0 +1,235 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	signatures["template"] = "template(text: STRING, data: HASH)"
+}
+
+// templateBuiltin fills in the placeholders of text from the string keys of
+// data. A template has three kinds of action:
+//
+//	{{name}} or {{user.name}}       the value, as toString would write it
+//	{{if name}} ... {{else}} ... {{end}}   a section kept when name is truthy
+//	{{for item in items}} ... {{end}}      a section repeated for each element
+//
+// Naming a value data does not have is an error, rather than leaving a gap
+// in the output.
+func (e *Evaluator) templateBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("template", args, 2); err != nil {
+		return err
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("template", args, 0, "STRING")
+	}
+	data, ok := args[1].(*object.Hash)
+	if !ok {
+		return argumentTypeError("template", args, 1, "HASH")
+	}
+
+	nodes, err := parseTemplate(text.Value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "template", err)
+	}
+
+	scope := map[string]object.Object{}
+	for _, pair := range data.Pairs {
+		if key, ok := pair.Key.(*object.String); ok {
+			scope[key.Value] = pair.Value
+		}
+	}
+
+	out := &output{e: e}
+	if err := renderTemplate(out, nodes, scope); err != nil {
+		if out.err != nil {
+			return out.err
+		}
+		return newError(message.BuiltinFailed, "template", err)
+	}
+	return &object.String{Value: out.String()}
+}
+
+type (
+	templateNode interface{}
+
+	templateText string
+
+	// templateValue is a placeholder, the keys leading to its value.
+	templateValue []string
+
+	templateIf struct {
+		condition       []string
+		then, otherwise []templateNode
+	}
+
+	templateFor struct {
+		name     string
+		iterable []string
+		body     []templateNode
+	}
+)
+
+func parseTemplate(text string) ([]templateNode, error) {
+	p := &templateParser{text: text}
+	nodes, _, err := p.parse()
+	return nodes, err
+}
+
+type templateParser struct {
+	text string
+}
+
+// parse parses nodes up to the end of the text or one of the actions in
+// stop, returning the action that ended them, or "" at the end of the text.
+func (p *templateParser) parse(stop ...string) ([]templateNode, string, error) {
+	nodes := []templateNode{}
+	for {
+		start := strings.Index(p.text, "{{")
+		if start < 0 {
+			nodes = append(nodes, templateText(p.text))
+			p.text = ""
+			if len(stop) > 0 {
+				return nil, "", errors.New("missing {{end}}")
+			}
+			return nodes, "", nil
+		}
+		end := strings.Index(p.text[start:], "}}")
+		if end < 0 {
+			return nil, "", errors.New("unclosed {{")
+		}
+
+		nodes = append(nodes, templateText(p.text[:start]))
+		action := strings.TrimSpace(p.text[start+2 : start+end])
+		p.text = p.text[start+end+2:]
+
+		fields := strings.Fields(action)
+		switch {
+		case len(fields) == 1 && contains(stop, fields[0]):
+			return nodes, fields[0], nil
+
+		case len(fields) == 2 && fields[0] == "if":
+			node := &templateIf{condition: strings.Split(fields[1], ".")}
+			var ended string
+			var err error
+			if node.then, ended, err = p.parse("else", "end"); err != nil {
+				return nil, "", err
+			}
+			if ended == "else" {
+				if node.otherwise, _, err = p.parse("end"); err != nil {
+					return nil, "", err
+				}
+			}
+			nodes = append(nodes, node)
+
+		case len(fields) == 4 && fields[0] == "for" && fields[2] == "in":
+			node := &templateFor{name: fields[1], iterable: strings.Split(fields[3], ".")}
+			var err error
+			if node.body, _, err = p.parse("end"); err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+
+		case len(fields) == 1 && !contains([]string{"if", "else", "end", "for"}, fields[0]):
+			nodes = append(nodes, templateValue(strings.Split(fields[0], ".")))
+
+		default:
+			return nil, "", fmt.Errorf("unexpected {{%s}}", action)
+		}
+	}
+}
+
+func contains(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func renderTemplate(out *output, nodes []templateNode, scope map[string]object.Object) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case templateText:
+			if _, err := out.WriteString(string(node)); err != nil {
+				return err
+			}
+
+		case templateValue:
+			value, err := templateLookup(scope, node)
+			if err != nil {
+				return err
+			}
+			if value != NULL {
+				if _, err := out.WriteString(stringValue(value)); err != nil {
+					return err
+				}
+			}
+
+		case *templateIf:
+			value, err := templateLookup(scope, node.condition)
+			if err != nil {
+				return err
+			}
+			section := node.otherwise
+			if isTruthy(value) {
+				section = node.then
+			}
+			if err := renderTemplate(out, section, scope); err != nil {
+				return err
+			}
+
+		case *templateFor:
+			value, err := templateLookup(scope, node.iterable)
+			if err != nil {
+				return err
+			}
+			var elements []object.Object
+			switch value := value.(type) {
+			case *object.Array:
+				elements = value.Elements
+			case *object.Tuple:
+				elements = value.Elements
+			default:
+				return fmt.Errorf("cannot loop over %s, a %s", strings.Join(node.iterable, "."), value.Type())
+			}
+
+			inner := make(map[string]object.Object, len(scope)+1)
+			for name, value := range scope {
+				inner[name] = value
+			}
+			for _, element := range elements {
+				inner[node.name] = element
+				if err := renderTemplate(out, node.body, inner); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// templateLookup follows path from scope through the string keys of nested
+// hashes.
+func templateLookup(scope map[string]object.Object, path []string) (object.Object, error) {
+	value, ok := scope[path[0]]
+	for i := 1; ok && i < len(path); i++ {
+		hash, isHash := value.(*object.Hash)
+		if !isHash {
+			return nil, fmt.Errorf("%s is a %s, not a hash", strings.Join(path[:i], "."), value.Type())
+		}
+		value, ok = hashValue(hash, path[i])
+	}
+	if !ok {
+		return nil, fmt.Errorf("no value for %s", strings.Join(path, "."))
+	}
+	return value, nil
+}
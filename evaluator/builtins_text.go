@@ -0,0 +1,223 @@
+package evaluator
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["formatNumber"] = &object.Builtin{Fn: formatNumberBuiltin}
+	builtins["formatTable"] = &object.Builtin{Fn: formatTableBuiltin}
+
+	signatures["padLeft"] = "padLeft(value, width: INTEGER, fill?: STRING)"
+	signatures["padRight"] = "padRight(value, width: INTEGER, fill?: STRING)"
+	signatures["center"] = "center(value, width: INTEGER, fill?: STRING)"
+	signatures["formatNumber"] = "formatNumber(n: INTEGER, separator?: STRING)"
+	signatures["formatTable"] = "formatTable(rows: ARRAY, columns?: ARRAY)"
+	signatures["printTable"] = "printTable(rows: ARRAY, columns?: ARRAY)"
+}
+
+// padBuiltin returns the builtin name, which writes a value as toString would
+// and fills it out to width characters with fill, a space unless given:
+// padLeft fills on the left, padRight on the right and center on both
+// sides, with the odd character on the right. Text already as wide as width
+// is left as it is.
+func (e *Evaluator) padBuiltin(name string) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 2 && len(args) != 3 {
+			return argumentError(name, args, message.ArgumentCountEither, 2, 3)
+		}
+		width, ok := args[1].(*object.Integer)
+		if !ok {
+			return argumentTypeError(name, args, 1, "INTEGER")
+		}
+		fill := " "
+		if len(args) == 3 {
+			str, ok := args[2].(*object.String)
+			if !ok || utf8.RuneCountInString(str.Value) != 1 {
+				return argumentError(name, args, message.SingleCharacter, "fill")
+			}
+			fill = str.Value
+		}
+
+		if width.Value > maxRepeatLength {
+			return argumentError(name, args, message.OutOfRange, "width", 0, maxRepeatLength, width.Value)
+		}
+
+		text := stringValue(args[0])
+		missing := int(width.Value) - utf8.RuneCountInString(text)
+		if missing <= 0 {
+			return &object.String{Value: text}
+		}
+
+		if err := e.checkElements(object.STRING_OBJ, len(text), missing*len(fill)); err != nil {
+			return err
+		}
+
+		left, right := 0, 0
+		switch name {
+		case "padLeft":
+			left = missing
+		case "padRight":
+			right = missing
+		default:
+			left = missing / 2
+			right = missing - left
+		}
+		return &object.String{Value: strings.Repeat(fill, left) + text + strings.Repeat(fill, right)}
+	}
+}
+
+// formatNumberBuiltin writes n with its digits in groups of three, split by
+// separator or a comma, as in 1,234,567.
+func formatNumberBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentError("formatNumber", args, message.ArgumentCountEither, 1, 2)
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("formatNumber", args, 0, "INTEGER")
+	}
+	separator := ","
+	if len(args) == 2 {
+		str, ok := args[1].(*object.String)
+		if !ok {
+			return argumentTypeError("formatNumber", args, 1, "STRING")
+		}
+		separator = str.Value
+	}
+
+	digits := strconv.FormatInt(n.Value, 10)
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+
+	var out strings.Builder
+	out.WriteString(sign)
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteString(separator)
+		}
+		out.WriteRune(digit)
+	}
+	return &object.String{Value: out.String()}
+}
+
+func formatTableBuiltin(args ...object.Object) object.Object {
+	table, err := formatTable("formatTable", args)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: table}
+}
+
+// printTableBuiltin writes rows to the configured Output as formatTable
+// lays them out.
+func (e *Evaluator) printTableBuiltin(args ...object.Object) object.Object {
+	table, err := formatTable("printTable", args)
+	if err != nil {
+		return err
+	}
+	return e.putsBuiltin(&object.String{Value: strings.TrimSuffix(table, "\n")})
+}
+
+// formatTable lays out rows, an array of hashes, as a table with a column
+// for each of columns, or for every key of the rows when there are none:
+// the keys of the first row in order, then any new ones of the next rows. A
+// header names the columns and a rule separates it from the rows. Columns
+// holding only integers are aligned right, and anything else left; a row
+// without a column's key leaves its cell empty.
+func formatTable(name string, args []object.Object) (string, *object.Error) {
+	if len(args) != 1 && len(args) != 2 {
+		return "", argumentError(name, args, message.ArgumentCountEither, 1, 2)
+	}
+	rowsArg, ok := args[0].(*object.Array)
+	if !ok {
+		return "", argumentTypeError(name, args, 0, "ARRAY")
+	}
+	rows := make([]*object.Hash, len(rowsArg.Elements))
+	for i, element := range rowsArg.Elements {
+		if rows[i], ok = element.(*object.Hash); !ok {
+			return "", argumentError(name, args, message.TableRowType, i+1, element.Type())
+		}
+	}
+
+	var columns []object.Object
+	if len(args) == 2 {
+		columnsArg, ok := args[1].(*object.Array)
+		if !ok {
+			return "", argumentTypeError(name, args, 1, "ARRAY")
+		}
+		columns = columnsArg.Elements
+	} else {
+		seen := map[object.HashKey]bool{}
+		for _, row := range rows {
+			for _, pair := range row.SortedPairs() {
+				key := pair.Key.(object.Hashable).HashKey()
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, pair.Key)
+				}
+			}
+		}
+	}
+
+	cells := make([][]string, len(rows)+1)
+	widths := make([]int, len(columns))
+	numeric := make([]bool, len(columns))
+	for i, column := range columns {
+		key, ok := column.(object.Hashable)
+		if !ok {
+			return "", newError(message.UnusableHashKey, column.Type())
+		}
+		cells[0] = append(cells[0], stringValue(column))
+		numeric[i] = len(rows) > 0
+		for j, row := range rows {
+			cell := ""
+			if pair, ok := row.Pairs[key.HashKey()]; ok {
+				cell = stringValue(pair.Value)
+				numeric[i] = numeric[i] && pair.Value.Type() == object.INTEGER_OBJ
+			}
+			cells[j+1] = append(cells[j+1], cell)
+		}
+		for _, line := range cells {
+			if width := utf8.RuneCountInString(line[i]); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	var out strings.Builder
+	writeLine := func(line []string) {
+		var text strings.Builder
+		for i, cell := range line {
+			if i > 0 {
+				text.WriteString("  ")
+			}
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if numeric[i] {
+				text.WriteString(padding + cell)
+			} else {
+				text.WriteString(cell + padding)
+			}
+		}
+		out.WriteString(strings.TrimRight(text.String(), " "))
+		out.WriteString("\n")
+	}
+
+	writeLine(cells[0])
+	rule := make([]string, len(columns))
+	for i, width := range widths {
+		rule[i] = strings.Repeat("-", width)
+	}
+	writeLine(rule)
+	for _, line := range cells[1:] {
+		writeLine(line)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,190 @@
+package evaluator
+
+import (
+	"time"
+	// Time zones work the same on systems without a zone database.
+	_ "time/tzdata"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["now"] = &object.Builtin{Fn: nowBuiltin}
+	builtins["parseTime"] = &object.Builtin{Fn: parseTimeBuiltin}
+	builtins["formatTime"] = &object.Builtin{Fn: formatTimeBuiltin}
+	builtins["fromUnix"] = &object.Builtin{Fn: fromUnixBuiltin}
+	builtins["unix"] = timeFieldBuiltin("unix", func(t time.Time) int64 { return t.Unix() })
+	builtins["addDuration"] = &object.Builtin{Fn: addDurationBuiltin}
+	builtins["diff"] = &object.Builtin{Fn: diffBuiltin}
+	builtins["inZone"] = &object.Builtin{Fn: inZoneBuiltin}
+	builtins["sleep"] = &object.Builtin{Fn: sleepBuiltin}
+
+	builtins["year"] = timeFieldBuiltin("year", func(t time.Time) int64 { return int64(t.Year()) })
+	builtins["month"] = timeFieldBuiltin("month", func(t time.Time) int64 { return int64(t.Month()) })
+	builtins["day"] = timeFieldBuiltin("day", func(t time.Time) int64 { return int64(t.Day()) })
+	builtins["hour"] = timeFieldBuiltin("hour", func(t time.Time) int64 { return int64(t.Hour()) })
+	builtins["minute"] = timeFieldBuiltin("minute", func(t time.Time) int64 { return int64(t.Minute()) })
+	builtins["second"] = timeFieldBuiltin("second", func(t time.Time) int64 { return int64(t.Second()) })
+	builtins["weekday"] = timeFieldBuiltin("weekday", func(t time.Time) int64 { return int64(t.Weekday()) })
+
+	signatures["now"] = "now()"
+	signatures["parseTime"] = "parseTime(text: STRING, layout?: STRING)"
+	signatures["formatTime"] = "formatTime(time: TIME, layout?: STRING)"
+	signatures["fromUnix"] = "fromUnix(seconds: INTEGER)"
+	signatures["addDuration"] = "addDuration(time: TIME, milliseconds: INTEGER)"
+	signatures["diff"] = "diff(a: TIME, b: TIME)"
+	signatures["inZone"] = "inZone(time: TIME, zone: STRING)"
+	signatures["sleep"] = "sleep(milliseconds: INTEGER)"
+	for _, name := range []string{"unix", "year", "month", "day", "hour", "minute", "second", "weekday"} {
+		signatures[name] = name + "(time: TIME)"
+	}
+
+	capabilities["now"] = Time
+	capabilities["sleep"] = Time
+}
+
+func nowBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("now", args, 0); err != nil {
+		return err
+	}
+	return &object.Time{Value: time.Now()}
+}
+
+func sleepBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("sleep", args, 1); err != nil {
+		return err
+	}
+	ms, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("sleep", args, 0, "INTEGER")
+	}
+	time.Sleep(time.Duration(ms.Value) * time.Millisecond)
+	return NULL
+}
+
+// layoutArg returns the optional layout of the builtin name at args[i],
+// which is written like Go's time package does and defaults to RFC 3339.
+func layoutArg(name string, args []object.Object, i int) (string, *object.Error) {
+	if len(args) != i && len(args) != i+1 {
+		return "", argumentError(name, args, message.ArgumentCountEither, i, i+1)
+	}
+	if len(args) == i {
+		return time.RFC3339Nano, nil
+	}
+	layout, ok := args[i].(*object.String)
+	if !ok {
+		return "", argumentTypeError(name, args, i, "STRING")
+	}
+	return layout.Value, nil
+}
+
+func parseTimeBuiltin(args ...object.Object) object.Object {
+	layout, err := layoutArg("parseTime", args, 1)
+	if err != nil {
+		return err
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("parseTime", args, 0, "STRING")
+	}
+
+	t, parseErr := time.Parse(layout, text.Value)
+	if parseErr != nil {
+		return newError(message.BuiltinFailed, "parseTime", parseErr)
+	}
+	return &object.Time{Value: t}
+}
+
+func formatTimeBuiltin(args ...object.Object) object.Object {
+	layout, err := layoutArg("formatTime", args, 1)
+	if err != nil {
+		return err
+	}
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return argumentTypeError("formatTime", args, 0, "TIME")
+	}
+	return &object.String{Value: t.Value.Format(layout)}
+}
+
+// fromUnixBuiltin returns the time a number of seconds after the Unix epoch,
+// in UTC.
+func fromUnixBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("fromUnix", args, 1); err != nil {
+		return err
+	}
+	seconds, ok := args[0].(*object.Integer)
+	if !ok {
+		return argumentTypeError("fromUnix", args, 0, "INTEGER")
+	}
+	return &object.Time{Value: time.Unix(seconds.Value, 0).UTC()}
+}
+
+// timeFieldBuiltin returns a builtin returning f of its only argument, a
+// time.
+func timeFieldBuiltin(name string, f func(time.Time) int64) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if err := checkArgumentCount(name, args, 1); err != nil {
+			return err
+		}
+		t, ok := args[0].(*object.Time)
+		if !ok {
+			return argumentTypeError(name, args, 0, "TIME")
+		}
+		return integer(f(t.Value))
+	}}
+}
+
+// addDurationBuiltin returns the time a number of milliseconds, which is how
+// durations are written, after a time.
+func addDurationBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("addDuration", args, 2); err != nil {
+		return err
+	}
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return argumentTypeError("addDuration", args, 0, "TIME")
+	}
+	ms, ok := args[1].(*object.Integer)
+	if !ok {
+		return argumentTypeError("addDuration", args, 1, "INTEGER")
+	}
+	return &object.Time{Value: t.Value.Add(time.Duration(ms.Value) * time.Millisecond)}
+}
+
+// diffBuiltin returns how many milliseconds a is after b.
+func diffBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("diff", args, 2); err != nil {
+		return err
+	}
+	for i := range args {
+		if args[i].Type() != object.TIME_OBJ {
+			return argumentTypeError("diff", args, i, "TIME")
+		}
+	}
+	a, b := args[0].(*object.Time).Value, args[1].(*object.Time).Value
+	return integer(a.Sub(b).Milliseconds())
+}
+
+// inZoneBuiltin returns the same instant shown in zone, a name from the IANA
+// time zone database such as "Europe/Lisbon", or "UTC" or "Local".
+func inZoneBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("inZone", args, 2); err != nil {
+		return err
+	}
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return argumentTypeError("inZone", args, 0, "TIME")
+	}
+	zone, ok := args[1].(*object.String)
+	if !ok {
+		return argumentTypeError("inZone", args, 1, "STRING")
+	}
+
+	location, loadErr := time.LoadLocation(zone.Value)
+	if loadErr != nil {
+		return newError(message.BuiltinFailed, "inZone", loadErr)
+	}
+	return &object.Time{Value: t.Value.In(location)}
+}
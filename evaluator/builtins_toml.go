@@ -0,0 +1,60 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	builtins["tomlDecode"] = &object.Builtin{Fn: tomlDecodeBuiltin}
+	builtins["tomlEncode"] = &object.Builtin{Fn: tomlEncodeBuiltin}
+
+	signatures["tomlDecode"] = "tomlDecode(text: STRING)"
+	signatures["tomlEncode"] = "tomlEncode(table: HASH)"
+}
+
+// tomlDecodeBuiltin returns the document in text as a hash. Dates and times
+// become strings.
+func tomlDecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("tomlDecode", args, 1); err != nil {
+		return err
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("tomlDecode", args, 0, "STRING")
+	}
+
+	var value map[string]interface{}
+	if _, err := toml.Decode(text.Value, &value); err != nil {
+		return newError(message.BuiltinFailed, "tomlDecode", err)
+	}
+	obj, err := fromGo(value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "tomlDecode", err)
+	}
+	return obj
+}
+
+// tomlEncodeBuiltin writes table as a TOML document. TOML only has string
+// keys and no null, so neither may appear in table.
+func tomlEncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("tomlEncode", args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() != object.HASH_OBJ {
+		return argumentTypeError("tomlEncode", args, 0, "HASH")
+	}
+
+	value, err := toGo(args[0])
+	if err != nil {
+		return newError(message.BuiltinFailed, "tomlEncode", err)
+	}
+	var out strings.Builder
+	if err := toml.NewEncoder(&out).Encode(value); err != nil {
+		return newError(message.BuiltinFailed, "tomlEncode", err)
+	}
+	return &object.String{Value: out.String()}
+}
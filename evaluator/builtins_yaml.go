@@ -0,0 +1,58 @@
+package evaluator
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	builtins["yamlDecode"] = &object.Builtin{Fn: yamlDecodeBuiltin}
+	builtins["yamlEncode"] = &object.Builtin{Fn: yamlEncodeBuiltin}
+
+	signatures["yamlDecode"] = "yamlDecode(text: STRING)"
+	signatures["yamlEncode"] = "yamlEncode(value)"
+}
+
+// yamlDecodeBuiltin returns the first document in text, or null if there is
+// none. Mappings become hashes and sequences arrays.
+func yamlDecodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("yamlDecode", args, 1); err != nil {
+		return err
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("yamlDecode", args, 0, "STRING")
+	}
+
+	var value interface{}
+	err := yaml.NewDecoder(strings.NewReader(text.Value)).Decode(&value)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return newError(message.BuiltinFailed, "yamlDecode", err)
+	}
+	obj, err := fromGo(value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "yamlDecode", err)
+	}
+	return obj
+}
+
+func yamlEncodeBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("yamlEncode", args, 1); err != nil {
+		return err
+	}
+
+	value, err := toGo(args[0])
+	if err != nil {
+		return newError(message.BuiltinFailed, "yamlEncode", err)
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return newError(message.BuiltinFailed, "yamlEncode", err)
+	}
+	return &object.String{Value: string(out)}
+}
@@ -0,0 +1,112 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// Capability names a kind of effect on the world outside the evaluation
+// that some builtins have. Builtins without a capability only compute values.
+type Capability string
+
+const (
+	// Output is writing to standard output.
+	Output Capability = "output"
+	// Dynamic is running source code that is only known at runtime, which
+	// may call any builtin.
+	Dynamic Capability = "dynamic"
+	// Concurrency is starting goroutines.
+	Concurrency Capability = "concurrency"
+	// FS is reading and writing files.
+	FS Capability = "fs"
+	// Net is opening network connections.
+	Net Capability = "net"
+	// Time is reading the clock and sleeping.
+	Time Capability = "time"
+	// Proc is starting processes and reading the process environment.
+	Proc Capability = "proc"
+	// Packs is importing builtin packs, whose builtins may have any effect.
+	Packs Capability = "packs"
+)
+
+var capabilities = map[string]Capability{
+	"puts":       Output,
+	"printTable": Output,
+	"dumpHeap":   Output,
+	"eval":       Dynamic,
+	"pmap":       Concurrency,
+	"pfilter":    Concurrency,
+	"async":      Concurrency,
+	"actor":      Concurrency,
+}
+
+// handleCalls are the builtins that also work on a handle, which they need no
+// capability for since making the handle needed one: exec runs a statement
+// when given a database instead of starting a process.
+var handleCalls = map[string]object.ObjectType{
+	"exec": object.DATABASE_OBJ,
+}
+
+// BuiltinCapability returns the capability the builtin name needs, if any.
+func BuiltinCapability(name string) (Capability, bool) {
+	capability, ok := capabilities[name]
+	return capability, ok
+}
+
+// Builtins returns the names of every builtin, sorted.
+func Builtins() []string {
+	var names []string
+	for name := range New(Config{}).builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsCapabilityDenied reports whether obj is the error a sandboxed evaluator
+// returns for a call to a builtin whose capability is not enabled.
+func IsCapabilityDenied(obj object.Object) bool {
+	err, ok := obj.(*object.Error)
+	return ok && err.Code == diagnostic.CapabilityDenied
+}
+
+// allows reports whether e's configuration enables capability.
+func (e *Evaluator) allows(capability Capability) bool {
+	if !e.config.Sandboxed {
+		return true
+	}
+	for _, enabled := range e.config.Capabilities {
+		if enabled == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// sandbox replaces the builtins of e that need a capability e's
+// configuration does not enable with ones that fail when called, so
+// referring to them still works.
+func (e *Evaluator) sandbox() {
+	enabled := make(map[Capability]bool)
+	for _, capability := range e.config.Capabilities {
+		enabled[capability] = true
+	}
+
+	for name, capability := range capabilities {
+		builtin, ok := e.builtins[name]
+		if !ok || enabled[capability] {
+			continue
+		}
+		err := newError(message.CapabilityDenied, name, capability)
+		handle := handleCalls[name]
+		e.builtins[name] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if handle != "" && len(args) > 0 && args[0].Type() == handle {
+				return builtin.Fn(args...)
+			}
+			return err
+		}}
+	}
+}
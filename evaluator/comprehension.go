@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func (e *Evaluator) evalArrayComprehension(node *ast.ArrayComprehension, env *object.Environment) object.Object {
+	elements := []object.Object{}
+
+	err := e.comprehend(node.Clause, env, func(scope *object.Environment) object.Object {
+		element := e.Eval(node.Element, scope)
+		if stopsEvaluation(element) {
+			return element
+		}
+		elements = append(elements, element)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+func (e *Evaluator) evalHashComprehension(node *ast.HashComprehension, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	err := e.comprehend(node.Clause, env, func(scope *object.Environment) object.Object {
+		key := e.Eval(node.Key, scope)
+		if stopsEvaluation(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(message.UnusableHashKey, key.Type())
+		}
+
+		value := e.Eval(node.Value, scope)
+		if stopsEvaluation(value) {
+			return value
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+// comprehend binds the clause's pattern to each item of its iterable in a
+// new scope and, when the condition holds there, calls each with that scope.
+// It stops with the first error or return value, from the clause or each.
+func (e *Evaluator) comprehend(
+	clause *ast.ComprehensionClause,
+	env *object.Environment,
+	each func(scope *object.Environment) object.Object,
+) object.Object {
+	iterable := e.Eval(clause.Iterable, env)
+	if stopsEvaluation(iterable) {
+		return iterable
+	}
+	items, err := iterationItems(iterable)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		scope := scopeEnvironment(env, clause.Locals)
+		if err := bindPattern(scope, clause.Pattern, item); err != nil {
+			return err
+		}
+
+		if clause.Condition != nil {
+			condition := e.Eval(clause.Condition, scope)
+			if stopsEvaluation(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				continue
+			}
+		}
+
+		if result := each(scope); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+// iterationItems lists what iterating over obj goes through: the elements of
+// an array or a tuple, the characters of a string, or the (key, value) tuples
+// of a hash in key order.
+func iterationItems(obj object.Object) ([]object.Object, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.String:
+		items := []object.Object{}
+		for _, ch := range obj.Value {
+			items = append(items, &object.Char{Value: ch})
+		}
+		return items, nil
+	case *object.Array:
+		return obj.Elements, nil
+	case *object.Tuple:
+		return obj.Elements, nil
+	case *object.Hash:
+		items := []object.Object{}
+		for _, pair := range obj.SortedPairs() {
+			items = append(items, &object.Tuple{Elements: []object.Object{pair.Key, pair.Value}})
+		}
+		return items, nil
+	default:
+		return nil, newError(message.NotIterable, obj.Type())
+	}
+}
+
+// scopeEnvironment creates the environment for a scope nested in env, with
+// slots for locals once the resolver has named them.
+func scopeEnvironment(env *object.Environment, locals []string) *object.Environment {
+	if locals != nil {
+		return object.NewSlotEnvironment(env, locals)
+	}
+	return object.NewEnclosedEnvironment(env)
+}
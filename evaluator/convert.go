@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// fromGo converts a value decoded from a document format such as YAML into
+// an object. Maps become hashes and slices arrays; Monkey has no floats, so
+// they and anything else without a Monkey type are an error.
+func fromGo(value interface{}) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return boolean(value), nil
+	case int:
+		return integer(int64(value)), nil
+	case int64:
+		return integer(value), nil
+	case uint64:
+		return integer(int64(value)), nil
+	case string:
+		return &object.String{Value: value}, nil
+	case []byte:
+		return &object.Bytes{Value: value}, nil
+	case time.Time:
+		return &object.Time{Value: value}, nil
+	case fmt.Stringer:
+		// Dates and times without a zone, such as TOML's local dates.
+		return &object.String{Value: value.String()}, nil
+
+	case []interface{}:
+		elements := make([]object.Object, len(value))
+		for i, element := range value {
+			obj, err := fromGo(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case []map[string]interface{}:
+		elements := make([]object.Object, len(value))
+		for i, element := range value {
+			obj, err := fromGo(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case map[string]interface{}:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(value))}
+		for key, element := range value {
+			if err := addFromGo(hash, key, element); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+
+	case map[interface{}]interface{}:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(value))}
+		for key, element := range value {
+			if err := addFromGo(hash, key, element); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %T", value, value)
+}
+
+func addFromGo(hash *object.Hash, key, value interface{}) error {
+	k, err := fromGo(key)
+	if err != nil {
+		return err
+	}
+	hashable, ok := k.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", k.Type())
+	}
+	v, err := fromGo(value)
+	if err != nil {
+		return err
+	}
+	hash.Pairs[hashable.HashKey()] = object.HashPair{Key: k, Value: v}
+	return nil
+}
+
+// toGo converts obj to the plain Go values document encoders understand. A
+// hash with only string keys becomes a map[string]interface{}, so formats
+// that need string keys can encode it.
+func toGo(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Char:
+		return string(obj.Value), nil
+	case *object.Bytes:
+		return obj.Value, nil
+	case *object.Time:
+		return obj.Value, nil
+
+	case *object.Array:
+		return toGoSlice(obj.Elements)
+	case *object.Tuple:
+		return toGoSlice(obj.Elements)
+
+	case *object.Hash:
+		if m, ok := stringKeyed(obj); ok {
+			for _, pair := range obj.Pairs {
+				value, err := toGo(pair.Value)
+				if err != nil {
+					return nil, err
+				}
+				m[pair.Key.(*object.String).Value] = value
+			}
+			return m, nil
+		}
+
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := toGo(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := toGo(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot encode %s", obj.Type())
+}
+
+func toGoSlice(elements []object.Object) ([]interface{}, error) {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		value, err := toGo(element)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// stringKeyed returns an empty map for hash if all its keys are strings.
+func stringKeyed(hash *object.Hash) (map[string]interface{}, bool) {
+	for _, pair := range hash.Pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			return nil, false
+		}
+	}
+	return make(map[string]interface{}, len(hash.Pairs)), true
+}
@@ -2,161 +2,594 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
 	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/token"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
+const DefaultMaxCallDepth = 10000
+
+// maxInternedLength is the longest string literal that is interned.
+const maxInternedLength = 64
+
+// maxRepeatLength bounds the lengths builtins are given, such as how much
+// read reads, so a mistaken count fails instead of exhausting memory. It is
+// also the MaxElements of evaluators configured without one.
+const maxRepeatLength = 1 << 28
+
+// interruptEvery is how many elements the loops building arrays and strings
+// go through between checks for an interrupt.
+const interruptEvery = 1 << 14
+
+// maxStackFrames is how many of the innermost calls an error reporting the
+// call stack lists.
+const maxStackFrames = 10
+
+type Config struct {
+	// MaxCallDepth is how many function calls may be active at once before
+	// evaluation stops with an error. Zero means DefaultMaxCallDepth.
+	MaxCallDepth int
+
+	// MaxElements bounds how many elements an array, and how many bytes a
+	// string or bytes value, may have when built by a range, by repeating
+	// one, by push or pushMut, by concatenating, or by the builtins joining,
+	// replacing, padding or writing text, so a script fails instead of
+	// exhausting memory. Zero means 1 << 28; sandboxes for
+	// untrusted code should allow far fewer.
+	MaxElements int
+
+	// MaxWorkers bounds how many goroutines parallel builtins such as pmap
+	// use. Zero means runtime.GOMAXPROCS(0).
+	MaxWorkers int
+
+	// Sandboxed disables every builtin that needs a capability missing from
+	// Capabilities. Calling one fails with an error IsCapabilityDenied
+	// recognizes.
+	Sandboxed    bool
+	Capabilities []Capability
+
+	// DeterministicRandom seeds the generator behind random with RandomSeed
+	// instead of the time, so every run gets the same numbers.
+	DeterministicRandom bool
+	RandomSeed          int64
+
+	// Output is where puts writes, os.Stdout when nil.
+	Output io.Writer
+
+	// LogOutput is where the log builtins write, os.Stderr when nil. Messages
+	// less important than LogLevel are dropped, and with LogJSON each message
+	// is written as a JSON object instead of as text.
+	LogOutput io.Writer
+	LogLevel  LogLevel
+	LogJSON   bool
+
+	// StrictIndexing makes indexing past the end of a string, an array, a
+	// tuple or bytes, and looking up a key a hash does not have, an error
+	// instead of null. Optional access such as a?.[i] still gives null.
+	StrictIndexing bool
+
+	// Language is the version of the language, and the features beyond it,
+	// that eval parses source as. Callers parsing programs for the
+	// evaluator should use NewParser, so they do the same.
+	Language parser.Config
+
+	// NoPrelude leaves the prelude's functions, such as map and filter, out
+	// of the environments NewEnvironment returns.
+	NoPrelude bool
+
+	// Stats, when set, collects counters about every evaluation that uses
+	// this configuration, at some cost in speed.
+	Stats *Stats
+
+	// Hooks, when set, are told of every node the evaluation evaluates and
+	// every call it makes.
+	Hooks *Hooks
+
+	// PostMortem keeps where the last evaluation to fail failed, for
+	// Failure to return, which keeps that environment from being collected.
+	PostMortem bool
+
+	// Arena allocates the integers arithmetic produces in chunks instead of
+	// one by one, starting a new chunk after each top-level statement. It
+	// cuts how often the garbage collector runs, but a chunk stays in memory
+	// for as long as any integer in it does.
+	Arena bool
+}
+
+// Evaluator holds the state of one evaluation, such as the current call
+// stack. It is not safe for concurrent use; builtins that evaluate in
+// parallel fork it for each goroutine.
+type Evaluator struct {
+	config   Config
+	builtins map[string]*object.Builtin
+	frames   []string
+
+	// returnValue wraps the value of the return statement being evaluated.
+	// It is unwrapped as soon as it reaches the enclosing function or program,
+	// before anything else is evaluated, so a single wrapper can be reused
+	// for every return.
+	returnValue object.ReturnValue
+
+	// interned holds the objects for short string literals, so evaluating the
+	// same literal again reuses the object and its cached hash key.
+	interned map[string]*object.String
+
+	// random is the generator behind the random builtin, or nil until it is
+	// first needed.
+	random *rand.Rand
+
+	// counting is set while evalCounting evaluates a node, so Eval knows the
+	// node has been counted already.
+	counting bool
+
+	// interrupted is set by Interrupt. Forks share it with their parent, so
+	// interrupting stops parallel builtins too.
+	interrupted *int32
+
+	// arena is where arithmetic allocates integers with Config.Arena, and nil
+	// without it.
+	arena *arena
+
+	// prepared holds the bodies body has prepared, by function literal. Forks
+	// share it with their parent.
+	prepared *sync.Map
+
+	// failure is where the last evaluation failed, with Config.PostMortem.
+	failure *Failure
+
+	// resources holds the resources builtins opened that are still open.
+	// Forks, and the evaluators of the actors started, share it with their
+	// parent.
+	resources *object.Tracker
+
+	// mailbox is that of the actor whose messages e handles, if any, so the
+	// actor cannot ask itself. Forks share it with their parent.
+	mailbox chan object.Message
+
+	// env is the environment of the outermost program being evaluated,
+	// which dumpHeap walks from. Forks share it with their parent.
+	env *object.Environment
+}
+
+func New(config Config) *Evaluator {
+	if config.MaxCallDepth <= 0 {
+		config.MaxCallDepth = DefaultMaxCallDepth
+	}
+	if config.MaxWorkers <= 0 {
+		config.MaxWorkers = runtime.GOMAXPROCS(0)
+	}
+	if config.MaxElements <= 0 {
+		config.MaxElements = maxRepeatLength
+	}
+
+	e := &Evaluator{
+		config:      config,
+		builtins:    make(map[string]*object.Builtin),
+		interned:    make(map[string]*object.String),
+		interrupted: new(int32),
+		prepared:    new(sync.Map),
+		resources:   new(object.Tracker),
+	}
+	for name, builtin := range builtins {
+		e.builtins[name] = builtin
+	}
+	for name, builtin := range e.evaluatorBuiltins() {
+		e.builtins[name] = builtin
+	}
+	e.limitElements()
+	if config.Sandboxed {
+		e.sandbox()
+	}
+	if config.Arena {
+		e.arena = &arena{}
+	}
+	return e
+}
+
+// limitElements replaces the builtins of e that grow an array in place or
+// into a copy, push and pushMut, with ones that fail rather than let it have
+// more than MaxElements.
+func (e *Evaluator) limitElements() {
+	for _, name := range []string{"push", "pushMut"} {
+		builtin := e.builtins[name]
+		e.builtins[name] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) > 0 {
+				if arr, ok := args[0].(*object.Array); ok && len(arr.Elements) >= e.config.MaxElements {
+					return newError(message.TooManyElements, object.ARRAY_OBJ, e.config.MaxElements)
+				}
+			}
+			return builtin.Fn(args...)
+		}}
+	}
+}
+
+// fork returns an Evaluator that can run on another goroutine alongside e. It
+// starts from e's call stack, so the call depth limit spans both.
+func (e *Evaluator) fork() *Evaluator {
+	child := New(e.config)
+	child.frames = append([]string(nil), e.frames...)
+	child.interrupted = e.interrupted
+	child.prepared = e.prepared
+	child.env = e.env
+	child.resources = e.resources
+	child.mailbox = e.mailbox
+	if e.config.DeterministicRandom {
+		// Seeding the child from e keeps scripts that call random from
+		// parallel builtins reproducible.
+		child.random = rand.New(rand.NewSource(e.rng().Int63()))
+	}
+	return child
+}
+
+// Interrupt makes the evaluation in progress on e, and any started after it,
+// stop with an error at the next node they evaluate, until ClearInterrupt is
+// called. It is safe to call from another goroutine.
+func (e *Evaluator) Interrupt() {
+	atomic.StoreInt32(e.interrupted, 1)
+}
+
+// ClearInterrupt lets e evaluate again after Interrupt.
+func (e *Evaluator) ClearInterrupt() {
+	atomic.StoreInt32(e.interrupted, 0)
+}
+
+// checkElements returns the error for a value of type t that would have as
+// many elements as lengths add up to, if that is more than MaxElements.
+func (e *Evaluator) checkElements(t object.ObjectType, lengths ...int) *object.Error {
+	total := 0
+	for _, n := range lengths {
+		if n > e.config.MaxElements-total {
+			return newError(message.TooManyElements, t, e.config.MaxElements)
+		}
+		total += n
+	}
+	return nil
+}
+
+// isInterrupted reports whether e has been interrupted, for the loops that
+// can run for long without evaluating a node to check.
+func (e *Evaluator) isInterrupted() bool {
+	return atomic.LoadInt32(e.interrupted) != 0
+}
+
+// Eval evaluates node with a new Evaluator using the default configuration.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New(Config{}).Eval(node, env)
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if e.config.Stats != nil {
+		if !e.counting {
+			return e.evalCounting(node, env)
+		}
+		e.counting = false
+	}
+	if e.isInterrupted() {
+		return newError(message.Interrupted)
+	}
+	if hooks := e.config.Hooks; hooks != nil && hooks.Node != nil {
+		hooks.Node(node, env)
+	}
 
 	switch node := node.(type) {
 	case *ast.ReturnStatement:
-		value := Eval(node.ReturnValue, env)
-		if isError(value) {
+		value := e.Eval(node.ReturnValue, env)
+		if stopsEvaluation(value) {
 			return value
 		}
-		return &object.ReturnValue{Value: value}
+		e.returnValue.Value = value
+		return &e.returnValue
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return integer(node.Value)
 
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 
 	case *ast.Boolean:
 		return boolean(node.Value)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := e.Eval(node.Right, env)
+		if stopsEvaluation(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		left := e.Eval(node.Left, env)
+		if stopsEvaluation(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
-		if isError(right) {
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return e.Eval(node.Right, env)
+		}
+		right := e.Eval(node.Right, env)
+		if stopsEvaluation(right) {
 			return right
 		}
-		return evalInfixExpression(left, node.Operator, right)
+		return e.evalInfix(left, node.Operator, right)
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		val := e.Eval(node.Value, env)
+		if stopsEvaluation(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			if err := bindPattern(env, node.Pattern, val); err != nil {
+				return err
+			}
+			return nil
+		}
+		bind(env, node.Name, val)
 
 	case *ast.Identifier:
-		return evalIdentifier(env, node)
+		return e.evalIdentifier(env, node)
 
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return e.stringLiteral(node.Value)
+
+	case *ast.BytesLiteral:
+		return &object.Bytes{Value: []byte(node.Value)}
+
+	case *ast.CharLiteral:
+		return &object.Char{Value: node.Value}
 
 	case *ast.FunctionLiteral:
 		return &object.Function{
 			Parameters: node.Parameters,
 			Body:       *node.Body,
 			Env:        env,
+			Locals:     node.Locals,
+			Literal:    node,
 		}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
+		function := e.Eval(node.Function, env)
+		if stopsEvaluation(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		args := e.evalExpressions(node.Arguments, env)
+		if len(args) == 1 && stopsEvaluation(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return e.callFunction(frameName(node.Function), function, args)
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && stopsEvaluation(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
+	case *ast.TupleLiteral:
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && stopsEvaluation(elements[0]) {
+			return elements[0]
+		}
+		return &object.Tuple{Elements: elements}
+
+	case *ast.ComparisonChain:
+		return e.evalComparisonChain(node, env)
+
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		left := e.Eval(node.Left, env)
+		if stopsEvaluation(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
-		if isError(index) {
+		if node.Optional && left == NULL {
+			return NULL
+		}
+		index := e.Eval(node.Index, env)
+		if stopsEvaluation(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return e.evalIndex(left, index, e.config.StrictIndexing && !node.Optional)
+
+	case *ast.ArrayComprehension:
+		return e.evalArrayComprehension(node, env)
+
+	case *ast.HashComprehension:
+		return e.evalHashComprehension(node, env)
+
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env)
+
+	case *ast.FieldExpression:
+		left := e.Eval(node.Left, env)
+		if stopsEvaluation(left) {
+			return left
+		}
+		if node.Optional && left == NULL {
+			return NULL
+		}
+		// The name's string is interned, so its hash key is only computed
+		// the first time the field is read.
+		name := e.stringLiteral(node.Name)
+		if e.config.StrictIndexing && !node.Optional && left.Type() == object.HASH_OBJ {
+			if err := checkIndex(left, name); err != nil {
+				return err
+			}
+		}
+		return evalFieldExpression(left, name)
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
 	}
 
 	return nil
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// callFunction applies fn while recording a frame for it on the call stack, so
+// runaway recursion ends with an error instead of exhausting the Go stack.
+func (e *Evaluator) callFunction(name string, fn object.Object, args []object.Object) object.Object {
+	if len(e.frames) >= e.config.MaxCallDepth {
+		err := newError(message.CallDepthExceeded, e.config.MaxCallDepth)
+		err.Stack = e.stackTrace()
+		return err
+	}
+
+	e.frames = append(e.frames, name)
+	if e.config.Stats != nil {
+		e.config.Stats.recordCallDepth(len(e.frames))
+	}
+	defer func() { e.frames = e.frames[:len(e.frames)-1] }()
+
+	return e.apply(name, fn, args)
+}
+
+// stackTrace lists the innermost frames of the call stack, most recent first,
+// noting how many frames were left out.
+func (e *Evaluator) stackTrace() []string {
+	stack := []string{}
+	for i := len(e.frames) - 1; i >= 0 && len(stack) < maxStackFrames; i-- {
+		stack = append(stack, e.frames[i])
+	}
+	if omitted := len(e.frames) - len(stack); omitted > 0 {
+		stack = append(stack, fmt.Sprintf("... %d more", omitted))
+	}
+	return stack
+}
+
+func frameName(function ast.Expression) string {
+	switch function := function.(type) {
+	case *ast.Identifier:
+		return function.Value
+	case *ast.FunctionLiteral:
+		return "<anonymous fn>"
+	default:
+		return function.String()
+	}
+}
+
+func (e *Evaluator) stringLiteral(value string) *object.String {
+	if len(value) > maxInternedLength {
+		return &object.String{Value: value}
+	}
+
+	str, ok := e.interned[value]
+	if !ok {
+		str = &object.String{Value: value}
+		e.interned[value] = str
+	}
+	return str
+}
+
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
+	return e.apply("<anonymous fn>", fn, args)
+}
+
+// apply calls fn, which is called by name, with args, telling the hooks.
+func (e *Evaluator) apply(name string, fn object.Object, args []object.Object) object.Object {
+	hooks := e.config.Hooks
+	if hooks == nil {
+		return e.call(fn, args)
+	}
+	if hooks.Call != nil {
+		hooks.Call(name, fn, args)
+	}
+	result := e.call(fn, args)
+	if hooks.Return != nil {
+		hooks.Return(name, fn, result)
+	}
+	return result
+}
+
+func (e *Evaluator) call(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) < len(fn.Parameters) {
+			return newError(message.WrongArgumentCount, len(fn.Parameters), len(args))
+		}
 		extendedEnv := extendedFunctionEnv(fn, args)
-		evaluated := Eval(&fn.Body, extendedEnv)
+		evaluated := e.Eval(e.body(fn), extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := fn.Fn(args...)
+		e.track(result)
+		return result
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError(message.NotAFunction, fn.Type())
 	}
 
 }
 
 func extendedFunctionEnv(function *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(function.Env)
+	var env *object.Environment
+	if function.Locals != nil {
+		env = object.NewSlotEnvironment(function.Env, function.Locals)
+	} else {
+		env = object.NewEnclosedEnvironment(function.Env)
+	}
 
 	for paramIdx, param := range function.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		bind(env, param, args[paramIdx])
 	}
 
 	return env
 }
 
+// bind stores val for ident in env, using the slot the resolver gave it when
+// there is one.
+func bind(env *object.Environment, ident *ast.Identifier, val object.Object) object.Object {
+	if ident.Resolved {
+		return env.SetSlot(ident.Index, val)
+	}
+	return env.Set(ident.Value, val)
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+		value := returnValue.Value
+		returnValue.Value = nil
+		return value
 	}
 	return obj
 }
 
-func evalExpressions(
+func (e *Evaluator) evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
-	var result []object.Object
+	if len(exps) == 0 {
+		return nil
+	}
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
-		if isError(evaluated) {
+	result := make([]object.Object, 0, len(exps))
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
+		if stopsEvaluation(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -169,29 +602,35 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalTupleIndexExpression(left, index)
+	case left.Type() == object.BYTES_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalBytesIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(message.IndexNotSupported, left.Type())
 	}
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
 	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
+		key := e.Eval(keyNode, env)
+		if stopsEvaluation(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError(message.UnusableHashKey, key.Type())
 		}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := e.Eval(valueNode, env)
+		if stopsEvaluation(value) {
 			return value
 		}
 
@@ -202,6 +641,13 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 	return &object.Hash{Pairs: pairs}
 }
 
+func evalFieldExpression(left object.Object, name *object.String) object.Object {
+	if left.Type() != object.HASH_OBJ {
+		return newError(message.FieldAccessNotSupported, left.Type())
+	}
+	return evalHashIndexExpression(left, name)
+}
+
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObj := array.(*object.Array)
 	idx := index.(*object.Integer).Value
@@ -212,12 +658,84 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObj.Elements[idx]
 }
 
+func evalTupleIndexExpression(tuple, index object.Object) object.Object {
+	tupleObj := tuple.(*object.Tuple)
+	idx := index.(*object.Integer).Value
+	max := int64(len(tupleObj.Elements) - 1)
+	if idx < 0 || idx > max {
+		return NULL
+	}
+	return tupleObj.Elements[idx]
+}
+
+func evalBytesIndexExpression(bytes, index object.Object) object.Object {
+	bytesObj := bytes.(*object.Bytes)
+	idx := index.(*object.Integer).Value
+	max := int64(len(bytesObj.Value) - 1)
+	if idx < 0 || idx > max {
+		return NULL
+	}
+	return integer(int64(bytesObj.Value[idx]))
+}
+
+// checkIndex returns an error for an index strict indexing rejects: one past
+// the end of a string, an array, a tuple or bytes, or a key a hash does not
+// have. Indexes that are wrong in any mode are left to evalIndexExpression.
+func checkIndex(left, index object.Object) *object.Error {
+	if hash, ok := left.(*object.Hash); ok {
+		if key, ok := index.(object.Hashable); ok {
+			if _, ok := hash.Pairs[key.HashKey()]; !ok {
+				return newError(message.KeyNotFound, index.Inspect())
+			}
+		}
+		return nil
+	}
+
+	idx, ok := index.(*object.Integer)
+	if !ok {
+		return nil
+	}
+	var length int
+	switch left := left.(type) {
+	case *object.String:
+		length = utf8.RuneCountInString(left.Value)
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.Tuple:
+		length = len(left.Elements)
+	case *object.Bytes:
+		length = len(left.Value)
+	default:
+		return nil
+	}
+	if idx.Value < 0 || idx.Value >= int64(length) {
+		return newError(message.IndexOutOfRange, idx.Value, left.Type(), length)
+	}
+	return nil
+}
+
+// evalStringIndexExpression returns the character at index, counting
+// characters rather than bytes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	idx := index.(*object.Integer).Value
+	if idx < 0 {
+		return NULL
+	}
+	for _, ch := range str.(*object.String).Value {
+		if idx == 0 {
+			return &object.Char{Value: ch}
+		}
+		idx--
+	}
+	return NULL
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(message.UnusableHashKey, index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -228,28 +746,57 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
-func evalIdentifier(env *object.Environment, node *ast.Identifier) object.Object {
+func (e *Evaluator) evalIdentifier(env *object.Environment, node *ast.Identifier) object.Object {
+	if node.Resolved {
+		if val := env.GetSlot(node.Depth, node.Index); val != nil {
+			return val
+		}
+	}
+
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.builtins[node.Value]; ok {
 		return builtin
 	}
 
-	return newError("identifier not found: %s", node.Value)
+	return newError(message.IdentifierNotFound, node.Value)
+}
+
+// evalComparisonChain evaluates each operand once, from left to right, and
+// stops at the first comparison that does not hold.
+func (e *Evaluator) evalComparisonChain(chain *ast.ComparisonChain, env *object.Environment) object.Object {
+	left := e.Eval(chain.Operands[0], env)
+	if stopsEvaluation(left) {
+		return left
+	}
+
+	for i, operator := range chain.Operators {
+		right := e.Eval(chain.Operands[i+1], env)
+		if stopsEvaluation(right) {
+			return right
+		}
+
+		result := e.evalInfix(left, operator, right)
+		if isError(result) || result == FALSE {
+			return result
+		}
+		left = right
+	}
+	return TRUE
 }
 
-func evalIfExpression(v *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(v.Condition, env)
-	if isError(condition) {
+func (e *Evaluator) evalIfExpression(v *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(v.Condition, env)
+	if stopsEvaluation(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(v.Consequence, env)
+		return e.Eval(v.Consequence, env)
 	}
 	if v.Alternative != nil {
-		return Eval(v.Alternative, env)
+		return e.Eval(v.Alternative, env)
 	}
 	return NULL
 }
@@ -258,36 +805,45 @@ func isTruthy(v object.Object) bool {
 	return v != FALSE && v != NULL
 }
 
-func evalInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+func (e *Evaluator) evalInfixExpression(left object.Object, operator string, right object.Object) object.Object {
 	switch {
+	case operator == "*" && isRepeatable(left) && right.Type() == object.INTEGER_OBJ:
+		return e.repeat(left, right.(*object.Integer).Value)
+	case operator == "*" && left.Type() == object.INTEGER_OBJ && isRepeatable(right):
+		return e.repeat(right, left.(*object.Integer).Value)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return operatorError(message.TypeMismatch, left.Type(), operator, right.Type())
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(left, operator, right)
+		return e.evalIntegerInfixExpression(left, operator, right)
 	case operator == "==":
-		return boolean(left == right)
+		return boolean(object.Equal(left, right))
 	case operator == "!=":
-		return boolean(left != right)
+		return boolean(!object.Equal(left, right))
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(left, operator, right)
+		return e.evalStringInfixExpression(left, operator, right)
+	case left.Type() == object.BYTES_OBJ && right.Type() == object.BYTES_OBJ:
+		return e.evalBytesInfixExpression(left, operator, right)
+	case left.Type() == object.TIME_OBJ && right.Type() == object.TIME_OBJ:
+		return evalTimeInfixExpression(left, operator, right)
+	case left.Type() == object.CHAR_OBJ && right.Type() == object.CHAR_OBJ:
+		return evalCharInfixExpression(left, operator, right)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+func (e *Evaluator) evalIntegerInfixExpression(left object.Object, operator string, right object.Object) object.Object {
 	leftVal := left.(*object.Integer)
 	rightVal := right.(*object.Integer)
 
+	if value, ok := integerArithmetic(leftVal.Value, operator, rightVal.Value); ok {
+		return integer(value)
+	}
 	switch operator {
-	case "+":
-		return integer(leftVal.Value + rightVal.Value)
-	case "-":
-		return integer(leftVal.Value - rightVal.Value)
-	case "*":
-		return integer(leftVal.Value * rightVal.Value)
-	case "/":
-		return integer(leftVal.Value / rightVal.Value)
+	case "/", "%":
+		return newError(message.DivisionByZero, leftVal.Value, operator)
+	case "..":
+		return e.integerRange(leftVal.Value, rightVal.Value)
 	case ">":
 		return boolean(leftVal.Value > rightVal.Value)
 	case "<":
@@ -297,21 +853,172 @@ func evalIntegerInfixExpression(left object.Object, operator string, right objec
 	case "!=":
 		return boolean(leftVal.Value != rightVal.Value)
 	}
-	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
+}
+
+// integerArithmetic applies the arithmetic operator to left and right. It
+// reports false for other operators and for division by zero.
+func integerArithmetic(left int64, operator string, right int64) (int64, bool) {
+	switch operator {
+	case "+":
+		return left + right, true
+	case "-":
+		return left - right, true
+	case "*":
+		return left * right, true
+	case "/":
+		if right != 0 {
+			return left / right, true
+		}
+	case "%":
+		if right != 0 {
+			return left % right, true
+		}
+	}
+	return 0, false
 }
 
-func evalStringInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+// integerRange returns the integers from start up to, but not including, end.
+func (e *Evaluator) integerRange(start, end int64) object.Object {
+	if end <= start {
+		return &object.Array{Elements: []object.Object{}}
+	}
+	if end-start > int64(e.config.MaxElements) || end-start < 0 {
+		return newError(message.RangeTooLong, start, end)
+	}
+
+	elements := make([]object.Object, 0, end-start)
+	for i := start; i < end; i++ {
+		if (i-start)%interruptEvery == 0 && e.isInterrupted() {
+			return newError(message.Interrupted)
+		}
+		elements = append(elements, integer(i))
+	}
+	return &object.Array{Elements: elements}
+}
+
+func (e *Evaluator) evalStringInfixExpression(left object.Object, operator string, right object.Object) object.Object {
 	leftVal := left.(*object.String)
 	rightVal := right.(*object.String)
 
 	switch operator {
 	case "+":
+		if err := e.checkElements(object.STRING_OBJ, len(leftVal.Value), len(rightVal.Value)); err != nil {
+			return err
+		}
 		return &object.String{Value: leftVal.Value + rightVal.Value}
 	}
-	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
+}
+
+// evalCharInfixExpression compares characters by code point.
+func evalCharInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+	leftVal := left.(*object.Char).Value
+	rightVal := right.(*object.Char).Value
+
+	switch operator {
+	case "<":
+		return boolean(leftVal < rightVal)
+	case ">":
+		return boolean(leftVal > rightVal)
+	}
+	return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
+}
+
+func isRepeatable(obj object.Object) bool {
+	return obj.Type() == object.STRING_OBJ || obj.Type() == object.ARRAY_OBJ
 }
 
+// repeat concatenates count copies of a string or an array. The elements of
+// a repeated array are not copied, so `[[]] * 2` holds the same array twice.
+func (e *Evaluator) repeat(obj object.Object, count int64) object.Object {
+	if count < 0 {
+		return newError(message.NegativeRepeat, count)
+	}
+	limit := int64(e.config.MaxElements)
+
+	switch obj := obj.(type) {
+	case *object.String:
+		if count > 0 && int64(len(obj.Value)) > limit/count {
+			return newError(message.RepeatTooLong, object.STRING_OBJ)
+		}
+		var out strings.Builder
+		out.Grow(len(obj.Value) * int(count))
+		for i := int64(0); i < count; i++ {
+			if i%interruptEvery == 0 && e.isInterrupted() {
+				return newError(message.Interrupted)
+			}
+			out.WriteString(obj.Value)
+		}
+		return &object.String{Value: out.String()}
+
+	case *object.Array:
+		if count > 0 && int64(len(obj.Elements)) > limit/count {
+			return newError(message.RepeatTooLong, object.ARRAY_OBJ)
+		}
+		elements := make([]object.Object, 0, int64(len(obj.Elements))*count)
+		for i := int64(0); i < count; i++ {
+			if i%interruptEvery == 0 && e.isInterrupted() {
+				return newError(message.Interrupted)
+			}
+			elements = append(elements, obj.Elements...)
+		}
+		return &object.Array{Elements: elements}
+
+	default:
+		return newError(message.CannotRepeat, obj.Type())
+	}
+}
+
+func (e *Evaluator) evalBytesInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+	leftVal := left.(*object.Bytes)
+	rightVal := right.(*object.Bytes)
+
+	switch operator {
+	case "+":
+		if err := e.checkElements(object.BYTES_OBJ, len(leftVal.Value), len(rightVal.Value)); err != nil {
+			return err
+		}
+		value := make([]byte, 0, len(leftVal.Value)+len(rightVal.Value))
+		value = append(value, leftVal.Value...)
+		value = append(value, rightVal.Value...)
+		return &object.Bytes{Value: value}
+	}
+	return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
+}
+
+func evalTimeInfixExpression(left object.Object, operator string, right object.Object) object.Object {
+	leftVal := left.(*object.Time).Value
+	rightVal := right.(*object.Time).Value
+
+	switch operator {
+	case "<":
+		return boolean(leftVal.Before(rightVal))
+	case ">":
+		return boolean(leftVal.After(rightVal))
+	}
+	return operatorError(message.UnknownInfixOperator, left.Type(), operator, right.Type())
+}
+
+// Integers from smallIntegerMin to smallIntegerMax are allocated once and
+// shared, as integer objects are never modified.
+const (
+	smallIntegerMin = -128
+	smallIntegerMax = 1024
+)
+
+var smallIntegers = func() []*object.Integer {
+	integers := make([]*object.Integer, smallIntegerMax-smallIntegerMin+1)
+	for i := range integers {
+		integers[i] = &object.Integer{Value: int64(i + smallIntegerMin)}
+	}
+	return integers
+}()
+
 func integer(number int64) *object.Integer {
+	if number >= smallIntegerMin && number <= smallIntegerMax {
+		return smallIntegers[number-smallIntegerMin]
+	}
 	return &object.Integer{Value: number}
 }
 
@@ -329,16 +1036,16 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusOperator(right)
 	default:
-		return newError("unkown operator: %s%s", operator, right.Type())
+		return newError(message.UnknownPrefixOperator, operator, right.Type())
 	}
 }
 
 func evalMinusOperator(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(message.UnknownPrefixOperator, "-", right.Type())
 	}
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return integer(-value)
 }
 
 func evalBangOperator(value object.Object) object.Object {
@@ -354,33 +1061,144 @@ func evalBangOperator(value object.Object) object.Object {
 	}
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	resolve(program)
+	if e.env == nil {
+		e.env = env
+		defer func() { e.env = nil }()
+	}
+
 	var last object.Object
 	for _, stmt := range program.Statements {
-		last = Eval(stmt, env)
+		last = e.Eval(stmt, env)
+		if e.arena != nil {
+			e.arena.release()
+		}
 		switch last := last.(type) {
 		case *object.ReturnValue:
-			return last.Value
+			return unwrapReturnValue(last)
 		case *object.Error:
-			return last
+			return e.positioned(last, stmt, env)
 		}
 	}
 	return last
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var last object.Object
 	for _, stmt := range block.Statements {
-		last = Eval(stmt, env)
-		if last.Type() == object.ERROR_OBJ || last.Type() == object.RETURN_VALUE_OBJ {
+		last = e.Eval(stmt, env)
+		if last != nil && (last.Type() == object.ERROR_OBJ || last.Type() == object.RETURN_VALUE_OBJ) {
+			if err, ok := last.(*object.Error); ok {
+				return e.positioned(err, stmt, env)
+			}
 			return last
 		}
 	}
 	return last
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+// clearPositions removes the positions of the nodes in program, so that
+// errors in it take the position of the statement in the script that ran
+// it, and hooks are not told of them. Positions in the prelude or in a
+// string given to eval would mean nothing in the script.
+func clearPositions(program *ast.Program) {
+	ast.Inspect(program, func(node ast.Node) bool {
+		v := reflect.ValueOf(node)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return true
+		}
+		if field := v.Elem().FieldByName("Token"); field.IsValid() {
+			if tok, ok := field.Addr().Interface().(*token.Token); ok {
+				tok.Line, tok.Column = 0, 0
+			}
+		}
+		return true
+	})
+}
+
+// positioned is positioned for stmt evaluated in env, recording the
+// failure with Config.PostMortem when stmt is where err happened.
+func (e *Evaluator) positioned(err *object.Error, stmt ast.Statement, env *object.Environment) *object.Error {
+	result := positioned(err, stmt)
+	if e.config.PostMortem && result != err {
+		stack := make([]string, len(e.frames))
+		for i, frame := range e.frames {
+			stack[len(stack)-1-i] = frame
+		}
+		e.failure = &Failure{Error: result, Env: env, Stack: stack}
+	}
+	return result
+}
+
+// positioned returns err with the position of stmt, unless it already has
+// the position of a statement nested in stmt. Errors can be shared, so err
+// is copied rather than changed.
+func positioned(err *object.Error, stmt ast.Statement) *object.Error {
+	if err.Line != 0 {
+		return err
+	}
+	var tok token.Token
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		tok = stmt.Token
+	case *ast.ReturnStatement:
+		tok = stmt.Token
+	case *ast.ExpressionStatement:
+		tok = stmt.Token
+	case *ast.BlockStatement:
+		tok = stmt.Token
+	}
+	if tok.Line == 0 {
+		return err
+	}
+	copied := *err
+	copied.Line, copied.Column = tok.Line, tok.Column
+	return &copied
+}
+
+func newError(id message.ID, a ...interface{}) *object.Error {
+	return &object.Error{Code: message.Code(id), Message: message.Format(id, a...)}
+}
+
+type operatorErrorKey struct {
+	id       message.ID
+	left     object.ObjectType
+	operator string
+	right    object.ObjectType
+}
+
+var (
+	operatorErrorsMu      sync.RWMutex
+	operatorErrors        = make(map[operatorErrorKey]*object.Error)
+	operatorErrorsVersion uint64
+)
+
+// operatorError returns the error for an infix operator that does not apply
+// to its operands. The message only depends on the operand types, so each
+// error is created once and shared until the messages change; callers must
+// not modify it.
+func operatorError(id message.ID, left object.ObjectType, operator string, right object.ObjectType) *object.Error {
+	key := operatorErrorKey{id, left, operator, right}
+	version := message.Version()
+
+	operatorErrorsMu.RLock()
+	err, ok := operatorErrors[key]
+	current := operatorErrorsVersion == version
+	operatorErrorsMu.RUnlock()
+	if ok && current {
+		return err
+	}
+
+	err = newError(id, left, operator, right)
+	operatorErrorsMu.Lock()
+	if operatorErrorsVersion != version {
+		operatorErrors = make(map[operatorErrorKey]*object.Error)
+		operatorErrorsVersion = version
+	}
+	operatorErrors[key] = err
+	operatorErrorsMu.Unlock()
+	return err
 }
 
 func isError(obj object.Object) bool {
@@ -389,3 +1207,12 @@ func isError(obj object.Object) bool {
 	}
 	return false
 }
+
+// stopsEvaluation reports whether obj is an error or a value being returned,
+// either of which ends the evaluation of the expression containing it.
+func stopsEvaluation(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ || obj.Type() == object.RETURN_VALUE_OBJ
+	}
+	return false
+}
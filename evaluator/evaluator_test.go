@@ -1,9 +1,16 @@
 package evaluator
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fcidade/monkey-lang/diagnostic"
 	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/message"
 	"github.com/fcidade/monkey-lang/object"
 	"github.com/fcidade/monkey-lang/parser"
 )
@@ -193,6 +200,10 @@ func TestErrorHandling(t *testing.T) {
 			"5 + true; 5;",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"let f = fn(a, b) { b }; f(1);",
+			"wrong number of arguments: want 2, got 1",
+		},
 		{
 			"-true",
 			"unknown operator: -BOOLEAN",
@@ -308,6 +319,44 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestImmediatelyInvokedFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn(x) { x }(5)", 5},
+		{"(fn(x) { x })(5)", 5},
+		{"fn() { fn(y) { y * 2 } }()(4)", 8},
+		{"let y = fn(x) { x * 2 }(3); y", 6},
+		{"fn(x) { x }(5) + 1", 6},
+		{"let h = {\"f\": fn(x) { x + 1 }}; h[\"f\"](1)", 2},
+		{"[fn() { 3 }][0]()", 3},
+		{"let x = 10; fn() { let x = 1; x }(); x", 10},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLambdaShorthand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = |x| x * 2; double(21)", 42},
+		{"(|| 7)()", 7},
+		{"let add = |a, b| a + b; add(2, 3)", 5},
+		{"let adder = |x| |y| x + y; adder(2)(3)", 5},
+		{"let apply = fn(f, x) { f(x) }; apply(|x| x - 1, 10)", 9},
+		{"let sum = [f(1) for f in [|x| x, |x| x * 10]]; sum[0] + sum[1]", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 	evaluated := testEval(input)
@@ -340,8 +389,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` not supported, got INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len(1)`, "len(value: STRING|ARRAY|TUPLE|BYTES): argument 1 must be STRING, ARRAY, TUPLE or BYTES; called with (INTEGER)"},
+		{`len("one", "two")`, "len(value: STRING|ARRAY|TUPLE|BYTES): wrong number of arguments, want 1; called with (STRING, STRING)"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -515,3 +564,2081 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`eval("1 + 2")`, 3},
+		{`eval("let a = 5; a * 2")`, 10},
+		{`let f = fn(x) { x + " * 3" }; eval(f("3"))`, 9},
+		{`let a = 1; eval("a")`, "identifier not found: a"},
+		{`eval("let")`, "parser errors: expected next token to be IDENT, got  instead"},
+		{`eval(1)`, "eval(source: STRING): argument 1 must be STRING; called with (INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestParseBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`parse("1 + 2")["type"]`, "Program"},
+		{`parse("1 + 2")["statements"][0]["expression"]["operator"]`, "+"},
+		{`parse("1 + 2")["statements"][0]["expression"]["right"]["value"]`, 2},
+		{`parse("let x = fn(a, b) { a }")["statements"][0]["value"]["parameters"][1]`, "b"},
+		{`parse("if (x) { 1 }")["statements"][0]["expression"]["alternative"]`, nil},
+		{`parse("match x { _ => 1 }")["statements"][0]["expression"]["arms"][0]["pattern"]["type"]`, "WildcardPattern"},
+		{`len(parse("f(1, 2, 3)")["statements"][0]["expression"]["arguments"])`, 3},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestStringBuilder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`toString(builder())`, ""},
+		{`toString(builder("abc"))`, "abc"},
+		{`toString(append(builder("a"), "b", "c"))`, "abc"},
+		{`let b = builder(); append(b, "x"); append(b, 1, true); toString(b)`, "x1true"},
+		{`let b = builder(); let c = append(b, "x"); toString(b) + toString(c)`, "xx"},
+		{`toString(5)`, "5"},
+		{`toString([1, "a"])`, "[1, a]"},
+		{`builder(1)`, errorMessage("builder(initial?: STRING): argument 1 must be STRING; called with (INTEGER)")},
+		{`append("a", "b")`, errorMessage("append(builder: STRING_BUILDER, values...): argument 1 must be STRING_BUILDER; called with (STRING, STRING)")},
+		{`toString()`, errorMessage("toString(value, base?: INTEGER): wrong number of arguments, want 1 or 2; called with ()")},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+// errorMessage marks an expected value in table tests as the message of an
+// error object rather than a string result.
+type errorMessage string
+
+const concatenationLoop = `
+let loop = fn(n, s) { if (n == 0) { s } else { loop(n - 1, s + "monkey") } };
+len(loop(2000, ""));`
+
+const builderLoop = `
+let loop = fn(n, b) { if (n == 0) { toString(b) } else { loop(n - 1, append(b, "monkey")) } };
+len(loop(2000, builder()));`
+
+func BenchmarkStringConcatenation(b *testing.B) {
+	benchmarkProgram(b, concatenationLoop)
+}
+
+func BenchmarkStringBuilder(b *testing.B) {
+	benchmarkProgram(b, builderLoop)
+}
+
+func benchmarkProgram(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+func TestArrayPush(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`push([1, 2], 3)`, "[1, 2, 3]"},
+		{`let a = [1]; let b = push(a, 2); [a, b]`, "[[1], [1, 2]]"},
+		{`pushMut([1, 2], 3)`, "[1, 2, 3]"},
+		{`let a = [1]; let b = pushMut(a, 2); [a, b]`, "[[1, 2], [1, 2]]"},
+		{`let a = []; pushMut(a, 1); pushMut(a, 2); len(a)`, "2"},
+		{`let a = push([], 1); let b = push(a, 2); pushMut(a, 3); [a, b]`, "[[1, 3], [1, 2]]"},
+		{`pushMut(1, 2)`, "Error: pushMut(array: ARRAY, value): argument 1 must be ARRAY; called with (INTEGER, INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+const pushLoop = `
+let loop = fn(n, arr) { if (n == 0) { arr } else { loop(n - 1, push(arr, n)) } };
+len(loop(5000, []));`
+
+const pushMutLoop = `
+let loop = fn(n, arr) { if (n == 0) { arr } else { loop(n - 1, pushMut(arr, n)) } };
+len(loop(5000, []));`
+
+func BenchmarkPush(b *testing.B) {
+	benchmarkProgram(b, pushLoop)
+}
+
+func BenchmarkPushMut(b *testing.B) {
+	benchmarkProgram(b, pushMutLoop)
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`b"abc"`, `b"abc"`},
+		{`bytes("abc")`, `b"abc"`},
+		{`bytes([0, 104, 105, 255])`, `b"\x00hi\xff"`},
+		{`b"ab" + b"cd"`, `b"abcd"`},
+		{`len(b"abc")`, "3"},
+		{`b"abc"[0]`, "97"},
+		{`b"abc"[3]`, "null"},
+		{`toString(b"abc")`, "abc"},
+		{`hexEncode(b"hi")`, "6869"},
+		{`hexDecode("6869")`, `b"hi"`},
+		{`{b"key": 1}[b"key"]`, "1"},
+		{`slice(b"monkey", 1, 3)`, `b"on"`},
+		{`slice([1, 2, 3], 1)`, "[2, 3]"},
+		{`slice("héllo", 1, 3)`, "él"},
+		{`slice([1, 2, 3], 2, 1)`, "Error: slice(value: ARRAY|BYTES|STRING, start: INTEGER, end?: INTEGER): bounds [2:1] out of range with length 3; called with (ARRAY, INTEGER, INTEGER)"},
+		{`bytes([256])`, "Error: bytes(value: STRING|BYTES|ARRAY): argument 1 must only contain integers between 0 and 255, got 256; called with (ARRAY)"},
+		{`hexDecode("zz")`, "Error: hexDecode(hex: STRING): could not decode hex: encoding/hex: invalid byte: U+007A 'z'; called with (STRING)"},
+		{`b"a" + "a"`, "Error: type mismatch: BYTES + STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEncodingBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`hexEncode(sha256("abc"))`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`hexEncode(sha256(b"abc"))`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`hexEncode(md5("abc"))`, "900150983cd24fb0d6963f7d28e17f72"},
+		{`hexEncode("hi")`, "6869"},
+		{`base64Encode("monkey")`, "bW9ua2V5"},
+		{`base64Encode(bytes([0]))`, "AA=="},
+		{`base64Decode("bW9ua2V5")`, `b"monkey"`},
+		{`toString(base64Decode(base64Encode("round trip")))`, "round trip"},
+		{`urlEncode("a b&c=d")`, "a+b%26c%3Dd"},
+		{`urlDecode("a+b%26c%3Dd")`, "a b&c=d"},
+		{`sha256(1)`, "Error: sha256(data: STRING|BYTES): argument 1 must be STRING or BYTES; called with (INTEGER)"},
+		{`base64Decode("!!")`, "Error: base64Decode(data: STRING|BYTES): could not decode base64: illegal base64 data at input byte 0; called with (STRING)"},
+		{`urlDecode("%zz")`, `Error: urlDecode(data: STRING|BYTES): could not decode url: invalid URL escape "%zz"; called with (STRING)`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSortBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([])`, "[]"},
+		{`let a = [2, 1]; sort(a); a`, "[2, 1]"},
+		{`sortBy(["ccc", "a", "bb"], len)`, "[a, bb, ccc]"},
+		{`sortBy([1, 2, 3], fn(x) { -x })`, "[3, 2, 1]"},
+		{`sortBy([[1, "b"], [0, "a"], [1, "a"], [0, "b"]], fn(p) { p[0] })`, "[[0, a], [0, b], [1, b], [1, a]]"},
+		{`sortBy([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]"},
+		{`sortBy([3, 1, 2], fn(a, b) { a - b })`, "[1, 2, 3]"},
+		{`sort([1, "a"])`, "Error: sort(array: ARRAY): cannot compare INTEGER with STRING; called with (ARRAY)"},
+		{`sort([true])`, "Error: sort(array: ARRAY): can only order INTEGER or STRING values, got BOOLEAN; called with (ARRAY)"},
+		{`sortBy([1, "a"], fn(x) { x })`, "Error: sortBy(array: ARRAY, fn: FUNCTION): cannot compare INTEGER with STRING; called with (ARRAY, FUNCTION)"},
+		{`sortBy([1, 2], fn(x) { x + true })`, "Error: type mismatch: INTEGER + BOOLEAN"},
+		{`sortBy([1, 2], fn(a, b) { a + true })`, "Error: type mismatch: INTEGER + BOOLEAN"},
+		{`sortBy([1, 2], fn(a, b) { "yes" })`, "Error: sortBy(array: ARRAY, fn: FUNCTION): the comparator must return BOOLEAN or INTEGER, got STRING; called with (ARRAY, FUNCTION)"},
+		{`sortBy([1, 2], fn() { 1 })`, "Error: sortBy(array: ARRAY, fn: FUNCTION): argument 2 must be a FUNCTION taking 1 or 2 parameters; called with (ARRAY, FUNCTION)"},
+		{`sortBy([1, 2], 3)`, "Error: sortBy(array: ARRAY, fn: FUNCTION): argument 2 must be FUNCTION; called with (ARRAY, INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCloneAndFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1, [2]]; let b = clone(a); pushMut(b[1], 3); [a, b]`, "[[1, [2]], [1, [2, 3]]]"},
+		{`let h = {"k": [1]}; let c = clone(h); pushMut(c["k"], 2); [h["k"], c["k"]]`, "[[1], [1, 2]]"},
+		{`clone(5)`, "5"},
+		{`let b = builder("a"); let c = clone(b); append(c, "b"); [toString(b), toString(c)]`, "[a, ab]"},
+		{`let a = [1]; pushMut(a, a); let b = clone(a); b[1][1][1][0]`, "1"},
+		{`let a = []; pushMut(a, a); let b = clone(a); len(b[0][0])`, "1"},
+		{`let a = freeze([1]); pushMut(a, 2)`, "Error: cannot modify frozen ARRAY"},
+		{`let a = freeze({"k": [1]}); pushMut(a["k"], 2)`, "Error: cannot modify frozen ARRAY"},
+		{`let a = freeze([[1]]); pushMut(a[0], 2)`, "Error: cannot modify frozen ARRAY"},
+		{`let a = freeze([1]); push(a, 2)`, "[1, 2]"},
+		{`let a = freeze([1]); let b = clone(a); pushMut(b, 2); [isFrozen(a), isFrozen(b), b]`, "[true, false, [1, 2]]"},
+		{`[isFrozen([]), isFrozen(freeze({})), isFrozen(1), freeze(1)]`, "[false, true, false, 1]"},
+		{`let a = []; pushMut(a, a); freeze(a); isFrozen(a[0])`, "true"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEqualityOfValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"monkey" == "monkey"`, true},
+		{`"monkey" != "monkey"`, false},
+		{`"monkey" == "ape"`, false},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, 2] != [1, 2, 3]`, true},
+		{`{"a": [1], 2: true} == {2: true, "a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`b"ab" == bytes("ab")`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+		{`let a = [1]; pushMut(a, a); let b = [1]; pushMut(b, b); a == b`, true},
+	}
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := `let f = fn(n) { f(n + 1) }; f(0)`
+	evaluated := testEval(input)
+	if !testErrorObject(t, evaluated, "maximum call depth exceeded (10000)") {
+		return
+	}
+	stack := evaluated.(*object.Error).Stack
+	if len(stack) != 11 || stack[0] != "f" || stack[10] != "... 9990 more" {
+		t.Errorf("wrong stack. got=%q", stack)
+	}
+
+	deep := `let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } }; count(9000)`
+	testIntegerObject(t, testEval(deep), 9000)
+}
+
+func TestConfiguredMaxCallDepth(t *testing.T) {
+	input := `
+	let inner = fn(n) { inner(n) };
+	let outer = fn() { inner(1) };
+	let h = {"start": fn() { outer() }};
+	h["start"]()`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluated := New(Config{MaxCallDepth: 5}).Eval(program, object.NewEnvironment())
+
+	expected := "Error: maximum call depth exceeded (5)\n" +
+		"\tat inner\n\tat inner\n\tat inner\n\tat outer\n\tat (h[start])"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestMaxElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len(0..10)`, "10"},
+		{`0..11`, "Error: range 0..11 is too long"},
+		{`len("ab" * 5)`, "10"},
+		{`repeat("ab", 6)`, "Error: repeated STRING would be too long"},
+		{`[1, 2] * 6`, "Error: repeated ARRAY would be too long"},
+		{`"abcdef" + "abcde"`, "Error: STRING would have more than 10 elements"},
+		{`len(pushMut(0..9, 1))`, "10"},
+		{`push(0..10, 1)`, "Error: ARRAY would have more than 10 elements"},
+		{`let a = 0..10; pushMut(a, 1)`, "Error: ARRAY would have more than 10 elements"},
+		{`b"abcdef" + b"abcde"`, "Error: BYTES would have more than 10 elements"},
+		{`append(builder("abcdef"), "ab", "cde")`, "Error: STRING_BUILDER would have more than 10 elements"},
+		{`strings.join(["abcd", "abcd"], "xyz")`, "Error: STRING would have more than 10 elements"},
+		{`strings.join(["abcd", "abcd"], "xy")`, "abcdxyabcd"},
+		{`strings.replace("aaaa", "a", "bbb")`, "Error: STRING would have more than 10 elements"},
+		{`strings.replace("aaaaaaaaaaaa", "aa", "b")`, "bbbbbb"},
+		{`padLeft("a", 11)`, "Error: STRING would have more than 10 elements"},
+		{`center("a", 5, "é")`, "ééaéé"},
+		{`center("a", 6, "é")`, "Error: STRING would have more than 10 elements"},
+		{`template("{{a}}{{a}}", {"a": "abcdef"})`, "Error: STRING would have more than 10 elements"},
+		{`csvEncode([["abcdef", "abcdef"]])`, "Error: STRING would have more than 10 elements"},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New(Config{MaxElements: 10})
+		evaluated := e.Eval(program, e.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	e := New(Config{})
+	e.Interrupt()
+	for _, result := range []object.Object{e.integerRange(0, 1<<20), e.repeat(&object.String{Value: "a"}, 1<<20)} {
+		if err, ok := result.(*object.Error); !ok || err.Message != "interrupted" {
+			t.Errorf("expected building to be interrupted, got %s", result.Inspect())
+		}
+	}
+}
+
+func TestStringLiteralsAreInterned(t *testing.T) {
+	program := parser.New(lexer.New(`["monkey", "monkey"]`)).ParseProgram()
+	arr := New(Config{}).Eval(program, object.NewEnvironment()).(*object.Array)
+	if arr.Elements[0] != arr.Elements[1] {
+		t.Errorf("short string literals should evaluate to the same object")
+	}
+}
+
+const hashLookupLoop = `
+let config = {"name": "monkey", "version": 1, "interpreted": true, "authors": ["thorsten"]};
+let loop = fn(n, acc) {
+	if (n == 0) { acc } else { loop(n - 1, acc + config["version"] + len(config["name"])) }
+};
+loop(5000, 0);`
+
+func BenchmarkHashLookups(b *testing.B) {
+	benchmarkProgram(b, hashLookupLoop)
+}
+
+const methodCalls = `
+let counter = {"step": 2, "next": fn(n) { n + 1 }};
+let loop = fn(n, acc) {
+	if (n == 0) { acc } else { loop(n - 1, counter.next(acc) + counter.step) }
+};
+loop(5000, 0);`
+
+const operatorMethodCalls = `
+let Meters = fn(n) { {"n": n, "__add__": fn(a, b) { Meters(a.n + b.n) }} };
+let loop = fn(n, acc) { if (n == 0) { acc.n } else { loop(n - 1, acc + Meters(1)) } };
+loop(2000, Meters(0));`
+
+func BenchmarkMethodCalls(b *testing.B) {
+	benchmarkProgram(b, methodCalls)
+}
+
+func BenchmarkOperatorMethods(b *testing.B) {
+	benchmarkProgram(b, operatorMethodCalls)
+}
+
+func TestResolvedVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = 1; let f = fn() { let x = x + 1; x }; [f(), x]`, "[2, 1]"},
+		{`let f = fn() { let g = fn(n) { if (n == 0) { 0 } else { g(n - 1) } }; g(3) }; f()`, "0"},
+		{`let f = fn(x) { fn(y) { fn(z) { x + y + z } } }; f(1)(2)(3)`, "6"},
+		{`let f = fn() { let x = 1; let h = fn() { let g = fn() { x }; let x = 2; g() }; h() }; f()`, "2"},
+		{`let f = fn(c) { if (c) { let y = 1; }; y }; f(true)`, "1"},
+		{`let f = fn(c) { if (c) { let y = 1; }; y }; f(false)`, "Error: identifier not found: y"},
+		{`let f = fn(len) { len }; f(3)`, "3"},
+		{`let f = fn(a, a) { a }; f(1, 2)`, "2"},
+		{`let f = fn(x) { eval("x") }; f(1)`, "Error: identifier not found: x"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+const fibonacci = `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+fib(20);`
+
+const closureCalls = `
+let adder = fn(x) { fn(y) { let sum = x + y; sum } };
+let loop = fn(n, acc) {
+	if (n == 0) { acc } else { let add = adder(n); loop(n - 1, add(acc)) }
+};
+loop(5000, 0);`
+
+func BenchmarkFibonacci(b *testing.B) {
+	benchmarkProgram(b, fibonacci)
+}
+
+func BenchmarkClosures(b *testing.B) {
+	benchmarkProgram(b, closureCalls)
+}
+
+const constantArithmetic = `
+let seconds = fn(days) { days * 24 * 60 * 60 + (60 * 60 * 24 - 86400) };
+let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + seconds(1) / (10 * 10)) } };
+loop(5000, 0);`
+
+func BenchmarkConstantArithmetic(b *testing.B) {
+	benchmarkProgram(b, constantArithmetic)
+}
+
+func BenchmarkFibonacciArena(b *testing.B) {
+	benchmarkArena(b, fibonacci)
+}
+
+func BenchmarkClosuresArena(b *testing.B) {
+	benchmarkArena(b, closureCalls)
+}
+
+func benchmarkArena(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(Config{Arena: true}).Eval(program, object.NewEnvironment())
+	}
+}
+
+func TestArena(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{fibonacci, "6765"},
+		{closureCalls, "12502500"},
+		{`let xs = [2000 + 1, 3000 * 2]; let y = xs[0] - 1; [xs, y]`, "[[2001, 6000], 2000]"},
+		{`let big = 9223372036854775807; big + 1`, "-9223372036854775808"},
+		{`5000 / 0`, "Error: division by zero: 5000 / 0"},
+		{`2000 % 0`, "Error: division by zero: 2000 % 0"},
+		{`[2000 + 3000 for x in 0..3]`, "[5000, 5000, 5000]"},
+	}
+	for _, tt := range tests {
+		ev := New(Config{Arena: true})
+		evaluated := ev.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPreparedBodies(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(x) { x * (60 * 60) }; [f(1), f(2)]`, "[3600, 7200]"},
+		{`let f = fn() { if (1 < 2) { "a" + "b" } else { -(3 - 1) } }; [f(), f()]`, "[ab, ab]"},
+		{`let f = fn(x) { if (x) { 1 / 0 } else { 2 % 1 } }; [f(false), f(true)]`, "Error: division by zero: 1 / 0"},
+		{`let f = fn() { let a = [1 + 1, 2 * 3]; a[3 - 2] }; f()`, "6"},
+		{`let f = fn() { 9223372036854775807 + 1 }; f()`, "-9223372036854775808"},
+		{`let make = fn(n) { fn() { n + 2 * 2 } }; [make(1)(), make(2)()]`, "[5, 6]"},
+		{`let f = fn() { 1 + 2 }; f(); f`, "fn() {\n(1 + 2)\n}"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestReturnInsideExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn() { let x = if (true) { return 1; }; 2 }; f()`, "1"},
+		{`let f = fn() { [if (true) { return 1; }, 2] }; f()`, "1"},
+		{`let f = fn() { 10 + if (true) { return 1; } }; f()`, "1"},
+		{`let f = fn() { puts(if (true) { return 1; }) }; f()`, "1"},
+		{`let f = fn() { return 1; }; let g = fn() { return f() + 1; }; [g(), f()]`, "[2, 1]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	tests := []struct {
+		setup     string
+		input     string
+		maxAllocs float64
+	}{
+		{``, `!true`, 0},
+		{``, `1 + 2`, 0},
+		{``, `1 + true`, 0},
+		{`let f = fn() { return 1; };`, `f()`, 1},
+		{`let f = fn(x) { x };`, `f(1)`, 3},
+	}
+	for _, tt := range tests {
+		ev := New(Config{})
+		env := object.NewEnvironment()
+		ev.Eval(parser.New(lexer.New(tt.setup)).ParseProgram(), env)
+		stmt := parser.New(lexer.New(tt.input)).ParseProgram().Statements[0]
+
+		allocs := testing.AllocsPerRun(100, func() { ev.Eval(stmt, env) })
+		if allocs > tt.maxAllocs {
+			t.Errorf("evaluating %q allocated too much. max=%v, got=%v",
+				tt.input, tt.maxAllocs, allocs)
+		}
+	}
+}
+
+const zeroArgCalls = `
+let one = fn() { 1 };
+let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + one()) } };
+loop(5000, 0);`
+
+const earlyReturns = `
+let pick = fn(n) { if (n > 0) { return n; } return 0; };
+let loop = fn(n, acc) { if (n == 0) { return acc; } return loop(n - 1, acc + pick(n)); };
+loop(5000, 0);`
+
+func BenchmarkZeroArgCalls(b *testing.B) {
+	benchmarkProgram(b, zeroArgCalls)
+}
+
+func BenchmarkReturns(b *testing.B) {
+	benchmarkProgram(b, earlyReturns)
+}
+
+func BenchmarkOperatorErrors(b *testing.B) {
+	benchmarkProgram(b, `1 + true`)
+}
+
+func TestParallelBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`pmap([1, 2, 3, 4, 5], fn(x) { x * x })`, "[1, 4, 9, 16, 25]"},
+		{`pmap([], fn(x) { x })`, "[]"},
+		{`pmap(["a", "bc"], len)`, "[1, 2]"},
+		{`let n = 10; pmap([1, 2], fn(x) { x + n })`, "[11, 12]"},
+		{`pmap([[1, 2], [3]], fn(xs) { pmap(xs, fn(x) { -x }) })`, "[[-1, -2], [-3]]"},
+		{`pfilter([1, 2, 3, 4, 5, 6], fn(x) { x > 3 })`, "[4, 5, 6]"},
+		{`pfilter([1, 2, 3], fn(x) { false })`, "[]"},
+		{`pmap([1, true, "a"], fn(x) { -x })`, "Error: unknown operator: -BOOLEAN"},
+		{`pmap(1, fn(x) { x })`, "Error: pmap(array: ARRAY, fn: FUNCTION): argument 1 must be ARRAY; called with (INTEGER, FUNCTION)"},
+		{`pfilter([1], 2)`, "Error: pfilter(array: ARRAY, fn: FUNCTION): argument 2 must be FUNCTION; called with (ARRAY, INTEGER)"},
+		{`pmap([1])`, "Error: pmap(array: ARRAY, fn: FUNCTION): wrong number of arguments, want 2; called with (ARRAY)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestParallelBuiltinsShareCallDepth(t *testing.T) {
+	input := `let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; pmap([1, 100], f)`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluated := New(Config{MaxCallDepth: 50, MaxWorkers: 2}).Eval(program, object.NewEnvironment())
+
+	testErrorObject(t, evaluated, "maximum call depth exceeded (50)")
+}
+
+func TestAsyncAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`await(async(fn() { 1 + 2 }))`, "3"},
+		{`let n = 5; let f = async(fn() { n * 2 }); await(f) + await(f)`, "20"},
+		{`let fs = [async(fn() { "a" }), async(fn() { "b" })]; await(fs[0]) + await(fs[1])`, "ab"},
+		{`await(async(fn() { await(async(fn() { 7 })) }))`, "7"},
+		{`let f = async(fn() { 1 + true }); await(f); 10`, "Error: type mismatch: INTEGER + BOOLEAN"},
+		{`let f = async(fn() { 1 }); await(f); f`, "future(1)"},
+		{`async(fn(x) { x })`, "Error: async(fn: FUNCTION): argument 1 must be a FUNCTION taking 0 parameters; called with (FUNCTION)"},
+		{`async(1)`, "Error: async(fn: FUNCTION): argument 1 must be FUNCTION; called with (INTEGER)"},
+		{`await(1)`, "Error: await(future: FUTURE): argument 1 must be FUTURE; called with (INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestActors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = actor(fn(x) { x * 2 }); ask(a, 21)`, "42"},
+		{`let log = []; let a = actor(fn(x) { pushMut(log, x); len(log) }); tell(a, 1); tell(a, 2); ask(a, 3)`, "3"},
+		{`let a = actor(fn(xs) { pushMut(xs, 1); xs }); let xs = [0]; let r = ask(a, xs); [xs, r]`, "[[0], [0, 1]]"},
+		{`let a = actor(fn(x) { x }); ask(a, ask(a, "echo"))`, "echo"},
+		{`let a = actor(fn(x) { x + true }); tell(a, 1); ask(a, 2)`, "Error: type mismatch: INTEGER + BOOLEAN"},
+		{`actor(len)`, "actor"},
+		{`tell(actor(len), "a")`, "null"},
+		{`actor(fn() { 1 })`, "Error: actor(fn: FUNCTION): argument 1 must be a FUNCTION taking 1 parameter; called with (FUNCTION)"},
+		{`ask(1, 2)`, "Error: ask(actor: ACTOR, message): argument 1 must be ACTOR; called with (INTEGER, INTEGER)"},
+		{`tell(actor(len))`, "Error: tell(actor: ACTOR, message): wrong number of arguments, want 2; called with (ACTOR)"},
+		{`let a = actor(fn(x) { if (x > 0) { ask(a, x - 1) } else { x } }); ask(a, 1)`,
+			"Error: ask(actor: ACTOR, message): an actor cannot ask itself, it would wait for its own reply; called with (ACTOR, INTEGER)"},
+		{`let a = actor(fn(x) { pmap([x], fn(y) { ask(a, y) }) }); ask(a, 1)`,
+			"Error: ask(actor: ACTOR, message): an actor cannot ask itself, it would wait for its own reply; called with (ACTOR, INTEGER)"},
+		{`let a = actor(fn(x) { x }); let b = actor(fn(x) { ask(a, x) + 1 }); ask(b, 1)`, "2"},
+		{`let a = actor(fn(x) { x }); close(a); tell(a, 1)`, "Error: tell(actor: ACTOR, message): the actor is stopped; called with (ACTOR, INTEGER)"},
+		{`let a = actor(fn(x) { x }); close(a); ask(a, 1)`, "Error: ask(actor: ACTOR, message): the actor is stopped; called with (ACTOR, INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestActorsStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	e := New(Config{})
+	program := e.NewParser(`let actors = [actor(fn(x) { x }) for _ in 0..10]; [ask(a, 1) for a in actors]; actors`).ParseProgram()
+	actors, ok := e.Eval(program, e.NewEnvironment()).(*object.Array)
+	if !ok {
+		t.Fatalf("expected the actors, got %v", actors)
+	}
+	if err := e.CloseResources(); err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range actors.Elements {
+		if !actor.(*object.Actor).Closed() {
+			t.Errorf("expected the actors to be stopped")
+		}
+	}
+	for deadline := time.Now().Add(2 * time.Second); runtime.NumGoroutine() > before; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the goroutines of the actors to exit, %d are left", runtime.NumGoroutine()-before)
+		}
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = [1, 2]; a + b`, "3"},
+		{`let [head, ...tail] = [1, 2, 3]; [head, tail]`, "[1, [2, 3]]"},
+		{`let [x, ...rest] = [1]; rest`, "[]"},
+		{`let {name, age} = {"name": "ana", "age": 30}; [name, age]`, "[ana, 30]"},
+		{`let {name: n, pets: [first, ...others]} = {"name": "ana", "pets": ["rex", "tom"]}; [n, first, others]`, "[ana, rex, [tom]]"},
+		{`let f = fn(pair) { let [a, b] = pair; b - a }; f([1, 5])`, "4"},
+		{`let f = fn(p) { let {x} = p; fn() { x } }; f({"x": 7})()`, "7"},
+		{`let [a, b] = [1];`, "Error: array pattern [a, b] expects exactly 2 elements, got 1"},
+		{`let [a] = [1, 2];`, "Error: array pattern [a] expects exactly 1 elements, got 2"},
+		{`let [a, b, ...c] = [1];`, "Error: array pattern [a, b, ...c] expects at least 2 elements, got 1"},
+		{`let [a] = 1;`, "Error: cannot destructure INTEGER with array pattern [a]"},
+		{`let {a} = [1];`, "Error: cannot destructure ARRAY with hash pattern {a}"},
+		{`let {name, age} = {"name": "ana"};`, `Error: hash pattern {name, age} is missing key "age"`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(1, "a", [2])`, "(1, a, [2])"},
+		{`(1,)`, "(1,)"},
+		{`()`, "()"},
+		{`let t = (1, 2); [t[0], t[1], t[2], len(t)]`, "[1, 2, null, 2]"},
+		{`let minmax = fn(a, b) { if (a < b) { return a, b; } return b, a; }; minmax(3, 1)`, "(1, 3)"},
+		{`let minmax = fn(a, b) { if (a < b) { return a, b; } return b, a; }; let lo, hi = minmax(3, 1); hi - lo`, "2"},
+		{`let (a, [b, c]) = (1, [2, 3]); a + b + c`, "6"},
+		{`(1, [2]) == (1, [2])`, "true"},
+		{`(1, 2) == (2, 1)`, "false"},
+		{`let a, b = (1, 2, 3);`, "Error: tuple pattern (a, b) expects 2 elements, got 3"},
+		{`let a, b = [1, 2];`, "Error: cannot destructure ARRAY with tuple pattern (a, b)"},
+		{`(1, 1 + true)`, "Error: type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestOptionalChainingAndNullCoalescing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let p = {"name": "ana", "pet": {"name": "rex"}}; p.pet.name`, "rex"},
+		{`let p = {"name": "ana"}; p.age`, "null"},
+		{`let p = {"name": "ana"}; p.pet?.name`, "null"},
+		{`let p = {"name": "ana"}; p.pet?.[0]`, "null"},
+		{`let p = {"tags": ["a"]}; p?.tags?.[0]`, "a"},
+		{`let p = {"name": "ana"}; p.pet?.name ?? "none"`, "none"},
+		{`0 ?? 1`, "0"},
+		{`false ?? 1`, "false"},
+		{`[][0] ?? "empty"`, "empty"},
+		{`1 ?? 1 + true`, "1"},
+		{`let p = {"name": "ana"}; p.pet.name`, "Error: field access not supported: NULL"},
+		{`[1].length`, "Error: field access not supported: ARRAY"},
+		{`1?.x`, "Error: field access not supported: INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestComparisonChains(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1 < 2 < 3`, "true"},
+		{`1 < 3 < 2`, "false"},
+		{`3 > 2 > 1`, "true"},
+		{`1 < 2 > 0`, "true"},
+		{`let x = 5; 0 < x < 10`, "true"},
+		{`let calls = []; let f = fn(x) { pushMut(calls, x); x }; f(1) < f(2) < f(3); calls`, "[1, 2, 3]"},
+		{`let calls = []; let f = fn(x) { pushMut(calls, x); x }; f(2) < f(1) < f(3); calls`, "[2, 1]"},
+		{`1 < 2 < true`, "Error: type mismatch: INTEGER < BOOLEAN"},
+		{`(1 < 2) < 3`, "Error: type mismatch: BOOLEAN < INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestRepetition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"ab" * 0`, ""},
+		{`[0] * 5`, "[0, 0, 0, 0, 0]"},
+		{`2 * [1, 2]`, "[1, 2, 1, 2]"},
+		{`[] * 3`, "[]"},
+		{`let row = [[]] * 2; pushMut(row[0], 1); row`, "[[1], [1]]"},
+		{`repeat("-", 4)`, "----"},
+		{`repeat([1], 2)`, "[1, 1]"},
+		{`repeat(0, 3)`, "[0, 0, 0]"},
+		{`repeat(true, 0)`, "[]"},
+		{`"a" * -1`, "Error: repeat count must not be negative, got -1"},
+		{`"ab" * 1000000000`, "Error: repeated STRING would be too long"},
+		{`repeat("a", "b")`, "Error: repeat(value, count: INTEGER): argument 2 must be INTEGER; called with (STRING, STRING)"},
+		{`"a" * "b"`, "Error: unknown operator: STRING * STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[x * x for x in 1..10 if x % 2 == 0]`, "[4, 16, 36, 64]"},
+		{`[x for x in []]`, "[]"},
+		{`let h = {"a": 1, "b": 2}; let d = {k: v * 2 for k, v in h}; [d["a"], d["b"]]`, "[2, 4]"},
+		{`[k for k, v in {"b": 2, "a": 1, "c": 3} if v > 1]`, `[b, c]`},
+		{`[a + b for [a, b] in [[1, 2], [3, 4]]]`, "[3, 7]"},
+		{`[[y * 10 for y in 0..x] for x in 1..4]`, "[[0], [0, 10], [0, 10, 20]]"},
+		{`let fs = [fn() { x } for x in 0..3]; [f() for f in fs]`, "[0, 1, 2]"},
+		{`let f = fn(n, m) { [x * m for x in 0..n] }; f(3, 2)`, "[0, 2, 4]"},
+		{`let f = fn() { let [x] = [9]; [x + y for y in 0..2] }; f()`, "[9, 10]"},
+		{`let x = 1; [x for x in 5..7]; x`, "1"},
+		{`let f = fn() { [if (x > 1) { return x; } else { x } for x in 0..5] }; f()`, "2"},
+		{`[x for x in 5]`, "Error: cannot iterate over INTEGER"},
+		{`{[x]: 1 for x in 0..2}`, "Error: unusable as hash key: ARRAY"},
+		{`[x for [x] in [1]]`, "Error: cannot destructure INTEGER with array pattern [x]"},
+		{`[1 / x for x in 0..2]`, "Error: division by zero: 1 / 0"},
+		{`7 % 3`, "1"},
+		{`5 % 0`, "Error: division by zero: 5 % 0"},
+		{`3..0`, "[]"},
+		{`0..10000000000`, "Error: range 0..10000000000 is too long"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vector := `
+	let vec = fn(x, y) {
+		{
+			"x": x,
+			"y": y,
+			"__add__": fn(a, b) { vec(a.x + b.x, a.y + b.y) },
+			"__mul__": fn(a, k) { vec(a.x * k, a.y * k) },
+			"__eq__": fn(a, b) { [a.x, a.y] == [b.x, b.y] },
+			"__lt__": fn(a, b) { a.x * a.x + a.y * a.y < b.x * b.x + b.y * b.y },
+			"__index__": fn(a, i) { if (i == 0) { a.x } else { a.y } },
+		}
+	};
+	`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let v = vec(1, 2) + vec(3, 4); [v.x, v.y]", "[4, 6]"},
+		{"let v = vec(1, 2) * 3; [v[0], v[1]]", "[3, 6]"},
+		{"vec(1, 2) == vec(1, 2)", "true"},
+		{"vec(1, 2) != vec(1, 2)", "false"},
+		{"vec(1, 2) != vec(2, 1)", "true"},
+		{"vec(0, 1) < vec(2, 2) < vec(3, 3)", "true"},
+		{"vec(1, 1) > vec(0, 0)", "Error: unknown operator: HASH > HASH"},
+		{"vec(1, 1) - vec(0, 0)", "Error: unknown operator: HASH - HASH"},
+		{"1 + vec(1, 1)", "Error: type mismatch: INTEGER + HASH"},
+		{`{"__add__": 1} + {}`, "Error: unknown operator: HASH + HASH"},
+		{`let h = {"__add__": fn(a, b) { a.missing.x }}; h + h`, "Error: field access not supported: NULL"},
+		{`{"__index__": fn(h, key) { len(key) }}["abc"]`, "3"},
+		{`let h = {"a": 1}; h["a"]`, "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(vector + tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let area = fn(shape) {
+			match shape {
+				{"type": "circle", "r": r} => 3 * r * r,
+				{"type": "rect", w, h} => w * h,
+				_ => 0,
+			}
+		};
+		[area({"type": "circle", "r": 2}), area({"type": "rect", "w": 2, "h": 5}), area(1)]`, "[12, 10, 0]"},
+		{`let sum = fn(xs) { match xs { [] => 0, [x, ...rest] => x + sum(rest) } }; sum([1, 2, 3, 4])`, "10"},
+		{`let sign = fn(n) { match n { 0 => "zero", x if x < 0 => "negative", _ => "positive" } };
+		[sign(0), sign(-3), sign(5)]`, "[zero, negative, positive]"},
+		{`match -1 { -1 => "minus one", _ => "other" }`, "minus one"},
+		{`match (1, true) { (1, false) => "a", (1, true) => "b" }`, "b"},
+		{`match [1, [2, 3]] { [a, [b, c]] => a + b + c }`, "6"},
+		{`match "hi" { "ho" => 1, "hi" => 2 }`, "2"},
+		{`match 1 { x => { let y = x + 1; y * 2 } }`, "4"},
+		{`match 1 { x => { let y = x; } }`, "null"},
+		{`let x = 10; match 1 { x => x }; x`, "10"},
+		{`let f = fn(n) { let k = 3; match n { m if m > k => m - k, _ => k } }; [f(5), f(1)]`, "[2, 3]"},
+		{`let f = fn() { match 1 { 1 => { return 5; } }; 0 }; f()`, "5"},
+		{`let fs = [match x { n => fn() { n } } for x in 0..3]; [f() for f in fs]`, "[0, 1, 2]"},
+		{`match 5 { 1 => 1 }`, "Error: no match arm matches 5"},
+		{`match missing { _ => 1 }`, "Error: identifier not found: missing"},
+		{`match 1 { x if y => 1 }`, "Error: identifier not found: y"},
+		{`let [1, x] = [1, 2]; x`, "2"},
+		{`let [1, x] = [2, 2]; x`, "Error: 2 does not match pattern 1"},
+		{`let _ = 5; 1`, "1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v",
+				tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestEveryBuiltinHasASignature(t *testing.T) {
+	names := []string{}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range New(Config{}).evaluatorBuiltins() {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		signature, ok := signatures[name]
+		if !ok {
+			t.Errorf("builtin %s has no signature", name)
+			continue
+		}
+		if !strings.HasPrefix(signature, name+"(") {
+			t.Errorf("signature of %s does not start with its name. got=%q", name, signature)
+		}
+	}
+}
+
+func TestCapabilitiesNameBuiltins(t *testing.T) {
+	names := make(map[string]bool)
+	for _, name := range Builtins() {
+		names[name] = true
+	}
+	for name := range capabilities {
+		if !names[name] {
+			t.Errorf("capability given to %s, which is not a builtin", name)
+		}
+	}
+}
+
+func TestSandboxedCapabilities(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`puts("hidden")`, "capability denied: puts needs the output capability"},
+		{`eval("1 + 2")`, "capability denied: eval needs the dynamic capability"},
+		{"let p = puts; len([p])", 1},
+		{"len(pmap([1, 2], fn(x) { x * 2 }))", 2},
+		{"pmap([1], fn(x) { puts(x) })", "capability denied: puts needs the output capability"},
+	}
+
+	config := Config{Sandboxed: true, Capabilities: []Capability{Concurrency}}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := New(config).Eval(program, object.NewEnvironment())
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testErrorObject(t, evaluated, expected)
+			if !IsCapabilityDenied(evaluated) {
+				t.Errorf("IsCapabilityDenied(%s) = false", evaluated.Inspect())
+			}
+		}
+	}
+
+	if IsCapabilityDenied(testEval("x")) {
+		t.Errorf("IsCapabilityDenied is true for other errors")
+	}
+	testIntegerObject(t, testEval(`eval("1 + 2")`), 3)
+}
+
+func TestStats(t *testing.T) {
+	stats := &Stats{}
+	program := parser.New(lexer.New("1 + 2")).ParseProgram()
+	New(Config{Stats: stats}).Eval(program, object.NewEnvironment())
+
+	// The program, its statement, the infix expression and both operands.
+	if stats.Steps != 5 || stats.Values[object.INTEGER_OBJ] != 5 || stats.MaxCallDepth != 0 {
+		t.Errorf("wrong stats for 1 + 2. got=%+v", stats)
+	}
+
+	stats = &Stats{}
+	input := "let f = fn(n) { if (n > 0) { f(n - 1) } else { n } }; f(3)"
+	program = parser.New(lexer.New(input)).ParseProgram()
+	evaluated := New(Config{Stats: stats}).Eval(program, object.NewEnvironment())
+	testIntegerObject(t, evaluated, 0)
+	if stats.MaxCallDepth != 4 || stats.Values[object.BOOLEAN_OBJ] != 4 {
+		t.Errorf("wrong stats for recursion. got=%+v", stats)
+	}
+
+	stats = &Stats{}
+	program = parser.New(lexer.New("pmap([1, 2, 3], fn(x) { x * 2 })")).ParseProgram()
+	New(Config{Stats: stats}).Eval(program, object.NewEnvironment())
+	if stats.Values[object.INTEGER_OBJ] != 18 || stats.MaxCallDepth != 1 {
+		t.Errorf("wrong stats for parallel evaluation. got=%+v", stats)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	run := func(config Config, input string) string {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		return New(config).Eval(program, object.NewEnvironment()).Inspect()
+	}
+	sample := "[random(1000) for x in 0..10]"
+	deterministic := Config{DeterministicRandom: true, RandomSeed: 42}
+
+	first := run(deterministic, sample)
+	if again := run(deterministic, sample); again != first {
+		t.Errorf("deterministic runs differ: %s and %s", first, again)
+	}
+	if other := run(Config{DeterministicRandom: true, RandomSeed: 7}, sample); other == first {
+		t.Errorf("different seeds gave the same numbers: %s", first)
+	}
+
+	parallel := "pmap(0..64, fn(x) { [random(1000) for y in 0..x] })"
+	if a, b := run(deterministic, parallel), run(deterministic, parallel); a != b {
+		t.Errorf("deterministic parallel runs differ: %s and %s", a, b)
+	}
+
+	seeded := "seedRandom(3); let a = " + sample + "; seedRandom(3); a == " + sample
+	if result := run(Config{}, seeded); result != "true" {
+		t.Errorf("seedRandom does not restart the sequence. got=%s", result)
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"random(1)", 0},
+		{"len(randomBytes(16))", 16},
+		{"randomBytes(16) == randomBytes(16)", false},
+		{"random(0)", "random(n: INTEGER): n must be positive, got 0; called with (INTEGER)"},
+		{`seedRandom("x")`, "seedRandom(seed: INTEGER): argument 1 must be INTEGER; called with (STRING)"},
+		{"randomBytes(-1)", "randomBytes(n: INTEGER): n must be between 0 and 268435456, got -1; called with (INTEGER)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestProcessBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`exec("echo", "a", "b").stdout`, "a b\n"},
+		{`exec("sh", "-c", "exit 3").exitCode`, 3},
+		{`exec("true").exitCode`, 0},
+		{`shell("echo out; echo err >&2").stdout`, "out\n"},
+		{`shell("echo out; echo err >&2").stderr`, "err\n"},
+		{`shell("printf abc | wc -c").stdout`, "3\n"},
+		{`exec()`, "exec(command: STRING, args...: STRING): wrong number of arguments, want at least 1; called with ()"},
+		{`exec("echo", 1)`, "exec(command: STRING, args...: STRING): argument 2 must be STRING; called with (STRING, INTEGER)"},
+		{`exec("/no/such/program")`, "exec: fork/exec /no/such/program: no such file or directory"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("wrong output for %s. expected=%q, got=%q", tt.input, expected, str.Value)
+				}
+				continue
+			}
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+
+	program := parser.New(lexer.New(`shell("echo hi")`)).ParseProgram()
+	evaluated := New(Config{Sandboxed: true}).Eval(program, object.NewEnvironment())
+	testErrorObject(t, evaluated, "capability denied: shell needs the proc capability")
+}
+
+func TestPathBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.mk", "a.mk", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`joinPath("a", "b/", "../c", "d.mk")`, "a/c/d.mk"},
+		{`joinPath()`, ""},
+		{`basename("/tmp/x/script.mk")`, "script.mk"},
+		{`dirname("/tmp/x/script.mk")`, "/tmp/x"},
+		{`ext("/tmp/x/script.mk")`, ".mk"},
+		{`ext("Makefile")`, ""},
+		{`absPath("x.mk")`, filepath.Join(wd, "x.mk")},
+		{`[basename(p) for p in glob(joinPath("` + dir + `", "*.mk"))]`, "[a.mk, b.mk]"},
+		{`glob("[")`, "Error: glob(pattern: STRING): syntax error in pattern; called with (STRING)"},
+		{`basename(1)`, "Error: basename(path: STRING): argument 1 must be STRING; called with (INTEGER)"},
+		{`joinPath("a", 1)`, "Error: joinPath(parts...: STRING): argument 2 must be STRING; called with (STRING, INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCSVBuiltins(t *testing.T) {
+	// Monkey strings have no escapes, so the inputs hold real newlines and
+	// tabs, and q is a double quote.
+	q := "let q = toString(bytes([34])); "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{q + "csvParse(\"a,b\n1,\" + q + \"x, \" + q + q + \"y\" + q + q + q + \"\n\")", `[[a, b], [1, x, "y"]]`},
+		{`csvParse("")`, "[]"},
+		{"csvParse(\"a\tb\n1\t2\", {\"separator\": \"\t\"})", "[[a, b], [1, 2]]"},
+		{"let rows = csvParse(\"name,age\nann,3\", {\"header\": true}); rows[0][\"name\"] + rows[0].age", "ann3"},
+		{`csvEncode([["a", "b,c"], [1, true]])`, "a,\"b,c\"\n1,true\n"},
+		{`csvEncode([[1, 2]], {"separator": "	"})`, "1\t2\n"},
+		{`csvEncode([{"age": 3, "name": "ann"}, ["bob", 4]], {"header": ["name", "age"]})`, "name,age\nann,3\nbob,4\n"},
+		{q + `let rows = [["a,b", q + "c" + q], ["x", ""]]; csvParse(csvEncode(rows)) == rows`, "true"},
+		{"csvParse(\"a,b\n1\")", "Error: csvParse: record on line 2: wrong number of fields"},
+		{`csvParse("a", {"separator": ";;"})`, "Error: csvParse(text: STRING, options?: HASH): separator must be a single character; called with (STRING, HASH)"},
+		{`csvParse("a", {"headers": true})`, "Error: csvParse(text: STRING, options?: HASH): unknown option headers; called with (STRING, HASH)"},
+		{`csvEncode([{"a": 1}])`, "Error: csvEncode(rows: ARRAY, options?: HASH): row 0 is a HASH, which needs the header option; called with (ARRAY)"},
+		{`csvEncode([1])`, "Error: csvEncode(rows: ARRAY, options?: HASH): row 0 must be an ARRAY or a HASH, got INTEGER; called with (ARRAY)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestYAMLAndTOMLBuiltins(t *testing.T) {
+	yamlDoc := `
+name: monkey
+tags: [fast, small]
+owner:
+  id: 7
+  admin: true
+1: one
+empty:
+`
+	tomlDoc := `
+title = "config"
+ports = [80, 443]
+born = 1979-05-27T07:32:00Z
+
+[server]
+host = "localhost"
+
+[[users]]
+name = "ann"
+`
+	// Documents are bound to doc, since Monkey strings cannot hold quotes.
+	tests := []struct {
+		doc      string
+		input    string
+		expected string
+	}{
+		{yamlDoc, "let c = yamlDecode(doc); [c.name, c.tags[1], c.owner.id, c.owner.admin, c[1], c.empty]", "[monkey, small, 7, true, one, null]"},
+		{"", "yamlDecode(doc)", "null"},
+		{"- 1\n- [2, 3]", "yamlDecode(doc)", "[1, [2, 3]]"},
+		{"", `yamlEncode({"b": [1, "x"], "a": {"c": first([])}})`, "a:\n    c: null\nb:\n    - 1\n    - x\n"},
+		{"", `let v = {"a": [1, {"b": true}], 2: "two"}; yamlDecode(yamlEncode(v)) == v`, "true"},
+		{"a: [1", "yamlDecode(doc)", "Error: yamlDecode: yaml: line 1: did not find expected ',' or ']'"},
+		{"a: 1.5", "yamlDecode(doc)", "Error: yamlDecode: unsupported value 1.5 of type float64"},
+		{"", "yamlEncode(fn(x) { x })", "Error: yamlEncode: cannot encode FUNCTION"},
+
+		{tomlDoc, "let c = tomlDecode(doc); [c.title, c.ports[1], c.server.host, c.users[0].name, c.born]", "[config, 443, localhost, ann, 1979-05-27T07:32:00Z]"},
+		{"", `tomlEncode({"name": "x", "server": {"port": 80}})`, "name = \"x\"\n\n[server]\n  port = 80\n"},
+		{"", `let v = {"a": [1, 2], "t": {"b": "c"}}; tomlDecode(tomlEncode(v)) == v`, "true"},
+		{"a = ", "tomlDecode(doc)", "Error: tomlDecode: toml: line 1 (last key \"a\"): unexpected EOF; expected value"},
+		{"", "tomlEncode({1: 2})", "Error: tomlEncode: toml: cannot encode a map with non-string key type"},
+		{"", "tomlEncode([1])", "Error: tomlEncode(table: HASH): argument 1 must be HASH; called with (ARRAY)"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("doc", &object.String{Value: tt.doc})
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCloseResources(t *testing.T) {
+	e := New(Config{})
+	env := e.NewEnvironment()
+	program := e.NewParser(`let kept = tcpListen(0); let closed = tcpListen(0); close(closed); let db = sqliteOpen(":memory:"); [kept, db]`).ParseProgram()
+	result := e.Eval(program, env)
+	opened, ok := result.(*object.Array)
+	if !ok {
+		t.Fatalf("expected the resources, got %s", result.Inspect())
+	}
+	if n := e.resources.Open(); n != 2 {
+		t.Errorf("expected the 2 resources left open to be tracked, got %d", n)
+	}
+
+	if err := e.CloseResources(); err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range opened.Elements {
+		if !resource.(object.Resource).Closed() {
+			t.Errorf("expected %s to be closed", resource.Inspect())
+		}
+	}
+	if n := e.resources.Open(); n != 0 {
+		t.Errorf("expected no resources open, got %d", n)
+	}
+	kept, _ := env.Get("kept")
+	program = e.NewParser(`close(kept)`).ParseProgram()
+	if result = e.Eval(program, env); !strings.HasSuffix(result.Inspect(), "the resource is already closed") {
+		t.Errorf("expected closing again to fail, got %s", result.Inspect())
+	}
+	if _, err := kept.(*object.Listener).Listener.Accept(); err == nil {
+		t.Errorf("expected the listener to be closed")
+	}
+}
+
+func TestTCPBuiltins(t *testing.T) {
+	input := `
+	let nl = toString(bytes([10]));
+	let listener = tcpListen(0);
+	let server = async(fn() {
+		let conn = accept(listener);
+		let message = read(conn);
+		write(conn, message + "!" + nl + "second" + nl + "last");
+		close(conn)
+	});
+
+	let conn = tcpConnect("127.0.0.1", port(listener));
+	let sent = write(conn, "hi");
+	let first = readLine(conn);
+	let rest = [readLine(conn), readLine(conn), readLine(conn)];
+	await(server);
+	close(conn);
+	close(listener);
+	[sent, first, rest]`
+
+	evaluated := testEval(input)
+	expected := "[2, hi!, [second, last, null]]"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`tcpListen(70000)`, "Error: tcpListen(port: INTEGER): port must be between 0 and 65535, got 70000; called with (INTEGER)"},
+		{`let l = tcpListen(0); close(l); close(l)`, "Error: close: "},
+		{`read(1)`, "Error: read(connection: CONNECTION, max?: INTEGER): argument 1 must be CONNECTION; called with (INTEGER)"},
+		{`close("x")`, "Error: close(value: CONNECTION|LISTENER|DATABASE|ACTOR): argument 1 must be CONNECTION, LISTENER, DATABASE or ACTOR; called with (STRING)"},
+		{`let l = tcpListen(0); let p = port(l); close(l); tcpConnect("127.0.0.1", p)`, "Error: tcpConnect: dial tcp 127.0.0.1:"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if !strings.HasPrefix(evaluated.Inspect(), tt.expected) {
+			t.Errorf("wrong result for %s. expected prefix %q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSQLiteBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let db = sqliteOpen(":memory:");
+		exec(db, "create table people (id integer primary key, name text, age integer, photo blob)");
+		let ann = exec(db, "insert into people (name, age) values (?, ?)", ["ann", 31]);
+		let bob = exec(db, "insert into people (name, age, photo) values (?, ?, ?)", ["bob", 27, b"png"]);
+		let rows = query(db, "select * from people order by age");
+		close(db);
+		[ann.lastInsertId, bob.rowsAffected, rows[0].name, rows[0].photo == b"png", rows[1].photo]`,
+			"[1, 1, bob, true, null]"},
+		{`let db = sqliteOpen("` + path + `"); exec(db, "create table t (x)"); exec(db, "insert into t values (1), (2)"); close(db);
+		len(query(sqliteOpen("` + path + `"), "select x from t where x > ?", [1]))`, "1"},
+		{`query(sqliteOpen(":memory:"), "select 1 as one")[0].one`, "1"},
+		{`query(sqliteOpen(":memory:"), "select 1.5")`, "Error: query: column 1.5: unsupported value 1.5 of type float64"},
+		{`query(sqliteOpen(":memory:"), "select nonsense")`, "Error: query: SQL logic error: no such column: nonsense (1)"},
+		{`exec(sqliteOpen(":memory:"), "select ?", [[1]])`, "Error: exec(db: DATABASE, sql: STRING, params?: ARRAY): cannot bind parameter 1 of type ARRAY; called with (DATABASE, STRING, ARRAY)"},
+		{`query(1, "select 1")`, "Error: query(db: DATABASE, sql: STRING, params?: ARRAY): argument 1 must be DATABASE; called with (INTEGER, STRING)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// Running statements in a database needs no proc capability, unlike
+	// running commands.
+	program := parser.New(lexer.New(`let db = sqliteOpen(":memory:"); exec(db, "create table t (x)").rowsAffected`)).ParseProgram()
+	evaluated := New(Config{Sandboxed: true, Capabilities: []Capability{FS}}).Eval(program, object.NewEnvironment())
+	testIntegerObject(t, evaluated, 0)
+	program = parser.New(lexer.New(`exec("true")`)).ParseProgram()
+	evaluated = New(Config{Sandboxed: true, Capabilities: []Capability{FS}}).Eval(program, object.NewEnvironment())
+	testErrorObject(t, evaluated, "capability denied: exec needs the proc capability")
+}
+
+func TestLogBuiltins(t *testing.T) {
+	input := `
+	logDebug("hidden");
+	logInfo("started", {"port": 80, "name": "my app", "ok": true});
+	logWarn("disk");
+	logError(404, {"path": "", "tags": [1, "a"]})`
+
+	tests := []struct {
+		config   Config
+		expected string
+	}{
+		{Config{}, `INFO started name="my app" ok=true port=80
+WARN disk
+ERROR 404 path="" tags="[1, a]"
+`},
+		{Config{LogLevel: LogWarn, LogJSON: true}, `{"level":"warn","msg":"disk"}
+{"level":"error","msg":"404","path":"","tags":[1,"a"]}
+`},
+		{Config{LogLevel: LogDebug}, `DEBUG hidden
+INFO started name="my app" ok=true port=80
+WARN disk
+ERROR 404 path="" tags="[1, a]"
+`},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		tt.config.LogOutput = &out
+		program := parser.New(lexer.New(input)).ParseProgram()
+		evaluated := New(tt.config).Eval(program, object.NewEnvironment())
+		testNullObject(t, evaluated)
+		if out.String() != tt.expected {
+			t.Errorf("wrong log output. expected=%q, got=%q", tt.expected, out.String())
+		}
+	}
+
+	testErrorObject(t, testEval(`logInfo("x", 1)`), "logInfo(message, fields?: HASH): argument 2 must be HASH; called with (STRING, INTEGER)")
+
+	for level, name := range logLevelNames {
+		if parsed, err := ParseLogLevel(level.String()); err != nil || parsed != level || level.String() != name {
+			t.Errorf("ParseLogLevel(%q) = %v, %v", name, parsed, err)
+		}
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`parseTime("2024-02-28T23:30:00Z")`, "2024-02-28T23:30:00Z"},
+		{`let t = parseTime("2024-02-28T23:30:00Z"); addDuration(t, 24 * 60 * 60 * 1000 + 1)`, "2024-02-29T23:30:00.001Z"},
+		{`let t = parseTime("2024-02-28T23:30:00Z"); [year(t), month(t), day(t), hour(t), minute(t), second(t), weekday(t)]`, "[2024, 2, 28, 23, 30, 0, 3]"},
+		{`diff(parseTime("2024-01-01T00:00:01Z"), parseTime("2024-01-01T00:00:00Z"))`, "1000"},
+		{`let t = inZone(parseTime("2024-02-28T23:30:00Z"), "Asia/Tokyo"); [formatTime(t), day(t)]`, "[2024-02-29T08:30:00+09:00, 29]"},
+		{`parseTime("2024-02-28T23:30:00Z") == inZone(parseTime("2024-02-28T23:30:00Z"), "Asia/Tokyo")`, "true"},
+		{`let a = fromUnix(0); let b = fromUnix(60); [a < b, a > b, a == b, a != b, unix(b)]`, "[true, false, false, true, 60]"},
+		{`formatTime(fromUnix(0), "2006/01/02")`, "1970/01/01"},
+		{`parseTime("01/02/2024", "01/02/2006")`, "2024-01-02T00:00:00Z"},
+		{`now() > fromUnix(0)`, "true"},
+		{`fromUnix(0) + fromUnix(1)`, "Error: unknown operator: TIME + TIME"},
+		{`fromUnix(0) < 1`, "Error: type mismatch: TIME < INTEGER"},
+		{`parseTime("yesterday")`, `Error: parseTime: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{`inZone(now(), "Mars/Olympus")`, "Error: inZone: unknown time zone Mars/Olympus"},
+		{`year(1)`, "Error: year(time: TIME): argument 1 must be TIME; called with (INTEGER)"},
+		{`[200ms, 2s, 1m, 1h30m, 1s500ms]`, "[200, 2000, 60000, 5400000, 1500]"},
+		{`addDuration(parseTime("2024-02-28T23:30:00Z"), 1h30m)`, "2024-02-29T01:00:00Z"},
+		{`let t = now(); sleep(20ms); diff(now(), t) > 19`, "true"},
+		{`sleep("1s")`, "Error: sleep(milliseconds: INTEGER): argument 1 must be INTEGER; called with (STRING)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCharacters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"'a'", "a"},
+		{"[ord('a'), ord('é'), chr(98), chr(ord('y') + 1)]", "[97, 233, b, z]"},
+		{"['a' < 'b', 'z' > 'y']", "[true, true]"},
+		{"['a' == 'a', 'a' == 'b', 'a' != 'b']", "[true, false, true]"},
+		{`"héllo"[1]`, "é"},
+		{`["abc"[0] == 'a', "abc"[3], "abc"[-1]]`, "[true, null, null]"},
+		{"{'a': 1}['a']", "1"},
+		{"match 'x' { 'a' => 1, 'x' => 2, _ => 3 }", "2"},
+		{"toString('q') + \"!\"", "q!"},
+		{"'a' + 'b'", "Error: unknown operator: CHAR + CHAR"},
+		{"chr(-1)", "Error: chr(codePoint: INTEGER): -1 is not a code point; called with (INTEGER)"},
+		{`ord("a")`, "Error: ord(char: CHAR): argument 1 must be CHAR; called with (STRING)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringIndexing(t *testing.T) {
+	tests := []struct {
+		input    string
+		strict   string
+		expected string
+	}{
+		{`"héllo"[4]`, "o", "o"},
+		{`"héllo"[5]`, "Error: index out of range: 5 with STRING of length 5", "null"},
+		{`""[0]`, "Error: index out of range: 0 with STRING of length 0", "null"},
+		{`"abc"[-1]`, "Error: index out of range: -1 with STRING of length 3", "null"},
+		{`[c for c in "héllo" if c != 'l']`, "[h, é, o]", "[h, é, o]"},
+		{`let s = "héllo"; [len(s), s[len(s) - 1]]`, "[5, o]", "[5, o]"},
+		{`let s = "日本"; [s[i] for i in 0..len(s)]`, "[日, 本]", "[日, 本]"},
+		{`len(bytes("héllo"))`, "6", "6"},
+		{`{c: ord(c) for c in "ab"}["b"]`, "Error: key not found: b", "null"},
+		{`{c: ord(c) for c in "ab"}['b']`, "98", "98"},
+		{`let [a, b] = ["a"[0], "b"[0]]; [a < b]`, "[true]", "[true]"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		strict := New(Config{StrictIndexing: true}).Eval(program, object.NewEnvironment())
+		if strict.Inspect() != tt.strict {
+			t.Errorf("wrong strict result for %s. expected=%q, got=%q", tt.input, tt.strict, strict.Inspect())
+		}
+		if evaluated := testEval(tt.input); evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStrictIndexing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][2]", "3"},
+		{"[1, 2, 3][3]", "Error: index out of range: 3 with ARRAY of length 3"},
+		{"[1, 2, 3][-1]", "Error: index out of range: -1 with ARRAY of length 3"},
+		{"(1, 2)[2]", "Error: index out of range: 2 with TUPLE of length 2"},
+		{`b"ab"[2]`, "Error: index out of range: 2 with BYTES of length 2"},
+		{`{"a": 1}["b"]`, "Error: key not found: b"},
+		{`{"a": 1}.b`, "Error: key not found: b"},
+		{`{"a": if (false) { 1 }}["a"]`, "null"},
+		{`[1]?.[5]`, "null"},
+		{`{"a": 1}?.b`, "null"},
+		{`{"a": 1}["b"] ?? 0`, "Error: key not found: b"},
+		{`let h = {"__index__": fn(h, k) { k }}; h["missing"]`, "missing"},
+		{`{[1]: 1}`, "Error: unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := New(Config{StrictIndexing: true}).Eval(program, object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestNumberConversions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`parseInt("42")`, "42"},
+		{`parseInt("-17")`, "-17"},
+		{`[parseInt("ff", 16), parseInt("101", 2), parseInt("z", 36)]`, "[255, 5, 35]"},
+		{`parseInt("12px")`, `Error: parseInt(text: STRING, base?: INTEGER): "12px" is not a base 10 integer; called with (STRING)`},
+		{`parseInt(" 1")`, `Error: parseInt(text: STRING, base?: INTEGER): " 1" is not a base 10 integer; called with (STRING)`},
+		{`parseInt("99999999999999999999")`, `Error: parseInt(text: STRING, base?: INTEGER): "99999999999999999999" does not fit in 64 bits; called with (STRING)`},
+		{`parseInt("1", 37)`, "Error: parseInt(text: STRING, base?: INTEGER): base must be between 2 and 36, got 37; called with (STRING, INTEGER)"},
+		{`parseInt(1)`, "Error: parseInt(text: STRING, base?: INTEGER): argument 1 must be STRING; called with (INTEGER)"},
+		{`[toString(255, 16), toString(5, 2), toString(-35, 36), toString(7)]`, "[ff, 101, -z, 7]"},
+		{`parseInt(toString(123456, 7), 7)`, "123456"},
+		{`toString("a", 16)`, "Error: toString(value, base?: INTEGER): argument 1 must be INTEGER; called with (STRING, INTEGER)"},
+		{`toString(1, 1)`, "Error: toString(value, base?: INTEGER): base must be between 2 and 36, got 1; called with (INTEGER, INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTextFormatting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"[" + padLeft("ab", 5) + "]"`, "[   ab]"},
+		{`"[" + padRight("ab", 5) + "]"`, "[ab   ]"},
+		{`"[" + center("ab", 5) + "]"`, "[ ab  ]"},
+		{`padLeft(42, 5, "0")`, "00042"},
+		{`padRight("héllo", 6, ".")`, "héllo."},
+		{`center("toolong", 3)`, "toolong"},
+		{`padLeft("a", 3, "ab")`, "Error: padLeft(value, width: INTEGER, fill?: STRING): fill must be a single character; called with (STRING, INTEGER, STRING)"},
+		{`padRight("a", "3")`, "Error: padRight(value, width: INTEGER, fill?: STRING): argument 2 must be INTEGER; called with (STRING, STRING)"},
+		{`[formatNumber(1234567), formatNumber(-1234), formatNumber(999), formatNumber(0)]`, "[1,234,567, -1,234, 999, 0]"},
+		{`formatNumber(1000000, ".")`, "1.000.000"},
+		{`formatNumber(-9223372036854775807 - 1)`, "-9,223,372,036,854,775,808"},
+		{`formatNumber("1")`, "Error: formatNumber(n: INTEGER, separator?: STRING): argument 1 must be INTEGER; called with (STRING)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`formatTable([{"name": "Ada", "age": 36}, {"name": "Grace", "age": 7}])`,
+			"age  name\n---  -----\n 36  Ada\n  7  Grace\n",
+		},
+		{
+			`formatTable([{"name": "Ada", "age": 36}, {"name": "Bob", "city": "Oslo"}], ["name", "age", "city"])`,
+			"name  age  city\n----  ---  ----\nAda    36\nBob        Oslo\n",
+		},
+		{`formatTable([{"id": 1}, {"id": "two"}])`, "id\n---\n1\ntwo\n"},
+		{`formatTable([])`, "\n\n"},
+		{`formatTable([{"a": 1}, 2])`, "Error: formatTable(rows: ARRAY, columns?: ARRAY): row 2 must be a HASH, got INTEGER; called with (ARRAY)"},
+		{`formatTable({})`, "Error: formatTable(rows: ARRAY, columns?: ARRAY): argument 1 must be ARRAY; called with (HASH)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	var out strings.Builder
+	program := parser.New(lexer.New(`printTable([{"n": 1}, {"n": 10}])`)).ParseProgram()
+	New(Config{Output: &out}).Eval(program, object.NewEnvironment())
+	if expected := " n\n--\n 1\n10\n"; out.String() != expected {
+		t.Errorf("wrong printTable output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestGetAndFetch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`get({"a": 1}, "a", 0)`, "1"},
+		{`get({"a": 1}, "b", 0)`, "0"},
+		{`let h = {"a": if (false) { 1 }}; [get(h, "a", "missing"), get(h, "b", "missing")]`, "[null, missing]"},
+		{`get({1: "one"}, 1, "")`, "one"},
+		{`get({}, [], 0)`, "Error: unusable as hash key: ARRAY"},
+		{`get([1], 0, 0)`, "Error: get(hash: HASH, key, default): argument 1 must be HASH; called with (ARRAY, INTEGER, INTEGER)"},
+		{`get({}, "a")`, "Error: get(hash: HASH, key, default): wrong number of arguments, want 3; called with (HASH, STRING)"},
+		{`fetch([1, 2], 1, 0)`, "2"},
+		{`[fetch([1, 2], 2, 0), fetch([1, 2], -1, 0)]`, "[0, 0]"},
+		{`fetch((1, 2), 0, 0)`, "1"},
+		{`fetch([1], "0", 0)`, "Error: fetch(array: ARRAY|TUPLE, index: INTEGER, default): argument 2 must be INTEGER; called with (ARRAY, STRING, INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTemplateBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`template("Hello, {{name}}!", {"name": "Ada"})`, "Hello, Ada!"},
+		{`template("{{ user.name }} is {{user.age}}", {"user": {"name": "Ada", "age": 36}})`, "Ada is 36"},
+		{`template("{{for x in xs}}<li>{{x}}</li>{{end}}", {"xs": [1, 2, 3]})`, "<li>1</li><li>2</li><li>3</li>"},
+		{`template("{{if admin}}hi boss{{else}}hi{{end}}", {"admin": false})`, "hi"},
+		{`template("{{if admin}}hi boss{{end}}", {"admin": true})`, "hi boss"},
+		{`template("{{for row in rows}}{{row.name}}{{if row.last}}.{{else}}, {{end}}{{end}}", {"rows": [{"name": "a", "last": false}, {"name": "b", "last": true}]})`, "a, b."},
+		{`template("[{{missing}}]", {"missing": {}.x})`, "[]"},
+		{`template("{{name}}", {})`, "Error: template: no value for name"},
+		{`template("{{user.name}}", {"user": 1})`, "Error: template: user is a INTEGER, not a hash"},
+		{`template("{{for x in n}}{{end}}", {"n": 1})`, "Error: template: cannot loop over n, a INTEGER"},
+		{`template("{{if a}}", {"a": 1})`, "Error: template: missing {{end}}"},
+		{`template("{{end}}", {})`, "Error: template: unexpected {{end}}"},
+		{`template("{{name", {})`, "Error: template: unclosed {{"},
+		{`template("{{}}", 1)`, "Error: template(text: STRING, data: HASH): argument 2 must be HASH; called with (STRING, INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected diagnostic.Code
+	}{
+		{"-true", diagnostic.UnknownOperator},
+		{`"a" - "b"`, diagnostic.UnknownOperator},
+		{"foobar", diagnostic.IdentifierNotFound},
+		{"1 + true", diagnostic.TypeMismatch},
+		{"1 / 0", diagnostic.DivisionByZero},
+		{"1(2)", diagnostic.NotAFunction},
+		{"fn(a) { a }()", diagnostic.WrongArgumentCount},
+		{"1[0]", diagnostic.IndexNotSupported},
+		{"{[1]: 2}", diagnostic.UnusableHashKey},
+		{"1.a", diagnostic.FieldAccessNotSupported},
+		{"let f = fn() { f() }; f()", diagnostic.CallDepthExceeded},
+		{"[x for x in 1]", diagnostic.NotIterable},
+		{"[1] * -1", diagnostic.InvalidRepeat},
+		{"match 1 { 2 => 3 }", diagnostic.NoMatchingArm},
+		{"let [a] = [1, 2]", diagnostic.PatternMismatch},
+		{"pushMut(freeze([1]), 2)", diagnostic.FrozenValue},
+		{"len(1)", diagnostic.InvalidArgument},
+		{`parseTime("yesterday")`, diagnostic.BuiltinFailed},
+		{`eval("let")`, diagnostic.EvalParseError},
+	}
+
+	for _, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s did not fail", tt.input)
+			continue
+		}
+		if err.Code != tt.expected {
+			t.Errorf("wrong code for %s. expected=%s, got=%s (%s)", tt.input, tt.expected, err.Code, err.Message)
+		}
+	}
+
+	p := parser.New(lexer.New("now()"))
+	err := New(Config{Sandboxed: true}).Eval(p.ParseProgram(), object.NewEnvironment()).(*object.Error)
+	if err.Code != diagnostic.CapabilityDenied {
+		t.Errorf("wrong code for a denied capability. got=%s", err.Code)
+	}
+}
+
+func TestCustomMessages(t *testing.T) {
+	defer message.Reset()
+
+	if got := testEval("1 + true").Inspect(); got != "Error: type mismatch: INTEGER + BOOLEAN" {
+		t.Fatalf("wrong default message. got=%q", got)
+	}
+	message.Set(map[message.ID]string{
+		message.TypeMismatch:  "tipos incompatíveis: %s %s %s",
+		message.ArgumentCount: "número errado de argumentos, esperava %d",
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "Error: tipos incompatíveis: INTEGER + BOOLEAN"},
+		{"len()", "Error: len(value: STRING|ARRAY|TUPLE|BYTES): número errado de argumentos, esperava 1; called with ()"},
+	}
+	for _, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok || err.Inspect() != tt.expected {
+			t.Errorf("wrong error for %s. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func FuzzEval(f *testing.F) {
+	for _, seed := range []string{
+		"let add = fn(x, y) { x + y }; add(1, 2)",
+		"let f = fn(a, b) { b }; f(1)",
+		"let [a, {b, c: [d, ...e]}] = [1, {b: 2, c: [3, 4, 5]}]; e",
+		`[x * 2 for x in 1..10 if x % 2] + {"a": 1}["a"]`,
+		"match (1, [2]) { (a, [b]) if a < b => a, _ => 0 }",
+		`let s = builder(); append(s, "a", 1); toString(s)`,
+		"reduce([1, 2], 0, fn(a, b) { a + b })",
+		`b"abc"[1] - 1 / 0`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return
+		}
+
+		// Without capabilities a program cannot sleep, wait on the network
+		// or touch the system, and a shallow call depth keeps runaway
+		// recursion quick. Failures must come back as error objects.
+		ev := New(Config{Sandboxed: true, MaxCallDepth: 32})
+		ev.Eval(program, object.NewEnvironment())
+	})
+}
+
+func TestPrelude(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"filter(1..10, fn(x) { x % 3 == 0 })", "[3, 6, 9]"},
+		{"reduce([1, 2, 3, 4], 10, fn(acc, x) { acc + x })", "20"},
+		{"reduce([], 1, fn(acc, x) { acc * x })", "1"},
+		{"compose(fn(x) { x + 1 }, fn(x) { x * 2 })(5)", "11"},
+		{"identity(\"a\")", "a"},
+		{"sum(1..101)", "5050"},
+		{"[any([1, 2], fn(x) { x > 1 }), all([1, 2], fn(x) { x > 1 })]", "[true, false]"},
+		{"let out = builder(); each([1, 2], fn(x) { append(out, toString(x)) }); toString(out)", "12"},
+		{"let map = fn(xs, f) { \"mine\" }; map([1], identity)", "mine"},
+	}
+
+	for _, tt := range tests {
+		e := New(Config{})
+		evaluated := e.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), e.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	e := New(Config{NoPrelude: true})
+	evaluated := e.Eval(parser.New(lexer.New("map")).ParseProgram(), e.NewEnvironment())
+	if evaluated.Inspect() != "Error: identifier not found: map" {
+		t.Errorf("map should not be defined without the prelude. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestModules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import("std/math"); [m.abs(-3), m.min(4, 2, 8), m.max(4, 2, 8), m.pow(-3, 3), m.sqrt(17)]`, "[3, 2, 8, -27, 4]"},
+		{"[math.sqrt(0), math.sqrt(9223372036854775807), math.pow(2, 62), math.pow(7, 0)]", "[0, 3037000499, 4611686018427387904, 1]"},
+		{"math.pow(2, 63)", "Error: math.pow(base: INTEGER, exponent: INTEGER): the result does not fit in 64 bits; called with (INTEGER, INTEGER)"},
+		{"math.sqrt(-1)", "Error: math.sqrt(n: INTEGER): n must not be negative, got -1; called with (INTEGER)"},
+		{"math.min()", "Error: math.min(values...: INTEGER): wrong number of arguments, want at least 1; called with ()"},
+		{`strings.split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`strings.split("  one two  ")`, "[one, two]"},
+		{`strings.join([1, "a", true], ", ")`, "1, a, true"},
+		{`[strings.trim("  a  "), strings.trim("xxaxx", "x"), strings.upper("aB"), strings.lower("aB")]`, "[a, a, AB, ab]"},
+		{`[strings.contains("monkey", "key"), strings.startsWith("monkey", "mon"), strings.endsWith("monkey", "mon")]`, "[true, true, false]"},
+		{`strings.replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`strings.padLeft(7, 3, "0")`, "007"},
+		{`json.encode({"b": [1, true, "<x>"], "a": if (false) { 1 }})`, `{"a":null,"b":[1,true,"<x>"]}`},
+		{"json.decode(`{\"list\": [1, -2], \"ok\": false}`).list", "[1, -2]"},
+		{"json.decode(`1.5`)", "Error: json.decode: 1.5 is not an integer"},
+		{`json.encode([fn(x) { x }])`, "Error: json.encode: cannot encode FUNCTION"},
+		{`fs.writeFile("` + path + `", "hello"); fs.readFile("` + path + `")`, "hello"},
+		{`fs.writeFile("` + path + `", bytes([0, 255])); fs.readFile("` + path + `", "bytes")`, `b"\x00\xff"`},
+		{`fs.writeFile("` + path + `", "hi"); fs.readFile("` + path + `", "string") + "!"`, "hi!"},
+		{`fs.readFile("` + path + `", "text")`, `Error: fs.readFile(path: STRING, as?: STRING): as must be "string" or "bytes"; called with (STRING, STRING)`},
+		{`let time = 5; time + 1`, "6"},
+		{`import("std/nope")`, `Error: import(path: STRING): no module named "std/nope"; called with (STRING)`},
+		{`isFrozen(import("std/math"))`, "true"},
+	}
+
+	for _, tt := range tests {
+		e := New(Config{})
+		evaluated := e.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), e.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestModuleMembersAreBuiltins(t *testing.T) {
+	e := New(Config{})
+	for _, path := range Modules() {
+		for name, builtin := range modules[path] {
+			if _, ok := e.builtins[builtin]; !ok {
+				t.Errorf("%s.%s is %s, which is not a builtin", path, name, builtin)
+			}
+		}
+		if _, ok := e.builtins[moduleName(path)]; ok {
+			t.Errorf("module %s would shadow the builtin %s", path, moduleName(path))
+		}
+	}
+
+	sandboxed := New(Config{Sandboxed: true})
+	evaluated := sandboxed.Eval(parser.New(lexer.New(`fs.readFile("x")`)).ParseProgram(), sandboxed.NewEnvironment())
+	if !IsCapabilityDenied(evaluated) {
+		t.Errorf("fs.readFile should need the fs capability. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestVersionBuiltin(t *testing.T) {
+	tests := []struct {
+		language parser.Config
+		input    string
+		expected string
+	}{
+		{parser.Config{}, `version()["language"]`, "2"},
+		{parser.Config{Version: 1}, `version()["language"]`, "1"},
+		{parser.Config{Version: 1, Features: []parser.Feature{parser.FeatureModulo}}, `version()["features"]`, "[modulo]"},
+		{parser.Config{}, `version()["interpreter"] == "(devel)"`, "true"},
+		{parser.Config{Version: 1}, `eval("let match = 2; match")`, "2"},
+		{parser.Config{Version: 1}, `eval("3 % 2")`, "Error: parser errors: % needs the modulo feature, which is part of language version 2"},
+	}
+
+	for _, tt := range tests {
+		e := New(Config{Language: tt.language})
+		evaluated := e.Eval(e.NewParser(tt.input).ParseProgram(), object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"1 + true", 1, 1},
+		{"let a = 1;\nlet b = a / 0;", 2, 1},
+		{"let f = fn(x) {\n  let y = x;\n  y + true\n};\n\nf(1)", 3, 3},
+		{"let f = fn() {\n  if (true) {\n    return -true;\n  }\n};\nf()", 3, 5},
+		{"let xs = [1];\nmap(xs, fn(x) { x + true })", 2, 17},
+		{"map(1, fn(x) { x })", 1, 1},
+		{"let x = 1;\n  eval(\"\\n\\nx + true\")", 2, 3},
+		{"let ok = 1", 0, 0},
+	}
+
+	for _, tt := range tests {
+		ev := New(Config{})
+		evaluated := ev.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), ev.NewEnvironment())
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			if tt.line != 0 {
+				t.Errorf("%q: expected an error, got %s", tt.input, evaluated.Inspect())
+			}
+			continue
+		}
+		if err.Line != tt.line || err.Column != tt.column {
+			t.Errorf("%q: error %q at %d:%d, expected %d:%d",
+				tt.input, err.Message, err.Line, err.Column, tt.line, tt.column)
+		}
+	}
+}
+
+func init() {
+	RegisterBuiltinPack("test/greetings", map[string]*object.Builtin{
+		"hello": {Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: "hello, " + args[0].Inspect()}
+		}},
+	})
+}
+
+func TestBuiltinPacks(t *testing.T) {
+	evaluated := testEval(`let g = import("test/greetings"); g.hello("pack")`)
+	if s, ok := evaluated.(*object.String); !ok || s.Value != "hello, pack" {
+		t.Errorf(`expected "hello, pack", got %s`, evaluated.Inspect())
+	}
+
+	program := parser.New(lexer.New(`import("test/greetings")`)).ParseProgram()
+	evaluated = New(Config{Sandboxed: true}).Eval(program, object.NewEnvironment())
+	testErrorObject(t, evaluated, "capability denied: test/greetings needs the packs capability")
+	evaluated = New(Config{Sandboxed: true, Capabilities: []Capability{Packs}}).Eval(program, object.NewEnvironment())
+	if _, ok := evaluated.(*object.Hash); !ok {
+		t.Errorf("expected the pack with the packs capability, got %s", evaluated.Inspect())
+	}
+
+	found := false
+	for _, name := range BuiltinPacks() {
+		found = found || name == "test/greetings"
+	}
+	if !found {
+		t.Errorf("expected test/greetings among %v", BuiltinPacks())
+	}
+
+	for _, name := range []string{"test/greetings", "std/extra", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registering %q to panic", name)
+				}
+			}()
+			RegisterBuiltinPack(name, nil)
+		}()
+	}
+
+	if err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Errorf("expected loading a missing plugin to fail")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var calls []string
+	hooks := &Hooks{
+		Call: func(name string, fn object.Object, args []object.Object) {
+			calls = append(calls, "call "+name+" "+(&object.Array{Elements: args}).Inspect())
+		},
+		Return: func(name string, fn object.Object, result object.Object) {
+			calls = append(calls, "return "+name+" "+result.Inspect())
+		},
+	}
+	input := `let double = fn(x) { x * 2 }; let twice = fn(f, x) { f(f(x)) }; twice(double, 1); len("ab")`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	New(Config{Hooks: hooks, NoPrelude: true}).Eval(program, object.NewEnvironment())
+
+	expected := []string{
+		"call twice [fn(x) {\n(x * 2)\n}, 1]",
+		"call f [1]", "return f 2",
+		"call f [2]", "return f 4",
+		"return twice 4",
+		"call len [ab]", "return len 2",
+	}
+	if strings.Join(calls, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected calls %q, got %q", expected, calls)
+	}
+
+	calls = nil
+	program = parser.New(lexer.New(`sortBy([2, 1], fn(x) { x })`)).ParseProgram()
+	New(Config{Hooks: hooks}).Eval(program, object.NewEnvironment())
+	if len(calls) < 3 || calls[0] != "call sortBy [[2, 1], fn(x) {\nx\n}]" || !strings.HasPrefix(calls[1], "call <anonymous fn> ") {
+		t.Errorf("expected the callbacks of sortBy to be reported, got %q", calls)
+	}
+}
+
+func TestPostMortem(t *testing.T) {
+	input := `let inner = fn(n) { let half = n / 2; half + true }; let outer = fn(x) { inner(x * 3) }; outer(4)`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	e := New(Config{PostMortem: true, NoPrelude: true})
+	result := e.Eval(program, object.NewEnvironment())
+
+	failure := e.Failure()
+	if failure == nil {
+		t.Fatalf("expected the failure to be kept, got %s", result.Inspect())
+	}
+	if failure.Error.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("unexpected error %q", failure.Error.Message)
+	}
+	if half, ok := failure.Env.Get("half"); !ok || half.Inspect() != "6" {
+		t.Errorf("expected half to be 6 where the error happened, got %v", half)
+	}
+	if strings.Join(failure.Stack, " ") != "inner outer" {
+		t.Errorf("expected the stack inner outer, got %q", failure.Stack)
+	}
+
+	e = New(Config{NoPrelude: true})
+	e.Eval(program, object.NewEnvironment())
+	if e.Failure() != nil {
+		t.Errorf("expected no failure kept without PostMortem")
+	}
+}
+
+func TestDumpHeap(t *testing.T) {
+	var out strings.Builder
+	program := parser.New(lexer.New(`let big = ["a", "b", "c"]; let f = fn() { dumpHeap() }; f()`)).ParseProgram()
+	result := New(Config{Output: &out, NoPrelude: true}).Eval(program, object.NewEnvironment())
+	if result != NULL {
+		t.Fatalf("expected null, got %s", result.Inspect())
+	}
+	report := out.String()
+	if !strings.HasPrefix(report, "6 objects and environments reachable") || !strings.Contains(report, "\n  big              ARRAY ") {
+		t.Errorf("expected a report of the program's environment, got %q", report)
+	}
+
+	program = parser.New(lexer.New(`dumpHeap()`)).ParseProgram()
+	result = New(Config{Sandboxed: true}).Eval(program, object.NewEnvironment())
+	if !IsCapabilityDenied(result) {
+		t.Errorf("expected dumpHeap to need the output capability, got %s", result.Inspect())
+	}
+}
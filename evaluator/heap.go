@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// maxHeapBindings is how many bindings a heap report lists.
+const maxHeapBindings = 10
+
+func init() {
+	signatures["dumpHeap"] = "dumpHeap()"
+}
+
+// dumpHeapBuiltin writes a report of the heap reachable from the program's
+// environment to the configured Output, as WriteHeapReport does.
+func (e *Evaluator) dumpHeapBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("dumpHeap", args, 0); err != nil {
+		return err
+	}
+	if e.env == nil {
+		return NULL
+	}
+	var report strings.Builder
+	WriteHeapReport(&report, object.NewHeap(e.env))
+	return e.putsBuiltin(&object.String{Value: strings.TrimSuffix(report.String(), "\n")})
+}
+
+// WriteHeapReport writes to out how much of heap there is of each type, and
+// the bindings of the environment it was taken from that keep the most of
+// it alive, with how many references their values have.
+func WriteHeapReport(out io.Writer, heap *object.Heap) {
+	type total struct {
+		name         string
+		count, bytes int
+	}
+	totals := map[string]*total{}
+	bytes := 0
+	for _, node := range heap.Nodes {
+		t := totals[node.Type]
+		if t == nil {
+			t = &total{name: node.Type}
+			totals[node.Type] = t
+		}
+		t.count++
+		t.bytes += node.Size
+		bytes += node.Size
+	}
+	types := make([]*total, 0, len(totals))
+	for _, t := range totals {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].bytes != types[j].bytes {
+			return types[i].bytes > types[j].bytes
+		}
+		return types[i].name < types[j].name
+	})
+
+	fmt.Fprintln(out, message.Format(message.HeapSummary, len(heap.Nodes), bytes))
+	fmt.Fprintln(out, message.Format(message.HeapByType))
+	for _, t := range types {
+		fmt.Fprintf(out, "  %-16s %8d %10d bytes\n", t.name, t.count, t.bytes)
+	}
+
+	if len(heap.Nodes) == 0 {
+		return
+	}
+	bindings := append([]object.HeapReference(nil), heap.Nodes[0].References...)
+	sort.Slice(bindings, func(i, j int) bool {
+		a, b := heap.Nodes[bindings[i].Node], heap.Nodes[bindings[j].Node]
+		if a.Retained != b.Retained {
+			return a.Retained > b.Retained
+		}
+		return bindings[i].Name < bindings[j].Name
+	})
+	if len(bindings) > maxHeapBindings {
+		bindings = bindings[:maxHeapBindings]
+	}
+	fmt.Fprintln(out, message.Format(message.HeapByBinding))
+	for _, binding := range bindings {
+		node := heap.Nodes[binding.Node]
+		fmt.Fprintf(out, "  %-16s %-16s %10d bytes %8d references\n", binding.Name, node.Type, node.Retained, len(node.References))
+	}
+}
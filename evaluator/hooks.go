@@ -0,0 +1,29 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// Hooks are told of the nodes an evaluation evaluates and the calls it
+// makes, for embedders tracing, auditing or stepping through scripts.
+// Bindings are observed on the environment instead, with
+// object.Environment.Observe.
+//
+// Builtins that evaluate in parallel call the hooks from each of their
+// goroutines, so hooks used with them must be safe for concurrent use.
+type Hooks struct {
+	// Node is called as each node is about to be evaluated, with the
+	// environment it is evaluated in.
+	Node func(node ast.Node, env *object.Environment)
+
+	// Call is called as a function or builtin is called, with the name it
+	// is called by, such as the identifier it is bound to, <anonymous fn>
+	// for the functions builtins such as sortBy call back, and the
+	// arguments.
+	Call func(name string, fn object.Object, args []object.Object)
+
+	// Return is called as the call returns, with what it returned, which is
+	// an *object.Error when it failed.
+	Return func(name string, fn object.Object, result object.Object)
+}
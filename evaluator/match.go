@@ -0,0 +1,46 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the subject and whose guard holds, in a scope holding what the
+// pattern bound. It is an error for no arm to apply.
+func (e *Evaluator) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := e.Eval(node.Subject, env)
+	if stopsEvaluation(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		if !patternMatches(arm.Pattern, subject) {
+			continue
+		}
+
+		scope := scopeEnvironment(env, arm.Locals)
+		if err := bindPattern(scope, arm.Pattern, subject); err != nil {
+			return err
+		}
+
+		if arm.Guard != nil {
+			guard := e.Eval(arm.Guard, scope)
+			if stopsEvaluation(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		result := e.Eval(arm.Body, scope)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	return newError(message.NoMatchingArm, subject.Inspect())
+}
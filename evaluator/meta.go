@@ -0,0 +1,271 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func init() {
+	builtins["parse"] = &object.Builtin{Fn: parseBuiltin}
+
+	signatures["eval"] = "eval(source: STRING)"
+	signatures["parse"] = "parse(source: STRING)"
+}
+
+func (e *Evaluator) evalBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("eval", args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() != object.STRING_OBJ {
+		return argumentTypeError("eval", args, 0, "STRING")
+	}
+
+	program, err := parseSource(args[0].(*object.String).Value, e.config.Language)
+	if err != nil {
+		return err
+	}
+	clearPositions(program)
+
+	evaluated := e.Eval(program, e.NewEnvironment())
+	if evaluated == nil {
+		return NULL
+	}
+	return evaluated
+}
+
+func parseBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("parse", args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() != object.STRING_OBJ {
+		return argumentTypeError("parse", args, 0, "STRING")
+	}
+
+	program, err := parseSource(args[0].(*object.String).Value, parser.Config{})
+	if err != nil {
+		return err
+	}
+	return astToHash(program)
+}
+
+func parseSource(source string, language parser.Config) (*ast.Program, *object.Error) {
+	p := parser.NewWithConfig(lexer.New(source), language)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError(message.EvalParseErrors, strings.Join(p.Errors(), "; "))
+	}
+	return program, nil
+}
+
+// astToHash converts node into nested hashes so Monkey programs can inspect
+// syntax trees. Every hash has a "type" key naming the node, plus one key per
+// child node or attribute.
+func astToHash(node ast.Node) object.Object {
+	switch node := node.(type) {
+	case *ast.Program:
+		return nodeHash("Program", "statements", statementsToArray(node.Statements))
+	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return nodeHash("LetStatement",
+				"pattern", astToHash(node.Pattern),
+				"value", astToHash(node.Value))
+		}
+		return nodeHash("LetStatement",
+			"name", &object.String{Value: node.Name.Value},
+			"annotation", annotationToObject(node.Name.Type),
+			"value", astToHash(node.Value))
+	case *ast.ArrayPattern:
+		elements := []object.Object{}
+		for _, el := range node.Elements {
+			elements = append(elements, astToHash(el))
+		}
+		var rest object.Object = NULL
+		if node.Rest != nil {
+			rest = &object.String{Value: node.Rest.Value}
+		}
+		return nodeHash("ArrayPattern",
+			"elements", &object.Array{Elements: elements},
+			"rest", rest)
+	case *ast.HashPattern:
+		pairs := []object.Object{}
+		for _, pair := range node.Pairs {
+			pairs = append(pairs, &object.Array{
+				Elements: []object.Object{&object.String{Value: pair.Key}, astToHash(pair.Value)},
+			})
+		}
+		return nodeHash("HashPattern", "pairs", &object.Array{Elements: pairs})
+	case *ast.LiteralPattern:
+		return nodeHash("LiteralPattern", "value", astToHash(node.Value))
+	case *ast.WildcardPattern:
+		return nodeHash("WildcardPattern")
+	case *ast.MatchExpression:
+		arms := []object.Object{}
+		for _, arm := range node.Arms {
+			var guard object.Object = NULL
+			if arm.Guard != nil {
+				guard = astToHash(arm.Guard)
+			}
+			arms = append(arms, nodeHash("MatchArm",
+				"pattern", astToHash(arm.Pattern),
+				"guard", guard,
+				"body", astToHash(arm.Body)))
+		}
+		return nodeHash("MatchExpression",
+			"subject", astToHash(node.Subject),
+			"arms", &object.Array{Elements: arms})
+	case *ast.ReturnStatement:
+		return nodeHash("ReturnStatement", "value", astToHash(node.ReturnValue))
+	case *ast.ExpressionStatement:
+		return nodeHash("ExpressionStatement", "expression", astToHash(node.Expression))
+	case *ast.BlockStatement:
+		return nodeHash("BlockStatement", "statements", statementsToArray(node.Statements))
+	case *ast.Identifier:
+		return nodeHash("Identifier", "value", &object.String{Value: node.Value})
+	case *ast.IntegerLiteral:
+		return nodeHash("IntegerLiteral", "value", &object.Integer{Value: node.Value})
+	case *ast.Boolean:
+		return nodeHash("Boolean", "value", boolean(node.Value))
+	case *ast.StringLiteral:
+		return nodeHash("StringLiteral", "value", &object.String{Value: node.Value})
+	case *ast.BytesLiteral:
+		return nodeHash("BytesLiteral", "value", &object.Bytes{Value: []byte(node.Value)})
+	case *ast.CharLiteral:
+		return nodeHash("CharLiteral", "value", &object.Char{Value: node.Value})
+	case *ast.PrefixExpression:
+		return nodeHash("PrefixExpression",
+			"operator", &object.String{Value: node.Operator},
+			"right", astToHash(node.Right))
+	case *ast.InfixExpression:
+		return nodeHash("InfixExpression",
+			"operator", &object.String{Value: node.Operator},
+			"left", astToHash(node.Left),
+			"right", astToHash(node.Right))
+	case *ast.IfExpression:
+		var alternative object.Object = NULL
+		if node.Alternative != nil {
+			alternative = astToHash(node.Alternative)
+		}
+		return nodeHash("IfExpression",
+			"condition", astToHash(node.Condition),
+			"consequence", astToHash(node.Consequence),
+			"alternative", alternative)
+	case *ast.ComparisonChain:
+		operators := []object.Object{}
+		for _, operator := range node.Operators {
+			operators = append(operators, &object.String{Value: operator})
+		}
+		return nodeHash("ComparisonChain",
+			"operands", expressionsToArray(node.Operands),
+			"operators", &object.Array{Elements: operators})
+	case *ast.FunctionLiteral:
+		params := []object.Object{}
+		paramTypes := []object.Object{}
+		for _, p := range node.Parameters {
+			params = append(params, &object.String{Value: p.Value})
+			paramTypes = append(paramTypes, annotationToObject(p.Type))
+		}
+		return nodeHash("FunctionLiteral",
+			"parameters", &object.Array{Elements: params},
+			"parameterTypes", &object.Array{Elements: paramTypes},
+			"returnType", annotationToObject(node.ReturnType),
+			"body", astToHash(node.Body))
+	case *ast.CallExpression:
+		return nodeHash("CallExpression",
+			"function", astToHash(node.Function),
+			"arguments", expressionsToArray(node.Arguments))
+	case *ast.ArrayLiteral:
+		return nodeHash("ArrayLiteral", "elements", expressionsToArray(node.Elements))
+	case *ast.TupleLiteral:
+		return nodeHash("TupleLiteral", "elements", expressionsToArray(node.Elements))
+	case *ast.TuplePattern:
+		elements := []object.Object{}
+		for _, el := range node.Elements {
+			elements = append(elements, astToHash(el))
+		}
+		return nodeHash("TuplePattern", "elements", &object.Array{Elements: elements})
+	case *ast.IndexExpression:
+		return nodeHash("IndexExpression",
+			"left", astToHash(node.Left),
+			"index", astToHash(node.Index),
+			"optional", boolean(node.Optional))
+	case *ast.ArrayComprehension:
+		return nodeHash("ArrayComprehension",
+			"element", astToHash(node.Element),
+			"clause", clauseToHash(node.Clause))
+	case *ast.HashComprehension:
+		return nodeHash("HashComprehension",
+			"key", astToHash(node.Key),
+			"value", astToHash(node.Value),
+			"clause", clauseToHash(node.Clause))
+	case *ast.FieldExpression:
+		return nodeHash("FieldExpression",
+			"left", astToHash(node.Left),
+			"name", &object.String{Value: node.Name},
+			"optional", boolean(node.Optional))
+	case *ast.HashLiteral:
+		pairs := []object.Object{}
+		for key, value := range node.Pairs {
+			pairs = append(pairs, &object.Array{
+				Elements: []object.Object{astToHash(key), astToHash(value)},
+			})
+		}
+		return nodeHash("HashLiteral", "pairs", &object.Array{Elements: pairs})
+	}
+	return NULL
+}
+
+// annotationToObject is the name of the annotated type, or null.
+func annotationToObject(annotation *ast.TypeAnnotation) object.Object {
+	if annotation == nil {
+		return NULL
+	}
+	return &object.String{Value: annotation.Name}
+}
+
+func clauseToHash(clause *ast.ComprehensionClause) *object.Hash {
+	var condition object.Object = NULL
+	if clause.Condition != nil {
+		condition = astToHash(clause.Condition)
+	}
+	return nodeHash("ComprehensionClause",
+		"pattern", astToHash(clause.Pattern),
+		"iterable", astToHash(clause.Iterable),
+		"condition", condition)
+}
+
+func nodeHash(nodeType string, fields ...interface{}) *object.Hash {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	add := func(key string, value object.Object) {
+		k := &object.String{Value: key}
+		pairs[k.HashKey()] = object.HashPair{Key: k, Value: value}
+	}
+
+	add("type", &object.String{Value: nodeType})
+	for i := 0; i < len(fields); i += 2 {
+		add(fields[i].(string), fields[i+1].(object.Object))
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func statementsToArray(stmts []ast.Statement) *object.Array {
+	elements := []object.Object{}
+	for _, s := range stmts {
+		elements = append(elements, astToHash(s))
+	}
+	return &object.Array{Elements: elements}
+}
+
+func expressionsToArray(exps []ast.Expression) *object.Array {
+	elements := []object.Object{}
+	for _, e := range exps {
+		elements = append(elements, astToHash(e))
+	}
+	return &object.Array{Elements: elements}
+}
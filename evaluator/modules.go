@@ -0,0 +1,197 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func init() {
+	signatures["import"] = "import(path: STRING)"
+}
+
+// modules maps the path of each standard library module, as import takes
+// it, to its members, each naming the builtin it is. Builtins that only
+// belong to a module are registered under a qualified name such as
+// "math.sqrt", which no identifier can spell, so they stay out of the
+// global namespace; the older global builtins are members too, under the
+// name they have in their module.
+var modules = map[string]map[string]string{
+	"std/math": {
+		"abs":  "math.abs",
+		"min":  "math.min",
+		"max":  "math.max",
+		"pow":  "math.pow",
+		"sqrt": "math.sqrt",
+	},
+	"std/strings": {
+		"split":        "strings.split",
+		"join":         "strings.join",
+		"trim":         "strings.trim",
+		"upper":        "strings.upper",
+		"lower":        "strings.lower",
+		"contains":     "strings.contains",
+		"replace":      "strings.replace",
+		"startsWith":   "strings.startsWith",
+		"endsWith":     "strings.endsWith",
+		"padLeft":      "padLeft",
+		"padRight":     "padRight",
+		"center":       "center",
+		"repeat":       "repeat",
+		"template":     "template",
+		"formatNumber": "formatNumber",
+		"formatTable":  "formatTable",
+		"parseInt":     "parseInt",
+		"ord":          "ord",
+		"chr":          "chr",
+	},
+	"std/json": {
+		"encode": "json.encode",
+		"decode": "json.decode",
+	},
+	"std/fs": {
+		"readFile":  "fs.readFile",
+		"writeFile": "fs.writeFile",
+		"glob":      "glob",
+	},
+	"std/path": {
+		"abs":  "absPath",
+		"base": "basename",
+		"dir":  "dirname",
+		"ext":  "ext",
+		"join": "joinPath",
+	},
+	"std/time": {
+		"now":      "now",
+		"sleep":    "sleep",
+		"unix":     "unix",
+		"fromUnix": "fromUnix",
+		"format":   "formatTime",
+		"parse":    "parseTime",
+		"add":      "addDuration",
+		"diff":     "diff",
+		"inZone":   "inZone",
+		"year":     "year",
+		"month":    "month",
+		"day":      "day",
+		"weekday":  "weekday",
+		"hour":     "hour",
+		"minute":   "minute",
+		"second":   "second",
+	},
+	"std/toml": {
+		"encode": "tomlEncode",
+		"decode": "tomlDecode",
+	},
+	"std/yaml": {
+		"encode": "yamlEncode",
+		"decode": "yamlDecode",
+	},
+	"std/csv": {
+		"encode": "csvEncode",
+		"parse":  "csvParse",
+	},
+	"std/hex": {
+		"encode": "hexEncode",
+		"decode": "hexDecode",
+	},
+	"std/base64": {
+		"encode": "base64Encode",
+		"decode": "base64Decode",
+	},
+	"std/url": {
+		"encode": "urlEncode",
+		"decode": "urlDecode",
+	},
+	"std/digest": {
+		"md5":    "md5",
+		"sha256": "sha256",
+	},
+	"std/net": {
+		"connect":  "tcpConnect",
+		"listen":   "tcpListen",
+		"accept":   "accept",
+		"port":     "port",
+		"read":     "read",
+		"readLine": "readLine",
+		"write":    "write",
+		"close":    "close",
+	},
+	"std/sqlite": {
+		"open":  "sqliteOpen",
+		"query": "query",
+		"exec":  "exec",
+		"close": "close",
+	},
+	"std/proc": {
+		"exec":  "exec",
+		"shell": "shell",
+	},
+}
+
+// Modules returns the paths of every standard library module, sorted.
+func Modules() []string {
+	paths := make([]string, 0, len(modules))
+	for path := range modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ModuleMembers returns the members of the standard library module at path,
+// each naming the builtin it is, as Builtins and BuiltinCapability do.
+func ModuleMembers(path string) (map[string]string, bool) {
+	members, ok := modules[path]
+	if !ok {
+		return nil, false
+	}
+	copied := make(map[string]string, len(members))
+	for name, builtin := range members {
+		copied[name] = builtin
+	}
+	return copied, true
+}
+
+func (e *Evaluator) importBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("import", args, 1); err != nil {
+		return err
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return argumentTypeError("import", args, 0, "STRING")
+	}
+
+	if module, ok := e.module(path.Value); ok {
+		return module
+	}
+	if pack, ok := e.pack(path.Value); ok {
+		return pack
+	}
+	return argumentError("import", args, message.UnknownModule, path.Value)
+}
+
+// module returns the module at path as a frozen hash from the names of its
+// members to e's builtins, so a sandboxed evaluator hands out the same
+// guarded builtins it would for their global names.
+func (e *Evaluator) module(path string) (*object.Hash, bool) {
+	members, ok := modules[path]
+	if !ok {
+		return nil, false
+	}
+
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(members)), Frozen: true}
+	for name, builtin := range members {
+		key := &object.String{Value: name}
+		hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: e.builtins[builtin]}
+	}
+	return hash, true
+}
+
+// moduleName is the name a module is preloaded under: the last element of
+// its path, such as math for std/math.
+func moduleName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
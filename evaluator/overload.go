@@ -0,0 +1,96 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+// operatorMethods maps each overloadable infix operator to the hash key a
+// user type defines it under. `!=` has no method of its own: it negates
+// `__eq__`.
+var operatorMethods = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"%":  "__mod__",
+	"<":  "__lt__",
+	">":  "__gt__",
+	"==": "__eq__",
+	"!=": "__eq__",
+}
+
+// indexMethod is the key a hash defines to take over `value[index]`. Field
+// access such as `value.name` always reads the stored pair, so the method can
+// still reach the hash's own fields.
+const indexMethod = "__index__"
+
+// methodKeys holds the hash keys of the method names, so looking a method up
+// does not hash its name every time.
+var methodKeys = func() map[string]object.HashKey {
+	keys := map[string]object.HashKey{indexMethod: (&object.String{Value: indexMethod}).HashKey()}
+	for _, name := range operatorMethods {
+		keys[name] = (&object.String{Value: name}).HashKey()
+	}
+	return keys
+}()
+
+// evalInfix applies operator, first giving a hash on the left a chance to
+// handle it with a method. The method is called with both operands; the
+// results of comparisons are converted to booleans.
+func (e *Evaluator) evalInfix(left object.Object, operator string, right object.Object) object.Object {
+	if e.arena != nil {
+		if result := e.arena.arithmetic(left, operator, right); result != nil {
+			return result
+		}
+	}
+	if hash, ok := left.(*object.Hash); ok {
+		if method := userMethod(hash, operatorMethods[operator]); method != nil {
+			return e.callOperatorMethod(method, left, operator, right)
+		}
+	}
+	return e.evalInfixExpression(left, operator, right)
+}
+
+func (e *Evaluator) callOperatorMethod(method, left object.Object, operator string, right object.Object) object.Object {
+	result := e.callFunction(operatorMethods[operator], method, []object.Object{left, right})
+	if isError(result) {
+		return result
+	}
+	switch operator {
+	case "==", "<", ">":
+		return boolean(isTruthy(result))
+	case "!=":
+		return boolean(!isTruthy(result))
+	}
+	return result
+}
+
+// evalIndex is evalIndexExpression with support for `__index__`, checking
+// the index first when strict.
+func (e *Evaluator) evalIndex(left, index object.Object, strict bool) object.Object {
+	if hash, ok := left.(*object.Hash); ok {
+		if method := userMethod(hash, indexMethod); method != nil {
+			return e.callFunction(indexMethod, method, []object.Object{left, index})
+		}
+	}
+	if strict {
+		if err := checkIndex(left, index); err != nil {
+			return err
+		}
+	}
+	return evalIndexExpression(left, index)
+}
+
+// userMethod returns the function hash stores under name, if it has one.
+func userMethod(hash *object.Hash, name string) object.Object {
+	if name == "" || len(hash.Pairs) == 0 {
+		return nil
+	}
+	pair, ok := hash.Pairs[methodKeys[name]]
+	if !ok {
+		return nil
+	}
+	switch pair.Value.(type) {
+	case *object.Function, *object.Builtin:
+		return pair.Value
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"fmt"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// packs holds the builtin packs programs registered, by name. A pack is
+// imported like a standard library module, with import(name), but is not
+// preloaded, so its name takes nothing from the global namespace.
+var (
+	packsMu sync.RWMutex
+	packs   = map[string]map[string]*object.Builtin{}
+)
+
+// RegisterBuiltinPack makes builtins importable as the module name, a hash
+// of the builtins by their names in the map, for the evaluators created
+// after it. It panics if a pack or standard library module already has the
+// name, or the name is in the std/ namespace, which is the standard
+// library's; packs are meant to be registered from init functions, like
+// database/sql drivers.
+func RegisterBuiltinPack(name string, builtins map[string]*object.Builtin) {
+	if name == "" || strings.HasPrefix(name, "std/") {
+		panic(fmt.Sprintf("evaluator: RegisterBuiltinPack: %q is not a name a pack can have", name))
+	}
+	packsMu.Lock()
+	defer packsMu.Unlock()
+	if _, ok := packs[name]; ok {
+		panic(fmt.Sprintf("evaluator: RegisterBuiltinPack called twice for %s", name))
+	}
+	pack := make(map[string]*object.Builtin, len(builtins))
+	for member, builtin := range builtins {
+		pack[member] = builtin
+	}
+	packs[name] = pack
+}
+
+// BuiltinPacks returns the names of the registered packs, sorted.
+func BuiltinPacks() []string {
+	packsMu.RLock()
+	defer packsMu.RUnlock()
+	names := make([]string, 0, len(packs))
+	for name := range packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinPackMembers returns the names of the builtins of the pack name,
+// sorted, if a pack has that name.
+func BuiltinPackMembers(name string) ([]string, bool) {
+	packsMu.RLock()
+	defer packsMu.RUnlock()
+	pack, ok := packs[name]
+	if !ok {
+		return nil, false
+	}
+	members := make([]string, 0, len(pack))
+	for member := range pack {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members, true
+}
+
+// LoadPlugin opens the Go plugin at path, a package built with
+// -buildmode=plugin whose init functions register its packs with
+// RegisterBuiltinPack. Plugins only load into a program built with the same
+// version of this module and of Go, on the systems Go supports them on.
+func LoadPlugin(path string) error {
+	if _, err := plugin.Open(path); err != nil {
+		return fmt.Errorf("could not load the plugin %s: %s", path, err)
+	}
+	return nil
+}
+
+// pack returns the pack name as a frozen hash, or, if e is sandboxed
+// without the Packs capability, the error importing it gives.
+func (e *Evaluator) pack(name string) (object.Object, bool) {
+	packsMu.RLock()
+	builtins, ok := packs[name]
+	packsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if !e.allows(Packs) {
+		return newError(message.CapabilityDenied, name, Packs), true
+	}
+
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(builtins)), Frozen: true}
+	for member, builtin := range builtins {
+		key := &object.String{Value: member}
+		hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: builtin}
+	}
+	return hash, true
+}
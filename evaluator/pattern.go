@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// bindPattern binds the identifiers in pattern to the matching parts of val.
+// It fails when val does not have the shape the pattern describes, possibly
+// after binding some of the identifiers.
+func bindPattern(env *object.Environment, pattern ast.Pattern, val object.Object) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		bind(env, pattern, val)
+
+	case *ast.WildcardPattern:
+
+	case *ast.LiteralPattern:
+		if !object.Equal(literalValue(pattern), val) {
+			return newError(message.PatternNotMatched, val.Inspect(), pattern)
+		}
+
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return newError(message.CannotDestructure, val.Type(), "array", pattern)
+		}
+
+		n := len(pattern.Elements)
+		if len(arr.Elements) < n || (pattern.Rest == nil && len(arr.Elements) > n) {
+			id := message.ArrayPatternExactly
+			if pattern.Rest != nil {
+				id = message.ArrayPatternAtLeast
+			}
+			return newError(id, pattern, n, len(arr.Elements))
+		}
+
+		for i, el := range pattern.Elements {
+			if err := bindPattern(env, el, arr.Elements[i]); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := make([]object.Object, len(arr.Elements)-n)
+			copy(rest, arr.Elements[n:])
+			bind(env, pattern.Rest, &object.Array{Elements: rest})
+		}
+
+	case *ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+		if !ok {
+			return newError(message.CannotDestructure, val.Type(), "tuple", pattern)
+		}
+		if len(tuple.Elements) != len(pattern.Elements) {
+			return newError(message.TuplePatternLength,
+				pattern, len(pattern.Elements), len(tuple.Elements))
+		}
+		for i, el := range pattern.Elements {
+			if err := bindPattern(env, el, tuple.Elements[i]); err != nil {
+				return err
+			}
+		}
+
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newError(message.CannotDestructure, val.Type(), "hash", pattern)
+		}
+
+		for _, pair := range pattern.Pairs {
+			key := &object.String{Value: pair.Key}
+			hashPair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return newError(message.HashPatternMissingKey, pattern, pair.Key)
+			}
+			if err := bindPattern(env, pair.Value, hashPair.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// patternMatches reports whether bindPattern would bind val to pattern
+// without failing. Unlike bindPattern, it binds nothing and allocates no
+// errors, so match arms that do not apply are cheap to try.
+func patternMatches(pattern ast.Pattern, val object.Object) bool {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return object.Equal(literalValue(pattern), val)
+
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return false
+		}
+		n := len(pattern.Elements)
+		if len(arr.Elements) < n || (pattern.Rest == nil && len(arr.Elements) > n) {
+			return false
+		}
+		for i, el := range pattern.Elements {
+			if !patternMatches(el, arr.Elements[i]) {
+				return false
+			}
+		}
+
+	case *ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+		if !ok || len(tuple.Elements) != len(pattern.Elements) {
+			return false
+		}
+		for i, el := range pattern.Elements {
+			if !patternMatches(el, tuple.Elements[i]) {
+				return false
+			}
+		}
+
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return false
+		}
+		for _, pair := range pattern.Pairs {
+			key := &object.String{Value: pair.Key}
+			hashPair, ok := hash.Pairs[key.HashKey()]
+			if !ok || !patternMatches(pair.Value, hashPair.Value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// literalValue is the value a literal pattern matches.
+func literalValue(pattern *ast.LiteralPattern) object.Object {
+	switch lit := pattern.Value.(type) {
+	case *ast.IntegerLiteral:
+		return integer(lit.Value)
+	case *ast.Boolean:
+		return boolean(lit.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: lit.Value}
+	case *ast.CharLiteral:
+		return &object.Char{Value: lit.Value}
+	}
+	return NULL
+}
+
+// letIdentifiers returns the identifiers a let statement binds.
+func letIdentifiers(let *ast.LetStatement) []*ast.Identifier {
+	if let.Pattern == nil {
+		return []*ast.Identifier{let.Name}
+	}
+	return patternIdentifiers(let.Pattern)
+}
+
+// patternIdentifiers returns the identifiers pattern binds.
+func patternIdentifiers(pattern ast.Pattern) []*ast.Identifier {
+	idents := []*ast.Identifier{}
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			idents = append(idents, ident)
+		}
+		return true
+	})
+	return idents
+}
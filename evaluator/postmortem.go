@@ -0,0 +1,26 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+// Failure is where an evaluation failed, as it was then: the environment
+// of the statement that failed, with the variables that led to the error,
+// and the calls that were active.
+type Failure struct {
+	// Error is the error as the evaluation returned it.
+	Error *object.Error
+
+	// Env is the environment the failed statement ran in. It is the one the
+	// script was using, so evaluating in it sees, and can change, the
+	// variables of that call and the ones around it.
+	Env *object.Environment
+
+	// Stack names the calls that were active, innermost first, all of them
+	// unlike the Stack some errors carry.
+	Stack []string
+}
+
+// Failure returns where the last evaluation on e to fail failed, or nil
+// when none has or e was not configured with PostMortem.
+func (e *Evaluator) Failure() *Failure {
+	return e.failure
+}
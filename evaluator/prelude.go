@@ -0,0 +1,45 @@
+package evaluator
+
+import (
+	_ "embed"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+// preludeSource defines the functions every program can use without being
+// builtins, such as map, filter and reduce.
+//
+//go:embed prelude.mk
+var preludeSource string
+
+// NewEnvironment returns an environment for a program to run in. Unless the
+// evaluator is configured with NoPrelude, it encloses an environment the
+// prelude was evaluated into, which also holds every standard library module
+// under the last element of its path, so the program can use the prelude's
+// functions and modules such as math and still shadow them with its own.
+func (e *Evaluator) NewEnvironment() *object.Environment {
+	if e.config.NoPrelude {
+		return object.NewEnvironment()
+	}
+
+	// The program is parsed again every time, rather than shared, since
+	// evaluating a program annotates it.
+	p := parser.New(lexer.New(preludeSource))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		panic("evaluator: prelude does not parse: " + p.Errors()[0])
+	}
+	clearPositions(program)
+
+	prelude := object.NewEnvironment()
+	if result := e.Eval(program, prelude); isError(result) {
+		panic("evaluator: prelude failed: " + result.Inspect())
+	}
+	for path := range modules {
+		module, _ := e.module(path)
+		prelude.Set(moduleName(path), module)
+	}
+	return object.NewEnclosedEnvironment(prelude)
+}
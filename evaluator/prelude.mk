@@ -0,0 +1,22 @@
+let map = fn(xs, f) { [f(x) for x in xs] };
+
+let filter = fn(xs, f) { [x for x in xs if f(x)] };
+
+let reduce = fn(xs, initial, f) {
+  let step = fn(i, acc) {
+    if (i == len(xs)) { acc } else { step(i + 1, f(acc, xs[i])) }
+  };
+  step(0, initial)
+};
+
+let each = fn(xs, f) { map(xs, f); if (false) { 0 } };
+
+let compose = fn(f, g) { fn(x) { f(g(x)) } };
+
+let identity = fn(x) { x };
+
+let sum = fn(xs) { reduce(xs, 0, fn(acc, x) { acc + x }) };
+
+let any = fn(xs, f) { len(filter(xs, f)) > 0 };
+
+let all = fn(xs, f) { len(filter(xs, f)) == len(xs) };
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// body returns the body to evaluate for a call of fn. The first call of a
+// function literal prepares its body, folding the operations whose operands
+// are all literals, and later calls, of any function made from the same
+// literal, reuse it. Slots are not part of it, as the resolver has given
+// them out before any function of the program runs.
+//
+// Folding builds new nodes wherever something changed and shares the rest,
+// so the literal itself is left alone for anything else evaluating it.
+func (e *Evaluator) body(fn *object.Function) *ast.BlockStatement {
+	if fn.Literal == nil {
+		return &fn.Body
+	}
+	if body, ok := e.prepared.Load(fn.Literal); ok {
+		return body.(*ast.BlockStatement)
+	}
+	body, _ := e.prepared.LoadOrStore(fn.Literal, e.foldBlock(&fn.Body))
+	return body.(*ast.BlockStatement)
+}
+
+// foldBlock returns block with its constant operations folded. Nested
+// function literals are left to be prepared by their own first call.
+func (e *Evaluator) foldBlock(block *ast.BlockStatement) *ast.BlockStatement {
+	if block == nil {
+		return nil
+	}
+	var folded []ast.Statement
+	for i, stmt := range block.Statements {
+		if f := e.foldStatement(stmt); f != stmt && folded == nil {
+			folded = append(make([]ast.Statement, 0, len(block.Statements)), block.Statements[:i]...)
+			folded = append(folded, f)
+		} else if folded != nil {
+			folded = append(folded, f)
+		}
+	}
+	if folded == nil {
+		return block
+	}
+	return &ast.BlockStatement{Token: block.Token, Statements: folded}
+}
+
+func (e *Evaluator) foldStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if value := e.foldExpression(stmt.Value); value != stmt.Value {
+			folded := *stmt
+			folded.Value = value
+			return &folded
+		}
+	case *ast.ReturnStatement:
+		if value := e.foldExpression(stmt.ReturnValue); value != stmt.ReturnValue {
+			return &ast.ReturnStatement{Token: stmt.Token, ReturnValue: value}
+		}
+	case *ast.ExpressionStatement:
+		if expr := e.foldExpression(stmt.Expression); expr != stmt.Expression {
+			return &ast.ExpressionStatement{Token: stmt.Token, Expression: expr}
+		}
+	}
+	return stmt
+}
+
+func (e *Evaluator) foldExpression(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		right := e.foldExpression(expr.Right)
+		if value, ok := constantValue(right); ok {
+			if folded := Literal(evalPrefixExpression(expr.Operator, value)); folded != nil {
+				return folded
+			}
+		}
+		if right != expr.Right {
+			return &ast.PrefixExpression{Token: expr.Token, Operator: expr.Operator, Right: right}
+		}
+
+	case *ast.InfixExpression:
+		left, right := e.foldExpression(expr.Left), e.foldExpression(expr.Right)
+		leftValue, leftOK := constantValue(left)
+		rightValue, rightOK := constantValue(right)
+		if leftOK && rightOK && expr.Operator != "??" {
+			if folded := Literal(e.evalInfix(leftValue, expr.Operator, rightValue)); folded != nil {
+				return folded
+			}
+		}
+		if left != expr.Left || right != expr.Right {
+			return &ast.InfixExpression{Token: expr.Token, Left: left, Operator: expr.Operator, Right: right}
+		}
+
+	case *ast.IfExpression:
+		condition := e.foldExpression(expr.Condition)
+		consequence, alternative := e.foldBlock(expr.Consequence), e.foldBlock(expr.Alternative)
+		if condition != expr.Condition || consequence != expr.Consequence || alternative != expr.Alternative {
+			return &ast.IfExpression{Token: expr.Token, Condition: condition, Consequence: consequence, Alternative: alternative}
+		}
+
+	case *ast.CallExpression:
+		if arguments, ok := e.foldExpressions(expr.Arguments); ok {
+			folded := *expr
+			folded.Arguments = arguments
+			return &folded
+		}
+
+	case *ast.ArrayLiteral:
+		if elements, ok := e.foldExpressions(expr.Elements); ok {
+			return &ast.ArrayLiteral{Token: expr.Token, Elements: elements}
+		}
+
+	case *ast.IndexExpression:
+		if index := e.foldExpression(expr.Index); index != expr.Index {
+			folded := *expr
+			folded.Index = index
+			return &folded
+		}
+	}
+	return expr
+}
+
+// foldExpressions folds exprs, reporting whether any of them changed.
+func (e *Evaluator) foldExpressions(exprs []ast.Expression) ([]ast.Expression, bool) {
+	folded := make([]ast.Expression, len(exprs))
+	changed := false
+	for i, expr := range exprs {
+		folded[i] = e.foldExpression(expr)
+		changed = changed || folded[i] != expr
+	}
+	return folded, changed
+}
+
+// constantValue returns the value of expr when it is a literal.
+func constantValue(expr ast.Expression) (object.Object, bool) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return integer(expr.Value), true
+	case *ast.Boolean:
+		return boolean(expr.Value), true
+	case *ast.StringLiteral:
+		return &object.String{Value: expr.Value}, true
+	case *ast.CharLiteral:
+		return &object.Char{Value: expr.Value}, true
+	}
+	return nil, false
+}
+
+// Literal returns the literal that evaluates to value, or nil when value is
+// not an integer, boolean, string or character.
+func Literal(value object.Object) ast.Expression {
+	switch value := value.(type) {
+	case *object.Integer:
+		literal := strconv.FormatInt(value.Value, 10)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: value.Value}
+	case *object.Boolean:
+		if value.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+		}
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+	case *object.String:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value.Value}, Value: value.Value}
+	case *object.Char:
+		return &ast.CharLiteral{Token: token.Token{Type: token.CHAR, Literal: string(value.Value)}, Value: value.Value}
+	}
+	return nil
+}
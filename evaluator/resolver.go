@@ -0,0 +1,161 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/ast"
+
+// scope collects the locals of one function literal.
+type scope struct {
+	names []string
+	index map[string]int
+}
+
+func newScope() *scope {
+	return &scope{names: []string{}, index: make(map[string]int)}
+}
+
+func (s *scope) declare(name string) {
+	if _, ok := s.index[name]; !ok {
+		s.index[name] = len(s.names)
+		s.names = append(s.names, name)
+	}
+}
+
+// resolver annotates identifiers that refer to function locals with the slot
+// holding them, so evaluation can skip looking them up by name. Top-level
+// bindings are left unresolved, since the REPL and eval add to them as they
+// go.
+//
+// A function's parameters and every let in its body, outside of nested
+// functions, share one scope. A comprehension also has a scope of its own,
+// holding its pattern and the lets in its element and condition; its
+// iterable belongs to the enclosing scope. Each arm of a match has a scope
+// for its pattern, guard and body in the same way, while the subject belongs
+// to the enclosing scope. Lets are declared before the body is resolved,
+// so an identifier always resolves to the nearest function that can bind it;
+// if the slot is still empty when the identifier is evaluated, the name is
+// looked up instead, just as it was before resolution existed.
+type resolver struct {
+	scopes []*scope
+}
+
+func resolve(node ast.Node) {
+	r := &resolver{}
+	ast.Inspect(node, r.visit)
+}
+
+func (r *resolver) visit(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.FunctionLiteral:
+		r.resolveFunction(node)
+		return false
+	case *ast.ArrayComprehension:
+		r.resolveComprehension(node.Clause, node.Element)
+		return false
+	case *ast.HashComprehension:
+		r.resolveComprehension(node.Clause, node.Key, node.Value)
+		return false
+	case *ast.MatchExpression:
+		r.resolveMatch(node)
+		return false
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	}
+	return true
+}
+
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	s := newScope()
+	for _, param := range fn.Parameters {
+		s.declare(param.Value)
+	}
+	declareLets(s, fn.Body)
+
+	r.scopes = append(r.scopes, s)
+	for _, param := range fn.Parameters {
+		r.resolveIdentifier(param)
+	}
+	ast.Inspect(fn.Body, r.visit)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	fn.Locals = s.names
+}
+
+func (r *resolver) resolveComprehension(clause *ast.ComprehensionClause, body ...ast.Expression) {
+	ast.Inspect(clause.Iterable, r.visit)
+
+	s := newScope()
+	for _, ident := range patternIdentifiers(clause.Pattern) {
+		s.declare(ident.Value)
+	}
+	declareLets(s, clause.Condition)
+	for _, exp := range body {
+		declareLets(s, exp)
+	}
+
+	r.scopes = append(r.scopes, s)
+	ast.Inspect(clause.Pattern, r.visit)
+	ast.Inspect(clause.Condition, r.visit)
+	for _, exp := range body {
+		ast.Inspect(exp, r.visit)
+	}
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	clause.Locals = s.names
+}
+
+func (r *resolver) resolveMatch(match *ast.MatchExpression) {
+	ast.Inspect(match.Subject, r.visit)
+
+	for _, arm := range match.Arms {
+		s := newScope()
+		for _, ident := range patternIdentifiers(arm.Pattern) {
+			s.declare(ident.Value)
+		}
+		declareLets(s, arm.Guard)
+		declareLets(s, arm.Body)
+
+		r.scopes = append(r.scopes, s)
+		ast.Inspect(arm.Pattern, r.visit)
+		ast.Inspect(arm.Guard, r.visit)
+		ast.Inspect(arm.Body, r.visit)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+
+		arm.Locals = s.names
+	}
+}
+
+// declareLets declares in s the names bound by the lets in node, leaving out
+// the ones in nested scopes.
+func declareLets(s *scope, node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ArrayComprehension:
+			declareLets(s, node.Clause.Iterable)
+			return false
+		case *ast.HashComprehension:
+			declareLets(s, node.Clause.Iterable)
+			return false
+		case *ast.MatchExpression:
+			declareLets(s, node.Subject)
+			return false
+		case *ast.LetStatement:
+			for _, ident := range letIdentifiers(node) {
+				s.declare(ident.Value)
+			}
+		}
+		return true
+	})
+}
+
+func (r *resolver) resolveIdentifier(ident *ast.Identifier) {
+	ident.Resolved = false
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if index, ok := r.scopes[i].index[ident.Value]; ok {
+			ident.Resolved = true
+			ident.Depth = len(r.scopes) - 1 - i
+			ident.Index = index
+			return
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package evaluator
+
+import "github.com/fcidade/monkey-lang/object"
+
+// track keeps result, when it is a resource a builtin opened, for
+// CloseResources to close.
+func (e *Evaluator) track(result object.Object) {
+	if resource, ok := result.(object.Resource); ok {
+		e.resources.Track(resource)
+	}
+}
+
+// CloseResources closes the resources, such as connections and databases,
+// that builtins opened for the evaluations on e and that scripts have not
+// closed, returning the first error closing one failed with. Embedders call
+// it once they are done with e, as its environments are torn down; the
+// resources scripts drop before then are closed as they are collected.
+func (e *Evaluator) CloseResources() error {
+	return e.resources.Close()
+}
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"sync"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// Stats counts what an evaluation did. Evaluators forked for parallel
+// builtins record into the same Stats, so it is safe for concurrent use;
+// read the fields once evaluation has finished.
+type Stats struct {
+	mu sync.Mutex
+
+	// Steps is how many nodes were evaluated.
+	Steps int
+
+	// MaxCallDepth is the deepest the call stack got.
+	MaxCallDepth int
+
+	// Values counts the values nodes evaluated to, by type. Objects are
+	// garbage collected without the evaluator noticing, so this is a measure
+	// of how much was allocated rather than of how much was alive at once.
+	Values map[object.ObjectType]int
+}
+
+func (s *Stats) recordStep(result object.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Steps++
+	if result != nil {
+		if s.Values == nil {
+			s.Values = make(map[object.ObjectType]int)
+		}
+		s.Values[result.Type()]++
+	}
+}
+
+func (s *Stats) recordCallDepth(depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if depth > s.MaxCallDepth {
+		s.MaxCallDepth = depth
+	}
+}
+
+// SetStats makes e record into stats from now on, as if it had been
+// configured with them, or stop recording when stats is nil.
+func (e *Evaluator) SetStats(stats *Stats) {
+	e.config.Stats = stats
+	e.counting = false
+}
+
+// evalCounting evaluates node like Eval, recording the step in the
+// configured Stats.
+func (e *Evaluator) evalCounting(node ast.Node, env *object.Environment) object.Object {
+	e.counting = true
+	result := e.Eval(node, env)
+	e.config.Stats.recordStep(result)
+	return result
+}
@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"runtime/debug"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func init() {
+	signatures["version"] = "version()"
+}
+
+// Version returns the version of the interpreter, as recorded when it was
+// built, or "(devel)" for a build that was not made from a tagged module.
+func Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// Language returns the language e is configured to parse source as.
+func (e *Evaluator) Language() parser.Config {
+	return e.config.Language
+}
+
+// LanguageVersion returns the language version e parses source as.
+func (e *Evaluator) LanguageVersion() int {
+	if e.config.Language.Version == 0 {
+		return parser.LatestVersion
+	}
+	return e.config.Language.Version
+}
+
+// NewParser returns a parser for source in the language e is configured
+// with.
+func (e *Evaluator) NewParser(source string) *parser.Parser {
+	return parser.NewWithConfig(lexer.New(source), e.config.Language)
+}
+
+// versionBuiltin returns a hash with the version of the interpreter and the
+// language version, and the features turned on beyond it, it parses
+// programs as.
+func (e *Evaluator) versionBuiltin(args ...object.Object) object.Object {
+	if err := checkArgumentCount("version", args, 0); err != nil {
+		return err
+	}
+
+	features := []object.Object{}
+	for _, feature := range parser.Features() {
+		if version, _ := parser.FeatureVersion(feature); version > e.LanguageVersion() && e.config.Language.Enabled(feature) {
+			features = append(features, &object.String{Value: string(feature)})
+		}
+	}
+
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	for _, pair := range []object.HashPair{
+		{Key: &object.String{Value: "interpreter"}, Value: &object.String{Value: Version()}},
+		{Key: &object.String{Value: "language"}, Value: integer(int64(e.LanguageVersion()))},
+		{Key: &object.String{Value: "features"}, Value: &object.Array{Elements: features}},
+	} {
+		hash.Pairs[pair.Key.(object.Hashable).HashKey()] = pair
+	}
+	return hash
+}
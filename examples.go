@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/examples"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func examplesCommand(args []string) int {
+	flags := flag.NewFlagSet("examples", flag.ExitOnError)
+	source := flags.Bool("source", false, "print the program instead of running it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey examples [name]")
+		fmt.Fprintln(flags.Output(), "lists the bundled examples, or runs the one called name")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	switch flags.NArg() {
+	case 0:
+		for _, name := range examples.Names() {
+			fmt.Println(name)
+		}
+		return 0
+	case 1:
+		if *source {
+			return printExample(flags.Arg(0), os.Stdout)
+		}
+		return runExample(flags.Arg(0), os.Stdout)
+	}
+	flags.Usage()
+	return 2
+}
+
+func printExample(name string, out io.Writer) int {
+	source, err := examples.Source(name)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	fmt.Fprint(out, source)
+	return 0
+}
+
+// runExample runs the example called name and writes its value to out.
+func runExample(name string, out io.Writer) int {
+	result, err := examples.Run(name, evaluator.New(evaluator.Config{}))
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	fmt.Fprintln(out, result.Inspect())
+	if result.Type() == object.ERROR_OBJ {
+		return 1
+	}
+	return 0
+}
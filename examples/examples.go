@@ -0,0 +1,68 @@
+// Package examples bundles example programs into the binary, so they can be
+// listed and run with `monkey examples` wherever it is installed. Each
+// example name.mk comes with name.out, what the program evaluates to, which
+// the tests hold it to.
+package examples
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+//go:embed *.mk *.out
+var files embed.FS
+
+// Names returns the names of the examples in alphabetical order.
+func Names() []string {
+	paths, _ := fs.Glob(files, "*.mk")
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = strings.TrimSuffix(path, ".mk")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the program of the example called name.
+func Source(name string) (string, error) {
+	return read(name + ".mk")
+}
+
+// Expected returns the Inspect of the value the example called name
+// evaluates to.
+func Expected(name string) (string, error) {
+	out, err := read(name + ".out")
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+func read(path string) (string, error) {
+	content, err := files.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("no example called %s", strings.TrimSuffix(path, ".mk"))
+	}
+	return string(content), nil
+}
+
+// Run evaluates the example called name with ev and returns its value,
+// which is an error object when the program fails at runtime.
+func Run(name string, ev *evaluator.Evaluator) (object.Object, error) {
+	source, err := Source(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", name, strings.Join(p.Errors(), "; "))
+	}
+	return ev.Eval(program, ev.NewEnvironment()), nil
+}
@@ -0,0 +1,44 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+func TestExamples(t *testing.T) {
+	if len(Names()) == 0 {
+		t.Fatal("no examples are bundled")
+	}
+
+	for _, name := range Names() {
+		expected, err := Expected(name)
+		if err != nil {
+			t.Errorf("%s has no expected value: %s", name, err)
+			continue
+		}
+
+		result, err := Run(name, evaluator.New(evaluator.Config{}))
+		if err != nil {
+			t.Errorf("could not run %s: %s", name, err)
+			continue
+		}
+		if result.Inspect() != expected {
+			t.Errorf("wrong value for %s. expected=%q, got=%q", name, expected, result.Inspect())
+		}
+	}
+
+	if _, err := Run("missing", evaluator.New(evaluator.Config{})); err == nil || err.Error() != "no example called missing" {
+		t.Errorf("wrong error for a missing example. got=%v", err)
+	}
+}
+
+func BenchmarkExamples(b *testing.B) {
+	for _, name := range Names() {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Run(name, evaluator.New(evaluator.Config{}))
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+let commits = yamlDecode("[
+	{author: ada, team: core, commits: 12},
+	{author: bob, team: docs, commits: 3},
+	{author: cy, team: core, commits: 7},
+	{author: dee, team: tools, commits: 5}
+]");
+
+let sum = fn(xs) {
+	if (len(xs) == 0) { 0 } else { first(xs) + sum(rest(xs)) }
+};
+
+let teams = sort([c.team for c in commits]);
+yamlEncode({team: sum([c.commits for c in commits if c.team == team]) for team in teams})
@@ -0,0 +1,19 @@
+let sum = fn(xs) {
+	if (len(xs) == 0) { 0 } else { first(xs) + sum(rest(xs)) }
+};
+
+let column = fn(m, j) { [row[j] for row in m] };
+let dot = fn(a, b) { sum([a[i] * b[i] for i in 0..len(a)]) };
+
+let multiply = fn(a, b) {
+	[[dot(row, column(b, j)) for j in 0..len(b[0])] for row in a]
+};
+
+let identity = fn(n) {
+	[[if (i == j) { 1 } else { 0 } for j in 0..n] for i in 0..n]
+};
+
+let a = [[1, 2], [3, 4], [5, 6]];
+let b = [[7, 8, 9], [10, 11, 12]];
+
+(multiply(a, b), multiply(a, identity(2)) == a)
@@ -0,0 +1,15 @@
+let concat = fn(a, b) {
+	if (len(b) == 0) { a } else { concat(push(a, first(b)), rest(b)) }
+};
+
+let quicksort = fn(xs) {
+	if (len(xs) < 2) { return xs; }
+	let pivot = first(xs);
+	let smaller = [x for x in rest(xs) if x < pivot];
+	let larger = [x for x in rest(xs) if !(x < pivot)];
+	concat(push(quicksort(smaller), pivot), quicksort(larger))
+};
+
+let words = ["pear", "fig", "banana", "kiwi", "apple"];
+
+(quicksort([5, 3, 9, 1, 7, 3, 8, 0]), sort(words), sortBy(words, len))
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fcidade/monkey-lang/format"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	overwrite := flags.Bool("w", false, "write the result back to each script instead of to stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey fmt [flags] <script.mk>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if !formatFile(path, *overwrite, os.Stdout) {
+			status = 1
+		}
+	}
+	return status
+}
+
+// formatFile formats the script at path, keeping its comments, and writes
+// the result to out or, with overwrite set, back to the script. A script
+// that does not parse is left alone and its errors written to out. It
+// reports whether the script was formatted.
+func formatFile(path string, overwrite bool, out io.Writer) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	p := parser.New(lexer.NewWithTrivia(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
+	}
+
+	formatted := format.Node(program) + "\n"
+	if !overwrite {
+		fmt.Fprint(out, formatted)
+		return true
+	}
+	if formatted == string(content) {
+		return true
+	}
+	if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+		fmt.Fprintf(out, "could not write %s: %s\n", path, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,324 @@
+package format
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Node renders node as Monkey source code that parses back into an
+// equivalent tree. Unlike the String methods on the AST, which are meant for
+// debugging, string literals keep their quotes and every statement is
+// terminated.
+//
+// Comments the parser kept are written where they were found, along with a
+// blank line wherever there were some. A block with comments in it is
+// written over several lines, indented with tabs; other blocks take one.
+func Node(node ast.Node) string {
+	out := &printer{}
+	write(out, node)
+	return out.String()
+}
+
+type printer struct {
+	bytes.Buffer
+
+	// indent is how many blocks deep the line being written is.
+	indent int
+}
+
+// line starts a new line at the current indentation.
+func (out *printer) line() {
+	out.WriteString("\n")
+	out.WriteString(strings.Repeat("\t", out.indent))
+}
+
+func write(out *printer, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		writeStatements(out, node.Statements, node.End, false)
+
+	case *ast.LetStatement:
+		out.WriteString("let ")
+		if node.Pattern != nil {
+			write(out, node.Pattern)
+		} else {
+			writeAnnotated(out, node.Name)
+		}
+		out.WriteString(" = ")
+		write(out, node.Value)
+		out.WriteString(";")
+
+	case *ast.ReturnStatement:
+		out.WriteString("return ")
+		write(out, node.ReturnValue)
+		out.WriteString(";")
+
+	case *ast.ExpressionStatement:
+		write(out, node.Expression)
+		out.WriteString(";")
+
+	case *ast.BlockStatement:
+		if !hasComments(node) {
+			out.WriteString("{")
+			for _, stmt := range node.Statements {
+				out.WriteString(" ")
+				write(out, stmt)
+			}
+			out.WriteString(" }")
+			break
+		}
+		out.WriteString("{")
+		out.indent++
+		writeStatements(out, node.Statements, node.End, true)
+		out.indent--
+		out.line()
+		out.WriteString("}")
+
+	case *ast.ArrayPattern, *ast.HashPattern, *ast.TuplePattern, *ast.LiteralPattern, *ast.WildcardPattern:
+		out.WriteString(node.String())
+
+	case *ast.Identifier:
+		out.WriteString(node.Value)
+
+	case *ast.IntegerLiteral:
+		out.WriteString(node.Token.Literal)
+
+	case *ast.Boolean:
+		out.WriteString(node.Token.Literal)
+
+	case *ast.StringLiteral:
+		out.WriteString(ast.Quote(node.Value))
+
+	case *ast.CharLiteral:
+		out.WriteString(node.String())
+
+	case *ast.BytesLiteral:
+		out.WriteString("b" + ast.Quote(node.Value))
+
+	case *ast.PrefixExpression:
+		out.WriteString("(")
+		out.WriteString(node.Operator)
+		write(out, node.Right)
+		out.WriteString(")")
+
+	case *ast.InfixExpression:
+		out.WriteString("(")
+		write(out, node.Left)
+		out.WriteString(" " + node.Operator + " ")
+		write(out, node.Right)
+		out.WriteString(")")
+
+	case *ast.ComparisonChain:
+		out.WriteString("(")
+		for i, operand := range node.Operands {
+			if i > 0 {
+				out.WriteString(" " + node.Operators[i-1] + " ")
+			}
+			write(out, operand)
+		}
+		out.WriteString(")")
+
+	case *ast.IfExpression:
+		out.WriteString("if (")
+		write(out, node.Condition)
+		out.WriteString(") ")
+		write(out, node.Consequence)
+		if node.Alternative != nil {
+			out.WriteString(" else ")
+			write(out, node.Alternative)
+		}
+
+	case *ast.FunctionLiteral:
+		out.WriteString("fn(")
+		for i, p := range node.Parameters {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeAnnotated(out, p)
+		}
+		out.WriteString(") ")
+		if node.ReturnType != nil {
+			out.WriteString(": " + node.ReturnType.Name + " ")
+		}
+		write(out, node.Body)
+
+	case *ast.CallExpression:
+		write(out, node.Function)
+		out.WriteString("(")
+		writeList(out, node.Arguments)
+		out.WriteString(")")
+
+	case *ast.ArrayLiteral:
+		out.WriteString("[")
+		writeList(out, node.Elements)
+		out.WriteString("]")
+
+	case *ast.TupleLiteral:
+		out.WriteString("(")
+		writeList(out, node.Elements)
+		if len(node.Elements) == 1 {
+			out.WriteString(",")
+		}
+		out.WriteString(")")
+
+	case *ast.ArrayComprehension:
+		out.WriteString("[")
+		write(out, node.Element)
+		writeClause(out, node.Clause)
+		out.WriteString("]")
+
+	case *ast.HashComprehension:
+		out.WriteString("{")
+		write(out, node.Key)
+		out.WriteString(": ")
+		write(out, node.Value)
+		writeClause(out, node.Clause)
+		out.WriteString("}")
+
+	case *ast.MatchExpression:
+		out.WriteString("match ")
+		write(out, node.Subject)
+		out.WriteString(" {")
+		for i, arm := range node.Arms {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(" ")
+			write(out, arm.Pattern)
+			if arm.Guard != nil {
+				out.WriteString(" if ")
+				write(out, arm.Guard)
+			}
+			out.WriteString(" => ")
+			write(out, arm.Body)
+		}
+		out.WriteString(" }")
+
+	case *ast.FieldExpression:
+		out.WriteString("(")
+		write(out, node.Left)
+		if node.Optional {
+			out.WriteString("?.")
+		} else {
+			out.WriteString(".")
+		}
+		out.WriteString(node.Name)
+		out.WriteString(")")
+
+	case *ast.IndexExpression:
+		out.WriteString("(")
+		write(out, node.Left)
+		if node.Optional {
+			out.WriteString("?.")
+		}
+		out.WriteString("[")
+		write(out, node.Index)
+		out.WriteString("])")
+
+	case *ast.HashLiteral:
+		pairs := []string{}
+		for key, value := range node.Pairs {
+			pair := &printer{indent: out.indent}
+			write(pair, key)
+			pair.WriteString(": ")
+			write(pair, value)
+			pairs = append(pairs, pair.String())
+		}
+		sort.Strings(pairs)
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+	}
+}
+
+// writeStatements writes stmts a line each, with their comments, followed
+// by the comments at the end of the block or program they are in. With
+// breakFirst set the first line is started too, as it is in a block.
+func writeStatements(out *printer, stmts []ast.Statement, end *ast.Comments, breakFirst bool) {
+	started := false
+	newLine := func(blank int) {
+		if started && blank > 0 {
+			out.WriteString("\n")
+		}
+		if started || breakFirst {
+			out.line()
+		}
+		started = true
+	}
+	leading := func(comments []token.Comment) {
+		for _, comment := range comments {
+			newLine(comment.Blank)
+			out.WriteString(comment.Text)
+		}
+	}
+
+	for _, stmt := range stmts {
+		comments := ast.CommentsOf(stmt)
+		if comments == nil {
+			newLine(0)
+			write(out, stmt)
+			continue
+		}
+
+		leading(comments.Leading)
+		newLine(comments.Blank)
+		write(out, stmt)
+		for i, comment := range comments.Trailing {
+			if i > 0 {
+				newLine(0)
+			} else {
+				out.WriteString(" ")
+			}
+			out.WriteString(comment)
+		}
+	}
+	if end != nil {
+		leading(end.Leading)
+	}
+}
+
+// hasComments reports whether block, or any block in it, has comments.
+func hasComments(block *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(block, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+			found = found || (node.End != nil && len(node.End.Leading) > 0)
+		case ast.Statement:
+			found = found || ast.CommentsOf(node) != nil
+		}
+		return !found
+	})
+	return found
+}
+
+func writeAnnotated(out *printer, ident *ast.Identifier) {
+	out.WriteString(ident.Value)
+	if ident.Type != nil {
+		out.WriteString(": " + ident.Type.Name)
+	}
+}
+
+func writeClause(out *printer, clause *ast.ComprehensionClause) {
+	out.WriteString(" for ")
+	write(out, clause.Pattern)
+	out.WriteString(" in ")
+	write(out, clause.Iterable)
+	if clause.Condition != nil {
+		out.WriteString(" if ")
+		write(out, clause.Condition)
+	}
+}
+
+func writeList(out *printer, exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		write(out, exp)
+	}
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func TestNode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5", "let x = 5;"},
+		{"return a + b * c", "return (a + (b * c));"},
+		{`"hello" + " " + "world"`, `(("hello" + " ") + "world");`},
+		{"if (x < y) { x } else { y }", "if ((x < y)) { x; } else { y; };"},
+		{"fn(x, y) { let z = x; z + y }", "fn(x, y) { let z = x; (z + y); };"},
+		{"add(1, [2, 3][0])", "add(1, ([2, 3][0]));"},
+		{`{"b": 2, "a": !true}`, `{"a": (!true), "b": 2};`},
+		{"1; 2", "1;\n2;"},
+		{"let [a, {b, c: [d, ...e]}] = x", "let [a, {b, c: [d, ...e]}] = x;"},
+		{"let a, b = (1,); return a, b", "let (a, b) = (1,);\nreturn (a, b);"},
+		{"a.b?.c?.[0] ?? d", "((((a.b)?.c)?.[0]) ?? d);"},
+		{"1 < x < 3", "(1 < x < 3);"},
+		{"sleep(1h30m + 5)", "sleep((1h30m + 5));"},
+		{`match x { [1, _] => a, {"k": -2} if y => { b } }`, `match x { [1, _] => { a; }, {k: -2} if y => { b; } };`},
+		{"[x for x, y in h if y]", "[x for (x, y) in h if y];"},
+		{"{x: 1 for x in 0..3}", "{x: 1 for x in (0 .. 3)};"},
+		{"let f = fn(a: int) : int { a }", "let f = fn(a: int) : int { a; };"},
+		{"`say \"hi\"`", "`say \"hi\"`;"},
+		{"match c { 'a' => ord('a') }", "match c { 'a' => { ord('a'); } };"},
+		{"`plain` + b`{\"a\": 1}`", "(\"plain\" + b`{\"a\": 1}`);"},
+	}
+
+	for _, tt := range tests {
+		formatted := format(t, tt.input)
+		if formatted != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, formatted)
+		}
+		if again := format(t, formatted); again != formatted {
+			t.Errorf("formatting %q is not stable. got=%q", formatted, again)
+		}
+	}
+}
+
+func format(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return Node(program)
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// x\nlet x = 5 // five", "// x\nlet x = 5; // five"},
+		{"\n\na\n\n\n\nb\n// end", "a;\n\nb;\n// end"},
+		{"if (x) { // yes\n  a\n} else { b }", "if (x) {\n\t// yes\n\ta;\n} else { b; };"},
+		{"let f = fn() {\n  let g = fn() {\n    // deep\n    1\n  }\n  g()\n}", "let f = fn() {\n\tlet g = fn() {\n\t\t// deep\n\t\t1;\n\t};\n\tg();\n};"},
+		{"[1, // one\n 2]", "[1, 2]; // one"},
+		{"fn() {\n  a\n\n  // last\n}", "fn() {\n\ta;\n\n\t// last\n};"},
+	}
+
+	for _, tt := range tests {
+		formatted := formatComments(t, tt.input)
+		if formatted != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, formatted)
+		}
+		if again := formatComments(t, formatted); again != formatted {
+			t.Errorf("formatting %q is not stable. got=%q", formatted, again)
+		}
+		if plain := format(t, formatted); plain != format(t, tt.input) {
+			t.Errorf("%q parses into a different program than %q", formatted, tt.input)
+		}
+	}
+}
+
+func formatComments(t *testing.T, input string) string {
+	p := parser.New(lexer.NewWithTrivia(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return Node(program)
+}
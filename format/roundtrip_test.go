@@ -0,0 +1,292 @@
+package format
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// TestRoundTrip formats random programs, parses the output and checks the
+// result is the program it started from, which catches operators printed
+// without the parentheses their precedence needs and constructs the parser
+// reads differently from how they are written.
+func TestRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 2000; seed++ {
+		g := &generator{rand: rand.New(rand.NewSource(seed))}
+		program := g.program()
+		formatted := Node(program)
+
+		p := parser.New(lexer.New(formatted))
+		reparsed := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("seed %d: %q does not parse: %v", seed, formatted, p.Errors())
+		}
+		if !equal(reflect.ValueOf(program), reflect.ValueOf(reparsed)) {
+			t.Fatalf("seed %d: %q parses into a different program, %q", seed, formatted, Node(reparsed))
+		}
+	}
+}
+
+// generator builds random programs that are valid Monkey, nesting
+// expressions no deeper than maxDepth.
+type generator struct {
+	rand  *rand.Rand
+	depth int
+}
+
+const maxDepth = 4
+
+var (
+	names           = []string{"a", "b", "c", "x", "y", "total"}
+	typeNames       = []string{"int", "string", "bool", "array", "fn"}
+	prefixOperators = []string{"!", "-"}
+	infixOperators  = []string{"+", "-", "*", "/", "%", "==", "!=", "<", ">", "..", "??"}
+)
+
+func (g *generator) pick(options []string) string {
+	return options[g.rand.Intn(len(options))]
+}
+
+func (g *generator) program() *ast.Program {
+	program := &ast.Program{}
+	for i := g.rand.Intn(4) + 1; i > 0; i-- {
+		program.Statements = append(program.Statements, g.statement())
+	}
+	return program
+}
+
+func (g *generator) statement() ast.Statement {
+	switch g.rand.Intn(4) {
+	case 0:
+		name := g.identifier()
+		if g.rand.Intn(3) == 0 {
+			name.Type = &ast.TypeAnnotation{Name: g.pick(typeNames)}
+		}
+		return &ast.LetStatement{Name: name, Value: g.expression()}
+	case 1:
+		return &ast.ReturnStatement{ReturnValue: g.expression()}
+	}
+	return &ast.ExpressionStatement{Expression: g.expression()}
+}
+
+func (g *generator) block() *ast.BlockStatement {
+	block := &ast.BlockStatement{}
+	for i := g.rand.Intn(3); i > 0; i-- {
+		block.Statements = append(block.Statements, g.statement())
+	}
+	return block
+}
+
+func (g *generator) identifier() *ast.Identifier {
+	return &ast.Identifier{Value: g.pick(names)}
+}
+
+func (g *generator) expressions(max int) []ast.Expression {
+	exps := []ast.Expression{}
+	for i := g.rand.Intn(max + 1); i > 0; i-- {
+		exps = append(exps, g.expression())
+	}
+	return exps
+}
+
+func (g *generator) literal() ast.Expression {
+	switch g.rand.Intn(5) {
+	case 0:
+		value := g.rand.Int63n(1000)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10)}, Value: value}
+	case 1:
+		return &ast.StringLiteral{Value: g.pick([]string{"", "a", "hello world", `say "hi"`})}
+	case 2:
+		value := g.rand.Intn(2) == 0
+		return &ast.Boolean{Token: token.Token{Literal: strconv.FormatBool(value)}, Value: value}
+	case 3:
+		return &ast.CharLiteral{Value: []rune("a'é")[g.rand.Intn(3)]}
+	}
+	return &ast.BytesLiteral{Value: g.pick([]string{"", "abc", `{"a": 1}`})}
+}
+
+func (g *generator) expression() ast.Expression {
+	if g.depth >= maxDepth || g.rand.Intn(4) == 0 {
+		if g.rand.Intn(2) == 0 {
+			return g.identifier()
+		}
+		return g.literal()
+	}
+	g.depth++
+	defer func() { g.depth-- }()
+
+	switch g.rand.Intn(16) {
+	case 0:
+		return &ast.PrefixExpression{Operator: g.pick(prefixOperators), Right: g.expression()}
+	case 1, 2:
+		return &ast.InfixExpression{Left: g.expression(), Operator: g.pick(infixOperators), Right: g.expression()}
+	case 3:
+		chain := &ast.ComparisonChain{}
+		for i := g.rand.Intn(2) + 3; i > 0; i-- {
+			chain.Operands = append(chain.Operands, g.expression())
+		}
+		for i := 1; i < len(chain.Operands); i++ {
+			chain.Operators = append(chain.Operators, g.pick([]string{"<", ">"}))
+		}
+		return chain
+	case 4:
+		exp := &ast.IfExpression{Condition: g.expression(), Consequence: g.block()}
+		if g.rand.Intn(2) == 0 {
+			exp.Alternative = g.block()
+		}
+		return exp
+	case 5:
+		fn := &ast.FunctionLiteral{Body: g.block()}
+		for _, name := range names[:g.rand.Intn(3)] {
+			param := &ast.Identifier{Value: name}
+			if g.rand.Intn(3) == 0 {
+				param.Type = &ast.TypeAnnotation{Name: g.pick(typeNames)}
+			}
+			fn.Parameters = append(fn.Parameters, param)
+		}
+		if g.rand.Intn(3) == 0 {
+			fn.ReturnType = &ast.TypeAnnotation{Name: g.pick(typeNames)}
+		}
+		return fn
+	case 6:
+		return &ast.CallExpression{Function: g.expression(), Arguments: g.expressions(3)}
+	case 7:
+		return &ast.ArrayLiteral{Elements: g.expressions(3)}
+	case 8:
+		return &ast.TupleLiteral{Elements: append(g.expressions(2), g.expression())}
+	case 9:
+		hash := &ast.HashLiteral{Pairs: map[ast.Expression]ast.Expression{}}
+		keys := map[string]bool{}
+		for i := g.rand.Intn(3); i > 0; i-- {
+			key := g.literal()
+			if !keys[Node(key)] {
+				keys[Node(key)] = true
+				hash.Pairs[key] = g.expression()
+			}
+		}
+		return hash
+	case 10:
+		return &ast.IndexExpression{Left: g.expression(), Index: g.expression(), Optional: g.rand.Intn(2) == 0}
+	case 11:
+		return &ast.FieldExpression{Left: g.expression(), Name: g.pick(names), Optional: g.rand.Intn(2) == 0}
+	case 12:
+		return &ast.ArrayComprehension{Element: g.expression(), Clause: g.clause()}
+	case 13:
+		return &ast.HashComprehension{Key: g.expression(), Value: g.expression(), Clause: g.clause()}
+	case 14:
+		match := &ast.MatchExpression{Subject: g.expression()}
+		for i := g.rand.Intn(3) + 1; i > 0; i-- {
+			arm := &ast.MatchArm{Pattern: g.pattern(), Body: g.block()}
+			if g.rand.Intn(3) == 0 {
+				arm.Guard = g.expression()
+			}
+			match.Arms = append(match.Arms, arm)
+		}
+		return match
+	}
+	return g.identifier()
+}
+
+func (g *generator) clause() *ast.ComprehensionClause {
+	clause := &ast.ComprehensionClause{Pattern: g.identifier(), Iterable: g.expression()}
+	if g.rand.Intn(2) == 0 {
+		clause.Condition = g.expression()
+	}
+	return clause
+}
+
+func (g *generator) pattern() ast.Pattern {
+	switch g.rand.Intn(4) {
+	case 0:
+		return &ast.WildcardPattern{}
+	case 1:
+		literal := g.literal()
+		if bytes, ok := literal.(*ast.BytesLiteral); ok {
+			literal = &ast.StringLiteral{Value: bytes.Value}
+		}
+		return &ast.LiteralPattern{Value: literal}
+	case 2:
+		pattern := &ast.ArrayPattern{}
+		for _, name := range names[:g.rand.Intn(3)] {
+			pattern.Elements = append(pattern.Elements, &ast.Identifier{Value: name})
+		}
+		if g.rand.Intn(2) == 0 {
+			pattern.Rest = &ast.Identifier{Value: "rest"}
+		}
+		return pattern
+	}
+	return g.identifier()
+}
+
+// equal reports whether a and b are the same tree, ignoring tokens, which
+// record where a node was read from, and the pairs of hash literals being
+// keyed by different nodes.
+func equal(a, b reflect.Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return equal(a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).Type == reflect.TypeOf(token.Token{}) {
+				continue
+			}
+			if !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		aKeys, bKeys := sortedKeys(a), sortedKeys(b)
+		for i := range aKeys {
+			if !equal(aKeys[i], bKeys[i]) || !equal(a.MapIndex(aKeys[i]), b.MapIndex(bKeys[i])) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// sortedKeys returns the keys of a hash literal's pairs in the order the
+// formatter writes them.
+func sortedKeys(pairs reflect.Value) []reflect.Value {
+	keys := pairs.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Compare(Node(keys[i].Interface().(ast.Node)), Node(keys[j].Interface().(ast.Node))) < 0
+	})
+	return keys
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/message"
+)
+
+func TestFormatFile(t *testing.T) {
+	tests := []struct {
+		content   string
+		overwrite bool
+		ok        bool
+		output    string
+		formatted string
+	}{
+		{"// sum\nlet a = 1+2 // three\n", false, true, "// sum\nlet a = (1 + 2); // three\n", "// sum\nlet a = 1+2 // three\n"},
+		{"// sum\nlet a = 1+2 // three\n", true, true, "", "// sum\nlet a = (1 + 2); // three\n"},
+		{"let a = ", true, false, message.Format(message.ParseErrorsHeader) + "\n\tno prefix parse function for  found\n", "let a = "},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "script.mk")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if ok := formatFile(path, tt.overwrite, &out); ok != tt.ok {
+			t.Errorf("%q: expected ok=%t, got %t", tt.content, tt.ok, ok)
+		}
+		if out.String() != tt.output {
+			t.Errorf("%q: expected output %q, got %q", tt.content, tt.output, out.String())
+		}
+		if content, _ := os.ReadFile(path); string(content) != tt.formatted {
+			t.Errorf("%q: expected the script to hold %q, got %q", tt.content, tt.formatted, content)
+		}
+	}
+}
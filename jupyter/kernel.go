@@ -0,0 +1,415 @@
+// Package jupyter is a Jupyter kernel for Monkey, so notebooks can run
+// Monkey cells. Cells run one after another in a single REPL session, so
+// the bindings of a cell are there for the cells after it, and a cell's
+// value is shown as the REPL would print it.
+package jupyter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+// protocolVersion is the version of the Jupyter messaging protocol the
+// kernel speaks.
+const protocolVersion = "5.3"
+
+// delimiter separates the routing identities of a message from its parts.
+const delimiter = "<IDS|MSG>"
+
+// ConnectionInfo is what a connection file, which Jupyter writes for each
+// kernel it starts, says about where the kernel is to listen and how it is
+// to sign its messages.
+type ConnectionInfo struct {
+	Transport       string `json:"transport"`
+	IP              string `json:"ip"`
+	ShellPort       int    `json:"shell_port"`
+	IOPubPort       int    `json:"iopub_port"`
+	StdinPort       int    `json:"stdin_port"`
+	ControlPort     int    `json:"control_port"`
+	HeartbeatPort   int    `json:"hb_port"`
+	Key             string `json:"key"`
+	SignatureScheme string `json:"signature_scheme"`
+}
+
+// ReadConnectionFile reads the connection file at path.
+func ReadConnectionFile(path string) (ConnectionInfo, error) {
+	var info ConnectionInfo
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(content, &info); err != nil {
+		return info, fmt.Errorf("%s is not a connection file: %s", path, err)
+	}
+	return info, nil
+}
+
+// Header is the header of a message, and of the one it answers.
+type Header struct {
+	MessageID string `json:"msg_id"`
+	Session   string `json:"session"`
+	Username  string `json:"username"`
+	Date      string `json:"date"`
+	Type      string `json:"msg_type"`
+	Version   string `json:"version"`
+}
+
+// Message is a message of the Jupyter protocol.
+type Message struct {
+	Identities [][]byte
+	Header     Header
+	Parent     Header
+	Metadata   map[string]interface{}
+	Content    json.RawMessage
+}
+
+// Kernel is a kernel listening where its ConnectionInfo says.
+type Kernel struct {
+	info    ConnectionInfo
+	key     []byte
+	session string
+
+	shell, control, stdin, iopub, heartbeat *socket
+
+	// repl is where cells run; count numbers the cells run.
+	repl       *repl.Session
+	count      int
+	interrupts chan os.Signal
+
+	mu       sync.Mutex
+	shutdown chan struct{}
+	done     bool
+}
+
+// Listen binds the sockets info lists, with ports of 0 chosen by the
+// system, and returns the kernel, which runs cells with config.
+func Listen(info ConnectionInfo, config evaluator.Config) (*Kernel, error) {
+	if info.Transport != "" && info.Transport != "tcp" {
+		return nil, fmt.Errorf("the %s transport is not supported", info.Transport)
+	}
+	if info.Key != "" && info.SignatureScheme != "" && info.SignatureScheme != "hmac-sha256" {
+		return nil, fmt.Errorf("the %s signature scheme is not supported", info.SignatureScheme)
+	}
+	if info.IP == "" {
+		info.IP = "127.0.0.1"
+	}
+
+	k := &Kernel{
+		info:       info,
+		key:        []byte(info.Key),
+		session:    newID(),
+		interrupts: make(chan os.Signal),
+		shutdown:   make(chan struct{}),
+	}
+	k.repl = repl.NewSession(repl.Config{Evaluator: config, Interrupts: k.interrupts})
+
+	sockets := []struct {
+		s    **socket
+		kind string
+		port *int
+	}{
+		{&k.shell, "ROUTER", &k.info.ShellPort},
+		{&k.control, "ROUTER", &k.info.ControlPort},
+		{&k.stdin, "ROUTER", &k.info.StdinPort},
+		{&k.iopub, "PUB", &k.info.IOPubPort},
+		{&k.heartbeat, "REP", &k.info.HeartbeatPort},
+	}
+	for _, s := range sockets {
+		bound, err := bind(s.kind, net.JoinHostPort(info.IP, strconv.Itoa(*s.port)))
+		if err != nil {
+			k.Close()
+			return nil, err
+		}
+		*s.s, *s.port = bound, bound.port()
+	}
+	return k, nil
+}
+
+// Info returns where the kernel listens, with the ports the system chose.
+func (k *Kernel) Info() ConnectionInfo {
+	return k.info
+}
+
+// Serve answers the kernel's messages until a shutdown request comes. Unless
+// a kernel.json says otherwise, Jupyter interrupts a kernel by sending it
+// SIGINT, so while Serve runs the os.Interrupt signals of the process
+// interrupt the running cell instead of ending the process.
+func (k *Kernel) Serve() error {
+	defer k.Close()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	go k.serveControl()
+	go k.serveHeartbeat()
+	go k.serveSignals(signals)
+	for {
+		select {
+		case <-k.shutdown:
+			return nil
+		case in := <-k.shell.incoming:
+			k.handle(k.shell, in.frames)
+		case <-k.stdin.incoming:
+			// Cells never ask for input, so no replies are expected.
+		}
+	}
+}
+
+// serveControl answers the messages on the control socket, which come
+// while a cell may be running on the shell.
+func (k *Kernel) serveControl() {
+	for {
+		select {
+		case <-k.shutdown:
+			return
+		case in := <-k.control.incoming:
+			k.handle(k.control, in.frames)
+		}
+	}
+}
+
+// serveHeartbeat echoes the heartbeats, which have to be answered while a
+// cell runs for Jupyter not to take the kernel for dead.
+func (k *Kernel) serveHeartbeat() {
+	for {
+		select {
+		case <-k.shutdown:
+			return
+		case in := <-k.heartbeat.incoming:
+			in.peer.writeMessage(in.frames)
+		}
+	}
+}
+
+// serveSignals interrupts the running cell for each of signals.
+func (k *Kernel) serveSignals(signals <-chan os.Signal) {
+	for {
+		select {
+		case <-k.shutdown:
+			return
+		case <-signals:
+			k.Interrupt()
+		}
+	}
+}
+
+// Interrupt interrupts the running cell, if any. It is safe to call from
+// another goroutine.
+func (k *Kernel) Interrupt() {
+	select {
+	case k.interrupts <- os.Interrupt:
+	default:
+		// No cell is running.
+	}
+}
+
+// Close stops listening, drops the kernel's connections and closes the
+// resources the cells left open.
+func (k *Kernel) Close() {
+	for _, s := range []*socket{k.shell, k.control, k.stdin, k.iopub, k.heartbeat} {
+		if s != nil {
+			s.close()
+		}
+	}
+	k.repl.Close()
+}
+
+func (k *Kernel) handle(s *socket, frames [][]byte) {
+	request, err := k.decode(frames)
+	if err != nil {
+		return
+	}
+
+	k.publish(request, "status", map[string]interface{}{"execution_state": "busy"})
+	defer k.publish(request, "status", map[string]interface{}{"execution_state": "idle"})
+
+	switch request.Header.Type {
+	case "kernel_info_request":
+		k.reply(s, request, "kernel_info_reply", map[string]interface{}{
+			"status":                 "ok",
+			"protocol_version":       protocolVersion,
+			"implementation":         "monkey",
+			"implementation_version": evaluator.Version(),
+			"language_info": map[string]interface{}{
+				"name":           "monkey",
+				"version":        evaluator.Version(),
+				"mimetype":       "text/x-monkey",
+				"file_extension": ".mk",
+			},
+			"banner": "Monkey " + evaluator.Version(),
+		})
+
+	case "execute_request":
+		k.execute(s, request)
+
+	case "is_complete_request":
+		k.reply(s, request, "is_complete_reply", map[string]interface{}{"status": "unknown"})
+
+	case "comm_info_request":
+		k.reply(s, request, "comm_info_reply", map[string]interface{}{"status": "ok", "comms": map[string]interface{}{}})
+
+	case "history_request":
+		k.reply(s, request, "history_reply", map[string]interface{}{"status": "ok", "history": []interface{}{}})
+
+	case "interrupt_request":
+		k.Interrupt()
+		k.reply(s, request, "interrupt_reply", map[string]interface{}{"status": "ok"})
+
+	case "shutdown_request":
+		var content struct {
+			Restart bool `json:"restart"`
+		}
+		json.Unmarshal(request.Content, &content)
+		k.reply(s, request, "shutdown_reply", map[string]interface{}{"status": "ok", "restart": content.Restart})
+		k.mu.Lock()
+		if !k.done {
+			k.done = true
+			close(k.shutdown)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// execute runs the code of an execute_request as a cell, publishing what
+// it puts and its value or errors, and replies with how it went.
+func (k *Kernel) execute(s *socket, request *Message) {
+	var content struct {
+		Code   string `json:"code"`
+		Silent bool   `json:"silent"`
+	}
+	json.Unmarshal(request.Content, &content)
+
+	if !content.Silent {
+		k.count++
+	}
+	k.publish(request, "execute_input", map[string]interface{}{"code": content.Code, "execution_count": k.count})
+
+	result, diagnostics := k.repl.EvalLine(strings.TrimRight(content.Code, "\n"))
+	if result.Output != "" && !content.Silent {
+		k.publish(request, "stream", map[string]interface{}{"name": "stdout", "text": result.Output})
+	}
+
+	if len(diagnostics) > 0 {
+		var traceback []string
+		switch {
+		case result.Error != nil:
+			traceback = strings.Split(result.Error.Inspect(), "\n")
+		default:
+			traceback = []string{message.Format(message.ParseErrorsHeader)}
+			for _, d := range diagnostics {
+				traceback = append(traceback, "\t"+d.Message)
+			}
+		}
+		failure := map[string]interface{}{
+			"ename":     string(diagnostics[0].Code),
+			"evalue":    diagnostics[0].Message,
+			"traceback": traceback,
+		}
+		k.publish(request, "error", failure)
+		failure["status"] = "error"
+		failure["execution_count"] = k.count
+		k.reply(s, request, "execute_reply", failure)
+		return
+	}
+
+	if result.Value != nil && !content.Silent {
+		k.publish(request, "execute_result", map[string]interface{}{
+			"execution_count": k.count,
+			"data":            map[string]interface{}{"text/plain": result.Value.Inspect()},
+			"metadata":        map[string]interface{}{},
+		})
+	}
+	k.reply(s, request, "execute_reply", map[string]interface{}{
+		"status":           "ok",
+		"execution_count":  k.count,
+		"user_expressions": map[string]interface{}{},
+		"payload":          []interface{}{},
+	})
+}
+
+func (k *Kernel) reply(s *socket, request *Message, kind string, content interface{}) {
+	s.send(k.encode(request.Identities, request, kind, content))
+}
+
+func (k *Kernel) publish(parent *Message, kind string, content interface{}) {
+	k.iopub.send(k.encode([][]byte{[]byte(kind)}, parent, kind, content))
+}
+
+// encode returns the frames of a message of kind answering parent.
+func (k *Kernel) encode(identities [][]byte, parent *Message, kind string, content interface{}) [][]byte {
+	header := Header{
+		MessageID: newID(),
+		Session:   k.session,
+		Username:  "kernel",
+		Date:      time.Now().UTC().Format(time.RFC3339Nano),
+		Type:      kind,
+		Version:   protocolVersion,
+	}
+	parts := make([][]byte, 4)
+	parts[0], _ = json.Marshal(header)
+	parts[1], _ = json.Marshal(parent.Header)
+	parts[2] = []byte("{}")
+	parts[3], _ = json.Marshal(content)
+
+	frames := append([][]byte(nil), identities...)
+	frames = append(frames, []byte(delimiter), []byte(k.sign(parts)))
+	return append(frames, parts...)
+}
+
+// decode returns the message frames hold, checking its signature.
+func (k *Kernel) decode(frames [][]byte) (*Message, error) {
+	i := 0
+	for i < len(frames) && string(frames[i]) != delimiter {
+		i++
+	}
+	if len(frames) < i+6 {
+		return nil, errors.New("the message is too short")
+	}
+	parts := frames[i+2 : i+6]
+	if !hmac.Equal([]byte(k.sign(parts)), frames[i+1]) {
+		return nil, errors.New("the message has the wrong signature")
+	}
+
+	m := &Message{Identities: frames[:i], Content: json.RawMessage(parts[3])}
+	if err := json.Unmarshal(parts[0], &m.Header); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(parts[1], &m.Parent)
+	json.Unmarshal(parts[2], &m.Metadata)
+	return m, nil
+}
+
+// sign returns the signature of the parts of a message, which is empty when
+// the kernel has no key.
+func (k *Kernel) sign(parts [][]byte) string {
+	if len(k.key) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
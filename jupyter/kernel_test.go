@@ -0,0 +1,271 @@
+package jupyter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+// client is the notebook end of a kernel's connections.
+type client struct {
+	t      *testing.T
+	signer *Kernel
+	shell  *zconn
+	iopub  *zconn
+}
+
+func connect(t *testing.T, k *Kernel, port int, socketType string) *zconn {
+	t.Helper()
+	c, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	z, err := handshake(c, socketType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func startKernel(t *testing.T) (*Kernel, *client, chan error) {
+	t.Helper()
+	k, err := Listen(ConnectionInfo{Key: "secret", SignatureScheme: "hmac-sha256"}, evaluator.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- k.Serve() }()
+
+	c := &client{t: t, signer: &Kernel{key: []byte("secret"), session: "notebook"}}
+	c.shell = connect(t, k, k.Info().ShellPort, "DEALER")
+	c.iopub = connect(t, k, k.Info().IOPubPort, "SUB")
+	// Messages are only published to subscribers the kernel has taken in.
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		k.iopub.mu.Lock()
+		subscribed := len(k.iopub.peers) > 0
+		k.iopub.mu.Unlock()
+		if subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the subscriber was never taken in")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return k, c, served
+}
+
+// request sends a request of kind on the shell and returns the reply and
+// the messages published for it, up to the kernel going idle.
+func (c *client) request(kind string, content interface{}) (*Message, []*Message) {
+	c.t.Helper()
+	frames := c.signer.encode(nil, &Message{}, kind, content)
+	if err := c.shell.writeMessage(frames); err != nil {
+		c.t.Fatal(err)
+	}
+	var request Header
+	json.Unmarshal(frames[2], &request)
+
+	frames, err := c.shell.readMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	reply, err := c.signer.decode(frames)
+	if err != nil {
+		c.t.Fatalf("could not decode the reply to %s: %s", kind, err)
+	}
+	if reply.Parent.MessageID != request.MessageID {
+		c.t.Fatalf("the reply to %s answers %s", kind, reply.Parent.Type)
+	}
+
+	var published []*Message
+	for {
+		frames, err := c.iopub.readMessage()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		m, err := c.signer.decode(frames)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		var status struct {
+			State string `json:"execution_state"`
+		}
+		json.Unmarshal(m.Content, &status)
+		if m.Header.Type == "status" {
+			if status.State == "idle" && m.Parent.MessageID == request.MessageID {
+				return reply, published
+			}
+			continue
+		}
+		published = append(published, m)
+	}
+}
+
+func content(t *testing.T, m *Message) map[string]interface{} {
+	t.Helper()
+	var c map[string]interface{}
+	if err := json.Unmarshal(m.Content, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestKernel(t *testing.T) {
+	_, c, served := startKernel(t)
+
+	reply, _ := c.request("kernel_info_request", map[string]interface{}{})
+	info := content(t, reply)
+	if reply.Header.Type != "kernel_info_reply" || info["language_info"].(map[string]interface{})["name"] != "monkey" {
+		t.Errorf("unexpected kernel info %s", reply.Content)
+	}
+
+	reply, published := c.request("execute_request", map[string]interface{}{"code": "let a = 2;\nputs(a);\na * 3"})
+	if got := content(t, reply); got["status"] != "ok" || got["execution_count"] != 1.0 {
+		t.Errorf("unexpected reply %s", reply.Content)
+	}
+	var kinds []string
+	for _, m := range published {
+		kinds = append(kinds, m.Header.Type)
+	}
+	if len(published) != 3 || kinds[0] != "execute_input" || kinds[1] != "stream" || kinds[2] != "execute_result" {
+		t.Fatalf("expected execute_input, stream and execute_result, got %v", kinds)
+	}
+	if text := content(t, published[1])["text"]; text != "2\n" {
+		t.Errorf("expected the cell to put 2, got %q", text)
+	}
+	if data := content(t, published[2])["data"].(map[string]interface{}); data["text/plain"] != "6" {
+		t.Errorf("expected the cell to be 6, got %v", data)
+	}
+
+	reply, published = c.request("execute_request", map[string]interface{}{"code": "a + true"})
+	failure := content(t, reply)
+	if failure["status"] != "error" || failure["ename"] != "E0003" || failure["execution_count"] != 2.0 {
+		t.Errorf("unexpected reply %s", reply.Content)
+	}
+	if len(published) != 2 || published[1].Header.Type != "error" {
+		t.Errorf("expected the error to be published, got %d messages", len(published))
+	}
+
+	// A message with the wrong signature is dropped.
+	forged := c.signer.encode(nil, &Message{}, "kernel_info_request", map[string]interface{}{})
+	forged[1] = []byte("0000")
+	c.shell.writeMessage(forged)
+
+	reply, _ = c.request("shutdown_request", map[string]interface{}{"restart": false})
+	if reply.Header.Type != "shutdown_reply" {
+		t.Errorf("expected a shutdown_reply, got %s", reply.Header.Type)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve failed: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the kernel did not shut down")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	k, _, _ := startKernel(t)
+	defer k.Close()
+
+	hb := connect(t, k, k.Info().HeartbeatPort, "REQ")
+	ping := [][]byte{{}, []byte("ping")}
+	if err := hb.writeMessage(ping); err != nil {
+		t.Fatal(err)
+	}
+	pong, err := hb.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pong) != 2 || string(pong[1]) != "ping" {
+		t.Errorf("expected the heartbeat echoed, got %q", pong)
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	k, _, _ := startKernel(t)
+	defer k.Close()
+
+	hb := connect(t, k, k.Info().HeartbeatPort, "REQ")
+	header := []byte{flagLong, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if _, err := hb.c.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	hb.c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := hb.readMessage(); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected the peer to be dropped, got %v", err)
+	}
+
+	z := &zconn{r: bufio.NewReader(bytes.NewReader(append([]byte{flagLong}, 0, 0, 0, 0, 2, 0, 0, 0)))}
+	if _, _, err := z.readFrame(); err != errTooLarge {
+		t.Errorf("expected a frame of 32 MiB to be refused, got %v", err)
+	}
+}
+
+// longCell runs until it is interrupted.
+const longCell = "let f = fn(n) { if (n < 2) { n } else { f(n - 1) + f(n - 2) } }; f(60)"
+
+func TestHeartbeatWhileRunning(t *testing.T) {
+	k, c, _ := startKernel(t)
+	defer k.Close()
+
+	hb := connect(t, k, k.Info().HeartbeatPort, "REQ")
+	echoed := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := hb.writeMessage([][]byte{{}, []byte("ping")}); err != nil {
+			echoed <- err
+			return
+		}
+		hb.c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err := hb.readMessage()
+		echoed <- err
+		k.Interrupt()
+	}()
+
+	reply, _ := c.request("execute_request", map[string]interface{}{"code": longCell})
+	if err := <-echoed; err != nil {
+		t.Errorf("expected the heartbeat echoed while the cell ran, got %s", err)
+	}
+	if got := content(t, reply); got["status"] != "error" {
+		t.Errorf("expected the cell to be interrupted, got %s", reply.Content)
+	}
+}
+
+func TestInterruptSignal(t *testing.T) {
+	k, c, _ := startKernel(t)
+	defer k.Close()
+
+	replied := make(chan struct{})
+	defer close(replied)
+	go func() {
+		// A signal sent before the cell starts interrupts nothing, so it is
+		// sent until the cell is done.
+		for {
+			select {
+			case <-replied:
+				return
+			case <-time.After(50 * time.Millisecond):
+				syscall.Kill(os.Getpid(), syscall.SIGINT)
+			}
+		}
+	}()
+
+	reply, _ := c.request("execute_request", map[string]interface{}{"code": longCell})
+	if got := content(t, reply); got["status"] != "error" || got["ename"] != string(diagnostic.Interrupted) {
+		t.Errorf("expected the cell to be interrupted, got %s", reply.Content)
+	}
+}
@@ -0,0 +1,338 @@
+package jupyter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+)
+
+// The kernel speaks ZMTP 3.0, the wire protocol of ZeroMQ, itself rather
+// than through a binding to libzmq, which would need cgo. Only what a
+// kernel needs is there: the NULL security mechanism Jupyter uses over
+// local connections, and the ROUTER, PUB and REP sockets it binds.
+
+const (
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+)
+
+// maxMessageSize is how many bytes the frames of a message a peer sends may
+// add up to, so one frame too. Peers are dropped when they send more, since
+// the size comes before anything can be checked against the key.
+const maxMessageSize = 16 << 20
+
+// errTooLarge is what reading a frame or message larger than maxMessageSize
+// returns.
+var errTooLarge = fmt.Errorf("the peer sent a message larger than %d bytes", maxMessageSize)
+
+// zconn is a connection to a peer, after the handshake.
+type zconn struct {
+	c net.Conn
+	r *bufio.Reader
+
+	// identity is the peer's name for routing, what it sent as its
+	// Identity or one made up for it.
+	identity []byte
+
+	mu sync.Mutex
+}
+
+// handshake exchanges greetings and READY commands with the peer on c,
+// announcing socketType, and returns the connection.
+func handshake(c net.Conn, socketType string) (*zconn, error) {
+	greeting := make([]byte, 64)
+	greeting[0], greeting[9] = 0xff, 0x7f
+	greeting[10], greeting[11] = 3, 0
+	copy(greeting[12:32], "NULL")
+	if _, err := c.Write(greeting); err != nil {
+		return nil, err
+	}
+
+	z := &zconn{c: c, r: bufio.NewReader(c)}
+	peer := make([]byte, 64)
+	if _, err := io.ReadFull(z.r, peer); err != nil {
+		return nil, err
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f || peer[10] < 3 {
+		return nil, errors.New("the peer does not speak ZMTP 3")
+	}
+	if mechanism := string(bytes.TrimRight(peer[12:32], "\x00")); mechanism != "NULL" {
+		return nil, fmt.Errorf("the peer asks for the %s mechanism, only NULL is supported", mechanism)
+	}
+
+	if err := z.writeFrame(flagCommand, command("READY", "Socket-Type", socketType)); err != nil {
+		return nil, err
+	}
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&flagCommand == 0 {
+			return nil, errors.New("the peer sent a message before READY")
+		}
+		name, properties := parseCommand(body)
+		if name != "READY" {
+			continue
+		}
+		z.identity = properties["Identity"]
+		return z, nil
+	}
+}
+
+// command returns the body of the command name, with properties given as
+// pairs of names and values.
+func command(name string, properties ...string) []byte {
+	var body bytes.Buffer
+	body.WriteByte(byte(len(name)))
+	body.WriteString(name)
+	for i := 0; i+1 < len(properties); i += 2 {
+		body.WriteByte(byte(len(properties[i])))
+		body.WriteString(properties[i])
+		binary.Write(&body, binary.BigEndian, uint32(len(properties[i+1])))
+		body.WriteString(properties[i+1])
+	}
+	return body.Bytes()
+}
+
+// parseCommand returns the name and properties of the command body. For
+// commands other than READY the properties are whatever follows the name.
+func parseCommand(body []byte) (string, map[string][]byte) {
+	if len(body) == 0 || int(body[0]) >= len(body) {
+		return "", nil
+	}
+	name, rest := string(body[1:1+body[0]]), body[1+body[0]:]
+	properties := map[string][]byte{}
+	if name != "READY" {
+		properties[""] = rest
+		return name, properties
+	}
+	for len(rest) > 0 {
+		n := int(rest[0])
+		if len(rest) < 1+n+4 {
+			break
+		}
+		key := string(rest[1 : 1+n])
+		size := int(binary.BigEndian.Uint32(rest[1+n:]))
+		rest = rest[1+n+4:]
+		if size > len(rest) {
+			break
+		}
+		properties[key] = rest[:size]
+		rest = rest[size:]
+	}
+	return name, properties
+}
+
+func (z *zconn) readFrame() (byte, []byte, error) {
+	flags, err := z.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		if err := binary.Read(z.r, binary.BigEndian, &size); err != nil {
+			return 0, nil, err
+		}
+	} else {
+		n, err := z.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(n)
+	}
+	if size > maxMessageSize || size > math.MaxInt {
+		return 0, nil, errTooLarge
+	}
+	body := make([]byte, size)
+	_, err = io.ReadFull(z.r, body)
+	return flags, body, err
+}
+
+func (z *zconn) writeFrame(flags byte, body []byte) error {
+	var header []byte
+	if len(body) > 255 {
+		header = make([]byte, 9)
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+	if _, err := z.c.Write(header); err != nil {
+		return err
+	}
+	_, err := z.c.Write(body)
+	return err
+}
+
+// readMessage returns the frames of the next message the peer sends,
+// answering the PINGs that come before it.
+func (z *zconn) readMessage() ([][]byte, error) {
+	var frames [][]byte
+	size := 0
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if size += len(body); size > maxMessageSize {
+			return nil, errTooLarge
+		}
+		if flags&flagCommand != 0 {
+			if name, properties := parseCommand(body); name == "PING" && len(properties[""]) >= 2 {
+				z.mu.Lock()
+				err = z.writeFrame(flagCommand, append(command("PONG"), properties[""][2:]...))
+				z.mu.Unlock()
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		frames = append(frames, body)
+		if flags&flagMore == 0 {
+			return frames, nil
+		}
+	}
+}
+
+func (z *zconn) writeMessage(frames [][]byte) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for i, frame := range frames {
+		var flags byte
+		if i < len(frames)-1 {
+			flags = flagMore
+		}
+		if err := z.writeFrame(flags, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// socket is a bound ZeroMQ socket: it accepts peers on a listener and
+// either routes messages by the identity of the peer that sent them
+// (ROUTER), sends each message to every peer (PUB) or sends replies
+// straight back (REP).
+type socket struct {
+	kind     string
+	listener net.Listener
+
+	mu    sync.Mutex
+	peers map[string]*zconn
+	next  uint32
+
+	// incoming holds the messages peers sent, each starting with the
+	// identity of the peer for a ROUTER.
+	incoming chan incoming
+}
+
+type incoming struct {
+	peer   *zconn
+	frames [][]byte
+}
+
+// bind listens for peers of a socket of kind on address.
+func bind(kind, address string) (*socket, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &socket{kind: kind, listener: listener, peers: map[string]*zconn{}, incoming: make(chan incoming)}
+	go s.accept()
+	return s, nil
+}
+
+func (s *socket) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// close stops listening and drops the peers.
+func (s *socket) close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for _, peer := range s.peers {
+		peer.c.Close()
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *socket) accept() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(c)
+	}
+}
+
+func (s *socket) serve(c net.Conn) {
+	defer c.Close()
+	z, err := handshake(c, s.kind)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	if len(z.identity) == 0 || s.peers[string(z.identity)] != nil {
+		s.next++
+		z.identity = []byte{0, byte(s.next >> 24), byte(s.next >> 16), byte(s.next >> 8), byte(s.next)}
+	}
+	s.peers[string(z.identity)] = z
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.peers, string(z.identity))
+		s.mu.Unlock()
+	}()
+
+	for {
+		frames, err := z.readMessage()
+		if err != nil {
+			return
+		}
+		switch s.kind {
+		case "PUB":
+			// Subscriptions are not kept: every peer gets every message.
+		case "ROUTER":
+			s.incoming <- incoming{z, append([][]byte{z.identity}, frames...)}
+		default:
+			s.incoming <- incoming{z, frames}
+		}
+	}
+}
+
+// send sends frames on s: to the peer the first frame names for a ROUTER,
+// and to every peer for a PUB.
+func (s *socket) send(frames [][]byte) error {
+	s.mu.Lock()
+	var peers []*zconn
+	if s.kind == "ROUTER" {
+		if peer := s.peers[string(frames[0])]; peer != nil {
+			peers = append(peers, peer)
+		}
+		frames = frames[1:]
+	} else {
+		for _, peer := range s.peers {
+			peers = append(peers, peer)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, peer := range peers {
+		if err := peer.writeMessage(frames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/jupyter"
+)
+
+func kernelCommand(args []string) int {
+	flags := flag.NewFlagSet("kernel", flag.ExitOnError)
+	connectionFile := flags.String("connection-file", "", "the connection file Jupyter wrote for the kernel")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey kernel --connection-file=<file>")
+		fmt.Fprintln(flags.Output(), "runs as a Jupyter kernel; a kernel.json with the argv")
+		fmt.Fprintln(flags.Output(), `["monkey", "kernel", "--connection-file={connection_file}"] installs it`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *connectionFile == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	info, err := jupyter.ReadConnectionFile(*connectionFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	kernel, err := jupyter.Listen(info, evaluator.Config{MaxCallDepth: evaluator.DefaultMaxCallDepth})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not start the kernel: %s\n", err)
+		return 1
+	}
+	if err := kernel.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+// languageFlags defines -lang-version and -features on flags. The function
+// it returns fills in config from them once flags have been parsed, and
+// fails for a version or a feature that does not exist.
+func languageFlags(flags *flag.FlagSet, config *parser.Config) func() error {
+	version := flags.Int("lang-version", parser.LatestVersion, fmt.Sprintf("language version to accept, from 1, the book's, to %d", parser.LatestVersion))
+	features := flags.String("features", "", "comma-separated features to turn on beyond the language version; see monkey version")
+
+	return func() error {
+		if *version < 1 || *version > parser.LatestVersion {
+			return fmt.Errorf("unknown language version %d, want 1 to %d", *version, parser.LatestVersion)
+		}
+		config.Version = *version
+		for _, name := range strings.Split(*features, ",") {
+			if name == "" {
+				continue
+			}
+			if _, ok := parser.FeatureVersion(parser.Feature(name)); !ok {
+				return fmt.Errorf("unknown feature %s", name)
+			}
+			config.Features = append(config.Features, parser.Feature(name))
+		}
+		return nil
+	}
+}
+
+func versionCommand(args []string) int {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey version")
+	}
+	flags.Parse(args)
+
+	printVersion(os.Stdout)
+	return 0
+}
+
+// printVersion writes the interpreter's version, the language versions it
+// accepts and the version each feature is part of to out.
+func printVersion(out io.Writer) {
+	fmt.Fprintf(out, "monkey %s\n", evaluator.Version())
+	fmt.Fprintf(out, "language versions: 1 to %d\n", parser.LatestVersion)
+	fmt.Fprintln(out, "features:")
+	for _, feature := range parser.Features() {
+		version, _ := parser.FeatureVersion(feature)
+		fmt.Fprintf(out, "  %s (version %d)\n", feature, version)
+	}
+}
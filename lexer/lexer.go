@@ -1,23 +1,75 @@
 package lexer
 
-import "github.com/fcidade/monkey-lang/token"
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/token"
+)
 
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column are the position of ch.
+	line   int
+	column int
+
+	// trivia is set for lexers that attach comments and blank lines to the
+	// tokens they come with.
+	trivia bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
-func (l *Lexer) NextToken() (tok token.Token) {
-	l.skipWhitespaces()
+// NewWithTrivia returns a lexer that keeps the comments and blank lines it
+// skips, setting the Trivia of the tokens they are found around.
+func NewWithTrivia(input string) *Lexer {
+	l := New(input)
+	l.trivia = true
+	return l
+}
+
+// NewAt returns a lexer that starts reading input at the byte offset, which
+// is at line and column.
+func NewAt(input string, offset, line, column int) *Lexer {
+	l := &Lexer{input: input, readPosition: offset}
+	l.readChar()
+	l.line, l.column = line, column
+	return l
+}
+
+// Offset returns the byte offset just past the last token read.
+func (l *Lexer) Offset() int {
+	return l.position
+}
+
+// NextToken returns the next token in the input, stamped with the line and
+// column it starts at.
+func (l *Lexer) NextToken() token.Token {
+	trivia := l.skipWhitespaces()
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
 
+	if l.trivia {
+		if comment := l.readTrailingComment(); comment != "" {
+			trivia.Trailing = comment
+		}
+		if len(trivia.Leading) > 0 || trivia.Blank > 0 || trivia.Trailing != "" {
+			tok.Trivia = &trivia
+		}
+	}
+	return tok
+}
+
+func (l *Lexer) readToken() (tok token.Token) {
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -25,6 +77,10 @@ func (l *Lexer) NextToken() (tok token.Token) {
 			l.readChar()
 			tok.Literal = string(ch) + string(l.ch)
 			tok.Type = token.EQ
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok.Literal = "=>"
+			tok.Type = token.ARROW
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -39,6 +95,32 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok.Literal = "..."
+			tok.Type = token.ELLIPSIS
+		} else if l.peekChar() == '.' {
+			l.readChar()
+			tok.Literal = ".."
+			tok.Type = token.DOT_DOT
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
+	case '?':
+		switch l.peekChar() {
+		case '?':
+			l.readChar()
+			tok.Literal = "??"
+			tok.Type = token.NULLISH
+		case '.':
+			l.readChar()
+			tok.Literal = "?."
+			tok.Type = token.QUESTION_DOT
+		default:
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	case '(':
@@ -63,6 +145,10 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '|':
+		tok = newToken(token.PIPE, l.ch)
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
@@ -70,10 +156,17 @@ func (l *Lexer) NextToken() (tok token.Token) {
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
-	case '"':
-		tok.Literal = l.readString()
-		tok.Type = token.STRING
+	case '"', '`':
+		tok = l.readString(token.STRING)
+	case '\'':
+		tok = l.readCharacter()
 	default:
+		if l.ch == 'b' && (l.peekChar() == '"' || l.peekChar() == '`') {
+			l.readChar()
+			tok = l.readString(token.BYTES)
+			break
+		}
+
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdentifier(tok.Literal)
@@ -81,12 +174,11 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		}
 
 		if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		}
 
-		tok = newToken(token.ILLEGAL, l.ch)
+		tok = l.readIllegal()
 	}
 
 	l.readChar()
@@ -94,13 +186,23 @@ func (l *Lexer) NextToken() (tok token.Token) {
 }
 
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if l.readPosition > len(l.input) {
+		return
+	}
+	if l.readPosition == len(l.input) {
 		l.ch = 0
 	} else {
 		l.ch = l.input[l.readPosition]
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
+
+	if l.position > 0 && l.input[l.position-1] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 }
 
 func (l *Lexer) peekChar() byte {
@@ -111,37 +213,172 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
-func (l *Lexer) skipWhitespaces() {
-	for l.ch == ' ' || l.ch == '\n' || l.ch == '\r' || l.ch == '\t' {
+// skipWhitespaces skips the whitespace and comments before the next token,
+// returning them as its trivia when the lexer keeps it.
+func (l *Lexer) skipWhitespaces() (trivia token.Trivia) {
+	// newlines counts the line breaks since the last token or comment. One
+	// ends the line they are on, and the rest make blank lines, except at
+	// the start of the input where every one does.
+	newlines := 0
+	if l.position == 0 {
+		newlines = 1
+	}
+	for {
+		switch {
+		case l.ch == '\n':
+			newlines++
+			l.readChar()
+		case l.ch == ' ' || l.ch == '\r' || l.ch == '\t':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			comment := l.readComment()
+			if l.trivia {
+				trivia.Leading = append(trivia.Leading, token.Comment{Text: comment, Blank: blank(newlines)})
+			}
+			newlines = 0
+		default:
+			trivia.Blank = blank(newlines)
+			return trivia
+		}
+	}
+}
+
+func blank(newlines int) int {
+	if newlines < 2 {
+		return 0
+	}
+	return newlines - 1
+}
+
+// readComment reads a comment up to the end of its line.
+func (l *Lexer) readComment() string {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return strings.TrimRight(l.input[position:l.position], " \t\r")
+}
+
+// readTrailingComment reads the comment after the token just read, if there
+// is one on the same line.
+func (l *Lexer) readTrailingComment() string {
+	i := l.position
+	for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t' || l.input[i] == '\r') {
+		i++
+	}
+	if !strings.HasPrefix(l.input[i:], "//") {
+		return ""
+	}
+	for l.position < i {
 		l.readChar()
 	}
+	return l.readComment()
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
+// readString reads a string or bytes literal whose opening quote, " or `,
+// is the current character, up to the same quote closing it. Neither has
+// escapes, so a literal in backticks is the way to write one containing ".
+// A literal the input ends in before its closing quote is ILLEGAL, with the
+// text as it was written for its literal.
+func (l *Lexer) readString(tokenType token.TokenType) token.Token {
+	start := l.position
+	if tokenType == token.BYTES {
+		start--
+	}
+	quote := l.ch
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		if l.ch == quote {
+			return token.Token{Type: tokenType, Literal: l.input[position:l.position]}
+		}
+		if l.ch == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start:]}
 		}
 	}
-	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readCharacter reads a character literal, one character between single
+// quotes. Anything else up to the closing quote, or to the end of the line
+// when there is none, is ILLEGAL.
+func (l *Lexer) readCharacter() token.Token {
+	start := l.position
+	_, size := utf8.DecodeRuneInString(l.input[start+1:])
+	if size > 0 && start+1+size < len(l.input) && l.input[start+1+size] == '\'' {
+		for i := 0; i <= size; i++ {
+			l.readChar()
+		}
+		return token.Token{Type: token.CHAR, Literal: l.input[start+1 : start+1+size]}
+	}
+
+	for l.peekChar() != '\'' && l.peekChar() != '\n' && l.peekChar() != 0 {
+		l.readChar()
+	}
+	if l.peekChar() == '\'' {
+		l.readChar()
+	}
+	return token.Token{Type: token.ILLEGAL, Literal: l.input[start : l.position+1]}
+}
+
+// readIllegal reads a character that starts no token, taking the whole of it
+// when it is more than a byte long so it can be shown in an error.
+func (l *Lexer) readIllegal() token.Token {
+	_, size := utf8.DecodeRuneInString(l.input[l.position:])
+	literal := l.input[l.position : l.position+size]
+	for i := 1; i < size; i++ {
+		l.readChar()
+	}
+	return token.Token{Type: token.ILLEGAL, Literal: literal}
+}
+
+// readNumber reads an integer, or a duration such as 1h30m when a unit
+// follows the digits.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	l.readDigits()
+	if l.durationUnit() == 0 {
+		return l.input[position:l.position], token.INT
+	}
+
+	for n := l.durationUnit(); n > 0; n = l.durationUnit() {
+		for i := 0; i < n; i++ {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return l.input[position:l.position], token.DURATION
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+}
+
+var durationUnits = []string{"ms", "s", "m", "h"}
+
+// durationUnit returns the length of the duration unit at the current
+// character, or 0 if there is none there. A unit followed by another letter
+// is part of an identifier instead, so 5sec is still 5 and sec.
+func (l *Lexer) durationUnit() int {
+	if l.ch == 0 {
+		return 0
+	}
+	rest := l.input[l.position:]
+	for _, unit := range durationUnits {
+		if strings.HasPrefix(rest, unit) && (len(rest) == len(unit) || !isLetter(rest[len(unit)])) {
+			return len(unit)
+		}
+	}
+	return 0
 }
 
 func isLetter(ch byte) bool {
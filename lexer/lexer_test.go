@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/fcidade/monkey-lang/token"
@@ -30,6 +31,14 @@ if (5 < 10) {
 
 [1, 2];
 {"foo": "bar"}
+b"bytes"
+sha256 x2y
+[...rest]
+a.b?.c ?? d
+[x for x in 1..n if x % 2]
+|x| x
+match x { _ => 1 }
+200ms 1h30m 5sec
 
 `
 
@@ -129,6 +138,49 @@ if (5 < 10) {
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
 
+		{token.BYTES, "bytes"},
+		{token.IDENTIFIER, "sha256"},
+		{token.IDENTIFIER, "x2y"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "rest"},
+		{token.RBRACKET, "]"},
+		{token.IDENTIFIER, "a"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "b"},
+		{token.QUESTION_DOT, "?."},
+		{token.IDENTIFIER, "c"},
+		{token.NULLISH, "??"},
+		{token.IDENTIFIER, "d"},
+		{token.LBRACKET, "["},
+		{token.IDENTIFIER, "x"},
+		{token.FOR, "for"},
+		{token.IDENTIFIER, "x"},
+		{token.IN, "in"},
+		{token.INT, "1"},
+		{token.DOT_DOT, ".."},
+		{token.IDENTIFIER, "n"},
+		{token.IF, "if"},
+		{token.IDENTIFIER, "x"},
+		{token.PERCENT, "%"},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.PIPE, "|"},
+		{token.IDENTIFIER, "x"},
+		{token.PIPE, "|"},
+		{token.IDENTIFIER, "x"},
+		{token.MATCH, "match"},
+		{token.IDENTIFIER, "x"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.DURATION, "200ms"},
+		{token.DURATION, "1h30m"},
+		{token.INT, "5"},
+		{token.IDENTIFIER, "sec"},
+
 		{token.EOF, ""},
 	}
 
@@ -146,3 +198,171 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 1;\n  x @ \u00e9\n\"open"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		line, column    int
+	}{
+		{token.LET, "let", 1, 1},
+		{token.IDENTIFIER, "x", 1, 5},
+		{token.ASSIGN, "=", 1, 7},
+		{token.INT, "1", 1, 9},
+		{token.SEMICOLON, ";", 1, 10},
+		{token.IDENTIFIER, "x", 2, 3},
+		{token.ILLEGAL, "@", 2, 5},
+		{token.ILLEGAL, "\u00e9", 2, 7},
+		{token.ILLEGAL, "\"open", 3, 1},
+		{token.EOF, "", 3, 6},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - wrong position for %q. expected=%d:%d, got=%d:%d",
+				i, tok.Literal, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// about x\nlet x = 1; // one\n\n\n// two\n\n// three\nx //\n"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedTrivia  *token.Trivia
+	}{
+		{"let", &token.Trivia{Leading: []token.Comment{{Text: "// about x"}}}},
+		{"x", nil},
+		{"=", nil},
+		{"1", nil},
+		{";", &token.Trivia{Trailing: "// one"}},
+		{"x", &token.Trivia{Leading: []token.Comment{{Text: "// two", Blank: 2}, {Text: "// three", Blank: 1}}, Trailing: "//"}},
+		{"", nil},
+	}
+
+	plain := New(input)
+	l := NewWithTrivia(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if fmt.Sprint(tok.Trivia) != fmt.Sprint(tt.expectedTrivia) {
+			t.Fatalf("tests[%d] - wrong trivia for %q. expected=%+v, got=%+v", i, tok.Literal, tt.expectedTrivia, tok.Trivia)
+		}
+		if tok := plain.NextToken(); tok.Literal != tt.expectedLiteral || tok.Trivia != nil {
+			t.Fatalf("tests[%d] - wrong token without trivia. expected=%q, got=%q %+v", i, tt.expectedLiteral, tok.Literal, tok.Trivia)
+		}
+	}
+}
+
+func TestNewAt(t *testing.T) {
+	input := "let x = 1;\n  x + 2\n"
+
+	tests := []struct {
+		expectedLiteral string
+		line, column    int
+		offset          int
+	}{
+		{"x", 2, 3, 14},
+		{"+", 2, 5, 16},
+		{"2", 2, 7, 18},
+		{"", 3, 1, 19},
+	}
+
+	l := NewAt(input, 11, 2, 1)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral || tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - expected %q at %d:%d, got %q at %d:%d",
+				i, tt.expectedLiteral, tt.line, tt.column, tok.Literal, tok.Line, tok.Column)
+		}
+		if l.Offset() != tt.offset {
+			t.Fatalf("tests[%d] - wrong offset. expected=%d, got=%d", i, tt.offset, l.Offset())
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	input := "`{\"name\": \"monkey\"}` b`\"q\"` `two\nlines` `open"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, `{"name": "monkey"}`},
+		{token.BYTES, `"q"`},
+		{token.STRING, "two\nlines"},
+		{token.ILLEGAL, "`open"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCharLiterals(t *testing.T) {
+	input := "'a' 'é' ''' 'ab' '\n' 'x\ny"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.CHAR, "a"},
+		{token.CHAR, "é"},
+		{token.CHAR, "'"},
+		{token.ILLEGAL, "'ab'"},
+		{token.CHAR, "\n"},
+		{token.ILLEGAL, "'x"},
+		{token.IDENTIFIER, "y"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, seed := range []string{
+		"let five = 5; five + 10",
+		`"unterminated`,
+		`b"bytes" 1..2 a?.b ?? c`,
+		"1h30m 5sec 200ms",
+		"match x { [a, ...b] => a }",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// Every token but EOF consumes input, so there are at most as many
+		// tokens as bytes.
+		l := New(input)
+		for i := 0; i <= len(input); i++ {
+			if l.NextToken().Type == token.EOF {
+				return
+			}
+		}
+		t.Fatalf("no EOF after %d tokens of %q", len(input)+1, input)
+	})
+}
@@ -1,22 +1,152 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"os/user"
 
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
 	"github.com/fcidade/monkey-lang/repl"
 )
 
 func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "check":
+			os.Exit(checkCommand(os.Args[2:]))
+		case "fmt":
+			os.Exit(formatCommand(os.Args[2:]))
+		case "refactor":
+			os.Exit(refactorCommand(os.Args[2:]))
+		case "defs":
+			os.Exit(defsCommand(os.Args[2:]))
+		case "test":
+			os.Exit(testCommand(os.Args[2:]))
+		case "watch":
+			os.Exit(watchCommand(os.Args[2:]))
+		case "kernel":
+			os.Exit(kernelCommand(os.Args[2:]))
+		case "daemon":
+			os.Exit(daemonCommand(os.Args[2:]))
+		case "serve-playground":
+			os.Exit(playgroundCommand(os.Args[2:]))
+		case "replay":
+			os.Exit(replayCommand(os.Args[2:]))
+		case "examples":
+			os.Exit(examplesCommand(os.Args[2:]))
+		case "version":
+			os.Exit(versionCommand(os.Args[2:]))
+		}
+	}
+
+	config := repl.DefaultConfig()
+	config.Banner = defaultBanner()
+	config.Evaluator.MaxCallDepth = evaluator.DefaultMaxCallDepth
+
+	home, _ := os.UserHomeDir()
+	wd, _ := os.Getwd()
+	if err := loadSettings(&config, home, wd, home); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if prompt, ok := os.LookupEnv("MONKEY_PROMPT"); ok {
+		config.Prompt = prompt
+	}
+	if banner, ok := os.LookupEnv("MONKEY_BANNER"); ok {
+		config.Banner = banner
+	}
+	if history, ok := os.LookupEnv("MONKEY_HISTORY"); ok {
+		config.HistoryFile = history
+	}
+
+	quiet := flag.Bool("quiet", false, "suppress the banner and prompt")
+	expression := flag.String("e", "", "evaluate this expression, print its value and exit")
+	flag.StringVar(&config.Prompt, "prompt", config.Prompt, "prompt printed before each line (env MONKEY_PROMPT)")
+	flag.StringVar(&config.Banner, "banner", config.Banner, "greeting printed on startup (env MONKEY_BANNER)")
+	flag.IntVar(&config.Evaluator.MaxCallDepth, "max-call-depth", config.Evaluator.MaxCallDepth, "how deeply functions may recurse")
+	flag.BoolVar(&config.Evaluator.StrictIndexing, "strict", config.Evaluator.StrictIndexing, "make out-of-range indexes and missing hash keys errors instead of null")
+	flag.BoolVar(&config.Evaluator.NoPrelude, "no-prelude", false, "leave out the prelude's functions, such as map, filter and reduce")
+	flag.StringVar(&config.HistoryFile, "history", config.HistoryFile, "file every input line is appended to (env MONKEY_HISTORY)")
+	flag.BoolVar(&config.Color, "color", config.Color, "write errors in red when the output is a terminal")
+	plugins := flag.String("plugins", "", pluginsUsage)
+	language := languageFlags(flag.CommandLine, &config.Evaluator.Language)
+	flag.Parse()
+	if err := language(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := loadPlugins(*plugins); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *expression != "" {
+		if !evalExpression(*expression, config.Evaluator, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *quiet || !isTerminal(os.Stdin) {
+		config.Prompt = ""
+		config.ContinuationPrompt = ""
+		config.Banner = ""
+		config.Goodbye = ""
+	}
+	config.BracketedPaste = isTerminal(os.Stdin) && isTerminal(os.Stdout)
+	config.Color = config.Color && isTerminal(os.Stdout)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	config.Interrupts = interrupts
+
+	if !repl.Start(os.Stdin, config) {
+		os.Exit(1)
+	}
+}
+
+// evalExpression evaluates source, which has to be a single expression, and
+// writes its value, or what went wrong, to out. It reports whether there was
+// nothing wrong.
+func evalExpression(source string, config evaluator.Config, out io.Writer) bool {
+	ev := evaluator.New(config)
+	p := ev.NewParser(source)
+	expr := p.ParseExpressionOnly()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
 	}
 
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
+	evaluated := ev.Eval(expr, ev.NewEnvironment())
+	if evaluated == nil {
+		evaluated = evaluator.NULL
+	}
+	fmt.Fprintln(out, evaluated.Inspect())
+	_, failed := evaluated.(*object.Error)
+	return !failed
+}
 
-	fmt.Print("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+func defaultBanner() string {
+	name := "there"
+	if user, err := user.Current(); err == nil {
+		name = user.Username
+	}
 
+	return fmt.Sprintf("Hello %s! This is the Monkey programming language!\n", name) +
+		"Feel free to type in commands\n"
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,338 @@
+// Package message holds the text of every message Monkey reports, so
+// programs embedding the interpreter can reword or translate them with Set.
+// Each message is a format in the syntax of the fmt package; a replacement
+// takes the same arguments, and can use explicit indexes such as %[2]s to
+// take them in another order.
+package message
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+)
+
+// ID names a message. IDs are stable, unlike the text of the messages.
+type ID string
+
+// Runtime errors.
+const (
+	UnknownPrefixOperator   ID = "unknown-prefix-operator"
+	UnknownInfixOperator    ID = "unknown-infix-operator"
+	TypeMismatch            ID = "type-mismatch"
+	IdentifierNotFound      ID = "identifier-not-found"
+	DivisionByZero          ID = "division-by-zero"
+	NotAFunction            ID = "not-a-function"
+	WrongArgumentCount      ID = "wrong-argument-count"
+	IndexNotSupported       ID = "index-not-supported"
+	UnusableHashKey         ID = "unusable-hash-key"
+	FieldAccessNotSupported ID = "field-access-not-supported"
+	CallDepthExceeded       ID = "call-depth-exceeded"
+	NotIterable             ID = "not-iterable"
+	RangeTooLong            ID = "range-too-long"
+	NegativeRepeat          ID = "negative-repeat"
+	RepeatTooLong           ID = "repeat-too-long"
+	CannotRepeat            ID = "cannot-repeat"
+	NoMatchingArm           ID = "no-matching-arm"
+	PatternNotMatched       ID = "pattern-not-matched"
+	CannotDestructure       ID = "cannot-destructure"
+	ArrayPatternExactly     ID = "array-pattern-exactly"
+	ArrayPatternAtLeast     ID = "array-pattern-at-least"
+	TuplePatternLength      ID = "tuple-pattern-length"
+	HashPatternMissingKey   ID = "hash-pattern-missing-key"
+	Frozen                  ID = "frozen"
+	CapabilityDenied        ID = "capability-denied"
+	EvalParseErrors         ID = "eval-parse-errors"
+	IndexOutOfRange         ID = "index-out-of-range"
+	KeyNotFound             ID = "key-not-found"
+	Interrupted             ID = "interrupted"
+	TooManyElements         ID = "too-many-elements"
+
+	// BuiltinArgument wraps the messages below it, which say what is wrong
+	// with the arguments of a builtin, in the builtin's signature and the
+	// types it was called with.
+	BuiltinArgument      ID = "builtin-argument"
+	ArgumentCount        ID = "argument-count"
+	ArgumentCountEither  ID = "argument-count-either"
+	ArgumentCountAtLeast ID = "argument-count-at-least"
+	ArgumentType         ID = "argument-type"
+	ArgumentByteRange    ID = "argument-byte-range"
+	SliceBounds          ID = "slice-bounds"
+	DecodeFailed         ID = "decode-failed"
+	InvalidGlob          ID = "invalid-glob"
+	SingleCharacter      ID = "single-character"
+	UnknownOption        ID = "unknown-option"
+	OptionType           ID = "option-type"
+	RowNeedsHeader       ID = "row-needs-header"
+	RowType              ID = "row-type"
+	OutOfRange           ID = "out-of-range"
+	NotPositive          ID = "not-positive"
+	CannotBind           ID = "cannot-bind"
+	NotCodePoint         ID = "not-code-point"
+	NotAnInteger         ID = "not-an-integer"
+	IntegerTooLarge      ID = "integer-too-large"
+	TableRowType         ID = "table-row-type"
+	Negative             ID = "negative"
+	Overflow             ID = "overflow"
+	SortComparator       ID = "sort-comparator"
+	SortUnorderable      ID = "sort-unorderable"
+	SortIncomparable     ID = "sort-incomparable"
+	ActorStopped         ID = "actor-stopped"
+	ActorAsksItself      ID = "actor-asks-itself"
+	UnknownModule        ID = "unknown-module"
+
+	// BuiltinFailed reports a builtin failing for reasons outside the
+	// program, such as a missing file, with the error from the system.
+	BuiltinFailed ID = "builtin-failed"
+	ColumnFailed  ID = "column-failed"
+)
+
+// Parser errors.
+const (
+	ExpectedNextToken  ID = "expected-next-token"
+	NoPrefixParseFn    ID = "no-prefix-parse-fn"
+	InvalidInteger     ID = "invalid-integer"
+	InvalidDuration    ID = "invalid-duration"
+	ExpectedType       ID = "expected-type"
+	ExpectedPattern    ID = "expected-pattern"
+	UnexpectedIllegal  ID = "unexpected-illegal"
+	UnterminatedString ID = "unterminated-string"
+	UnclosedBlock      ID = "unclosed-block"
+	TrailingTokens     ID = "trailing-tokens"
+	InvalidCharacter   ID = "invalid-character"
+	ReservedWord       ID = "reserved-word"
+	FeatureDisabled    ID = "feature-disabled"
+)
+
+// Type errors, and the descriptions of what has the wrong type.
+const (
+	TypeExpected      ID = "type-expected"
+	UnknownType       ID = "unknown-type"
+	PrefixNotDefined  ID = "prefix-not-defined"
+	InfixNotDefined   ID = "infix-not-defined"
+	NotCallable       ID = "not-callable"
+	TypeArgumentCount ID = "type-argument-count"
+	TypeOfLet         ID = "type-of-let"
+	TypeOfArgument    ID = "type-of-argument"
+	TypeOfResult      ID = "type-of-result"
+)
+
+// REPL messages.
+const (
+	ParseErrorsHeader ID = "parse-errors-header"
+	HistoryFailed     ID = "history-failed"
+	SaveUsage         ID = "save-usage"
+	SaveFailed        ID = "save-failed"
+	Saved             ID = "saved"
+	SaveSkipped       ID = "save-skipped"
+	LoadUsage         ID = "load-usage"
+	LoadFailed        ID = "load-failed"
+	Loaded            ID = "loaded"
+	PreloadFailed     ID = "preload-failed"
+	InspectUsage      ID = "inspect-usage"
+	TimingOn          ID = "timing-on"
+	TimingOff         ID = "timing-off"
+	TimeReport        ID = "time-report"
+	UnknownCommand    ID = "unknown-command"
+	HeapSummary       ID = "heap-summary"
+	HeapByType        ID = "heap-by-type"
+	HeapByBinding     ID = "heap-by-binding"
+)
+
+type entry struct {
+	code diagnostic.Code
+	text string
+}
+
+var defaults = map[ID]entry{
+	UnknownPrefixOperator:   {diagnostic.UnknownOperator, "unknown operator: %s%s"},
+	UnknownInfixOperator:    {diagnostic.UnknownOperator, "unknown operator: %s %s %s"},
+	TypeMismatch:            {diagnostic.TypeMismatch, "type mismatch: %s %s %s"},
+	IdentifierNotFound:      {diagnostic.IdentifierNotFound, "identifier not found: %s"},
+	DivisionByZero:          {diagnostic.DivisionByZero, "division by zero: %d %s 0"},
+	NotAFunction:            {diagnostic.NotAFunction, "not a function: %s"},
+	WrongArgumentCount:      {diagnostic.WrongArgumentCount, "wrong number of arguments: want %d, got %d"},
+	IndexNotSupported:       {diagnostic.IndexNotSupported, "index operator not supported: %s"},
+	UnusableHashKey:         {diagnostic.UnusableHashKey, "unusable as hash key: %s"},
+	FieldAccessNotSupported: {diagnostic.FieldAccessNotSupported, "field access not supported: %s"},
+	CallDepthExceeded:       {diagnostic.CallDepthExceeded, "maximum call depth exceeded (%d)"},
+	NotIterable:             {diagnostic.NotIterable, "cannot iterate over %s"},
+	RangeTooLong:            {diagnostic.RangeTooLong, "range %d..%d is too long"},
+	NegativeRepeat:          {diagnostic.InvalidRepeat, "repeat count must not be negative, got %d"},
+	RepeatTooLong:           {diagnostic.InvalidRepeat, "repeated %s would be too long"},
+	CannotRepeat:            {diagnostic.InvalidRepeat, "cannot repeat %s"},
+	NoMatchingArm:           {diagnostic.NoMatchingArm, "no match arm matches %s"},
+	PatternNotMatched:       {diagnostic.PatternMismatch, "%s does not match pattern %s"},
+	CannotDestructure:       {diagnostic.PatternMismatch, "cannot destructure %s with %s pattern %s"},
+	ArrayPatternExactly:     {diagnostic.PatternMismatch, "array pattern %s expects exactly %d elements, got %d"},
+	ArrayPatternAtLeast:     {diagnostic.PatternMismatch, "array pattern %s expects at least %d elements, got %d"},
+	TuplePatternLength:      {diagnostic.PatternMismatch, "tuple pattern %s expects %d elements, got %d"},
+	HashPatternMissingKey:   {diagnostic.PatternMismatch, "hash pattern %s is missing key %q"},
+	Frozen:                  {diagnostic.FrozenValue, "cannot modify frozen %s"},
+	CapabilityDenied:        {diagnostic.CapabilityDenied, "capability denied: %s needs the %s capability"},
+	EvalParseErrors:         {diagnostic.EvalParseError, "parser errors: %s"},
+	IndexOutOfRange:         {diagnostic.IndexOutOfRange, "index out of range: %d with %s of length %d"},
+	KeyNotFound:             {diagnostic.KeyNotFound, "key not found: %s"},
+	Interrupted:             {diagnostic.Interrupted, "interrupted"},
+	TooManyElements:         {diagnostic.TooManyElements, "%s would have more than %d elements"},
+
+	BuiltinArgument:      {diagnostic.InvalidArgument, "%s: %s; called with (%s)"},
+	ArgumentCount:        {"", "wrong number of arguments, want %d"},
+	ArgumentCountEither:  {"", "wrong number of arguments, want %d or %d"},
+	ArgumentCountAtLeast: {"", "wrong number of arguments, want at least %d"},
+	ArgumentType:         {"", "argument %d must be %s"},
+	ArgumentByteRange:    {"", "argument %d must only contain integers between 0 and 255, got %s"},
+	SliceBounds:          {"", "bounds [%d:%d] out of range with length %d"},
+	DecodeFailed:         {"", "could not decode %s: %s"},
+	InvalidGlob:          {"", "%s"},
+	SingleCharacter:      {"", "%s must be a single character"},
+	UnknownOption:        {"", "unknown option %s"},
+	OptionType:           {"", "%s must be %s"},
+	RowNeedsHeader:       {"", "row %d is a HASH, which needs the header option"},
+	RowType:              {"", "row %d must be an ARRAY or a HASH, got %s"},
+	OutOfRange:           {"", "%s must be between %d and %d, got %d"},
+	NotPositive:          {"", "%s must be positive, got %d"},
+	CannotBind:           {"", "cannot bind parameter %d of type %s"},
+	NotCodePoint:         {"", "%d is not a code point"},
+	NotAnInteger:         {"", "%q is not a base %d integer"},
+	IntegerTooLarge:      {"", "%q does not fit in 64 bits"},
+	TableRowType:         {"", "row %d must be a HASH, got %s"},
+	Negative:             {"", "%s must not be negative, got %d"},
+	Overflow:             {"", "the result does not fit in 64 bits"},
+	SortComparator:       {"", "the comparator must return BOOLEAN or INTEGER, got %s"},
+	SortUnorderable:      {"", "can only order INTEGER or STRING values, got %s"},
+	SortIncomparable:     {"", "cannot compare %s with %s"},
+	ActorStopped:         {"", "the actor is stopped"},
+	ActorAsksItself:      {"", "an actor cannot ask itself, it would wait for its own reply"},
+	UnknownModule:        {"", "no module named %q"},
+
+	BuiltinFailed: {diagnostic.BuiltinFailed, "%s: %s"},
+	ColumnFailed:  {diagnostic.BuiltinFailed, "%s: column %s: %s"},
+
+	ExpectedNextToken:  {diagnostic.UnexpectedToken, "expected next token to be %s, got %s instead"},
+	NoPrefixParseFn:    {diagnostic.NoPrefixParse, "no prefix parse function for %s found"},
+	InvalidInteger:     {diagnostic.InvalidInteger, "could not parse %q as integer"},
+	InvalidDuration:    {diagnostic.InvalidDuration, "could not parse %q as duration"},
+	ExpectedType:       {diagnostic.ExpectedType, "expected a type, got %s instead"},
+	ExpectedPattern:    {diagnostic.ExpectedPattern, "expected a pattern, got %s instead"},
+	UnexpectedIllegal:  {diagnostic.IllegalToken, "unexpected ILLEGAL token '%s' at %d:%d"},
+	UnterminatedString: {diagnostic.IllegalToken, "unterminated string starting at %d:%d"},
+	UnclosedBlock:      {diagnostic.UnexpectedToken, "block starting at %d:%d is not closed with }"},
+	TrailingTokens:     {diagnostic.UnexpectedToken, "expected the end of the input after the expression, got %s instead"},
+	InvalidCharacter:   {diagnostic.IllegalToken, "invalid character literal %s at %d:%d, which has to hold exactly one character"},
+	ReservedWord:       {diagnostic.ReservedWord, "%s is a reserved word and cannot be used as a name"},
+	FeatureDisabled:    {diagnostic.FeatureDisabled, "%s needs the %s feature, which is part of language version %d"},
+
+	TypeExpected:      {diagnostic.TypeError, "%s: expected %s, got %s"},
+	UnknownType:       {diagnostic.TypeError, "unknown type %s"},
+	PrefixNotDefined:  {diagnostic.TypeError, "operator %s not defined for %s"},
+	InfixNotDefined:   {diagnostic.TypeError, "operator %s not defined for %s and %s"},
+	NotCallable:       {diagnostic.TypeError, "cannot call %s of type %s"},
+	TypeArgumentCount: {diagnostic.TypeError, "%s takes %d arguments, got %d"},
+	TypeOfLet:         {"", "let %s"},
+	TypeOfArgument:    {"", "argument %d to %s"},
+	TypeOfResult:      {"", "result of %s"},
+
+	ParseErrorsHeader: {"", "Woops! We ran into some monkey business here!\n parser errors:"},
+	HistoryFailed:     {"", "could not open history file: %s"},
+	SaveUsage:         {"", "usage: :save <file>"},
+	SaveFailed:        {"", "could not save session: %s"},
+	Saved:             {"", "saved %d bindings to %s"},
+	SaveSkipped:       {"", "skipped values that cannot be serialized: %s"},
+	LoadUsage:         {"", "usage: :load-session <file>"},
+	LoadFailed:        {"", "could not load session: %s"},
+	Loaded:            {"", "loaded session from %s"},
+	PreloadFailed:     {"", "could not load %s: %s"},
+	InspectUsage:      {"", "usage: %s <code>"},
+	TimingOn:          {"", "timing is on"},
+	TimingOff:         {"", "timing is off"},
+	TimeReport:        {"", "took %s, %d steps, %d values, %d bytes allocated"},
+	UnknownCommand:    {"", "unknown command: %s"},
+	HeapSummary:       {"", "%d objects and environments reachable, %d bytes"},
+	HeapByType:        {"", "by type:"},
+	HeapByBinding:     {"", "largest bindings, by the bytes only they keep alive:"},
+}
+
+var (
+	mu      sync.RWMutex
+	texts   = copyDefaults()
+	version uint64
+)
+
+func copyDefaults() map[ID]string {
+	texts := make(map[ID]string, len(defaults))
+	for id, entry := range defaults {
+		texts[id] = entry.text
+	}
+	return texts
+}
+
+// Format returns the text of the message id with args.
+func Format(id ID, args ...interface{}) string {
+	mu.RLock()
+	text, ok := texts[id]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Sprintf("%s %v", id, args)
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Code returns the code of the errors reported with the message id, which
+// is empty for messages that are not errors of their own.
+func Code(id ID) diagnostic.Code {
+	return defaults[id].code
+}
+
+// Text returns the current text of the message id.
+func Text(id ID) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return texts[id]
+}
+
+// IDs returns the IDs of every message in alphabetical order.
+func IDs() []ID {
+	ids := make([]ID, 0, len(defaults))
+	for id := range defaults {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Set replaces the text of the messages in replacements, leaving the
+// others as they are. It fails without changing anything if an ID is not
+// one of IDs.
+func Set(replacements map[ID]string) error {
+	for id := range replacements {
+		if _, ok := defaults[id]; !ok {
+			return fmt.Errorf("unknown message %q", id)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, text := range replacements {
+		texts[id] = text
+	}
+	version++
+	return nil
+}
+
+// Reset restores the default text of every message.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	texts = copyDefaults()
+	version++
+}
+
+// Version changes whenever Set or Reset does, so callers that keep
+// formatted messages around know when to format them again.
+func Version() uint64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	return version
+}
@@ -0,0 +1,54 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+)
+
+func TestSet(t *testing.T) {
+	defer Reset()
+
+	if got := Format(IdentifierNotFound, "x"); got != "identifier not found: x" {
+		t.Errorf("wrong default text. got=%q", got)
+	}
+
+	err := Set(map[ID]string{IdentifierNotFound: "identificador não encontrado: %s", TypeMismatch: "%[3]s %[2]s %[1]s?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Format(IdentifierNotFound, "x"); got != "identificador não encontrado: x" {
+		t.Errorf("wrong replaced text. got=%q", got)
+	}
+	if got := Format(TypeMismatch, "INTEGER", "+", "BOOLEAN"); got != "BOOLEAN + INTEGER?" {
+		t.Errorf("wrong reordered text. got=%q", got)
+	}
+	if Code(IdentifierNotFound) != diagnostic.IdentifierNotFound {
+		t.Errorf("replacing the text changed the code. got=%s", Code(IdentifierNotFound))
+	}
+
+	version := Version()
+	if err := Set(map[ID]string{"no-such-message": "x", NotIterable: "y"}); err == nil || err.Error() != `unknown message "no-such-message"` {
+		t.Errorf("wrong error for an unknown message. got=%v", err)
+	}
+	if Text(NotIterable) != "cannot iterate over %s" || Version() != version {
+		t.Errorf("a failed Set changed the messages")
+	}
+
+	Reset()
+	if got := Format(IdentifierNotFound, "x"); got != "identifier not found: x" || Version() == version {
+		t.Errorf("Reset did not restore the default. got=%q", got)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	for _, id := range IDs() {
+		if Text(id) == "" {
+			t.Errorf("%s has no text", id)
+		}
+		if strings.Contains(Text(id), "unkown") {
+			t.Errorf("%s has a typo: %q", id, Text(id))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package object
+
+import "runtime"
+
+// Actor is a handle to a function processing messages one at a time on its
+// own goroutine, which stops once the actor is closed.
+type Actor struct {
+	*Handle
+	Mailbox chan Message
+	done    chan struct{}
+}
+
+// Message is sent to an actor. When Reply is not nil the sender waits on it
+// for the result of handling the message.
+type Message struct {
+	Value Object
+	Reply chan Object
+}
+
+var _ Resource = &Actor{}
+
+// NewActor returns an actor whose mailbox holds up to size messages.
+// Closing it, or it being collected, closes Done.
+func NewActor(size int) *Actor {
+	done := make(chan struct{})
+	a := &Actor{Handle: NewHandle(stopper(done)), Mailbox: make(chan Message, size), done: done}
+	runtime.SetFinalizer(a, func(a *Actor) { a.Close() })
+	return a
+}
+
+// Done is closed when the actor is, for the goroutine handling its messages
+// and those waiting on it to stop.
+func (a *Actor) Done() <-chan struct{} {
+	return a.done
+}
+
+func (a *Actor) Inspect() string {
+	return "actor"
+}
+
+func (a *Actor) Type() ObjectType {
+	return ACTOR_OBJ
+}
+
+// stopper closes its channel when closed.
+type stopper chan struct{}
+
+func (s stopper) Close() error {
+	close(s)
+	return nil
+}
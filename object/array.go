@@ -1,26 +1,14 @@
 package object
 
-import (
-	"bytes"
-	"strings"
-)
-
 type Array struct {
 	Elements []Object
+	Frozen   bool
 }
 
 var _ Object = &Array{}
 
 func (ao *Array) Inspect() string {
-	var out bytes.Buffer
-	elements := []string{}
-	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
-	}
-	out.WriteString("[")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("]")
-	return out.String()
+	return inspect(ao, map[Object]bool{})
 }
 
 func (a *Array) Type() ObjectType {
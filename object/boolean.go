@@ -6,6 +6,12 @@ type Boolean struct {
 	Value bool
 }
 
+// TRUE and FALSE are the booleans; the evaluator never makes others.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
 var _ Object = &Boolean{}
 var _ Hashable = &Boolean{}
 
@@ -0,0 +1,41 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+)
+
+type Bytes struct {
+	Value []byte
+}
+
+var _ Object = &Bytes{}
+var _ Hashable = &Bytes{}
+
+func (b *Bytes) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString(`b"`)
+	for _, c := range b.Value {
+		if c >= ' ' && c <= '~' && c != '"' && c != '\\' {
+			out.WriteByte(c)
+		} else {
+			fmt.Fprintf(&out, "\\x%02x", c)
+		}
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
+
+func (b *Bytes) Type() ObjectType {
+	return BYTES_OBJ
+}
+
+func (b *Bytes) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value)
+	return HashKey{
+		Type:  b.Type(),
+		Value: h.Sum64(),
+	}
+}
@@ -0,0 +1,24 @@
+package object
+
+// Char is a single Unicode code point, written 'a'.
+type Char struct {
+	Value rune
+}
+
+var _ Object = &Char{}
+var _ Hashable = &Char{}
+
+func (c *Char) Inspect() string {
+	return string(c.Value)
+}
+
+func (c *Char) Type() ObjectType {
+	return CHAR_OBJ
+}
+
+func (c *Char) HashKey() HashKey {
+	return HashKey{
+		Type:  c.Type(),
+		Value: uint64(c.Value),
+	}
+}
@@ -0,0 +1,53 @@
+package object
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+)
+
+// Connection is an open network connection. Reads go through Reader, so
+// reading lines and reading blocks can be mixed.
+type Connection struct {
+	*Handle
+	Conn   net.Conn
+	Reader *bufio.Reader
+}
+
+var _ Resource = &Connection{}
+
+func NewConnection(conn net.Conn) *Connection {
+	c := &Connection{Handle: NewHandle(conn), Conn: conn, Reader: bufio.NewReader(conn)}
+	runtime.SetFinalizer(c, func(c *Connection) { c.Close() })
+	return c
+}
+
+func (c *Connection) Inspect() string {
+	return "connection to " + c.Conn.RemoteAddr().String()
+}
+
+func (c *Connection) Type() ObjectType {
+	return CONNECTION_OBJ
+}
+
+// Listener accepts network connections.
+type Listener struct {
+	*Handle
+	Listener net.Listener
+}
+
+var _ Resource = &Listener{}
+
+func NewListener(listener net.Listener) *Listener {
+	l := &Listener{Handle: NewHandle(listener), Listener: listener}
+	runtime.SetFinalizer(l, func(l *Listener) { l.Close() })
+	return l
+}
+
+func (l *Listener) Inspect() string {
+	return "listener on " + l.Listener.Addr().String()
+}
+
+func (l *Listener) Type() ObjectType {
+	return LISTENER_OBJ
+}
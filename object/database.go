@@ -0,0 +1,29 @@
+package object
+
+import (
+	"database/sql"
+	"runtime"
+)
+
+// Database is an open SQLite database.
+type Database struct {
+	*Handle
+	DB   *sql.DB
+	Path string
+}
+
+var _ Resource = &Database{}
+
+func NewDatabase(db *sql.DB, path string) *Database {
+	d := &Database{Handle: NewHandle(db), DB: db, Path: path}
+	runtime.SetFinalizer(d, func(d *Database) { d.Close() })
+	return d
+}
+
+func (d *Database) Inspect() string {
+	return "database " + d.Path
+}
+
+func (d *Database) Type() ObjectType {
+	return DATABASE_OBJ
+}
@@ -1,10 +1,39 @@
 package object
 
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
 type Environment struct {
+	// id tells the environment apart from the others, assigned by ID the
+	// first time it is asked for. It comes first to be aligned for atomic
+	// access.
+	id uint64
+
 	store map[string]Object
 	outer *Environment
+
+	// names and slots hold the locals of a function call, which are resolved
+	// to an index ahead of time instead of being looked up by name.
+	names []string
+	slots []Object
+
+	// shared is set by Share once other goroutines may use the environment,
+	// from then on every access holds mu.
+	shared int32
+	mu     sync.RWMutex
+
+	// observers are told of every binding in the environment.
+	observers []Observer
 }
 
+// Observer is told that name was bound to value in env. Previous is what
+// name was bound to in env itself before, nil when the binding is new, so
+// a let that binds a name again is told apart from one that defines it.
+type Observer func(env *Environment, name string, value, previous Object)
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s}
@@ -13,18 +42,181 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.observers = outer.observers
 	return env
 }
 
+// NewSlotEnvironment creates an environment with one slot for each of names,
+// to be accessed through GetSlot and SetSlot.
+func NewSlotEnvironment(outer *Environment, names []string) *Environment {
+	return &Environment{
+		outer:     outer,
+		names:     names,
+		slots:     make([]Object, len(names)),
+		observers: outer.observers,
+	}
+}
+
+// lastID is the last ID given to an environment.
+var lastID uint64
+
+// ID returns a number identifying e among all the environments of the
+// process, starting from 1, for telling them apart without holding on to
+// them.
+func (e *Environment) ID() uint64 {
+	if id := atomic.LoadUint64(&e.id); id != 0 {
+		return id
+	}
+	atomic.CompareAndSwapUint64(&e.id, 0, atomic.AddUint64(&lastID, 1))
+	return atomic.LoadUint64(&e.id)
+}
+
+// Outer returns the environment e is enclosed in, nil for an outermost one.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Observe makes observer be told of every binding in e and in the
+// environments created enclosed in e from then on, such as those of the
+// calls of functions defined in e. With environments used by several
+// goroutines, it is told from each of them. It must not be called while
+// other goroutines use e.
+func (e *Environment) Observe(observer Observer) {
+	observers := make([]Observer, len(e.observers), len(e.observers)+1)
+	copy(observers, e.observers)
+	e.observers = append(observers, observer)
+}
+
+func (e *Environment) notify(name string, value, previous Object) {
+	for _, observer := range e.observers {
+		observer(e, name, value, previous)
+	}
+}
+
+// Share makes e and the environments enclosing it safe for concurrent use.
+// It must be called before any other goroutine can reach e, as environments
+// that were never shared are accessed without locking.
+func (e *Environment) Share() {
+	for env := e; env != nil; env = env.outer {
+		atomic.StoreInt32(&env.shared, 1)
+	}
+}
+
+func (e *Environment) rlock() bool {
+	if atomic.LoadInt32(&e.shared) == 0 {
+		return false
+	}
+	e.mu.RLock()
+	return true
+}
+
+func (e *Environment) lock() bool {
+	if atomic.LoadInt32(&e.shared) == 0 {
+		return false
+	}
+	e.mu.Lock()
+	return true
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
+	locked := e.rlock()
 	obj, ok := e.store[name]
+	if !ok {
+		obj, ok = e.getSlotByName(name)
+	}
+	if locked {
+		e.mu.RUnlock()
+	}
+
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 
+func (e *Environment) getSlotByName(name string) (Object, bool) {
+	for i, slotName := range e.names {
+		if slotName == name && e.slots[i] != nil {
+			return e.slots[i], true
+		}
+	}
+	return nil, false
+}
+
 func (e *Environment) Set(name string, obj Object) Object {
+	previous := e.set(name, obj)
+	if len(e.observers) != 0 {
+		e.notify(name, obj, previous)
+	}
+	return obj
+}
+
+// set binds name to obj and returns what name was bound to before.
+func (e *Environment) set(name string, obj Object) Object {
+	if e.lock() {
+		defer e.mu.Unlock()
+	}
+
+	for i, slotName := range e.names {
+		if slotName == name {
+			previous := e.slots[i]
+			e.slots[i] = obj
+			return previous
+		}
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
+	previous := e.store[name]
 	e.store[name] = obj
+	return previous
+}
+
+// GetSlot returns the value in slot index of the environment depth levels
+// out from e, or nil when that slot has not been set yet.
+func (e *Environment) GetSlot(depth, index int) Object {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.outer
+	}
+
+	if env.rlock() {
+		defer env.mu.RUnlock()
+	}
+	return env.slots[index]
+}
+
+func (e *Environment) SetSlot(index int, obj Object) Object {
+	locked := e.lock()
+	previous := e.slots[index]
+	e.slots[index] = obj
+	if locked {
+		e.mu.Unlock()
+	}
+
+	if len(e.observers) != 0 {
+		e.notify(e.names[index], obj, previous)
+	}
 	return obj
 }
+
+// Names returns the sorted names bound directly in this environment, leaving
+// out the ones inherited from enclosing environments.
+func (e *Environment) Names() []string {
+	if e.rlock() {
+		defer e.mu.RUnlock()
+	}
+
+	names := make([]string, 0, len(e.store)+len(e.names))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for i, name := range e.names {
+		if e.slots[i] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,13 +1,31 @@
 package object
 
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+)
+
 type Error struct {
+	// Code identifies the kind of error, whatever its message says.
+	Code    diagnostic.Code
 	Message string
+	// Stack lists the calls that were active when the error happened, most
+	// recent first. It is only recorded for some errors.
+	Stack []string
+	// Line and Column are where the innermost statement being evaluated when
+	// the error happened starts, zero when the error has no position.
+	Line   int
+	Column int
 }
 
 var _ Object = &Error{}
 
 func (e *Error) Inspect() string {
-	return "Error: " + e.Message
+	if len(e.Stack) == 0 {
+		return "Error: " + e.Message
+	}
+	return "Error: " + e.Message + "\n\tat " + strings.Join(e.Stack, "\n\tat ")
 }
 
 func (e *Error) Type() ObjectType {
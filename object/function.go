@@ -11,6 +11,14 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       ast.BlockStatement
 	Env        *Environment
+
+	// Locals names the slots of the environment created for each call, or is
+	// nil when the function body looks its variables up by name.
+	Locals []string
+
+	// Literal is the function literal the function was made from, which
+	// the evaluator keys what it prepares for running the body on.
+	Literal *ast.FunctionLiteral
 }
 
 var _ Object = &Function{}
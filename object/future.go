@@ -0,0 +1,40 @@
+package object
+
+// Future holds the result of a computation running on another goroutine. The
+// result is set once with Resolve and read with Wait.
+type Future struct {
+	done   chan struct{}
+	result Object
+}
+
+var _ Object = &Future{}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) Inspect() string {
+	select {
+	case <-f.done:
+		return "future(" + f.result.Inspect() + ")"
+	default:
+		return "future(pending)"
+	}
+}
+
+func (f *Future) Type() ObjectType {
+	return FUTURE_OBJ
+}
+
+// Resolve sets the result and wakes everyone waiting for it. It must be
+// called exactly once.
+func (f *Future) Resolve(result Object) {
+	f.result = result
+	close(f.done)
+}
+
+// Wait blocks until the future is resolved and returns its result.
+func (f *Future) Wait() Object {
+	<-f.done
+	return f.result
+}
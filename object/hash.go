@@ -2,29 +2,54 @@ package object
 
 import (
 	"bytes"
-	"fmt"
-	"strings"
+	"sort"
 )
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	Pairs  map[HashKey]HashPair
+	Frozen bool
 }
 
 var _ Object = &Hash{}
 
 func (h *Hash) Inspect() string {
-	var out bytes.Buffer
-	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
-	}
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-	return out.String()
+	return inspect(h, map[Object]bool{})
 }
 
 func (e *Hash) Type() ObjectType {
 	return HASH_OBJ
 }
+
+// SortedPairs returns the pairs ordered by key, so iterating over a hash
+// gives the same order every time. Keys are grouped by type, and within a
+// type integers sort numerically, false before true, and strings and bytes
+// lexically.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+func lessKey(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Bytes:
+		return bytes.Compare(a.Value, b.(*Bytes).Value) < 0
+	default:
+		return a.Inspect() < b.Inspect()
+	}
+}
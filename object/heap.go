@@ -0,0 +1,259 @@
+package object
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// EnvironmentType is the type of the nodes of a Heap that stand for
+// environments rather than objects.
+const EnvironmentType = "ENVIRONMENT"
+
+// maxReferenceName is how many bytes of an inspected hash key the name of a
+// reference keeps.
+const maxReferenceName = 32
+
+// Heap is the graph of the objects and environments reachable from an
+// environment, for finding what keeps memory alive. Sizes are estimates of
+// what the values themselves take, leaving out the syntax trees functions
+// share and what builtins hold on to.
+type Heap struct {
+	// Nodes are the objects and environments, starting with the environment
+	// the heap was taken from.
+	Nodes []HeapNode
+}
+
+// HeapNode is an object or environment of a Heap.
+type HeapNode struct {
+	Type string
+	// Size is how many bytes the node takes by itself, and Retained how
+	// many would be freed with it: its own and those of the nodes only
+	// reachable through it.
+	Size     int
+	Retained int
+
+	References []HeapReference
+}
+
+// HeapReference is a reference from a node to Nodes[Node], by Name: the
+// name of a binding, an index such as [0], or env, outer, result or value.
+type HeapReference struct {
+	Name string
+	Node int
+}
+
+// NewHeap walks the objects reachable from env, including through the
+// environments enclosing it, and returns their graph. It must not be called
+// while other goroutines change what they reach.
+func NewHeap(env *Environment) *Heap {
+	h := &heapWalk{ids: map[interface{}]int{}}
+	h.visit(env)
+	h.retain()
+	return &Heap{Nodes: h.nodes}
+}
+
+type heapWalk struct {
+	nodes []HeapNode
+	ids   map[interface{}]int
+}
+
+// visit adds the node for value, an Object or an *Environment, and those it
+// references, returning its index.
+func (h *heapWalk) visit(value interface{}) int {
+	if id, ok := h.ids[value]; ok {
+		return id
+	}
+	id := len(h.nodes)
+	h.ids[value] = id
+	h.nodes = append(h.nodes, HeapNode{})
+
+	var node HeapNode
+	refer := func(name string, to interface{}) {
+		node.References = append(node.References, HeapReference{name, h.visit(to)})
+	}
+
+	switch value := value.(type) {
+	case *Environment:
+		node.Type = EnvironmentType
+		locked := value.rlock()
+		names, objects := value.bindings()
+		if locked {
+			value.mu.RUnlock()
+		}
+		node.Size = int(unsafe.Sizeof(*value)) + len(value.names)*int(unsafe.Sizeof(Object(nil)))
+		for i, name := range names {
+			node.Size += len(name) + int(unsafe.Sizeof(name)+unsafe.Sizeof(Object(nil)))
+			refer(name, objects[i])
+		}
+		if value.outer != nil {
+			refer("outer", value.outer)
+		}
+
+	case Object:
+		node.Type = string(value.Type())
+		node.Size = objectSize(value)
+		switch value := value.(type) {
+		case *Array:
+			for i, element := range value.Elements {
+				refer("["+strconv.Itoa(i)+"]", element)
+			}
+		case *Tuple:
+			for i, element := range value.Elements {
+				refer("["+strconv.Itoa(i)+"]", element)
+			}
+		case *Hash:
+			for _, pair := range value.Pairs {
+				name := pair.Key.Inspect()
+				if len(name) > maxReferenceName {
+					name = name[:maxReferenceName] + "..."
+				}
+				refer("["+name+"]", pair.Value)
+				refer("key "+name, pair.Key)
+			}
+		case *Function:
+			if value.Env != nil {
+				refer("env", value.Env)
+			}
+		case *Future:
+			select {
+			case <-value.done:
+				if value.result != nil {
+					refer("result", value.result)
+				}
+			default:
+			}
+		case *ReturnValue:
+			refer("value", value.Value)
+		}
+	}
+
+	h.nodes[id] = node
+	return id
+}
+
+// bindings returns the names bound in env and what they are bound to.
+func (e *Environment) bindings() ([]string, []Object) {
+	var names []string
+	var objects []Object
+	for name, obj := range e.store {
+		names = append(names, name)
+		objects = append(objects, obj)
+	}
+	for i, name := range e.names {
+		if e.slots[i] != nil {
+			names = append(names, name)
+			objects = append(objects, e.slots[i])
+		}
+	}
+	return names, objects
+}
+
+// objectSize estimates how many bytes obj takes, not counting the objects
+// it references.
+func objectSize(obj Object) int {
+	size := int(unsafe.Sizeof(obj))
+	if t := reflect.TypeOf(obj); t.Kind() == reflect.Ptr {
+		size = int(t.Elem().Size())
+	}
+	word := int(unsafe.Sizeof(Object(nil)))
+	switch obj := obj.(type) {
+	case *String:
+		size += len(obj.Value)
+	case *Bytes:
+		size += cap(obj.Value)
+	case *StringBuilder:
+		size += obj.builder.Cap()
+	case *Array:
+		size += cap(obj.Elements) * word
+	case *Tuple:
+		size += cap(obj.Elements) * word
+	case *Hash:
+		size += len(obj.Pairs) * int(unsafe.Sizeof(HashKey{})+unsafe.Sizeof(HashPair{}))
+	case *Function:
+		size += (len(obj.Parameters) + len(obj.Locals)) * word
+	case *Error:
+		size += len(obj.Message)
+	}
+	return size
+}
+
+// retain works out the retained sizes, as the sizes of the nodes each node
+// dominates: those that every path from the first node to them goes
+// through. Dominators are found with the iterative algorithm of Cooper,
+// Harvey and Kennedy.
+func (h *heapWalk) retain() {
+	n := len(h.nodes)
+	order := make([]int, 0, n) // nodes in postorder
+	number := make([]int, n)   // position in order
+	visited := make([]bool, n)
+	predecessors := make([][]int, n)
+
+	// The graph can be deep, so the depth-first search keeps its own stack.
+	type frame struct{ node, next int }
+	stack := []frame{{0, 0}}
+	visited[0] = true
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		references := h.nodes[top.node].References
+		if top.next < len(references) {
+			to := references[top.next].Node
+			top.next++
+			predecessors[to] = append(predecessors[to], top.node)
+			if !visited[to] {
+				visited[to] = true
+				stack = append(stack, frame{to, 0})
+			}
+			continue
+		}
+		number[top.node] = len(order)
+		order = append(order, top.node)
+		stack = stack[:len(stack)-1]
+	}
+
+	dominator := make([]int, n)
+	for i := range dominator {
+		dominator[i] = -1
+	}
+	dominator[0] = 0
+	intersect := func(a, b int) int {
+		for a != b {
+			for number[a] < number[b] {
+				a = dominator[a]
+			}
+			for number[b] < number[a] {
+				b = dominator[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 2; i >= 0; i-- {
+			node := order[i]
+			idom := -1
+			for _, p := range predecessors[node] {
+				if dominator[p] == -1 {
+					continue
+				}
+				if idom == -1 {
+					idom = p
+				} else {
+					idom = intersect(p, idom)
+				}
+			}
+			if dominator[node] != idom {
+				dominator[node] = idom
+				changed = true
+			}
+		}
+	}
+
+	// Postorder has every node after those it dominates.
+	for _, node := range order {
+		h.nodes[node].Retained += h.nodes[node].Size
+		if node != 0 {
+			h.nodes[dominator[node]].Retained += h.nodes[node].Retained
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// inspect renders obj like Inspect does, except that containers which are
+// already being rendered further up are printed as `[...]` or `{...}`, so
+// values that contain themselves do not recurse forever.
+func inspect(obj Object, seen map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if seen[obj] {
+			return "[...]"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		var out bytes.Buffer
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, inspect(e, seen))
+		}
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString("]")
+		return out.String()
+
+	case *Tuple:
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, inspect(e, seen))
+		}
+		if len(elements) == 1 {
+			return "(" + elements[0] + ",)"
+		}
+		return "(" + strings.Join(elements, ", ") + ")"
+
+	case *Hash:
+		if seen[obj] {
+			return "{...}"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		var out bytes.Buffer
+		pairs := []string{}
+		for _, pair := range obj.SortedPairs() {
+			pairs = append(pairs, fmt.Sprintf("%s: %s",
+				inspect(pair.Key, seen), inspect(pair.Value, seen)))
+		}
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+		return out.String()
+
+	default:
+		return obj.Inspect()
+	}
+}
+
+// Equal reports whether a and b hold the same value. Arrays, tuples and hashes are
+// compared element by element; a pair of containers that is already being
+// compared further up is assumed to be equal, which keeps the comparison of
+// self-referencing values finite. Functions and other objects without a
+// value of their own are only equal to themselves.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Char:
+		return a.Value == b.(*Char).Value
+	case *Bytes:
+		return bytes.Equal(a.Value, b.(*Bytes).Value)
+	case *Null:
+		return true
+	case *Time:
+		return a.Value.Equal(b.(*Time).Value)
+
+	case *Array:
+		other := b.(*Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		pair := [2]Object{a, other}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		for i := range a.Elements {
+			if !equal(a.Elements[i], other.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Tuple:
+		other := b.(*Tuple)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], other.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		other := b.(*Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		pair := [2]Object{a, other}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		for key, p := range a.Pairs {
+			o, ok := other.Pairs[key]
+			if !ok || !equal(p.Value, o.Value, seen) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
@@ -2,6 +2,9 @@ package object
 
 type Null struct{}
 
+// NULL is the null value; the evaluator never makes another.
+var NULL = &Null{}
+
 var _ Object = &Null{}
 
 func (n *Null) Inspect() string {
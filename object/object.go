@@ -12,7 +12,18 @@ const (
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
+	TUPLE_OBJ        = "TUPLE"
 	HASH_OBJ         = "HASH"
+	BYTES_OBJ        = "BYTES"
+	FUTURE_OBJ       = "FUTURE"
+	ACTOR_OBJ        = "ACTOR"
+	CONNECTION_OBJ   = "CONNECTION"
+	LISTENER_OBJ     = "LISTENER"
+	DATABASE_OBJ     = "DATABASE"
+	TIME_OBJ         = "TIME"
+	CHAR_OBJ         = "CHAR"
+
+	STRING_BUILDER_OBJ = "STRING_BUILDER"
 )
 
 type Object interface {
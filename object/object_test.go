@@ -1,6 +1,13 @@
 package object
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +24,488 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestInspectCycles(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	arr.Elements = append(arr.Elements, arr)
+	if arr.Inspect() != "[1, [...]]" {
+		t.Errorf("wrong Inspect for self-referencing array. got=%q", arr.Inspect())
+	}
+
+	key := &String{Value: "self"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: &Array{Elements: []Object{hash}}}
+	if hash.Inspect() != "{self: [{...}]}" {
+		t.Errorf("wrong Inspect for self-referencing hash. got=%q", hash.Inspect())
+	}
+
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	outer := &Array{Elements: []Object{shared, shared}}
+	if outer.Inspect() != "[[2], [2]]" {
+		t.Errorf("shared values should be printed in full. got=%q", outer.Inspect())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	one := &Integer{Value: 1}
+	str := &String{Value: "k"}
+
+	a := &Array{Elements: []Object{one}}
+	a.Elements = append(a.Elements, a)
+	b := &Array{Elements: []Object{&Integer{Value: 1}}}
+	b.Elements = append(b.Elements, b)
+	c := &Array{Elements: []Object{&Integer{Value: 2}}}
+	c.Elements = append(c.Elements, c)
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{one, &Integer{Value: 1}, true},
+		{one, &Integer{Value: 2}, false},
+		{str, &String{Value: "k"}, true},
+		{&Bytes{Value: []byte("x")}, &Bytes{Value: []byte("x")}, true},
+		{one, str, false},
+		{&Array{Elements: []Object{one, str}}, &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "k"}}}, true},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{one, one}}, false},
+		{
+			&Hash{Pairs: map[HashKey]HashPair{str.HashKey(): {Key: str, Value: one}}},
+			&Hash{Pairs: map[HashKey]HashPair{str.HashKey(): {Key: str, Value: &Integer{Value: 1}}}},
+			true,
+		},
+		{
+			&Hash{Pairs: map[HashKey]HashPair{str.HashKey(): {Key: str, Value: one}}},
+			&Hash{Pairs: map[HashKey]HashPair{one.HashKey(): {Key: one, Value: one}}},
+			false,
+		},
+		{a, b, true},
+		{a, c, false},
+	}
+
+	for i, tt := range tests {
+		if Equal(tt.a, tt.b) != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. expected=%t",
+				i, tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestStringHashKeyIsCached(t *testing.T) {
+	s := &String{Value: "cached"}
+	first := s.HashKey()
+	if s.hash == 0 {
+		t.Fatalf("hash key was not cached")
+	}
+	if s.HashKey() != first {
+		t.Errorf("cached hash key differs from the computed one")
+	}
+}
+
+func BenchmarkStringHashKey(b *testing.B) {
+	s := &String{Value: "a moderately long hash key used in a lookup"}
+	for i := 0; i < b.N; i++ {
+		s.HashKey()
+	}
+}
+
+func BenchmarkStringHashKeyUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := &String{Value: "a moderately long hash key used in a lookup"}
+		s.HashKey()
+	}
+}
+
+func TestFuture(t *testing.T) {
+	f := NewFuture()
+	if f.Inspect() != "future(pending)" {
+		t.Errorf("wrong inspect for a pending future. got=%q", f.Inspect())
+	}
+
+	go f.Resolve(&String{Value: "done"})
+	if got := f.Wait().Inspect(); got != "done" {
+		t.Errorf("wrong result. got=%q", got)
+	}
+	if f.Inspect() != "future(done)" {
+		t.Errorf("wrong inspect for a resolved future. got=%q", f.Inspect())
+	}
+}
+
+func TestHashSortedPairs(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range []Object{
+		&String{Value: "b"}, &Integer{Value: 10}, &Boolean{Value: true},
+		&String{Value: "a"}, &Integer{Value: -1}, &Boolean{Value: false},
+	} {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: key}
+	}
+
+	keys := []string{}
+	for _, pair := range hash.SortedPairs() {
+		keys = append(keys, pair.Key.Inspect())
+	}
+	if got := strings.Join(keys, " "); got != "false true -1 10 a b" {
+		t.Errorf("wrong order. got=%q", got)
+	}
+}
+
+func TestHashInspectIsSorted(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, value := range []int64{3, 1, 2, 10} {
+		key := &Integer{Value: value}
+		hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: key}
+	}
+	if hash.Inspect() != "{1: 1, 2: 2, 3: 3, 10: 10}" {
+		t.Errorf("wrong Inspect. got=%q", hash.Inspect())
+	}
+}
+
+type point struct {
+	X, Y   int
+	Label  string `monkey:"label"`
+	Hidden bool   `monkey:"-"`
+	secret int
+}
+
+func TestFromGoValue(t *testing.T) {
+	label := "origin"
+	shared := []int{1, 2}
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{uint8(7), "7"},
+		{"hi", "hi"},
+		{[]byte("ab"), `b"ab"`},
+		{&label, "origin"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{point{X: 1, Y: 2, Label: "p", Hidden: true}, "{X: 1, Y: 2, label: p}"},
+		{[]*point{nil}, "[null]"},
+		{map[string]interface{}{"list": []interface{}{1, "x"}}, "{list: [1, x]}"},
+		{[][]int{shared[:1], shared, shared}, "[[1], [1, 2], [1, 2]]"},
+	}
+	for _, tt := range tests {
+		obj, err := FromGoValue(tt.value)
+		if err != nil {
+			t.Errorf("FromGoValue(%#v) failed: %s", tt.value, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGoValue(%#v) = %s, want %s", tt.value, obj.Inspect(), tt.expected)
+		}
+	}
+
+	if obj, _ := FromGoValue(false); obj != FALSE {
+		t.Errorf("expected false to be FALSE")
+	}
+
+	type node struct{ Next *node }
+	loop := &node{}
+	loop.Next = loop
+	self := []interface{}{nil}
+	self[0] = self
+	hashes := map[string]interface{}{}
+	hashes["self"] = hashes
+	for _, value := range []interface{}{1.5, make(chan int), loop, self, hashes, uint64(1 << 63)} {
+		if _, err := FromGoValue(value); err == nil {
+			t.Errorf("expected FromGoValue(%T) to fail", value)
+		}
+	}
+}
+
+func TestFromGoValueFunc(t *testing.T) {
+	add := func(a, b int) int { return a + b }
+	divide := func(a, b int) (int, int, error) {
+		if b == 0 {
+			return 0, 0, errors.New("division by zero")
+		}
+		return a / b, a % b, nil
+	}
+	join := func(sep string, parts ...string) string { return strings.Join(parts, sep) }
+
+	call := func(fn interface{}, args ...Object) Object {
+		obj, err := FromGoValue(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj.(*Builtin).Fn(args...)
+	}
+	one, two, zero := &Integer{Value: 1}, &Integer{Value: 2}, &Integer{Value: 0}
+
+	if got := call(add, one, two).Inspect(); got != "3" {
+		t.Errorf("expected add(1, 2) to be 3, got %s", got)
+	}
+	if got := call(divide, &Integer{Value: 7}, two).Inspect(); got != "(3, 1)" {
+		t.Errorf("expected divide(7, 2) to be (3, 1), got %s", got)
+	}
+	if got := call(join, &String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"}).Inspect(); got != "a-b" {
+		t.Errorf("expected join to be a-b, got %s", got)
+	}
+
+	failures := []struct {
+		got      Object
+		expected string
+	}{
+		{call(divide, one, zero), "object.TestFromGoValueFunc.func2: division by zero"},
+		{call(add, one), "object.TestFromGoValueFunc.func1: wrong number of arguments, want 2; called with (INTEGER)"},
+		{call(add, one, &String{Value: "2"}), "object.TestFromGoValueFunc.func1: argument 2 must be int; called with (INTEGER, STRING)"},
+	}
+	for _, tt := range failures {
+		err, ok := tt.got.(*Error)
+		if !ok || err.Message != tt.expected {
+			t.Errorf("expected the error %q, got %s", tt.expected, tt.got.Inspect())
+		}
+	}
+}
+
+func TestToGoValue(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i := 0; i < len(pairs); i += 2 {
+			h.Pairs[pairs[i].(Hashable).HashKey()] = HashPair{Key: pairs[i], Value: pairs[i+1]}
+		}
+		return h
+	}
+	str := func(s string) *String { return &String{Value: s} }
+	integer := func(i int64) *Integer { return &Integer{Value: i} }
+
+	var p point
+	if err := ToGoValue(hash(str("X"), integer(3), str("label"), str("q"), str("Hidden"), TRUE, str("extra"), NULL), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{X: 3, Label: "q"}) {
+		t.Errorf("unexpected point %+v", p)
+	}
+
+	var points []*point
+	if err := ToGoValue(&Array{Elements: []Object{hash(str("Y"), integer(1)), NULL}}, &points); err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].Y != 1 || points[1] != nil {
+		t.Errorf("unexpected points %+v", points)
+	}
+
+	var counts map[string]uint16
+	if err := ToGoValue(hash(str("a"), integer(1)), &counts); err != nil || counts["a"] != 1 {
+		t.Errorf("unexpected counts %v (%v)", counts, err)
+	}
+
+	var plain interface{}
+	now := time.Now()
+	ToGoValue(&Array{Elements: []Object{integer(1), hash(str("k"), &Time{Value: now})}}, &plain)
+	if expected := []interface{}{int64(1), map[string]interface{}{"k": now}}; !reflect.DeepEqual(plain, expected) {
+		t.Errorf("expected %#v, got %#v", expected, plain)
+	}
+
+	plain = nil
+	if err := ToGoValue(hash(&Bytes{Value: []byte("b")}, integer(1), integer(2), integer(3)), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[interface{}]interface{}{"b": int64(1), int64(2): int64(3)}; !reflect.DeepEqual(plain, expected) {
+		t.Errorf("expected %#v, got %#v", expected, plain)
+	}
+	if err := ToGoValue(hash(&Bytes{Value: []byte("b")}, integer(1), &Char{Value: 'b'}, integer(2)), &plain); err == nil {
+		t.Errorf("expected keys converting to the same string to fail")
+	}
+
+	loop := &Array{Elements: []Object{integer(1)}}
+	loop.Elements = append(loop.Elements, &Tuple{Elements: []Object{loop}})
+	selfHash := hash(str("k"), NULL)
+	selfHash.Pairs[str("k").HashKey()] = HashPair{Key: str("k"), Value: selfHash}
+	selfNext := hash(str("Next"), NULL)
+	selfNext.Pairs[str("Next").HashKey()] = HashPair{Key: str("Next"), Value: selfNext}
+	type node struct{ Next *node }
+	var list []interface{}
+	var fields map[string]interface{}
+	var n node
+	for _, tt := range []struct {
+		obj    Object
+		target interface{}
+	}{
+		{loop, &plain},
+		{loop, &list},
+		{selfHash, &plain},
+		{selfHash, &fields},
+		{selfNext, &n},
+	} {
+		if err := ToGoValue(tt.obj, tt.target); err == nil || !strings.Contains(err.Error(), "contains itself") {
+			t.Errorf("expected converting %s into %T to fail, got %v", tt.obj.Type(), tt.target, err)
+		}
+	}
+	shared := &Array{Elements: []Object{integer(1)}}
+	if err := ToGoValue(&Array{Elements: []Object{shared, shared}}, &list); err != nil {
+		t.Errorf("expected an array held twice to convert, got %v", err)
+	}
+
+	var double func(int) (int, error)
+	builtin := &Builtin{Fn: func(args ...Object) Object {
+		if n := args[0].(*Integer).Value; n >= 0 {
+			return integer(n * 2)
+		}
+		return &Error{Message: "negative"}
+	}}
+	if err := ToGoValue(builtin, &double); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := double(4); n != 8 || err != nil {
+		t.Errorf("expected double(4) to be 8, got %d (%v)", n, err)
+	}
+	if _, err := double(-1); err == nil || err.Error() != "negative" {
+		t.Errorf("expected double(-1) to fail, got %v", err)
+	}
+
+	var small int8
+	var pair [2]int
+	var name string
+	for _, tt := range []struct {
+		obj      Object
+		target   interface{}
+		expected string
+	}{
+		{integer(300), &small, "300 does not fit in int8"},
+		{&Array{Elements: []Object{integer(1)}}, &pair, "cannot convert ARRAY of length 1 to [2]int"},
+		{integer(1), &name, "cannot convert INTEGER to string"},
+		{hash(str("X"), str("1")), &p, "field X: cannot convert STRING to int"},
+		{integer(1), name, "the target has to be a non-nil pointer, got string"},
+	} {
+		if err := ToGoValue(tt.obj, tt.target); err == nil || err.Error() != tt.expected {
+			t.Errorf("expected the error %q, got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestEnvironmentObserve(t *testing.T) {
+	var seen []string
+	global := NewEnvironment()
+	before := NewEnclosedEnvironment(global)
+	global.Observe(func(env *Environment, name string, value, previous Object) {
+		old := "nil"
+		if previous != nil {
+			old = previous.Inspect()
+		}
+		seen = append(seen, name+": "+old+" -> "+value.Inspect())
+	})
+
+	global.Set("a", &Integer{Value: 1})
+	global.Set("a", &Integer{Value: 2})
+	call := NewSlotEnvironment(global, []string{"x"})
+	call.SetSlot(0, &Integer{Value: 3})
+	NewEnclosedEnvironment(call).Set("y", &Integer{Value: 4})
+	before.Set("z", &Integer{Value: 5})
+
+	expected := []string{"a: nil -> 1", "a: 1 -> 2", "x: nil -> 3", "y: nil -> 4"}
+	if strings.Join(seen, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("expected %q, got %q", expected, seen)
+	}
+}
+
+func TestNewHeap(t *testing.T) {
+	env := NewEnvironment()
+	shared := &Array{Elements: []Object{&String{Value: "shared"}}}
+	own := &Array{Elements: []Object{&String{Value: "own"}, &String{Value: "own too"}}}
+	own.Elements = append(own.Elements, own)
+	env.Set("a", shared)
+	env.Set("b", shared)
+	env.Set("c", own)
+	env.Set("f", &Function{Env: env})
+
+	heap := NewHeap(env)
+	root := heap.Nodes[0]
+	if root.Type != EnvironmentType || len(root.References) != 4 || len(heap.Nodes) != 7 {
+		t.Fatalf("unexpected heap %+v", heap.Nodes)
+	}
+	references := map[string]HeapNode{}
+	for _, reference := range root.References {
+		references[reference.Name] = heap.Nodes[reference.Node]
+	}
+
+	a, c := references["a"], references["c"]
+	if a.Type != "ARRAY" || a.Retained != a.Size+heap.Nodes[a.References[0].Node].Size {
+		t.Errorf("expected a to retain its string, got %+v", a)
+	}
+	strings := heap.Nodes[c.References[0].Node].Size + heap.Nodes[c.References[1].Node].Size
+	if len(c.References) != 3 || c.References[2].Name != "[2]" || c.Retained != c.Size+strings {
+		t.Errorf("expected c to retain its strings once, got %+v", c)
+	}
+	if f := references["f"]; f.Retained != f.Size {
+		t.Errorf("expected f to retain nothing else, got %+v", f)
+	}
+
+	total := 0
+	for _, node := range heap.Nodes {
+		total += node.Size
+	}
+	if root.Retained != total {
+		t.Errorf("expected the environment to retain all %d bytes, got %d", total, root.Retained)
+	}
+}
+
+type closer struct{ closed chan struct{} }
+
+func (c *closer) Close() error {
+	close(c.closed)
+	return nil
+}
+
+type resource struct {
+	*Handle
+}
+
+func (r *resource) Type() ObjectType { return "RESOURCE" }
+func (r *resource) Inspect() string  { return "resource" }
+
+func TestTracker(t *testing.T) {
+	var tracker Tracker
+	a := &resource{NewHandle(&closer{make(chan struct{})})}
+	b := &resource{NewHandle(&closer{make(chan struct{})})}
+	tracker.Track(a)
+	tracker.Track(b)
+	tracker.Track(a)
+	if tracker.Open() != 2 {
+		t.Fatalf("expected 2 open resources, got %d", tracker.Open())
+	}
+
+	if err := a.Close(); err != nil || !a.Closed() || tracker.Open() != 1 {
+		t.Errorf("expected closing a to forget it, got %v and %d open", err, tracker.Open())
+	}
+	if err := a.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected closing a again to fail, got %v", err)
+	}
+	if err := tracker.Close(); err != nil || !b.Closed() || tracker.Open() != 0 {
+		t.Errorf("expected the tracker to close b, got %v and %d open", err, tracker.Open())
+	}
+	tracker.Track(a)
+	if tracker.Open() != 0 {
+		t.Errorf("expected a closed resource not to be tracked")
+	}
+}
+
+func TestResourceFinalizer(t *testing.T) {
+	var tracker Tracker
+	c := &closer{make(chan struct{})}
+	func() {
+		dropped := &resource{NewHandle(c)}
+		runtime.SetFinalizer(dropped, func(r *resource) { r.Close() })
+		tracker.Track(dropped)
+	}()
+
+	// The tracker holds the handle rather than the object, which can be
+	// collected.
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case <-c.closed:
+			if tracker.Open() != 0 {
+				t.Errorf("expected the collected resource to be forgotten")
+			}
+			return
+		case <-deadline:
+			t.Fatal("the dropped resource was never closed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,656 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fcidade/monkey-lang/message"
+)
+
+// A program embedding the interpreter converts its data to and from objects
+// with FromGoValue and ToGoValue:
+//
+//   - booleans, integers, strings, byte slices and times are the objects of
+//     those types; Monkey has no floats, so they cannot be converted;
+//   - slices and arrays are arrays, maps are hashes, and structs are hashes
+//     keyed by the names of their exported fields, or by the name a
+//     `monkey:"name"` tag gives, with `monkey:"-"` leaving a field out;
+//   - pointers are the values they point to, and nil is null;
+//   - functions are builtins converting their arguments and results. A
+//     trailing error result that is not nil makes the builtin fail, and
+//     several other results come back as a tuple.
+//
+// Values that refer back to themselves, through pointers, slices or maps,
+// and objects that contain themselves cannot be converted either way, and
+// are an error rather than endless.
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// FromGoValue converts value to an object.
+func FromGoValue(value interface{}) (Object, error) {
+	return fromGoValue(reflect.ValueOf(value), map[reference]bool{})
+}
+
+// reference is a pointer, slice or map being converted: where it points, of
+// what type, and how many elements a slice has, since a slice shares its
+// address with the slices of its start.
+type reference struct {
+	pointer uintptr
+	t       reflect.Type
+	len     int
+}
+
+// fromGoValue converts v; visiting holds the pointers, slices and maps
+// being converted, so a value that refers back to itself is an error
+// instead of endless.
+func fromGoValue(v reflect.Value, visiting map[reference]bool) (Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return NULL, nil
+			}
+		}
+		return v.Interface().(Object), nil
+	}
+	if v.Type() == timeType {
+		return &Time{Value: v.Interface().(time.Time)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d does not fit in an integer", v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromGoValue(v.Elem(), visiting)
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		leave, err := enterGoValue(v, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return fromGoValue(v.Elem(), visiting)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return &Bytes{Value: append([]byte(nil), v.Bytes()...)}, nil
+		}
+		if v.Kind() == reflect.Slice {
+			leave, err := enterGoValue(v, visiting)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			element, err := fromGoValue(v.Index(i), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %s", i, err)
+			}
+			elements[i] = element
+		}
+		return &Array{Elements: elements}, nil
+
+	case reflect.Map:
+		leave, err := enterGoValue(v, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		hash := &Hash{Pairs: make(map[HashKey]HashPair, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromGoValue(iter.Key(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := fromGoValue(iter.Value(), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %s", key.Inspect(), err)
+			}
+			hash.Pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &Hash{Pairs: map[HashKey]HashPair{}}
+		for _, field := range fields(v.Type()) {
+			value, err := fromGoValue(v.Field(field.index), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", field.name, err)
+			}
+			key := &String{Value: field.name}
+			hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return builtinFromFunc(v), nil
+	}
+	return nil, fmt.Errorf("cannot convert a value of type %s", v.Type())
+}
+
+// enterGoValue records that the pointer, slice or map v is being converted,
+// returning a function forgetting it again, or an error if it already is:
+// v refers back to itself.
+func enterGoValue(v reflect.Value, visiting map[reference]bool) (func(), error) {
+	r := reference{pointer: v.Pointer(), t: v.Type()}
+	if v.Kind() == reflect.Slice {
+		r.len = v.Len()
+	}
+	if visiting[r] {
+		return nil, fmt.Errorf("%s refers back to itself", v.Type())
+	}
+	visiting[r] = true
+	return func() { delete(visiting, r) }, nil
+}
+
+type field struct {
+	name  string
+	index int
+}
+
+// fields returns the fields of the struct type t that are converted, with
+// the keys they have in a hash.
+func fields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("monkey"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, field{name, i})
+	}
+	return fields
+}
+
+// builtinFromFunc returns a builtin calling the function fn.
+func builtinFromFunc(fn reflect.Value) *Builtin {
+	t := fn.Type()
+	name := funcName(fn)
+	results := t.NumOut()
+	failable := results > 0 && t.Out(results-1) == errorType
+	if failable {
+		results--
+	}
+
+	return &Builtin{Fn: func(args ...Object) Object {
+		types := make([]string, len(args))
+		for i, arg := range args {
+			types[i] = string(arg.Type())
+		}
+		fail := func(id message.ID, a ...interface{}) Object {
+			return &Error{
+				Code:    message.Code(message.BuiltinArgument),
+				Message: message.Format(message.BuiltinArgument, name, message.Format(id, a...), strings.Join(types, ", ")),
+			}
+		}
+
+		switch {
+		case t.IsVariadic() && len(args) < t.NumIn()-1:
+			return fail(message.ArgumentCountAtLeast, t.NumIn()-1)
+		case !t.IsVariadic() && len(args) != t.NumIn():
+			return fail(message.ArgumentCount, t.NumIn())
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var param reflect.Type
+			if t.IsVariadic() && i >= t.NumIn()-1 {
+				param = t.In(t.NumIn() - 1).Elem()
+			} else {
+				param = t.In(i)
+			}
+			in[i] = reflect.New(param).Elem()
+			if err := toGoValue(arg, in[i], map[Object]bool{}); err != nil {
+				return fail(message.ArgumentType, i+1, param.String())
+			}
+		}
+
+		out := fn.Call(in)
+		if failable && !out[results].IsNil() {
+			err := out[results].Interface().(error)
+			return &Error{Code: message.Code(message.BuiltinFailed), Message: message.Format(message.BuiltinFailed, name, err)}
+		}
+		values := make([]Object, results)
+		for i := range values {
+			value, err := fromGoValue(out[i], map[reference]bool{})
+			if err != nil {
+				return &Error{Code: message.Code(message.BuiltinFailed), Message: message.Format(message.BuiltinFailed, name, err)}
+			}
+			values[i] = value
+		}
+		switch len(values) {
+		case 0:
+			return NULL
+		case 1:
+			return values[0]
+		}
+		return &Tuple{Elements: values}
+	}}
+}
+
+// funcName returns the name of the function fn without its package path,
+// such as main.greet, which errors from its builtin start with.
+func funcName(fn reflect.Value) string {
+	name := "function"
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		name = f.Name()
+	}
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// ToGoValue converts obj into what target, which has to be a pointer,
+// points to, the reverse of FromGoValue. Hash keys that name no field of a
+// struct are ignored, and null makes a pointer, slice, map or interface
+// nil. Into an empty interface obj converts to a bool, an int64, a string,
+// a []byte, a time.Time, a []interface{} or a map, of string keys when it
+// has no other; bytes keys become strings.
+func ToGoValue(obj Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("the target has to be a non-nil pointer, got %T", target)
+	}
+	return toGoValue(obj, v.Elem(), map[Object]bool{})
+}
+
+// toGoValue converts obj into v; visiting holds the arrays, tuples and
+// hashes being converted, so one that contains itself is an error instead
+// of endless.
+func toGoValue(obj Object, v reflect.Value, visiting map[Object]bool) error {
+	t := v.Type()
+	if reflect.TypeOf(obj).AssignableTo(t) && !(t.Kind() == reflect.Interface && t.NumMethod() == 0) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if _, ok := obj.(*Null); ok {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func:
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+	}
+	mismatch := func() error {
+		return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+	}
+	if t == timeType {
+		tm, ok := obj.(*Time)
+		if !ok {
+			return mismatch()
+		}
+		v.Set(reflect.ValueOf(tm.Value))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b.Value)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		if v.OverflowInt(i.Value) {
+			return fmt.Errorf("%d does not fit in %s", i.Value, t)
+		}
+		v.SetInt(i.Value)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("%d does not fit in %s", i.Value, t)
+		}
+		v.SetUint(uint64(i.Value))
+
+	case reflect.String:
+		switch obj := obj.(type) {
+		case *String:
+			v.SetString(obj.Value)
+		case *Char:
+			v.SetString(string(obj.Value))
+		default:
+			return mismatch()
+		}
+
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return mismatch()
+		}
+		value, err := plainGoValue(obj, visiting)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(t))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+
+	case reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if err := toGoValue(obj, elem.Elem(), visiting); err != nil {
+			return err
+		}
+		v.Set(elem)
+
+	case reflect.Slice:
+		if b, ok := obj.(*Bytes); ok && t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), b.Value...))
+			return nil
+		}
+		elements, ok := sequence(obj)
+		if !ok {
+			return mismatch()
+		}
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		slice := reflect.MakeSlice(t, len(elements), len(elements))
+		for i, element := range elements {
+			if err := toGoValue(element, slice.Index(i), visiting); err != nil {
+				return fmt.Errorf("element %d: %s", i, err)
+			}
+		}
+		v.Set(slice)
+
+	case reflect.Array:
+		elements, ok := sequence(obj)
+		if !ok {
+			return mismatch()
+		}
+		if len(elements) != t.Len() {
+			return fmt.Errorf("cannot convert %s of length %d to %s", obj.Type(), len(elements), t)
+		}
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		for i, element := range elements {
+			if err := toGoValue(element, v.Index(i), visiting); err != nil {
+				return fmt.Errorf("element %d: %s", i, err)
+			}
+		}
+
+	case reflect.Map:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		m := reflect.MakeMapWithSize(t, len(hash.Pairs))
+		for _, pair := range hash.SortedPairs() {
+			key := reflect.New(t.Key()).Elem()
+			if err := toGoValue(pair.Key, key, visiting); err != nil {
+				return fmt.Errorf("key %s: %s", pair.Key.Inspect(), err)
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := toGoValue(pair.Value, value, visiting); err != nil {
+				return fmt.Errorf("key %s: %s", pair.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		for _, field := range fields(t) {
+			key := &String{Value: field.name}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				continue
+			}
+			if err := toGoValue(pair.Value, v.Field(field.index), visiting); err != nil {
+				return fmt.Errorf("field %s: %s", field.name, err)
+			}
+		}
+
+	case reflect.Func:
+		builtin, ok := obj.(*Builtin)
+		if !ok {
+			// Calling a Monkey function takes an evaluator, which objects
+			// know nothing of.
+			return mismatch()
+		}
+		v.Set(funcFromBuiltin(builtin, t))
+
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// sequence returns the elements of obj if it is an array or a tuple.
+func sequence(obj Object) ([]Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+	case *Tuple:
+		return obj.Elements, true
+	}
+	return nil, false
+}
+
+// enterObject records that the array, tuple or hash obj is being converted,
+// returning a function forgetting it again, or an error if it already is:
+// obj contains itself.
+func enterObject(obj Object, visiting map[Object]bool) (func(), error) {
+	if visiting[obj] {
+		return nil, fmt.Errorf("%s contains itself", obj.Type())
+	}
+	visiting[obj] = true
+	return func() { delete(visiting, obj) }, nil
+}
+
+// plainGoValue converts obj for an empty interface, with visiting as for
+// toGoValue.
+func plainGoValue(obj Object, visiting map[Object]bool) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Null:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Char:
+		return string(obj.Value), nil
+	case *Bytes:
+		return append([]byte(nil), obj.Value...), nil
+	case *Time:
+		return obj.Value, nil
+	case *Array, *Tuple:
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		elements, _ := sequence(obj)
+		values := make([]interface{}, len(elements))
+		for i, element := range elements {
+			value, err := plainGoValue(element, visiting)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	case *Hash:
+		leave, err := enterObject(obj, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		byString := make(map[string]interface{}, len(obj.Pairs))
+		other := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			value, err := plainGoValue(pair.Value, visiting)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := pair.Key.(*String); ok {
+				byString[s.Value] = value
+			}
+			key, err := plainGoValue(pair.Key, visiting)
+			if err != nil {
+				return nil, err
+			}
+			// A []byte cannot be a map key, so bytes keys are strings.
+			if b, ok := key.([]byte); ok {
+				key = string(b)
+			}
+			if _, ok := other[key]; ok {
+				return nil, fmt.Errorf("more than one key of the hash converts to %#v", key)
+			}
+			other[key] = value
+		}
+		if len(byString) == len(obj.Pairs) {
+			return byString, nil
+		}
+		return other, nil
+	case *Builtin:
+		return obj, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+}
+
+// funcFromBuiltin returns a function of type t calling builtin. An error the
+// builtin returns comes back as the function's trailing error result, if it
+// has one, and panics otherwise.
+func funcFromBuiltin(builtin *Builtin, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		var values []reflect.Value
+		for i, arg := range in {
+			if t.IsVariadic() && i == len(in)-1 {
+				for j := 0; j < arg.Len(); j++ {
+					values = append(values, arg.Index(j))
+				}
+				continue
+			}
+			values = append(values, arg)
+		}
+		args := make([]Object, len(values))
+		for i, value := range values {
+			obj, err := fromGoValue(value, map[reference]bool{})
+			if err != nil {
+				return failWith(t, err)
+			}
+			args[i] = obj
+		}
+
+		result := builtin.Fn(args...)
+		if err, ok := result.(*Error); ok {
+			return failWith(t, fmt.Errorf("%s", err.Message))
+		}
+
+		out := make([]reflect.Value, t.NumOut())
+		results := t.NumOut()
+		if results > 0 && t.Out(results-1) == errorType {
+			results--
+			out[results] = reflect.Zero(errorType)
+		}
+		if results == 0 {
+			return out
+		}
+		elements := []Object{result}
+		if tuple, ok := result.(*Tuple); ok && results != 1 {
+			elements = tuple.Elements
+		}
+		if len(elements) != results {
+			return failWith(t, fmt.Errorf("the builtin gave %d results, want %d", len(elements), results))
+		}
+		for i := 0; i < results; i++ {
+			out[i] = reflect.New(t.Out(i)).Elem()
+			if err := toGoValue(elements[i], out[i], map[Object]bool{}); err != nil {
+				return failWith(t, err)
+			}
+		}
+		return out
+	})
+}
+
+// failWith returns the results of a function of type t failing with err:
+// zero values and err when its last result is an error.
+func failWith(t reflect.Type, err error) []reflect.Value {
+	n := t.NumOut()
+	if n == 0 || t.Out(n-1) != errorType {
+		panic(err)
+	}
+	out := make([]reflect.Value, n)
+	for i := 0; i < n-1; i++ {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	out[n-1] = reflect.ValueOf(&err).Elem()
+	return out
+}
@@ -0,0 +1,126 @@
+package object
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is what closing a resource again returns.
+var ErrClosed = errors.New("the resource is already closed")
+
+// Resource is an object backed by something the host holds for the script,
+// such as a socket or a database, which has to be closed once the script is
+// done with it. Resources embed a *Handle, which closes them.
+type Resource interface {
+	Object
+	Close() error
+	Closed() bool
+	handle() *Handle
+}
+
+// Handle closes the host resource of an object once: when the script closes
+// it, when the evaluation that opened it is torn down, or when the object is
+// collected, whichever comes first.
+type Handle struct {
+	mu      sync.Mutex
+	closer  io.Closer
+	closed  bool
+	tracker *Tracker
+}
+
+// NewHandle returns a handle closing closer.
+func NewHandle(closer io.Closer) *Handle {
+	return &Handle{closer: closer}
+}
+
+// Close closes the resource, or returns ErrClosed if it has been already.
+func (h *Handle) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return ErrClosed
+	}
+	h.closed = true
+	tracker := h.tracker
+	h.tracker = nil
+	h.mu.Unlock()
+
+	if tracker != nil {
+		tracker.forget(h)
+	}
+	return h.closer.Close()
+}
+
+// Closed reports whether the resource has been closed.
+func (h *Handle) Closed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+func (h *Handle) handle() *Handle {
+	return h
+}
+
+// Tracker keeps the resources an evaluation opened that are still open, so
+// they can be closed together when it is torn down. It holds their handles
+// rather than the objects, so that objects a script drops can still be
+// collected, their finalizers closing them. The zero value is ready to use
+// and it is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	handles map[*Handle]struct{}
+}
+
+// Track adds r to the resources t closes, unless it is closed or tracked
+// already.
+func (t *Tracker) Track(r Resource) {
+	h := r.handle()
+	h.mu.Lock()
+	if h.closed || h.tracker != nil {
+		h.mu.Unlock()
+		return
+	}
+	h.tracker = t
+	h.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handles == nil {
+		t.handles = map[*Handle]struct{}{}
+	}
+	t.handles[h] = struct{}{}
+}
+
+// Open returns how many of the resources t tracks are still open.
+func (t *Tracker) Open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.handles)
+}
+
+// Close closes every resource t tracks that is still open, returning the
+// first error closing one failed with.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	handles := make([]*Handle, 0, len(t.handles))
+	for h := range t.handles {
+		handles = append(handles, h)
+	}
+	t.mu.Unlock()
+
+	var first error
+	for _, h := range handles {
+		if err := h.Close(); err != nil && err != ErrClosed && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t *Tracker) forget(h *Handle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.handles, h)
+}
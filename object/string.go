@@ -1,9 +1,15 @@
 package object
 
-import "hash/fnv"
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
 
 type String struct {
 	Value string
+
+	// hash caches the result of HashKey. Zero means it was not computed yet.
+	hash uint64
 }
 
 var _ Object = &String{}
@@ -18,10 +24,16 @@ func (s *String) Type() ObjectType {
 }
 
 func (s *String) HashKey() HashKey {
-	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	hash := atomic.LoadUint64(&s.hash)
+	if hash == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(s.Value))
+		hash = h.Sum64()
+		atomic.StoreUint64(&s.hash, hash)
+	}
+
 	return HashKey{
 		Type:  s.Type(),
-		Value: h.Sum64(),
+		Value: hash,
 	}
 }
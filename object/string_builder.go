@@ -0,0 +1,33 @@
+package object
+
+import "strings"
+
+// StringBuilder accumulates strings in place, so building a string piece by
+// piece does not copy everything written so far on every step the way
+// repeated `+` does.
+type StringBuilder struct {
+	builder strings.Builder
+}
+
+var _ Object = &StringBuilder{}
+
+func (sb *StringBuilder) Inspect() string {
+	return `builder("` + sb.builder.String() + `")`
+}
+
+func (sb *StringBuilder) Type() ObjectType {
+	return STRING_BUILDER_OBJ
+}
+
+func (sb *StringBuilder) WriteString(s string) {
+	sb.builder.WriteString(s)
+}
+
+func (sb *StringBuilder) String() string {
+	return sb.builder.String()
+}
+
+// Len returns how many bytes have been written to sb.
+func (sb *StringBuilder) Len() int {
+	return sb.builder.Len()
+}
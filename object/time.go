@@ -0,0 +1,18 @@
+package object
+
+import "time"
+
+// Time is an instant with the location it is shown in.
+type Time struct {
+	Value time.Time
+}
+
+var _ Object = &Time{}
+
+func (t *Time) Inspect() string {
+	return t.Value.Format(time.RFC3339Nano)
+}
+
+func (t *Time) Type() ObjectType {
+	return TIME_OBJ
+}
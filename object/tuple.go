@@ -0,0 +1,17 @@
+package object
+
+// Tuple is a fixed sequence of values, such as the several results of a
+// function. Unlike an array it cannot be modified.
+type Tuple struct {
+	Elements []Object
+}
+
+var _ Object = &Tuple{}
+
+func (t *Tuple) Inspect() string {
+	return inspect(t, map[Object]bool{})
+}
+
+func (t *Tuple) Type() ObjectType {
+	return TUPLE_OBJ
+}
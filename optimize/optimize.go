@@ -0,0 +1,280 @@
+// Package optimize rewrites programs into simpler ones that evaluate to the
+// same result, so the evaluator has less to do at run time.
+//
+// There are two levels. Basic folds operators applied to literals, replaces
+// an if with a constant condition by the branch it takes and drops the
+// statements after a return. Full also substitutes the value of a let bound
+// to a constant wherever the name is used, and removes lets whose names are
+// never used.
+package optimize
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// The optimization levels, from leaving the program alone to applying every
+// pass.
+const (
+	None  = 0
+	Basic = 1
+	Full  = 2
+)
+
+// Program optimizes program at level, rewriting it in place, and returns it.
+// Operators are folded by the evaluator itself, so an expression that would
+// fail, such as 1 / 0, is left for the run to report.
+func Program(program *ast.Program, level int) *ast.Program {
+	if level <= None {
+		return program
+	}
+
+	o := &optimizer{
+		ev:  evaluator.New(evaluator.Config{Sandboxed: true}),
+		env: object.NewEnvironment(),
+	}
+	// Names looked up at run time, as eval does, cannot be told apart from
+	// unused ones, so such programs get only the basic passes.
+	if level >= Full && !dynamic(program) {
+		o.bindings = countBindings(program)
+		o.scopes = []map[string]ast.Expression{{}}
+	}
+
+	program.Statements = o.statements(program.Statements, true)
+	if o.bindings != nil {
+		for removeUnused(program) {
+		}
+	}
+	return program
+}
+
+type optimizer struct {
+	ev  *evaluator.Evaluator
+	env *object.Environment
+
+	// bindings counts the places each name is bound; it is nil when constants
+	// are not propagated.
+	bindings map[string]int
+
+	// scopes holds, for the function bodies being optimized, the literals
+	// names are bound to so far. The first is the program's.
+	scopes []map[string]ast.Expression
+}
+
+// statements optimizes stmts, dropping those after a return. A let is only
+// propagated when it is certain to run, that is when stmts are those of the
+// program or a function body rather than of a branch.
+func (o *optimizer) statements(stmts []ast.Statement, certain bool) []ast.Statement {
+	optimized := make([]ast.Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		optimized = append(optimized, o.statement(stmt, certain))
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			break
+		}
+	}
+	return optimized
+}
+
+func (o *optimizer) statement(stmt ast.Statement, certain bool) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Value == nil {
+			return stmt
+		}
+		stmt.Value = o.expression(stmt.Value)
+		if certain && o.bindings != nil && stmt.Name != nil && stmt.Pattern == nil && o.bindings[stmt.Name.Value] == 1 {
+			if constant(stmt.Value) {
+				o.scopes[len(o.scopes)-1][stmt.Name.Value] = stmt.Value
+			}
+		}
+	case *ast.ReturnStatement:
+		if stmt.ReturnValue != nil {
+			stmt.ReturnValue = o.expression(stmt.ReturnValue)
+		}
+	case *ast.ExpressionStatement:
+		if stmt.Expression != nil {
+			stmt.Expression = o.expression(stmt.Expression)
+		}
+	case *ast.BlockStatement:
+		o.block(stmt, certain)
+	}
+	return stmt
+}
+
+func (o *optimizer) block(block *ast.BlockStatement, certain bool) {
+	if block != nil {
+		block.Statements = o.statements(block.Statements, certain)
+	}
+}
+
+func (o *optimizer) expressions(exprs []ast.Expression) {
+	for i, expr := range exprs {
+		exprs[i] = o.expression(expr)
+	}
+}
+
+func (o *optimizer) expression(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		if o.bindings != nil {
+			for i := len(o.scopes) - 1; i >= 0; i-- {
+				if value, ok := o.scopes[i][expr.Value]; ok {
+					return copyLiteral(value)
+				}
+			}
+		}
+
+	case *ast.PrefixExpression:
+		expr.Right = o.expression(expr.Right)
+		return o.fold(expr, expr.Right)
+
+	case *ast.InfixExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Right = o.expression(expr.Right)
+		return o.fold(expr, expr.Left, expr.Right)
+
+	case *ast.ComparisonChain:
+		o.expressions(expr.Operands)
+		return o.fold(expr, expr.Operands...)
+
+	case *ast.IfExpression:
+		expr.Condition = o.expression(expr.Condition)
+		o.block(expr.Consequence, false)
+		o.block(expr.Alternative, false)
+		return branch(expr)
+
+	case *ast.FunctionLiteral:
+		if o.bindings != nil {
+			o.scopes = append(o.scopes, map[string]ast.Expression{})
+			defer func() { o.scopes = o.scopes[:len(o.scopes)-1] }()
+		}
+		o.block(expr.Body, true)
+
+	case *ast.CallExpression:
+		expr.Function = o.expression(expr.Function)
+		o.expressions(expr.Arguments)
+
+	case *ast.ArrayLiteral:
+		o.expressions(expr.Elements)
+
+	case *ast.TupleLiteral:
+		o.expressions(expr.Elements)
+
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(expr.Pairs))
+		for key, value := range expr.Pairs {
+			pairs[o.expression(key)] = o.expression(value)
+		}
+		expr.Pairs = pairs
+
+	case *ast.ArrayComprehension:
+		expr.Element = o.expression(expr.Element)
+		o.clause(expr.Clause)
+
+	case *ast.HashComprehension:
+		expr.Key = o.expression(expr.Key)
+		expr.Value = o.expression(expr.Value)
+		o.clause(expr.Clause)
+
+	case *ast.MatchExpression:
+		expr.Subject = o.expression(expr.Subject)
+		for _, arm := range expr.Arms {
+			if arm.Guard != nil {
+				arm.Guard = o.expression(arm.Guard)
+			}
+			o.block(arm.Body, false)
+		}
+
+	case *ast.FieldExpression:
+		expr.Left = o.expression(expr.Left)
+
+	case *ast.IndexExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Index = o.expression(expr.Index)
+	}
+	return expr
+}
+
+func (o *optimizer) clause(clause *ast.ComprehensionClause) {
+	if clause == nil {
+		return
+	}
+	clause.Iterable = o.expression(clause.Iterable)
+	if clause.Condition != nil {
+		clause.Condition = o.expression(clause.Condition)
+	}
+}
+
+// fold evaluates expr when its operands are all literals and returns the
+// result as a literal, or expr itself when it cannot.
+func (o *optimizer) fold(expr ast.Expression, operands ...ast.Expression) ast.Expression {
+	for _, operand := range operands {
+		if !constant(operand) {
+			return expr
+		}
+	}
+	if folded := evaluator.Literal(o.ev.Eval(expr, o.env)); folded != nil {
+		return folded
+	}
+	return expr
+}
+
+// branch returns the branch ie takes when its condition is a literal, or ie
+// itself when it is not.
+func branch(ie *ast.IfExpression) ast.Expression {
+	if !constant(ie.Condition) {
+		return ie
+	}
+
+	taken := ie.Consequence
+	if b, ok := ie.Condition.(*ast.Boolean); ok && !b.Value {
+		taken = ie.Alternative
+	}
+	if taken != nil && len(taken.Statements) == 1 {
+		if stmt, ok := taken.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression
+		}
+	}
+
+	// Otherwise the if stays, so that the lets of the branch it takes are
+	// still bound in the enclosing scope, but the other branch goes.
+	if taken == nil {
+		ie.Condition = evaluator.Literal(&object.Boolean{Value: false})
+		ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token}
+	} else {
+		ie.Condition = evaluator.Literal(&object.Boolean{Value: true})
+		ie.Consequence = taken
+	}
+	ie.Alternative = nil
+	return ie
+}
+
+// constant reports whether expr is a literal fold can work with.
+func constant(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.StringLiteral, *ast.CharLiteral:
+		return true
+	}
+	return false
+}
+
+// copyLiteral returns a copy of the literal expr, so that a propagated
+// constant does not share its node with the let it came from.
+func copyLiteral(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		c := *expr
+		return &c
+	case *ast.Boolean:
+		c := *expr
+		return &c
+	case *ast.StringLiteral:
+		c := *expr
+		return &c
+	case *ast.CharLiteral:
+		c := *expr
+		return &c
+	}
+	return expr
+}
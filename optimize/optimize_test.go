@@ -0,0 +1,83 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func TestProgram(t *testing.T) {
+	tests := []struct {
+		input    string
+		level    int
+		expected string
+	}{
+		{"1 + 2 * 3", None, "(1 + (2 * 3))"},
+		{"1 + 2 * 3", Basic, "7"},
+		{"-(2 - 5)", Basic, "3"},
+		{`"mon" + "key"`, Basic, "monkey"},
+		{"1 < 2 == true", Basic, "true"},
+		{"1 < 2 < 3", Basic, "true"},
+		{"x + 2 * 3", Basic, "(x + 6)"},
+		{"1 / 0", Basic, "(1 / 0)"},
+		{"1..3", Basic, "(1 .. 3)"},
+		{"if (1 < 2) { 10 } else { 20 }", Basic, "10"},
+		{"if (1 > 2) { 10 } else { 20 }", Basic, "20"},
+		{"if (false) { 10 }", Basic, "if false {  }"},
+		{"if (true) { let a = 1; a }", Basic, "if true { let a = 1;a }"},
+		{"fn() { return 1; puts(2); }", Basic, "fn () { return 1; }"},
+		{"let x = 2; let y = x * 3; puts(y)", Basic, "let x = 2;let y = (x * 3);puts(y)"},
+		{"let x = 2; let y = x * 3; puts(y)", Full, "puts(6)"},
+		{"let x = 2; let f = fn(y) { x + y }; f(1)", Full, "let f = fn (y) { (2 + y) };f(1)"},
+		{"let x = 2; let x = 3; x", Full, "let x = 2;let x = 3;x"},
+		{"let f = fn(x) { x }; let x = 1; f(x)", Full, "let f = fn (x) { x };let x = 1;f(x)"},
+		{"if (c) { let x = 1; }; x", Full, "if c { let x = 1; }x"},
+		{"let unused = fn() { 1 }; puts(1)", Full, "puts(1)"},
+		{"let kept = puts(1); 2", Full, "let kept = puts(1);2"},
+		{"let x = 1; x", Full, "1"},
+		{`let x = 1; eval("x")`, Full, `let x = 1;eval(x)`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+		}
+
+		got := Program(program, tt.level).String()
+		if got != tt.expected {
+			t.Errorf("Program(%q, %d) = %q, want %q", tt.input, tt.level, got, tt.expected)
+		}
+	}
+}
+
+// TestSameResult checks that optimized programs evaluate to what the
+// programs they came from do.
+func TestSameResult(t *testing.T) {
+	inputs := []string{
+		"let x = 10; let double = fn(n) { n * 2 }; double(x) + 1",
+		"let limit = 3; [i * limit for i in 1..limit if i > 1]",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"let name = `monkey`; {name: len(name)}",
+		"let a = 9223372036854775807; a + 1",
+		"1 % 0",
+		"if (0) { 1 } else { 2 }",
+		"let t = (1, 2); let (a, b) = t; a - b",
+		"len(1)",
+	}
+
+	for _, input := range inputs {
+		ev := evaluator.New(evaluator.Config{})
+		want := ev.Eval(parser.New(lexer.New(input)).ParseProgram(), ev.NewEnvironment())
+		for level := Basic; level <= Full; level++ {
+			program := Program(parser.New(lexer.New(input)).ParseProgram(), level)
+			got := ev.Eval(program, ev.NewEnvironment())
+			if got.Inspect() != want.Inspect() {
+				t.Errorf("%q at level %d evaluated to %s, want %s", input, level, got.Inspect(), want.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package optimize
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+// dynamic reports whether program refers to a builtin that runs code only
+// known at run time.
+func dynamic(program *ast.Program) bool {
+	found := false
+	ast.Inspect(program, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Identifier); ok {
+			if capability, ok := evaluator.BuiltinCapability(id.Value); ok && capability == evaluator.Dynamic {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// bindingSites returns the identifiers that bind a name rather than refer to
+// one: those of lets, parameters and patterns.
+func bindingSites(program *ast.Program) map[*ast.Identifier]bool {
+	sites := map[*ast.Identifier]bool{}
+	pattern := func(pattern ast.Pattern) {
+		ast.Inspect(pattern, func(node ast.Node) bool {
+			if id, ok := node.(*ast.Identifier); ok {
+				sites[id] = true
+			}
+			return true
+		})
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Name != nil {
+				sites[node.Name] = true
+			}
+			pattern(node.Pattern)
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				sites[param] = true
+			}
+		case *ast.ArrayComprehension:
+			if node.Clause != nil {
+				pattern(node.Clause.Pattern)
+			}
+		case *ast.HashComprehension:
+			if node.Clause != nil {
+				pattern(node.Clause.Pattern)
+			}
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				pattern(arm.Pattern)
+			}
+		}
+		return true
+	})
+	return sites
+}
+
+// countBindings counts the places each name is bound in program.
+func countBindings(program *ast.Program) map[string]int {
+	counts := map[string]int{}
+	for id := range bindingSites(program) {
+		counts[id.Value]++
+	}
+	return counts
+}
+
+// countReferences counts the places each name is referred to in program,
+// wherever they are: a name used anywhere keeps every let of it.
+func countReferences(program *ast.Program) map[string]int {
+	sites := bindingSites(program)
+	counts := map[string]int{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Identifier); ok && !sites[id] {
+			counts[id.Value]++
+		}
+		return true
+	})
+	return counts
+}
+
+// removeUnused removes the lets of program that bind a name nothing refers
+// to a value that cannot fail, and reports whether it removed any. The last
+// statement of a block is kept, as it is the block's value.
+func removeUnused(program *ast.Program) bool {
+	references := countReferences(program)
+	removed := false
+	filter := func(stmts []ast.Statement) []ast.Statement {
+		kept := stmts[:0]
+		for i, stmt := range stmts {
+			let, ok := stmt.(*ast.LetStatement)
+			if ok && i < len(stmts)-1 && let.Name != nil && let.Pattern == nil && references[let.Name.Value] == 0 && pure(let.Value) {
+				removed = true
+				continue
+			}
+			kept = append(kept, stmt)
+		}
+		return kept
+	}
+
+	program.Statements = filter(program.Statements)
+	ast.Inspect(program, func(node ast.Node) bool {
+		if block, ok := node.(*ast.BlockStatement); ok {
+			block.Statements = filter(block.Statements)
+		}
+		return true
+	})
+	return removed
+}
+
+// pure reports whether evaluating expr can neither fail nor have an effect.
+func pure(expr ast.Expression) bool {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.StringLiteral, *ast.CharLiteral, *ast.BytesLiteral, *ast.FunctionLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		return allPure(expr.Elements)
+	case *ast.TupleLiteral:
+		return allPure(expr.Elements)
+	}
+	return false
+}
+
+func allPure(exprs []ast.Expression) bool {
+	for _, expr := range exprs {
+		if !pure(expr) {
+			return false
+		}
+	}
+	return true
+}
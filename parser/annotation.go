@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// parseOptionalAnnotation parses `: type` into *annotation when the next
+// token is a colon. It reports false if the annotation is malformed.
+func (p *Parser) parseOptionalAnnotation(annotation **ast.TypeAnnotation) bool {
+	if !p.peekTokenIs(token.COLON) {
+		return true
+	}
+	p.nextToken()
+	p.nextToken()
+
+	// `fn` is a keyword, but it is also the name of the function type.
+	if !p.curTokenIs(token.IDENTIFIER) && !p.curTokenIs(token.FUNCTION) {
+		p.errorf(p.curToken, message.ExpectedType, p.curToken.Type)
+		return false
+	}
+	*annotation = &ast.TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+	return true
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// parseArrayComprehension parses the rest of `[element for ... ]` once the
+// element has been parsed and `for` is the next token.
+func (p *Parser) parseArrayComprehension(tok token.Token, element ast.Expression) ast.Expression {
+	clause := p.parseComprehensionClause()
+	if clause == nil || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return &ast.ArrayComprehension{Token: tok, Element: element, Clause: clause}
+}
+
+// parseHashComprehension parses the rest of `{key: value for ... }`.
+func (p *Parser) parseHashComprehension(tok token.Token, key, value ast.Expression) ast.Expression {
+	clause := p.parseComprehensionClause()
+	if clause == nil || !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return &ast.HashComprehension{Token: tok, Key: key, Value: value, Clause: clause}
+}
+
+// parseComprehensionClause parses `for pattern in iterable`, optionally
+// followed by `if condition`.
+func (p *Parser) parseComprehensionClause() *ast.ComprehensionClause {
+	p.nextToken()
+	clause := &ast.ComprehensionClause{Token: p.curToken}
+
+	p.nextToken()
+	clause.Pattern = p.parsePattern()
+	if clause.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.COMMA) {
+		clause.Pattern = p.parseBarePatternList(clause.Pattern)
+		if clause.Pattern == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	clause.Iterable = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		clause.Condition = p.parseExpression(LOWEST)
+	}
+	return clause
+}
@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// LatestVersion is the language version programs are parsed as unless the
+// parser is configured otherwise. Version 1 is the language of the book,
+// Writing An Interpreter In Go; every later version adds features to it.
+const LatestVersion = 2
+
+// Feature names syntax added to the language after version 1.
+type Feature string
+
+const (
+	FeatureMatch          Feature = "match"
+	FeatureComprehensions Feature = "comprehensions"
+	FeatureFieldAccess    Feature = "field-access"
+	FeatureNullish        Feature = "nullish"
+	FeatureRanges         Feature = "ranges"
+	FeatureLambdas        Feature = "lambdas"
+	FeatureModulo         Feature = "modulo"
+	FeatureDurations      Feature = "durations"
+	FeatureBytes          Feature = "bytes"
+	FeatureChars          Feature = "chars"
+	FeatureRestPatterns   Feature = "rest-patterns"
+)
+
+// featureVersions holds the language version each feature is part of. A
+// feature that is not ready to be on by default gets a version above
+// LatestVersion, so it can only be turned on by name.
+var featureVersions = map[Feature]int{
+	FeatureMatch:          2,
+	FeatureComprehensions: 2,
+	FeatureFieldAccess:    2,
+	FeatureNullish:        2,
+	FeatureRanges:         2,
+	FeatureLambdas:        2,
+	FeatureModulo:         2,
+	FeatureDurations:      2,
+	FeatureBytes:          2,
+	FeatureChars:          2,
+	FeatureRestPatterns:   2,
+}
+
+// featureTokens maps the tokens only a feature uses to that feature.
+var featureTokens = map[token.TokenType]Feature{
+	token.MATCH:        FeatureMatch,
+	token.ARROW:        FeatureMatch,
+	token.FOR:          FeatureComprehensions,
+	token.IN:           FeatureComprehensions,
+	token.DOT:          FeatureFieldAccess,
+	token.QUESTION_DOT: FeatureFieldAccess,
+	token.NULLISH:      FeatureNullish,
+	token.DOT_DOT:      FeatureRanges,
+	token.PIPE:         FeatureLambdas,
+	token.PERCENT:      FeatureModulo,
+	token.DURATION:     FeatureDurations,
+	token.BYTES:        FeatureBytes,
+	token.CHAR:         FeatureChars,
+	token.ELLIPSIS:     FeatureRestPatterns,
+}
+
+// Features returns every feature, sorted.
+func Features() []Feature {
+	features := make([]Feature, 0, len(featureVersions))
+	for feature := range featureVersions {
+		features = append(features, feature)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return features
+}
+
+// FeatureVersion returns the language version feature is part of, and
+// whether there is such a feature.
+func FeatureVersion(feature Feature) (int, bool) {
+	version, ok := featureVersions[feature]
+	return version, ok
+}
+
+// Config selects the language a parser accepts: that of Version,
+// LatestVersion when it is 0, along with the Features named, whichever
+// version they are part of.
+type Config struct {
+	Version  int
+	Features []Feature
+}
+
+// Enabled reports whether programs parsed with c may use feature.
+func (c Config) Enabled(feature Feature) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	version := c.Version
+	if version == 0 {
+		version = LatestVersion
+	}
+	return featureVersions[feature] <= version
+}
+
+// gate checks tok against the features the parser has enabled. The keywords
+// of a feature that is off are plain identifiers, as they were in the
+// versions before it, and other tokens of such a feature are an error.
+func (p *Parser) gate(tok token.Token) token.Token {
+	feature, ok := featureTokens[tok.Type]
+	if !ok || p.config.Enabled(feature) {
+		return tok
+	}
+	if token.IsKeyword(tok.Literal) {
+		tok.Type = token.IDENTIFIER
+		return tok
+	}
+	source := tok.Literal
+	switch tok.Type {
+	case token.CHAR:
+		source = "'" + source + "'"
+	case token.BYTES:
+		source = "b" + ast.Quote(source)
+	}
+	p.errorf(tok, message.FeatureDisabled, source, feature, featureVersions[feature])
+	return tok
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// lookahead is the most bytes the lexer reads past the end of a token to
+// tell where it ends.
+const lookahead = 3
+
+// Incremental parses successive versions of a source, such as a file being
+// edited, parsing again only the top-level statements an edit touched.
+//
+// Every top-level statement is kept with the span of source it was parsed
+// from. Those before the edit, up to the token that ended them, are reused
+// as they are, and those after it as soon as the statements parsed again
+// reach one of them. Statements that had errors are always parsed again, so
+// the errors come out as a full parse would report them.
+//
+// A statement reused after the edit is moved to its new position in place,
+// which moves it in the program the previous version gave too; only the
+// program of the latest version is to be used.
+type Incremental struct {
+	config     Config
+	src        string
+	statements []span
+}
+
+// span is a top-level statement and where it was parsed from.
+type span struct {
+	stmt        ast.Statement
+	diagnostics []diagnostic.Diagnostic
+
+	// start is the offset of the statement's first token, at line and
+	// column, and end the offset just past the token after its last, which
+	// the parser looked at to know the statement had ended.
+	start, end   int
+	line, column int
+
+	// ownLine is set when the statement starts on a later line than the
+	// statement before it ends, so that no part of it was parsed on a line
+	// the statement before reported an ILLEGAL token on.
+	ownLine bool
+}
+
+// NewIncremental returns an incremental parser for the language config
+// selects, with no version parsed yet.
+func NewIncremental(config Config) *Incremental {
+	return &Incremental{config: config}
+}
+
+// Parse parses src, the new version of the source, and returns its program
+// and the errors found in it, reusing what it can of the last version.
+func (inc *Incremental) Parse(src string) (*ast.Program, []diagnostic.Diagnostic) {
+	prefix, suffix := commonAffixes(inc.src, src)
+	old, delta := inc.statements, len(src)-len(inc.src)
+
+	var statements []span
+	for _, s := range old {
+		if len(s.diagnostics) > 0 || s.end+lookahead > prefix {
+			break
+		}
+		statements = append(statements, s)
+	}
+
+	start, line, column, ownLine := 0, 1, 1, true
+	if n := len(statements); n > 0 {
+		start, line, column, ownLine = old[n].start, old[n].line, old[n].column, old[n].ownLine
+	}
+
+	lines := lineStarts(src)
+	p := NewWithConfig(lexer.NewAt(src, start, line, column), inc.config)
+	// ILLEGAL tokens before the first statement are reported with it, and a
+	// statement reported with them cannot be reused once they are gone.
+	errors := 0
+	ownLine = ownLine && len(p.errors) == 0
+	for !p.curTokenIs(token.EOF) {
+		s := span{
+			start:   offset(lines, p.curToken),
+			line:    p.curToken.Line,
+			column:  p.curToken.Column,
+			ownLine: ownLine,
+		}
+		s.stmt = p.parseStatement()
+		s.end = p.l.Offset()
+		ownLine = p.peekToken.Line > p.curToken.Line
+		p.nextToken()
+		s.diagnostics = append([]diagnostic.Diagnostic(nil), p.errors[errors:]...)
+		errors = len(p.errors)
+		statements = append(statements, s)
+
+		if !ownLine || p.curTokenIs(token.EOF) {
+			continue
+		}
+		if next := offset(lines, p.curToken); next >= len(src)-suffix {
+			if i, ok := find(old, next-delta); ok && old[i].ownLine {
+				statements = append(statements, move(old[i:], delta, p.curToken)...)
+				break
+			}
+		}
+	}
+
+	inc.src, inc.statements = src, statements
+
+	program := &ast.Program{Statements: make([]ast.Statement, len(statements))}
+	diagnostics := []diagnostic.Diagnostic{}
+	for i, s := range statements {
+		program.Statements[i] = s.stmt
+		diagnostics = append(diagnostics, s.diagnostics...)
+	}
+	diagnostics = append(diagnostics, p.errors[errors:]...)
+	return program, diagnostics
+}
+
+// commonAffixes returns the lengths of the longest prefix and suffix a and b
+// share, the suffix cut short so that the two do not overlap in either.
+func commonAffixes(a, b string) (prefix, suffix int) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for prefix < n && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < n-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+// lineStarts returns the offset each line of src starts at.
+func lineStarts(src string) []int {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// offset returns the offset of tok in the source lines are the starts of.
+func offset(lines []int, tok token.Token) int {
+	return lines[tok.Line-1] + tok.Column - 1
+}
+
+// find returns the index of the statement of spans that starts at offset.
+func find(spans []span, offset int) (int, bool) {
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].start >= offset })
+	return i, i < len(spans) && spans[i].start == offset
+}
+
+// move moves spans, the statements from the one that now starts at first,
+// delta bytes along the source to where they are in the new version.
+func move(spans []span, delta int, first token.Token) []span {
+	from := spans[0].line
+	lines, columns := first.Line-from, first.Column-spans[0].column
+	position := func(line, column *int) {
+		if *line == from {
+			*column += columns
+		}
+		*line += lines
+	}
+
+	moved := make([]span, len(spans))
+	seen := map[uintptr]bool{}
+	for i, s := range spans {
+		// An edit within a line leaves the statements after that line where
+		// they were.
+		if lines != 0 || columns != 0 {
+			if s.stmt != nil {
+				shift(reflect.ValueOf(s.stmt), position, seen)
+			}
+			s.diagnostics = append([]diagnostic.Diagnostic(nil), s.diagnostics...)
+			for j := range s.diagnostics {
+				position(&s.diagnostics[j].Line, &s.diagnostics[j].Column)
+			}
+		}
+		s.start += delta
+		s.end += delta
+		position(&s.line, &s.column)
+		moved[i] = s
+	}
+	return moved
+}
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// shift calls position with the line and column of every token in the tree
+// v is part of. Tokens the parser made up rather than read, which have no
+// position, are left alone.
+func shift(v reflect.Value, position func(line, column *int), seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		shift(v.Elem(), position, seen)
+	case reflect.Interface:
+		if !v.IsNil() {
+			shift(v.Elem(), position, seen)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			shift(v.Index(i), position, seen)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			shift(iter.Key(), position, seen)
+			shift(iter.Value(), position, seen)
+		}
+	case reflect.Struct:
+		if v.Type() == tokenType && v.CanAddr() {
+			tok := v.Addr().Interface().(*token.Token)
+			if tok.Line > 0 {
+				position(&tok.Line, &tok.Column)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			shift(v.Field(i), position, seen)
+		}
+	}
+}
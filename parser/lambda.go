@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// parseLambda parses the shorthand `|x, y| body`, whose body is a single
+// expression, into the same FunctionLiteral that `fn(x, y) { body }` yields.
+func (p *Parser) parseLambda() ast.Expression {
+	lit := &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+		Parameters: []*ast.Identifier{},
+	}
+
+	for !p.peekTokenIs(token.PIPE) {
+		if len(lit.Parameters) > 0 {
+			if !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			if p.peekTokenIs(token.PIPE) {
+				break
+			}
+		}
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.parseOptionalAnnotation(&param.Type) {
+			return nil
+		}
+		lit.Parameters = append(lit.Parameters, param)
+	}
+	p.nextToken()
+
+	p.nextToken()
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+	lit.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+
+	return lit
+}
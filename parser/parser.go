@@ -1,19 +1,25 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
 	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/message"
 	"github.com/fcidade/monkey-lang/token"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	NULLISH     // ??
 	EQUALS      // ==
 	LESSGREATER // < >
+	RANGE       // ..
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -x OR !x
@@ -30,8 +36,14 @@ var precedences = map[token.TokenType]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.DOT_DOT:  RANGE,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+
+	token.NULLISH:      NULLISH,
+	token.DOT:          INDEX,
+	token.QUESTION_DOT: INDEX,
 }
 
 type (
@@ -41,25 +53,46 @@ type (
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []diagnostic.Diagnostic
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// illegalLines are the lines an ILLEGAL token was reported on. Other
+	// errors on them are dropped, as they follow from the same mistake.
+	illegalLines map[int]bool
+
+	// comments collects the comments of the tokens read for the statement
+	// being parsed, past its first, when the lexer keeps trivia.
+	comments []string
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	config Config
 }
 
+// New returns a parser for the latest version of the language.
 func New(l *lexer.Lexer) *Parser {
+	return NewWithConfig(l, Config{})
+}
+
+// NewWithConfig returns a parser for the language config selects.
+func NewWithConfig(l *lexer.Lexer, config Config) *Parser {
 	p := &Parser{
 		l:              l,
-		errors:         []string{},
+		config:         config,
+		errors:         []diagnostic.Diagnostic{},
+		illegalLines:   make(map[int]bool),
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
 		infixParseFns:  make(map[token.TokenType]infixParseFn),
 	}
 	p.registerPrefix(token.IDENTIFIER, p.parseIdentifier)
 	p.registerPrefix(token.STRING, p.parseString)
+	p.registerPrefix(token.BYTES, p.parseBytes)
+	p.registerPrefix(token.CHAR, p.parseChar)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.DURATION, p.parseDurationLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
@@ -67,16 +100,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.PIPE, p.parseLambda)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.registerInfix(token.EQ, p.parseInfixExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.DOT, p.parseFieldExpression)
+	p.registerInfix(token.QUESTION_DOT, p.parseOptionalAccess)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
-	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT, p.parseComparison)
+	p.registerInfix(token.GT, p.parseComparison)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.DOT_DOT, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
@@ -109,21 +149,73 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+// Errors returns the messages of the errors found while parsing.
 func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.Message
+	}
+	return messages
+}
+
+// Diagnostics returns the errors found while parsing with their codes.
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
 	return p.errors
 }
 
+// errorf records an error at tok.
+func (p *Parser) errorf(tok token.Token, id message.ID, a ...interface{}) {
+	if p.illegalLines[tok.Line] {
+		return
+	}
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Code:    message.Code(id),
+		Message: message.Format(id, a...),
+	})
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if t == token.IDENTIFIER && token.IsKeyword(p.peekToken.Literal) {
+		p.errorf(p.peekToken, message.ReservedWord, p.peekToken.Literal)
+		return
+	}
+	p.errorf(p.peekToken, message.ExpectedNextToken, t, p.peekToken.Type)
 }
 
+// nextToken advances to the next token, reporting and skipping any ILLEGAL
+// ones so the rest of the parser never sees them.
 func (p *Parser) nextToken() {
+	p.collect(&p.curToken)
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekTokenIs(token.ILLEGAL) {
+		p.illegalError(p.peekToken)
+		p.peekToken = p.l.NextToken()
+	}
+	p.peekToken = p.gate(p.peekToken)
+}
+
+func (p *Parser) illegalError(tok token.Token) {
+	if quote := strings.TrimPrefix(tok.Literal, "b"); strings.HasPrefix(quote, `"`) || strings.HasPrefix(quote, "`") {
+		p.errorf(tok, message.UnterminatedString, tok.Line, tok.Column)
+	} else if strings.HasPrefix(tok.Literal, "'") {
+		p.errorf(tok, message.InvalidCharacter, tok.Literal, tok.Line, tok.Column)
+	} else {
+		p.errorf(tok, message.UnexpectedIllegal, tok.Literal, tok.Line, tok.Column)
+	}
+
+	// An unterminated string runs to the end of the input, so the lines it
+	// spans are all part of the mistake.
+	for line := tok.Line; line <= tok.Line+strings.Count(tok.Literal, "\n"); line++ {
+		p.illegalLines[line] = true
+	}
 }
 
+// ParseProgram parses the whole input. It always returns a program, however
+// malformed the input, and reports what is wrong with it in Errors rather
+// than panicking; parts it could not parse are left nil in the tree.
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 
@@ -134,18 +226,103 @@ func (p *Parser) ParseProgram() *ast.Program {
 		program.Statements = append(program.Statements, stmt)
 		p.nextToken()
 	}
+	program.End = p.leadingComments()
 
 	return program
 }
 
+// ParseExpressionOnly parses src as a single expression, which may be
+// followed by a semicolon but nothing else, with the latest language.
+func ParseExpressionOnly(src string) (ast.Expression, []string) {
+	p := New(lexer.New(src))
+	expr := p.ParseExpressionOnly()
+	return expr, p.Errors()
+}
+
+// ParseExpressionOnly parses the input as a single expression, which may be
+// followed by a semicolon, reporting anything after it in Errors.
+func (p *Parser) ParseExpressionOnly() ast.Expression {
+	expr := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.peekTokenIs(token.EOF) {
+		p.errorf(p.peekToken, message.TrailingTokens, p.peekToken.Literal)
+	}
+	return expr
+}
+
+// parseStatement parses the statement starting at the current token, along
+// with its comments.
 func (p *Parser) parseStatement() ast.Statement {
+	comments := p.leadingComments()
+	outer := p.comments
+	p.comments = nil
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	p.collect(&p.curToken)
+	if len(p.comments) > 0 {
+		if comments == nil {
+			comments = &ast.Comments{}
+		}
+		comments.Trailing = p.comments
+	}
+	p.comments = outer
+	if comments != nil {
+		setComments(stmt, comments)
+	}
+	return stmt
+}
+
+// leadingComments takes the comments before the current token, returning
+// nil when there are none.
+func (p *Parser) leadingComments() *ast.Comments {
+	trivia := p.curToken.Trivia
+	if trivia == nil || (len(trivia.Leading) == 0 && trivia.Blank == 0) {
+		return nil
+	}
+	p.curToken.Trivia = &token.Trivia{Trailing: trivia.Trailing}
+	return &ast.Comments{Leading: trivia.Leading, Blank: trivia.Blank}
+}
+
+// collect adds the comments of tok to those of the statement being parsed,
+// and takes them off tok so they are not collected twice.
+func (p *Parser) collect(tok *token.Token) {
+	if tok.Trivia == nil {
+		return
+	}
+	for _, comment := range tok.Trivia.Leading {
+		p.comments = append(p.comments, comment.Text)
+	}
+	if tok.Trivia.Trailing != "" {
+		p.comments = append(p.comments, tok.Trivia.Trailing)
+	}
+	tok.Trivia = nil
+}
+
+func setComments(stmt ast.Statement, comments *ast.Comments) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt != nil {
+			stmt.Comments = comments
+		}
+	case *ast.ReturnStatement:
+		if stmt != nil {
+			stmt.Comments = comments
+		}
+	case *ast.ExpressionStatement:
+		if stmt != nil {
+			stmt.Comments = comments
+		}
 	}
 }
 
@@ -156,6 +333,20 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
+	// `return a, b` returns the tuple (a, b).
+	if p.peekTokenIs(token.COMMA) {
+		tuple := &ast.TupleLiteral{
+			Token:    token.Token{Type: token.LPAREN, Literal: "("},
+			Elements: []ast.Expression{stmt.ReturnValue},
+		}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+		stmt.ReturnValue = tuple
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -166,11 +357,30 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENTIFIER) {
-		return nil
-	}
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.parseOptionalAnnotation(&stmt.Name.Type) {
+			return nil
+		}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		// `let a, b = t` destructures the tuple t.
+		if p.peekTokenIs(token.COMMA) {
+			stmt.Pattern = p.parseBarePatternList(stmt.Name)
+			if stmt.Pattern == nil {
+				return nil
+			}
+			stmt.Name = nil
+		}
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -206,7 +416,7 @@ func (p *Parser) parseExpression(procedence int) ast.Expression {
 	}
 	leftExp := prefix()
 
-	for !p.peekTokenIs(token.SEMICOLON) && procedence < p.peekPrecedence() {
+	for !p.peekTokenIs(token.SEMICOLON) && !p.peekStartsStatement() && procedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -219,6 +429,24 @@ func (p *Parser) parseExpression(procedence int) ast.Expression {
 	return leftExp
 }
 
+// peekStartsStatement reports whether a line break ends the statement
+// before the peek token. A line starting with "(", "[" or "-" could either
+// continue the expression before it or begin a new one, and is taken to
+// begin a new one, so semicolons can be left out. Any other operator
+// starting a line, such as "+" or ".", continues the expression before it.
+// So a call, an index or a subtraction carries on over lines only when the
+// line breaks after its "(", "[" or "-".
+func (p *Parser) peekStartsStatement() bool {
+	if p.peekToken.Line <= p.curToken.Line {
+		return false
+	}
+	switch p.peekToken.Type {
+	case token.LPAREN, token.LBRACKET, token.MINUS:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -227,15 +455,33 @@ func (p *Parser) parseString() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseChar() ast.Expression {
+	value, _ := utf8.DecodeRuneInString(p.curToken.Literal)
+	return &ast.CharLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseBytes() ast.Expression {
+	return &ast.BytesLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, message.InvalidInteger, p.curToken.Literal)
 	}
 	return &ast.IntegerLiteral{Token: p.curToken, Value: lit}
 }
 
+// parseDurationLiteral parses a duration such as 1h30m into an integer of
+// milliseconds, which is how durations are written.
+func (p *Parser) parseDurationLiteral() ast.Expression {
+	d, err := time.ParseDuration(p.curToken.Literal)
+	if err != nil {
+		p.errorf(p.curToken, message.InvalidDuration, p.curToken.Literal)
+	}
+	return &ast.IntegerLiteral{Token: p.curToken, Value: d.Milliseconds()}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	boolean := &ast.Boolean{
 		Token: p.curToken,
@@ -258,8 +504,19 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	tuple := &ast.TupleLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return tuple
+	}
+
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COMMA) {
+		tuple.Elements = append(tuple.Elements, exp)
+		return p.parseTupleLiteral(tuple)
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -267,6 +524,24 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 
 }
 
+// parseTupleLiteral parses the elements after the first one, up to the
+// closing parenthesis. A trailing comma is allowed.
+func (p *Parser) parseTupleLiteral(tuple *ast.TupleLiteral) ast.Expression {
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+		p.nextToken()
+		tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return tuple
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	exp := &ast.IfExpression{
 		Token: p.curToken,
@@ -313,6 +588,9 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseOptionalAnnotation(&lit.ReturnType) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -325,8 +603,31 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
-	array := &ast.ArrayLiteral{Token: p.curToken}
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.FOR) {
+		return p.parseArrayComprehension(array.Token, first)
+	}
+
+	array.Elements = append(array.Elements, first)
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RBRACKET) {
+			break
+		}
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
 	return array
 }
 
@@ -344,6 +645,9 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
+		if len(hash.Pairs) == 0 && p.peekTokenIs(token.FOR) {
+			return p.parseHashComprehension(hash.Token, key, value)
+		}
 		hash.Pairs[key] = value
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
@@ -358,6 +662,8 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+// parseExpressionList parses a comma separated list up to end, allowing a
+// trailing comma.
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -371,6 +677,9 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		if p.peekTokenIs(end) {
+			break
+		}
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
@@ -390,15 +699,28 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.parseOptionalAnnotation(&ident.Type) {
+		return nil
+	}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.parseOptionalAnnotation(&ident.Type) {
+			return nil
+		}
 		identifiers = append(identifiers, ident)
 	}
 
@@ -433,17 +755,66 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseFieldExpression(left ast.Expression) ast.Expression {
+	exp := &ast.FieldExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.QUESTION_DOT),
+	}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	exp.Name = p.curToken.Literal
+
+	return exp
+}
+
+// parseOptionalAccess parses what follows `?.`, either a field name or an
+// index in brackets.
+func (p *Parser) parseOptionalAccess(left ast.Expression) ast.Expression {
+	if !p.peekTokenIs(token.LBRACKET) {
+		return p.parseFieldExpression(left)
+	}
+
+	p.nextToken()
+	exp := p.parseIndexExpression(left)
+	if exp == nil {
+		return nil
+	}
+	exp.(*ast.IndexExpression).Optional = true
+	return exp
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	// A comment after the opening brace goes with what follows it.
+	var opening string
+	if trivia := p.curToken.Trivia; trivia != nil && trivia.Trailing != "" {
+		opening = trivia.Trailing
+		p.curToken.Trivia = &token.Trivia{Leading: trivia.Leading}
+	}
 	p.nextToken()
+	if opening != "" {
+		trivia := token.Trivia{}
+		if p.curToken.Trivia != nil {
+			trivia = *p.curToken.Trivia
+		}
+		trivia.Leading = append([]token.Comment{{Text: opening}}, trivia.Leading...)
+		p.curToken.Trivia = &trivia
+	}
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		block.Statements = append(block.Statements, stmt)
 		p.nextToken()
 	}
+	block.End = p.leadingComments()
+	if p.curTokenIs(token.EOF) {
+		p.errorf(block.Token, message.UnclosedBlock, block.Token.Line, block.Token.Column)
+	}
 
 	return block
 }
@@ -462,9 +833,31 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseComparison parses an ordering comparison. When another one follows,
+// as in `a < b < c`, the whole run becomes a ComparisonChain that compares
+// each operand with the next, instead of comparing a boolean with c.
+func (p *Parser) parseComparison(left ast.Expression) ast.Expression {
+	expression := p.parseInfixExpression(left).(*ast.InfixExpression)
+	if p.peekPrecedence() != LESSGREATER {
+		return expression
+	}
+
+	chain := &ast.ComparisonChain{
+		Token:     expression.Token,
+		Operands:  []ast.Expression{expression.Left, expression.Right},
+		Operators: []string{expression.Operator},
+	}
+	for p.peekPrecedence() == LESSGREATER {
+		p.nextToken()
+		chain.Operators = append(chain.Operators, p.curToken.Literal)
+		p.nextToken()
+		chain.Operands = append(chain.Operands, p.parseExpression(LESSGREATER))
+	}
+	return chain
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.curToken, message.NoPrefixParseFn, t)
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -2,9 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
 	"github.com/fcidade/monkey-lang/lexer"
 )
 
@@ -109,6 +113,115 @@ func TestIdentifierExpression(t *testing.T) {
 	}
 }
 
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []diagnostic.Code
+	}{
+		{"let = 5;", []diagnostic.Code{diagnostic.UnexpectedToken, diagnostic.NoPrefixParse}},
+		{"99999999999999999999", []diagnostic.Code{diagnostic.InvalidInteger}},
+		{"1h30", []diagnostic.Code{diagnostic.InvalidDuration}},
+		{"let x: 5 = 1", []diagnostic.Code{diagnostic.ExpectedType}},
+		{"match x { + => 1 }", []diagnostic.Code{diagnostic.ExpectedPattern}},
+		{"let x = $;", []diagnostic.Code{diagnostic.IllegalToken}},
+		{"let if = 1;", []diagnostic.Code{diagnostic.ReservedWord}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) < len(tt.expected) {
+			t.Errorf("%s: expected %d diagnostics, got %v", tt.input, len(tt.expected), diagnostics)
+			continue
+		}
+		for i, code := range tt.expected {
+			if diagnostics[i].Code != code || diagnostics[i].Message != p.Errors()[i] {
+				t.Errorf("%s: wrong diagnostic %d. expected code %s, got %+v", tt.input, i, code, diagnostics[i])
+			}
+		}
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1;\nlet b = 2;\nlet c @ 3;", []string{"unexpected ILLEGAL token '@' at 3:7"}},
+		{"let x = $ + 1;\nlet y = ;", []string{
+			"unexpected ILLEGAL token '$' at 1:9",
+			"no prefix parse function for ; found",
+		}},
+		{"puts(\"hello);", []string{"unterminated string starting at 1:6"}},
+		{"let s = `{}", []string{"unterminated string starting at 1:9"}},
+		{"let c = 'ab';", []string{"invalid character literal 'ab' at 1:9, which has to hold exactly one character"}},
+		{"let c = '';", []string{"invalid character literal '' at 1:9, which has to hold exactly one character"}},
+		{"let fn = 1;", []string{"fn is a reserved word and cannot be used as a name"}},
+		{"fn(x, match) { x }", []string{"match is a reserved word and cannot be used as a name"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) < len(tt.expected) {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+			continue
+		}
+		for i, expected := range tt.expected {
+			if errors[i] != expected {
+				t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+				break
+			}
+		}
+	}
+
+	p := New(lexer.New("let a = 1;\nlet b @ 2;"))
+	p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) != 1 || diagnostics[0].Line != 2 || diagnostics[0].Column != 7 {
+		t.Errorf("expected a single error at 2:7, got %+v", diagnostics)
+	}
+}
+
+func TestDurationLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"200ms", 200},
+		{"5s", 5000},
+		{"2m", 120000},
+		{"2h", 7200000},
+		{"1h30m15s", 5415000},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%s: literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %s. got=%s", tt.input, literal.String())
+		}
+	}
+
+	p := New(lexer.New("1h30"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != `could not parse "1h30" as duration` {
+		t.Errorf("wrong errors for 1h30. got=%v", p.Errors())
+	}
+}
+
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 
@@ -237,6 +350,79 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
+func TestNewlineTerminatedStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5\nlet y = 10", []string{"let x = 5;", "let y = 10;"}},
+		{"let x = 5\n-3", []string{"let x = 5;", "(-3)"}},
+		{"let f = g\n(1 + 2)", []string{"let f = g;", "(1 + 2)"}},
+		{"a\n[0]", []string{"a", "[0]"}},
+		{"a -\n1", []string{"(a - 1)"}},
+		{"a\n+ 1", []string{"(a + 1)"}},
+		{"f(1, 2)\n.size", []string{"(f(1, 2).size)"}},
+		{"a -1", []string{"(a - 1)"}},
+		{"g\n(1)\nh(2)", []string{"g", "1", "h(2)"}},
+		{"return x\n[1]", []string{"return x;", "[1]"}},
+		{"if (x) { a\n-1 } else { b }", []string{"if x { a(-1) }else b"}},
+		{"let add = fn(x, y) {\n  let sum = x + y\n  sum\n}\nadd(1, 2)", []string{
+			"let add = fn (x, y) { let sum = (x + y);sum };",
+			"add(1, 2)",
+		}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("%q: expected %d statements, got %d: %q", tt.input, len(tt.expected), len(program.Statements), program.String())
+			continue
+		}
+		for i, expected := range tt.expected {
+			if got := program.Statements[i].String(); got != expected {
+				t.Errorf("%q: statement %d: expected=%q, got=%q", tt.input, i, expected, got)
+			}
+		}
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3,]", "[1, 2, 3]"},
+		{"[\n  1,\n  2,\n]", "[1, 2]"},
+		{"{\"a\": 1,}", "{a:1}"},
+		{"add(1, 2,)", "add(1, 2)"},
+		{"print(\n  x,\n)", "print(x)"},
+		{"fn(x, y,) { x }", "fn (x, y) { x }"},
+		{"|x, y,| x", "fn (x, y) { x }"},
+		{"(1, 2,)", "(1, 2)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"[1,,]", "add(,)", "fn(,) { 1 }"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", input)
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -350,6 +536,38 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a.b.c + d?.e",
+			"(((a.b).c) + (d?.e))",
+		},
+		{
+			"a?.[i + 1].f(x)",
+			"((a?.[(i + 1)]).f)(x)",
+		},
+		{
+			"a ?? b == c ?? d",
+			"((a ?? (b == c)) ?? d)",
+		},
+		{
+			"a < b < c",
+			"(a < b < c)",
+		},
+		{
+			"0 < x + 1 > y == true",
+			"((0 < (x + 1) > y) == true)",
+		},
+		{
+			"(a < b) < c",
+			"((a < b) < c)",
+		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"0..n + 1 == r",
+			"((0 .. (n + 1)) == r)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -565,6 +783,9 @@ func TestFunctionParameterParsing(t *testing.T) {
 		{input: "fn() {};", expectedParams: []string{}},
 		{input: "fn(x) {};", expectedParams: []string{"x"}},
 		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{input: "|| 1;", expectedParams: []string{}},
+		{input: "|x| x;", expectedParams: []string{"x"}},
+		{input: "|x, y, z| z;", expectedParams: []string{"x", "y", "z"}},
 	}
 
 	for _, tt := range tests {
@@ -587,6 +808,112 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestLambdaShorthand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"|x| x + 1", "fn (x) { (x + 1) }"},
+		{"map(arr, |x| x * 2)", "map(arr, fn (x) { (x * 2) })"},
+		{"|x| |y| x + y", "fn (x) { fn (y) { (x + y) } }"},
+		{"(|x| x)(5)", "fn (x) { x }(5)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"|x y| x", "expected next token to be ,, got IDENT instead"},
+		{"|1| x", "expected next token to be IDENT, got INT instead"},
+		{"|x|", "no prefix parse function for  found"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let add = fn(a: int, b) : int { a + b };", "let add = fn (a: int, b) : int { (a + b) };"},
+		{"fn(f: fn): fn { f }", "fn (f: fn) : fn { f }"},
+		{"|x: string| x", "fn (x: string) { x }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 5", "expected a type, got = instead"},
+		{"fn(a: 1) { a }", "expected a type, got INT instead"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestCallingArbitraryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x) { x }(5)", "fn (x) { x }(5)"},
+		{"(fn(x) { x })(5)", "fn (x) { x }(5)"},
+		{"fn() { fn(y) { y } }()(4)", "fn () { fn (y) { y } }()(4)"},
+		{"h[\"f\"](1)", "(h[f])(1)"},
+		{"[f][0](1)", "([f][0])(1)"},
+		{"(a + b)(c)", "(a + b)(c)"},
+		{"-fn(x) { x }(5)", "(-fn (x) { x }(5))"},
+		{"fn(x) { x }(5) + 1", "(fn (x) { x }(5) + 1)"},
+		{"let y = fn(x) { x * 2 }(3);", "let y = fn (x) { (x * 2) }(3);"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		if len(program.Statements) != 1 {
+			t.Errorf("%q parsed into %d statements", tt.input, len(program.Statements))
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -840,6 +1167,150 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBytesLiteralExpression(t *testing.T) {
+	input := `b"hello world";`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.BytesLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BytesLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestCharLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected rune
+	}{
+		{"'a'", 'a'},
+		{"'é'", 'é'},
+		{"'''", '\''},
+		{"' '", ' '},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.CharLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.CharLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestLetStatementPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [] = arr;", "let [] = arr;"},
+		{"let [head, ...tail] = arr;", "let [head, ...tail] = arr;"},
+		{"let [...all] = arr;", "let [...all] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{"let {name: n, pets: [first]} = person;", "let {name: n, pets: [first]} = person;"},
+		{"let [{x}, [y, z]] = points;", "let [{x}, [y, z]] = points;"},
+		{"let (a, b) = t;", "let (a, b) = t;"},
+		{"let a, [b, c] = t;", "let (a, [b, c]) = t;"},
+		{"let (a,) = t;", "let (a,) = t;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Errorf("let statement for %q has no pattern", tt.input)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestLetStatementPatternErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, ...b, c] = arr;", "expected next token to be ], got , instead"},
+		{"let [*] = arr;", "expected a pattern, got * instead"},
+		{"let {\"name\"} = person;", "expected next token to be :, got } instead"},
+		{"let {1: a} = person;", "expected next token to be IDENT, got INT instead"},
+		{"let [-a] = arr;", "expected next token to be INT, got IDENT instead"},
+		{"let [a b] = arr;", "expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestParsingMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 => a, _ => b }", "match x { 1 => a, _ => b }"},
+		{"match x { [x, ...rest] => x, [] => 0, }", "match x { [x, ...rest] => x, [] => 0 }"},
+		{`match s { {"type": "circle", "r": r} => r * r, {w, h} => w * h }`,
+			`match s { {type: "circle", r} => (r * r), {w, h} => (w * h) }`},
+		{`match h { {"first name": n} => n }`, `match h { {"first name": n} => n }`},
+		{"match n { x if x < 0 => -x, -1 => 1, true => 2 }", "match n { x if (x < 0) => (-x), -1 => 1, true => 2 }"},
+		{"match p { (a, b) => { let c = a; c + b } }", "match p { (a, b) => let c = a;(c + b) }"},
+		{"let y = match x { _ => 1 } + 1;", "let y = (match x { _ => 1 } + 1);"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 2 }", "expected next token to be =>, got INT instead"},
+		{"match x { 1 => 2 3 }", "expected next token to be ,, got INT instead"},
+		{"match x 1", "expected next token to be {, got INT instead"},
+		{"match x { + => 1 }", "expected a pattern, got + instead"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	l := lexer.New(input)
@@ -859,6 +1330,52 @@ func TestParsingArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
 
+func TestParsingTupleLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(1, 2 * 2)", "(1, (2 * 2))"},
+		{"(1,)", "(1,)"},
+		{"(1, 2,)", "(1, 2)"},
+		{"()", "()"},
+		{"(1)", "1"},
+		{"return a, b + 1;", "return (a, (b + 1));"},
+		{"f((1, 2), 3)", "f((1, 2), 3)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParsingComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * x for x in 1..10 if x % 2 == 0]", "[(x * x) for x in (1 .. 10) if ((x % 2) == 0)]"},
+		{"[x for x in xs]", "[x for x in xs]"},
+		{"[a + b for [a, b] in pairs]", "[(a + b) for [a, b] in pairs]"},
+		{"{k: v * 2 for k, v in h}", "{k: (v * 2) for (k, v) in h}"},
+		{"[[y for y in row] for row in grid]", "[[y for y in row] for row in grid]"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 	l := lexer.New(input)
@@ -961,3 +1478,274 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func FuzzParser(f *testing.F) {
+	for _, seed := range []string{
+		"let add = fn(x, y) { x + y }; add(1, 2)",
+		"let [a, {b, c: [d, ...e]}] = x",
+		"if (a) { b } else { c }",
+		"[x * 2 for x in 1..10 if x % 2]",
+		`{"a": 1, b: |x| x}`,
+		"match x { [1, _] => a, {k: -2} if y => { b } }",
+		"let f = fn(a: int) : int { a }",
+		"a.b?.[0] ?? 1 < x < 3",
+		"fn(",
+		"let a = 1 // one\n// two\n\nfn() { // three\n}",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// Malformed input is reported as errors, never a panic, and a
+		// program without errors can be printed.
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Fatalf("no program for %q", input)
+		}
+		ast.Inspect(program, func(ast.Node) bool { return true })
+		for _, d := range p.Diagnostics() {
+			if d.Line < 1 || d.Column < 1 || d.Message == "" {
+				t.Errorf("diagnostic without a position or message for %q: %+v", input, d)
+			}
+		}
+		if len(p.Errors()) == 0 {
+			_ = program.String()
+		}
+
+		// Keeping comments does not change what is parsed.
+		trivia := New(lexer.NewWithTrivia(input))
+		withComments := trivia.ParseProgram()
+		if fmt.Sprint(trivia.Errors()) != fmt.Sprint(p.Errors()) {
+			t.Errorf("errors for %q differ with trivia: %q, without: %q", input, trivia.Errors(), p.Errors())
+		} else if len(p.Errors()) == 0 && withComments.String() != program.String() {
+			t.Errorf("%q parses into %q with trivia, %q without", input, withComments.String(), program.String())
+		}
+	})
+}
+
+func TestMalformedInput(t *testing.T) {
+	// Each of these once risked a nil dereference or was accepted silently.
+	// The corpus in testdata/fuzz/FuzzParser holds them too, so the fuzzer
+	// starts from them.
+	inputs := []string{
+		"-", "return", "1 ?? ", "let x =", "}", "...",
+		"{1: }", "{1 2}", "{,}", "{1: 2",
+		`"abc`, "`abc", `b"`, "'ab'",
+		"fn(", "fn(x", "fn(x) {", "if (x) {", "if (x) { 1 } else",
+		"a[", "a(", "a.", "a?.", "(1, ", "|x",
+		"[x for]", "[x for x in]", "match {", "match x {", "match x { 1 => }",
+		"let {a: } = b", "let (a, ] = b", "let x: = 1",
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if program == nil {
+			t.Errorf("%q: no program", input)
+			continue
+		}
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected errors, got none and %q", input, program.String())
+		}
+	}
+}
+
+func TestLanguageVersions(t *testing.T) {
+	book := Config{Version: 1}
+	tests := []struct {
+		config   Config
+		input    string
+		expected string
+		errors   []string
+	}{
+		{book, "let match = fn(in) { in * 2 }; match(1)", "let match = fn (in) { (in * 2) };match(1)", nil},
+		{book, "let a = 1; a + 2", "let a = 1;(a + 2)", nil},
+		{book, "5 % 2", "", []string{"% needs the modulo feature, which is part of language version 2"}},
+		{book, "h.name", "", []string{". needs the field-access feature, which is part of language version 2"}},
+		{book, "let c = 'a';", "", []string{"'a' needs the chars feature, which is part of language version 2"}},
+		{Config{Version: 1, Features: []Feature{FeatureModulo, FeatureRanges}}, "1..5 % 2", "(1 .. (5 % 2))", nil},
+		{Config{Version: 1, Features: []Feature{FeatureMatch}}, "match x { _ => 1 }", "match x { _ => 1 }", nil},
+		{Config{}, "5 % 2", "(5 % 2)", nil},
+		{Config{Version: 2}, "h?.name", "(h?.name)", nil},
+		{book, `b"ab"`, "", []string{`b"ab" needs the bytes feature, which is part of language version 2`}},
+	}
+
+	for _, tt := range tests {
+		p := NewWithConfig(lexer.New(tt.input), tt.config)
+		program := p.ParseProgram()
+		errors := p.Errors()
+		if tt.errors == nil {
+			if len(errors) != 0 {
+				t.Errorf("unexpected errors for %q: %q", tt.input, errors)
+			} else if program.String() != tt.expected {
+				t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+			}
+			continue
+		}
+		if len(errors) < len(tt.errors) || errors[0] != tt.errors[0] {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.errors, errors)
+		}
+	}
+}
+
+func TestParseExpressionOnly(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		errors   []string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))", nil},
+		{"add(1, 2);", "add(1, 2)", nil},
+		{"fn(x) { x; 1 }", "fn (x) { x1 }", nil},
+		{"1 2", "", []string{"expected the end of the input after the expression, got 2 instead"}},
+		{"a; b", "", []string{"expected the end of the input after the expression, got b instead"}},
+		{"let x = 1", "", []string{"no prefix parse function for LET found", "expected the end of the input after the expression, got x instead"}},
+		{"", "", []string{"no prefix parse function for  found"}},
+	}
+
+	for _, tt := range tests {
+		expr, errors := ParseExpressionOnly(tt.input)
+		if len(errors) != len(tt.errors) {
+			t.Errorf("%q: expected errors %q, got %q", tt.input, tt.errors, errors)
+			continue
+		}
+		for i, msg := range tt.errors {
+			if errors[i] != msg {
+				t.Errorf("%q: wrong error %d. expected=%q, got=%q", tt.input, i, msg, errors[i])
+			}
+		}
+		if tt.errors == nil && expr.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, expr.String())
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	versions := []string{
+		"let a = 1\nlet b = fn(x) { x * 2 }\nlet c = {\"k\": b(a)}\nc[\"k\"]",
+		"let a = 1\nlet b = fn(x) { x * 3 }\nlet c = {\"k\": b(a)}\nc[\"k\"]",
+		"let a = 1\n\n\nlet b = fn(x) { x * 3 }\n  let c = {\"k\": b(a)}\nc[\"k\"]",
+		"let a = 1\nlet b = fn(x) { x *\nlet c = {\"k\": b(a)}\nc[\"k\"]",
+		"let a = 1\nlet b = fn(x) { x * 3 }\nlet c = {\"k\": b(a)}\nc[\"k\"] + a",
+		"let a = 1; let b = 2\n\"unterminated\nlet c = 3",
+		"let a = 1; let b = 2\nlet c = 3",
+		"",
+		"let a = 1",
+	}
+
+	inc := NewIncremental(Config{})
+	for _, src := range versions {
+		program, diagnostics := inc.Parse(src)
+		checkSameParse(t, src, program, diagnostics)
+	}
+}
+
+func TestIncrementalReuse(t *testing.T) {
+	inc := NewIncremental(Config{})
+	before, _ := inc.Parse("let a = 1\nlet b = a + 1\nlet c = b * 2\nc")
+	after, diagnostics := inc.Parse("let a = 1\nlet b = a + 10\n\nlet c = b * 2\nc")
+	checkSameParse(t, "let a = 1\nlet b = a + 10\n\nlet c = b * 2\nc", after, diagnostics)
+
+	for i, reused := range []bool{true, false, true, true} {
+		if got := before.Statements[i] == after.Statements[i]; got != reused {
+			t.Errorf("statement %d: expected reused=%t, got %t", i, reused, got)
+		}
+	}
+}
+
+// checkSameParse checks program and diagnostics are what a full parse of src
+// gives, including the position of every token. Programs with errors cannot
+// be printed, so for them only the positions and diagnostics are compared.
+func checkSameParse(t *testing.T, src string, program *ast.Program, diagnostics []diagnostic.Diagnostic) {
+	t.Helper()
+	p := New(lexer.New(src))
+	expected := p.ParseProgram()
+	if len(p.Errors()) == 0 && program.String() != expected.String() {
+		t.Errorf("%q: expected program %q, got %q", src, expected.String(), program.String())
+	}
+	if fmt.Sprint(tokenPositions(program)) != fmt.Sprint(tokenPositions(expected)) {
+		t.Errorf("%q: expected tokens at %v, got %v", src, tokenPositions(expected), tokenPositions(program))
+	}
+	if fmt.Sprint(diagnostics) != fmt.Sprint(p.Diagnostics()) {
+		t.Errorf("%q: expected diagnostics %v, got %v", src, p.Diagnostics(), diagnostics)
+	}
+}
+
+// tokenPositions returns the positions of the tokens of the nodes in
+// program, sorted, as the order hash pairs are visited in is not fixed.
+func tokenPositions(program *ast.Program) []string {
+	var positions []string
+	shift(reflect.ValueOf(program), func(line, column *int) {
+		positions = append(positions, fmt.Sprintf("%d:%d", *line, *column))
+	}, map[uintptr]bool{})
+	sort.Strings(positions)
+	return positions
+}
+
+func FuzzIncremental(f *testing.F) {
+	f.Add("let a = 1\nlet b = 2\nb", "let a = 1\nlet b = 22\nb")
+	f.Add("let f = fn(x) {\n x\n}\nf(1)", "let f = fn(x) {\n\n x\n}\n f(1)")
+	f.Add("a\n\"b\nc", "a\nb\nc")
+
+	f.Fuzz(func(t *testing.T, before, after string) {
+		inc := NewIncremental(Config{})
+		inc.Parse(before)
+		program, diagnostics := inc.Parse(after)
+		checkSameParse(t, after, program, diagnostics)
+	})
+}
+
+func BenchmarkIncrementalEdit(b *testing.B) {
+	var src strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&src, "let f%d = fn(x) { if (x > %d) { x * 2 } else { [x, x + 1] } }\n", i, i)
+	}
+	versions := []string{src.String(), strings.Replace(src.String(), "x > 500", "x > 5000", 1)}
+
+	inc := NewIncremental(Config{})
+	inc.Parse(versions[0])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inc.Parse(versions[(i+1)%2])
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// add adds.
+let add = fn(x, y) { // the sum
+
+	x + // of both
+	y
+	// nothing else
+} // end of add
+
+add(1, 2)
+// the end`
+
+	p := New(lexer.NewWithTrivia(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		stmt     ast.Statement
+		expected string
+	}{
+		{program.Statements[0], "&{Leading:[{Text:// add adds. Blank:0}] Blank:0 Trailing:[// end of add]}"},
+		{program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body.Statements[0], "&{Leading:[{Text:// the sum Blank:0}] Blank:1 Trailing:[// of both]}"},
+		{program.Statements[1], "&{Leading:[] Blank:1 Trailing:[]}"},
+	}
+	for i, tt := range tests {
+		if got := fmt.Sprintf("%+v", ast.CommentsOf(tt.stmt)); got != tt.expected {
+			t.Errorf("statement %d: expected comments %s, got %s", i, tt.expected, got)
+		}
+	}
+
+	body := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body
+	if got := fmt.Sprintf("%+v", body.End); got != "&{Leading:[{Text:// nothing else Blank:0}] Blank:0 Trailing:[]}" {
+		t.Errorf("wrong comments at the end of the block: %s", got)
+	}
+	if got := fmt.Sprintf("%+v", program.End); got != "&{Leading:[{Text:// the end Blank:0}] Blank:0 Trailing:[]}" {
+		t.Errorf("wrong comments at the end of the program: %s", got)
+	}
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENTIFIER:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.MINUS:
+		return p.parseIntegerPattern()
+	case token.STRING:
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.parseString()}
+	case token.CHAR:
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.parseChar()}
+	case token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.parseBoolean()}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.LPAREN:
+		return p.parseTuplePattern()
+	default:
+		p.errorf(p.curToken, message.ExpectedPattern, p.curToken.Type)
+		return nil
+	}
+}
+
+// parseIntegerPattern parses an integer literal pattern, which may be
+// negative.
+func (p *Parser) parseIntegerPattern() ast.Pattern {
+	tok := p.curToken
+	if p.curTokenIs(token.MINUS) && !p.expectPeek(token.INT) {
+		return nil
+	}
+
+	lit := p.parseIntegerLiteral().(*ast.IntegerLiteral)
+	if tok.Type == token.MINUS {
+		lit.Token.Literal = "-" + lit.Token.Literal
+		lit.Value = -lit.Value
+	}
+	return &ast.LiteralPattern{Token: tok, Value: lit}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.STRING) {
+			p.nextToken()
+			pair := ast.HashPatternPair{Key: p.curToken.Literal}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+			pattern.Pairs = append(pattern.Pairs, pair)
+
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		key := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		pair := ast.HashPatternPair{Key: key.Value, Value: key}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
+func (p *Parser) parseTuplePattern() ast.Pattern {
+	pattern := &ast.TuplePattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return pattern
+}
+
+// parseBarePatternList parses the patterns following first and a comma, as in
+// `a, b` without parentheses, into a tuple pattern.
+func (p *Parser) parseBarePatternList(first ast.Pattern) ast.Pattern {
+	pattern := &ast.TuplePattern{
+		Token:    token.Token{Type: token.LPAREN, Literal: "("},
+		Elements: []ast.Pattern{first},
+	}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+	}
+	return pattern
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/fcidade/monkey-lang/playground"
+)
+
+func playgroundCommand(args []string) int {
+	limits := playground.DefaultLimits
+	flags := flag.NewFlagSet("serve-playground", flag.ExitOnError)
+	flags.DurationVar(&limits.Timeout, "timeout", limits.Timeout, "how long code may run")
+	flags.IntVar(&limits.MaxOutput, "max-output", limits.MaxOutput, "how many bytes of what code puts are kept")
+	flags.IntVar(&limits.MaxCallDepth, "max-call-depth", limits.MaxCallDepth, "how deeply functions may recurse")
+	flags.IntVar(&limits.MaxElements, "max-elements", limits.MaxElements, "how many elements an array, or bytes a string, code builds may have")
+	flags.IntVar(&limits.MaxConcurrent, "max-concurrent", limits.MaxConcurrent, "how many evaluations may run at once")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey serve-playground [flags] [address]")
+		fmt.Fprintln(flags.Output(), "serves a page to try Monkey in at address, :8080 by default, and POST /eval,")
+		fmt.Fprintln(flags.Output(), `which runs {"code": ...} sandboxed and answers with its stdout, result and diagnostics`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	address := ":8080"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		address = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	fmt.Fprintf(os.Stderr, "serving the playground on %s\n", address)
+	if err := http.ListenAndServe(address, playground.Handler(limits)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+// Package playground serves a page where Monkey can be tried out in a
+// browser, and the endpoint the page runs code with. Code from the page is
+// untrusted, so every evaluation is sandboxed to puts, given little time,
+// output, call depth and memory, and runs alongside only a few others.
+package playground
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/sessions"
+)
+
+//go:embed index.html
+var page []byte
+
+// Limits bound what an evaluation may use.
+type Limits struct {
+	// Timeout is how long an evaluation may run before it is interrupted.
+	Timeout time.Duration
+
+	// MaxSource is the most bytes of code a request may hold, and MaxOutput
+	// the most bytes of what it puts that are kept.
+	MaxSource int64
+	MaxOutput int
+
+	// MaxCallDepth is how many function calls may be active at once.
+	MaxCallDepth int
+
+	// MaxElements is how many elements an array, and bytes a string, that
+	// ranges, repeating and growing build may have, as in evaluator.Config.
+	MaxElements int
+
+	// MaxConcurrent is how many evaluations may run at once; requests that
+	// come while that many run are turned away.
+	MaxConcurrent int
+}
+
+// DefaultLimits are the limits of `monkey serve-playground`.
+var DefaultLimits = Limits{
+	Timeout:       2 * time.Second,
+	MaxSource:     64 << 10,
+	MaxOutput:     64 << 10,
+	MaxCallDepth:  200,
+	MaxElements:   1 << 20,
+	MaxConcurrent: 4,
+}
+
+// Request is the body of a POST to /eval.
+type Request struct {
+	Code string `json:"code"`
+}
+
+// Response is what /eval answers with. Result is the Inspect of the value
+// of the code, and empty when it has none or failed.
+type Response struct {
+	Stdout      string                  `json:"stdout"`
+	Truncated   bool                    `json:"truncated,omitempty"`
+	Result      string                  `json:"result,omitempty"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// Handler serves the page at / and evaluates code POSTed to /eval within
+// limits.
+func Handler(limits Limits) http.Handler {
+	var running int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.HandleFunc("/eval", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "code is evaluated with POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request Request
+		body := http.MaxBytesReader(w, r.Body, limits.MaxSource)
+		if err := json.NewDecoder(body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("the request is not {\"code\": ...} of at most %d bytes", limits.MaxSource), http.StatusBadRequest)
+			return
+		}
+
+		if atomic.AddInt32(&running, 1) > int32(limits.MaxConcurrent) {
+			atomic.AddInt32(&running, -1)
+			http.Error(w, "too many evaluations are running, try again shortly", http.StatusServiceUnavailable)
+			return
+		}
+		response := Eval(request.Code, limits)
+		atomic.AddInt32(&running, -1)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+	return mux
+}
+
+// Eval evaluates code within limits.
+func Eval(code string, limits Limits) Response {
+	result := sessions.NewManager(sessions.Config{
+		Evaluator: evaluator.Config{
+			Sandboxed:    true,
+			Capabilities: []evaluator.Capability{evaluator.Output},
+			LogOutput:    io.Discard,
+			MaxCallDepth: limits.MaxCallDepth,
+			MaxElements:  limits.MaxElements,
+		},
+		Limits: sessions.Limits{Timeout: limits.Timeout, MaxOutput: limits.MaxOutput},
+	}).Run(code)
+
+	response := Response{Stdout: result.Output, Truncated: result.Truncated, Diagnostics: result.Diagnostics}
+	if result.Value != nil {
+		response.Result = result.Value.Inspect()
+	}
+	if response.Diagnostics == nil {
+		response.Diagnostics = []diagnostic.Diagnostic{}
+	}
+	return response
+}
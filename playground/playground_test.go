@@ -0,0 +1,113 @@
+package playground
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+)
+
+func TestEval(t *testing.T) {
+	limits := DefaultLimits
+	limits.Timeout = 100 * time.Millisecond
+	limits.MaxOutput = 10
+
+	tests := []struct {
+		code      string
+		stdout    string
+		truncated bool
+		result    string
+		failure   diagnostic.Code
+		message   string
+	}{
+		{code: `puts("hi"); 1 + 2`, stdout: "hi\n", result: "3"},
+		{code: `let x = ;`, failure: diagnostic.NoPrefixParse},
+		{code: `puts(1); 1 + true`, stdout: "1\n", failure: diagnostic.TypeMismatch},
+		{code: `import("std/fs").readFile("/etc/passwd")`, failure: diagnostic.CapabilityDenied},
+		{code: `let f = fn() { f() }; f()`, failure: diagnostic.CallDepthExceeded},
+		{code: `let f = fn() { puts("abc"); f() }; f()`, stdout: "abc\nabc\nab", truncated: true, failure: diagnostic.CallDepthExceeded},
+		{code: `len(0..30000000)`, failure: diagnostic.RangeTooLong},
+		{code: `len("ab" * 1000000)`, failure: diagnostic.InvalidRepeat},
+		{code: `let grow = fn(s) { grow(s + s) }; grow("ab")`, failure: diagnostic.TooManyElements},
+		{code: `let a = 0..600000; a * 2`, failure: diagnostic.InvalidRepeat},
+		{code: `let a = 0..1048576; pushMut(a, 1)`, failure: diagnostic.TooManyElements},
+		{code: `let f = fn(s, n) { if (n == 0) { len(s) } else { f(s + s, n - 1) } }; f(b"a", 40)`, failure: diagnostic.TooManyElements},
+		{code: `let f = fn(s, n) { if (n == 0) { len(s) } else { f(strings.join([s, s], ""), n - 1) } }; f("a", 40)`, failure: diagnostic.TooManyElements},
+		{code: `let f = fn(s, n) { if (n == 0) { len(s) } else { f(template("{{a}}{{a}}", {"a": s}), n - 1) } }; f("a", 40)`, failure: diagnostic.TooManyElements},
+		{code: `let f = fn(s, n) { if (n == 0) { len(s) } else { f(strings.replace(s, "a", "aa"), n - 1) } }; f("a", 40)`, failure: diagnostic.TooManyElements},
+		{code: `let f = fn(s, n) { if (n == 0) { len(s) } else { f(csvEncode([[s, s]]), n - 1) } }; f("a", 40)`, failure: diagnostic.TooManyElements},
+		{code: `let b = builder("a"); let f = fn(n) { if (n == 0) { 0 } else { append(b, toString(b)); f(n - 1) } }; f(40)`, failure: diagnostic.TooManyElements},
+		{code: `[padLeft("a", 2000000), padRight("a", 2000000), center("a", 2000000)]`, failure: diagnostic.TooManyElements},
+		{code: `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(50)`, failure: diagnostic.Interrupted, message: "stopped after the time limit of 100ms"},
+	}
+	for _, tt := range tests {
+		response := Eval(tt.code, limits)
+		if response.Stdout != tt.stdout || response.Truncated != tt.truncated || response.Result != tt.result {
+			t.Errorf("%s: expected stdout %q (truncated %t) and result %q, got %q (%t) and %q",
+				tt.code, tt.stdout, tt.truncated, tt.result, response.Stdout, response.Truncated, response.Result)
+		}
+		if tt.failure == "" {
+			if len(response.Diagnostics) != 0 {
+				t.Errorf("%s: unexpected diagnostics %v", tt.code, response.Diagnostics)
+			}
+			continue
+		}
+		if len(response.Diagnostics) == 0 || response.Diagnostics[0].Code != tt.failure {
+			t.Errorf("%s: expected a diagnostic %s, got %v", tt.code, tt.failure, response.Diagnostics)
+			continue
+		}
+		if tt.message != "" && response.Diagnostics[0].Message != tt.message {
+			t.Errorf("%s: expected the message %q, got %q", tt.code, tt.message, response.Diagnostics[0].Message)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler(DefaultLimits))
+	defer server.Close()
+
+	page, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page.Body.Close()
+	if page.StatusCode != http.StatusOK || !strings.HasPrefix(page.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("expected the page, got %s %s", page.Status, page.Header.Get("Content-Type"))
+	}
+
+	resp, err := http.Post(server.URL+"/eval", "application/json", strings.NewReader(`{"code": "puts(\"hi\"); [1, 2]"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var response map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response["stdout"] != "hi\n" || response["result"] != "[1, 2]" || len(response["diagnostics"].([]interface{})) != 0 {
+		t.Errorf("unexpected response %v", response)
+	}
+
+	for _, request := range []struct {
+		method, body string
+		status       int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "puts(1)", http.StatusBadRequest},
+		{http.MethodPost, `{"code": "` + strings.Repeat("1", int(DefaultLimits.MaxSource)) + `"}`, http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest(request.method, server.URL+"/eval", strings.NewReader(request.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != request.status {
+			t.Errorf("%s /eval: expected %d, got %d", request.method, request.status, resp.StatusCode)
+		}
+	}
+}
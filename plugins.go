@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+// pluginsUsage describes the -plugins flag.
+const pluginsUsage = "load builtin packs from these comma-separated Go plugins, built with -buildmode=plugin"
+
+// loadPlugins loads the comma-separated Go plugins in list.
+func loadPlugins(list string) error {
+	for _, path := range strings.Split(list, ",") {
+		if path == "" {
+			continue
+		}
+		if err := evaluator.LoadPlugin(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
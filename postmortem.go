@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+// postMortem reads inputs from in and evaluates them where the script at
+// path failed, as failure describes, so the variables that led to the
+// error can be looked at. It writes to out what the REPL does, after where
+// the script failed and the calls active then.
+func postMortem(path string, failure *evaluator.Failure, config evaluator.Config, in io.Reader, out io.Writer, interrupts <-chan os.Signal) {
+	where := "at the top level"
+	if len(failure.Stack) > 0 {
+		where = "in " + failure.Stack[0]
+	}
+	fmt.Fprintf(out, "post-mortem: %s failed %s at %d:%d: %s\n", path, where, failure.Error.Line, failure.Error.Column, failure.Error.Message)
+	for _, frame := range failure.Stack {
+		fmt.Fprintf(out, "\tat %s\n", frame)
+	}
+	fmt.Fprintln(out, "inputs are evaluated where the error happened; Ctrl-D quits")
+
+	// What the REPL evaluates is not part of the run, were it recorded.
+	config.PostMortem, config.Hooks = false, nil
+	repl.Start(in, repl.Config{
+		Prompt:             "(post-mortem) " + repl.PROMPT,
+		ContinuationPrompt: repl.CONTINUATION_PROMPT,
+		Writer:             out,
+		Interrupts:         interrupts,
+		Evaluator:          config,
+		Environment:        failure.Env,
+	})
+}
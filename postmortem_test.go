@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func TestPostMortem(t *testing.T) {
+	config := evaluator.Config{PostMortem: true}
+	ev := evaluator.New(config)
+	program := parser.New(lexer.New("let f = fn(n) {\n  let half = n / 2;\n  half + true\n};\nf(10)")).ParseProgram()
+	ev.Eval(program, object.NewEnvironment())
+
+	var out bytes.Buffer
+	postMortem("script.mk", ev.Failure(), config, strings.NewReader("half\nn * 2\n"), &out, nil)
+	expected := "post-mortem: script.mk failed in f at 3:3: type mismatch: INTEGER + BOOLEAN\n" +
+		"\tat f\n" +
+		"inputs are evaluated where the error happened; Ctrl-D quits\n" +
+		"(post-mortem) >> 5\n" +
+		"(post-mortem) >> 20\n" +
+		"(post-mortem) >> "
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+}
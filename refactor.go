@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/refactor"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+func refactorCommand(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey refactor rename [flags] <old> <new> <script.mk>")
+		fmt.Fprintln(os.Stderr, "       monkey refactor extract-function [flags] <name> <first line> <last line> <script.mk>")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+
+	switch args[0] {
+	case "rename":
+		return renameCommand(args[1:])
+	case "extract-function":
+		return extractFunctionCommand(args[1:])
+	}
+	usage()
+	return 2
+}
+
+func renameCommand(args []string) int {
+	flags := flag.NewFlagSet("refactor rename", flag.ExitOnError)
+	overwrite := flags.Bool("w", false, "write the result back to the script instead of to stdout")
+	at := flags.String("at", "", "rename the binding of the identifier at `line:column`, when the name is bound in several places")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey refactor rename [flags] <old> <new> <script.mk>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	line, column := 0, 0
+	if *at != "" {
+		if _, err := fmt.Sscanf(*at, "%d:%d", &line, &column); err != nil {
+			fmt.Fprintf(flags.Output(), "invalid position %q for -at: expected line:column\n", *at)
+			flags.Usage()
+			return 2
+		}
+	}
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return 2
+	}
+
+	name, to, path := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	ok := refactorFile(path, *overwrite, os.Stdout, func(src string) ([]refactor.Edit, error) {
+		return refactor.Rename(src, name, line, column, to)
+	})
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+func extractFunctionCommand(args []string) int {
+	flags := flag.NewFlagSet("refactor extract-function", flag.ExitOnError)
+	overwrite := flags.Bool("w", false, "write the result back to the script instead of to stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey refactor extract-function [flags] <name> <first line> <last line> <script.mk>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 4 {
+		flags.Usage()
+		return 2
+	}
+	first, err := strconv.Atoi(flags.Arg(1))
+	if err != nil {
+		flags.Usage()
+		return 2
+	}
+	last, err := strconv.Atoi(flags.Arg(2))
+	if err != nil {
+		flags.Usage()
+		return 2
+	}
+
+	name, path := flags.Arg(0), flags.Arg(3)
+	ok := refactorFile(path, *overwrite, os.Stdout, func(src string) ([]refactor.Edit, error) {
+		return refactor.ExtractFunction(src, first, last, name)
+	})
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// refactorFile refactors the script at path with the edits refactoring
+// gives for it, and writes the result to out or, with overwrite set, back to
+// the script. A script that does not parse, or that refactoring refuses, is
+// left alone and why written to out. It reports whether the script was
+// refactored.
+func refactorFile(path string, overwrite bool, out io.Writer, refactoring func(src string) ([]refactor.Edit, error)) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	src := string(content)
+	p := parser.New(lexer.New(src))
+	p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
+	}
+
+	edits, err := refactoring(src)
+	if err != nil {
+		fmt.Fprintf(out, "cannot refactor %s: %s\n", path, err)
+		return false
+	}
+
+	refactored := refactor.Apply(src, edits)
+	if !overwrite {
+		fmt.Fprint(out, refactored)
+		return true
+	}
+	if refactored == src {
+		return true
+	}
+	if err := os.WriteFile(path, []byte(refactored), 0o644); err != nil {
+		fmt.Fprintf(out, "could not write %s: %s\n", path, err)
+		return false
+	}
+	return true
+}
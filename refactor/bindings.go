@@ -0,0 +1,55 @@
+package refactor
+
+import (
+	"sort"
+
+	"github.com/fcidade/monkey-lang/ast"
+)
+
+// Binding is a name bound in one scope of a program, as Rename renames it.
+type Binding struct {
+	Name string
+
+	// Definition is the first identifier in the source that binds the name,
+	// and Identifiers every identifier that binds it or refers to it, in the
+	// order they appear in the source.
+	Definition  *ast.Identifier
+	Identifiers []*ast.Identifier
+
+	// TopLevel is set for the bindings of the program's own scope.
+	TopLevel bool
+}
+
+// Bindings returns the bindings of program, resolved as the evaluator
+// resolves them, in the order their definitions appear in the source. The
+// program may have parse errors; what did parse is resolved.
+func Bindings(program *ast.Program) []*Binding {
+	r := resolve(program)
+
+	seen := map[*binding]bool{}
+	var bindings []*Binding
+	for _, b := range r.binding {
+		if b == nil || seen[b] {
+			continue
+		}
+		seen[b] = true
+
+		sortIdentifiers(b.idents)
+		binding := &Binding{Name: b.name, Identifiers: b.idents, TopLevel: b.scope == r.top}
+		for _, ident := range b.idents {
+			if r.sites[ident] {
+				binding.Definition = ident
+				break
+			}
+		}
+		if binding.Definition != nil {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		a, b := bindings[i].Definition.Token, bindings[j].Definition.Token
+		return before(a.Line, a.Column, b.Line, b.Column)
+	})
+	return bindings
+}
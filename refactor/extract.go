@@ -0,0 +1,227 @@
+package refactor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// ExtractFunction returns the edit that moves lines first to last of src,
+// which must hold whole statements of one block or of the program, into a
+// function called name. The function is bound just before the line range,
+// taking as parameters the locals of the enclosing functions the statements
+// use, and the statements are replaced by a call to it, so that the block
+// goes on to have the value it had.
+//
+// Statements that return from the function they are in, or that bind names
+// used elsewhere in it, cannot be moved out of it, and a name that would
+// change what other identifiers refer to is refused as Rename refuses it.
+func ExtractFunction(src string, first, last int, name string) ([]Edit, error) {
+	if !isIdentifier(name) {
+		return nil, fmt.Errorf("%q is not a name that can be bound", name)
+	}
+	if first < 1 || last < first {
+		return nil, fmt.Errorf("%d-%d is not a range of lines", first, last)
+	}
+	program, r, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	toks := tokens(src)
+	stmts, s, err := r.selection(program, src, toks, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[*ast.Identifier]bool{}
+	var idents []*ast.Identifier
+	var returns *ast.ReturnStatement
+	locals := newScope(nil)
+	for _, stmt := range stmts {
+		declareLets(locals, stmt)
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FunctionLiteral:
+				// Nested functions return from themselves, but their
+				// identifiers are still used by the statements.
+				ast.Inspect(node, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Identifier); ok {
+						selected[ident] = true
+						idents = append(idents, ident)
+					}
+					return true
+				})
+				return false
+			case *ast.ReturnStatement:
+				if returns == nil {
+					returns = node
+				}
+			case *ast.Identifier:
+				selected[node] = true
+				idents = append(idents, node)
+			}
+			return true
+		})
+	}
+	if returns != nil {
+		return nil, fmt.Errorf("the return at %s would return from %s instead", position(returns.Token), name)
+	}
+
+	var outside []*ast.Identifier
+	for local := range locals.bindings {
+		for _, ident := range s.bindings[local].idents {
+			if !selected[ident] {
+				outside = append(outside, ident)
+			}
+		}
+	}
+	if len(outside) > 0 {
+		sortIdentifiers(outside)
+		return nil, fmt.Errorf("%s is bound in lines %d-%d but used at %s", outside[0].Value, first, last, position(outside[0].Token))
+	}
+
+	if other, ok := s.bindings[name]; ok {
+		sortIdentifiers(other.idents)
+		return nil, fmt.Errorf("%s is already bound in the same scope, at %s", name, position(other.idents[0].Token))
+	}
+	if ident := r.captured(s, name); ident != nil {
+		return nil, fmt.Errorf("%s at %s would refer to the extracted function", name, position(ident.Token))
+	}
+
+	// The locals of the enclosing functions the statements use become the
+	// parameters; the program's bindings are seen from the function as they
+	// are from the statements.
+	sortIdentifiers(idents)
+	var params []string
+	seen := map[*binding]bool{}
+	for _, ident := range idents {
+		b := r.binding[ident]
+		if b == nil || b.scope == r.top || seen[b] || !s.within(b.scope) || b.scope == s && locals.bindings[b.name] != nil {
+			continue
+		}
+		seen[b] = true
+		params = append(params, b.name)
+	}
+
+	lines := lineStarts(src)
+	start, end := lines[first-1], len(src)
+	if last < len(lines) {
+		end = lines[last]
+	}
+	indent := src[start:]
+	indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+
+	// Lines that start within a token, such as a string written over
+	// several lines, are left as they are.
+	within := map[int]bool{}
+	for _, tok := range toks {
+		for line := tok.Line + 1; line <= tok.Line+strings.Count(src[tok.start:tok.end], "\n"); line++ {
+			within[line] = true
+		}
+	}
+
+	var out strings.Builder
+	call := name + "(" + strings.Join(params, ", ") + ")"
+	fmt.Fprintf(&out, "%slet %s = fn(%s) {\n", indent, name, strings.Join(params, ", "))
+	for i, line := range strings.SplitAfter(strings.TrimSuffix(src[start:end], "\n"), "\n") {
+		if within[first+i] {
+			out.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			out.WriteString("\t")
+		}
+		out.WriteString(strings.TrimRight(line, " \t\r\n") + "\n")
+	}
+	fmt.Fprintf(&out, "%s};\n%s%s;", indent, indent, call)
+	if strings.HasSuffix(src[start:end], "\n") {
+		out.WriteString("\n")
+	}
+
+	return []Edit{{Line: first, Column: 1, Length: end - start, Text: out.String()}}, nil
+}
+
+// selection returns the statements lines first to last of src hold, and
+// the scope they are in: the statements of the outermost block, or of the
+// program, that start in the lines, which must not share the first or the
+// last line with anything else.
+func (r *resolution) selection(program *ast.Program, src string, toks []lexed, first, last int) ([]ast.Statement, *scope, error) {
+	type list struct {
+		stmts []ast.Statement
+		scope *scope
+		end   int // the index of the token after the last statement
+	}
+	lines := lineStarts(src)
+	lists := []list{{program.Statements, r.top, len(toks) - 1}}
+	ast.Inspect(program, func(node ast.Node) bool {
+		// Blocks the parser made up around a single expression, such as the
+		// body of a lambda, are left to the statements they are part of.
+		if block, ok := node.(*ast.BlockStatement); ok && block.Token.Type == token.LBRACE {
+			lists = append(lists, list{block.Statements, r.blocks[block], closing(toks, lines, block.Token)})
+		}
+		return true
+	})
+
+	for _, l := range lists {
+		i := 0
+		for i < len(l.stmts) && statementToken(l.stmts[i]).Line < first {
+			i++
+		}
+		j := i
+		for j < len(l.stmts) && statementToken(l.stmts[j]).Line <= last {
+			j++
+		}
+		if i == j {
+			continue
+		}
+
+		from, _ := at(toks, offset(lines, statementToken(l.stmts[i])))
+		to := l.end
+		if j < len(l.stmts) {
+			to, _ = at(toks, offset(lines, statementToken(l.stmts[j])))
+		}
+		end := toks[to-1]
+		endLine := end.Line + strings.Count(src[end.start:end.end], "\n")
+		if from > 0 && toks[from-1].Line >= first || endLine > last || toks[to].Type != token.EOF && toks[to].Line <= last {
+			return nil, nil, fmt.Errorf("lines %d-%d do not hold whole statements", first, last)
+		}
+		return l.stmts[i:j], l.scope, nil
+	}
+	return nil, nil, fmt.Errorf("lines %d-%d hold no statements", first, last)
+}
+
+// closing returns the index of the token of toks that closes the brace open.
+func closing(toks []lexed, lines []int, open token.Token) int {
+	i, _ := at(toks, offset(lines, open))
+	depth := 0
+	for ; i < len(toks)-1; i++ {
+		switch toks[i].Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// statementToken returns the first token of stmt.
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
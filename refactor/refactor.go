@@ -0,0 +1,131 @@
+// Package refactor computes the changes to a script's source that refactor
+// it: renaming a binding everywhere it is used, or extracting lines into a
+// function of their own. Refactorings are given as edits to the source, so
+// that everything else in it, comments and layout included, stays where it
+// was.
+package refactor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Edit replaces the Length bytes of the source from Line and Column, which
+// count from 1 like those of tokens, with Text.
+type Edit struct {
+	Line   int
+	Column int
+	Length int
+	Text   string
+}
+
+// Apply returns src with edits made to it. The edits must not overlap.
+func Apply(src string, edits []Edit) string {
+	lines := lineStarts(src)
+	sorted := append([]Edit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return before(sorted[j].Line, sorted[j].Column, sorted[i].Line, sorted[i].Column)
+	})
+	for _, edit := range sorted {
+		start := offset(lines, token.Token{Line: edit.Line, Column: edit.Column})
+		src = src[:start] + edit.Text + src[start+edit.Length:]
+	}
+	return src
+}
+
+// parse parses src and resolves its names. A script that does not parse, or
+// that looks names up at run time, as eval does, cannot be refactored.
+func parse(src string) (*ast.Program, *resolution, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, nil, errors.New("the script does not parse")
+	}
+
+	var dynamic *ast.Identifier
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && dynamic == nil {
+			if capability, ok := evaluator.BuiltinCapability(ident.Value); ok && capability == evaluator.Dynamic {
+				dynamic = ident
+			}
+		}
+		return dynamic == nil
+	})
+	if dynamic != nil {
+		return nil, nil, fmt.Errorf("the script uses %s at %s, which looks names up as it runs", dynamic.Value, position(dynamic.Token))
+	}
+	return program, resolve(program), nil
+}
+
+// lexed is a token with the offsets of the source it was read from.
+type lexed struct {
+	token.Token
+	start, end int
+}
+
+// tokens returns every token of src, up to and including EOF.
+func tokens(src string) []lexed {
+	lines := lineStarts(src)
+	l := lexer.New(src)
+	var toks []lexed
+	for {
+		tok := l.NextToken()
+		toks = append(toks, lexed{tok, offset(lines, tok), l.Offset()})
+		if tok.Type == token.EOF {
+			return toks
+		}
+	}
+}
+
+// at returns the index of the token of toks that starts at offset.
+func at(toks []lexed, offset int) (int, bool) {
+	i := sort.Search(len(toks), func(i int) bool { return toks[i].start >= offset })
+	return i, i < len(toks) && toks[i].start == offset
+}
+
+// isIdentifier reports whether name can be bound, that is whether it lexes
+// as a single identifier rather than as a keyword or as several tokens.
+func isIdentifier(name string) bool {
+	l := lexer.New(name)
+	tok := l.NextToken()
+	return tok.Type == token.IDENTIFIER && tok.Literal == name && l.NextToken().Type == token.EOF
+}
+
+// lineStarts returns the offset each line of src starts at.
+func lineStarts(src string) []int {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// offset returns the offset of tok in the source lines are the starts of.
+func offset(lines []int, tok token.Token) int {
+	return lines[tok.Line-1] + tok.Column - 1
+}
+
+func before(line, column, otherLine, otherColumn int) bool {
+	return line < otherLine || line == otherLine && column < otherColumn
+}
+
+// sortIdentifiers sorts idents into the order they appear in the source.
+func sortIdentifiers(idents []*ast.Identifier) {
+	sort.Slice(idents, func(i, j int) bool {
+		a, b := idents[i].Token, idents[j].Token
+		return before(a.Line, a.Column, b.Line, b.Column)
+	})
+}
+
+func position(tok token.Token) string {
+	return fmt.Sprintf("%d:%d", tok.Line, tok.Column)
+}
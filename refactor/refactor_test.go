@@ -0,0 +1,225 @@
+package refactor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		input        string
+		name         string
+		line, column int
+		to           string
+		expected     string
+	}{
+		{"let x = 1; x + x", "x", 0, 0, "total", "let total = 1; total + total"},
+		{
+			"let x = 1;\nlet f = fn(x) {\n  x * 2 // doubled\n};\nf(x)",
+			"x", 2, 12, "n",
+			"let x = 1;\nlet f = fn(n) {\n  n * 2 // doubled\n};\nf(x)",
+		},
+		{
+			"let x = 1;\nlet f = fn(x) { x };\nf(x)",
+			"x", 3, 3, "y",
+			"let y = 1;\nlet f = fn(x) { x };\nf(y)",
+		},
+		{"let f = fn(x) { fn() { x } }", "x", 1, 12, "y", "let f = fn(y) { fn() { y } }"},
+		{"let {name, age} = p; name", "name", 0, 0, "n", "let {name: n, age} = p; n"},
+		{"let {name: name} = p; name", "name", 0, 0, "n", "let {name: n} = p; n"},
+		{"[x * 2 for x in xs]", "x", 0, 0, "item", "[item * 2 for item in xs]"},
+		{"match (v) { (a, b) => a + b }", "a", 0, 0, "first", "match (v) { (first, b) => first + b }"},
+		{"let h = 1; {h: h}", "h", 0, 0, "k", "let k = 1; {k: k}"},
+		{"let x = 1; x", "x", 0, 0, "x", "let x = 1; x"},
+	}
+
+	for _, tt := range tests {
+		edits, err := Rename(tt.input, tt.name, tt.line, tt.column, tt.to)
+		if err != nil {
+			t.Errorf("Rename(%q, %s) failed: %s", tt.input, tt.name, err)
+			continue
+		}
+		if got := Apply(tt.input, edits); got != tt.expected {
+			t.Errorf("Rename(%q, %s) = %q, want %q", tt.input, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		name         string
+		line, column int
+		to           string
+		expected     string
+	}{
+		{"let x = 1; x", "x", 0, 0, "fn", `"fn" is not a name that can be bound`},
+		{"let x = 1; x", "x", 0, 0, "a b", `"a b" is not a name that can be bound`},
+		{"let x = ; x", "x", 0, 0, "y", "the script does not parse"},
+		{"len(x)", "len", 0, 0, "size", "len is not bound in the script"},
+		{"len(x)", "len", 1, 1, "size", "len at 1:1 is not bound in the script"},
+		{"let x = 1; x", "x", 1, 2, "y", "there is no x at 1:2"},
+		{"let x = 1; fn(x) { x }", "x", 0, 0, "y", "x is bound in 2 places; give the position of the one to rename"},
+		{"let x = 1; let y = 2; x", "x", 0, 0, "y", "y is already bound in the same scope, at 1:16"},
+		{"let x = 1; fn(y) { x + y }", "x", 0, 0, "y", "x at 1:20 would refer to the y bound at 1:15"},
+		{"let f = fn(x) { len(x) }", "x", 0, 0, "len", "len at 1:17 would refer to the renamed x"},
+		{`let x = 1; eval("x")`, "x", 0, 0, "y", "the script uses eval at 1:12, which looks names up as it runs"},
+	}
+
+	for _, tt := range tests {
+		_, err := Rename(tt.input, tt.name, tt.line, tt.column, tt.to)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Rename(%q, %s, %s) error = %v, want %q", tt.input, tt.name, tt.to, err, tt.expected)
+		}
+	}
+}
+
+// TestRenameMatchesRewrite checks that the edits Rename makes give the
+// program ast.Rewrite does when it renames the same identifiers.
+func TestRenameMatchesRewrite(t *testing.T) {
+	input := `let total = fn(xs) {
+	let sum = 0;
+	let add = fn(n) { sum + n };
+	[add(x) for x in xs if x > sum]
+};
+total([1, 2, 3])`
+
+	program, r, err := parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.find(program, "sum", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := map[*ast.Identifier]bool{}
+	for _, ident := range b.idents {
+		renamed[ident] = true
+	}
+	want := ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok && renamed[ident] {
+			return &ast.Identifier{Token: ident.Token, Value: "acc"}
+		}
+		return node
+	}).String()
+
+	edits, err := Rename(input, "sum", 0, 0, "acc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parser.New(lexer.New(Apply(input, edits))).ParseProgram().String(); got != want {
+		t.Errorf("renamed program is %q, want %q", got, want)
+	}
+}
+
+func TestExtractFunction(t *testing.T) {
+	tests := []struct {
+		input       string
+		first, last int
+		name        string
+		expected    string
+	}{
+		{
+			"let a = 1;\nlet b = a + 1;\nputs(b);\n",
+			2, 3, "show",
+			"let a = 1;\nlet show = fn() {\n\tlet b = a + 1;\n\tputs(b);\n};\nshow();\n",
+		},
+		{
+			"let f = fn(x, y) {\n\tlet z = x * 2;\n\tz + y\n};\nf(1, 2)",
+			2, 3, "combine",
+			"let f = fn(x, y) {\n\tlet combine = fn(x, y) {\n\t\tlet z = x * 2;\n\t\tz + y\n\t};\n\tcombine(x, y);\n};\nf(1, 2)",
+		},
+		{
+			"let f = fn(n) {\n\tlet g = fn() { return n; };\n\tg()\n};\nf(4)",
+			2, 3, "h",
+			"let f = fn(n) {\n\tlet h = fn(n) {\n\t\tlet g = fn() { return n; };\n\t\tg()\n\t};\n\th(n);\n};\nf(4)",
+		},
+		{
+			"let f = fn(xs) {\n\t[x + 1 for x in xs]\n};\nf([1])",
+			2, 2, "inc",
+			"let f = fn(xs) {\n\tlet inc = fn(xs) {\n\t\t[x + 1 for x in xs]\n\t};\n\tinc(xs);\n};\nf([1])",
+		},
+	}
+
+	for _, tt := range tests {
+		edits, err := ExtractFunction(tt.input, tt.first, tt.last, tt.name)
+		if err != nil {
+			t.Errorf("ExtractFunction(%q, %d, %d) failed: %s", tt.input, tt.first, tt.last, err)
+			continue
+		}
+		got := Apply(tt.input, edits)
+		if got != tt.expected {
+			t.Errorf("ExtractFunction(%q, %d, %d) = %q, want %q", tt.input, tt.first, tt.last, got, tt.expected)
+			continue
+		}
+
+		if before, after := evaluate(tt.input), evaluate(got); before != after {
+			t.Errorf("ExtractFunction(%q, %d, %d) evaluates to %s, want %s", tt.input, tt.first, tt.last, after, before)
+		}
+	}
+}
+
+func TestExtractFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		first, last int
+		name        string
+		expected    string
+	}{
+		{"let a = 1;\na", 1, 1, "let", `"let" is not a name that can be bound`},
+		{"let a = 1;\na", 2, 1, "f", "2-1 is not a range of lines"},
+		{"let a = [\n1,\n2];\na", 2, 2, "f", "lines 2-2 hold no statements"},
+		{"let a = 1; let b = 2;\nb", 1, 1, "f", "b is bound in lines 1-1 but used at 2:1"},
+		{"let f = fn() {\nputs(1); }", 2, 2, "g", "lines 2-2 do not hold whole statements"},
+		{"let f = fn() { let a = 1;\na }", 2, 2, "g", "lines 2-2 do not hold whole statements"},
+		{"let f = fn(x) {\nif (x) { return 1; }\n2\n}", 2, 2, "g", "the return at 2:10 would return from g instead"},
+		{"let f = fn(x) {\nlet y = x;\ny\n}", 2, 2, "g", "y is bound in lines 2-2 but used at 3:1"},
+		{"let f = fn(x) {\nputs(x);\nlet g = 1;\n}", 2, 2, "g", "g is already bound in the same scope, at 3:5"},
+		{"let g = 1;\nlet f = fn(x) {\nputs(x);\ng\n}", 3, 3, "g", "g at 4:1 would refer to the extracted function"},
+	}
+
+	for _, tt := range tests {
+		_, err := ExtractFunction(tt.input, tt.first, tt.last, tt.name)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("ExtractFunction(%q, %d, %d) error = %v, want %q", tt.input, tt.first, tt.last, err, tt.expected)
+		}
+	}
+}
+
+func evaluate(input string) string {
+	var out strings.Builder
+	ev := evaluator.New(evaluator.Config{Output: &out})
+	result := ev.Eval(parser.New(lexer.New(input)).ParseProgram(), ev.NewEnvironment())
+	return out.String() + result.Inspect()
+}
+
+func TestBindings(t *testing.T) {
+	input := "let f = fn(n) { g(n); let g = fn(m) { m } };\nlet {a} = {a: 1};\n[x for x in [a]]"
+	program, _, err := parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, b := range Bindings(program) {
+		got = append(got, fmt.Sprintf("%s %s %d %t", b.Name, position(b.Definition.Token), len(b.Identifiers), b.TopLevel))
+	}
+	want := []string{
+		"f 1:5 1 true",
+		"n 1:12 2 false",
+		"g 1:27 2 false",
+		"m 1:34 2 false",
+		"a 2:6 3 true",
+		"x 3:8 2 false",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings(%q) = %q, want %q", input, got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package refactor
+
+import (
+	"fmt"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Rename returns the edits that rename the binding of name in src to to,
+// changing the identifier that binds it and every one that refers to it,
+// and nothing else. With line and column at 0, name must be bound in just
+// one place; otherwise they give the position of one of the binding's
+// identifiers, the way an editor gives the one under the cursor.
+//
+// A rename that would change what any name refers to is refused: to being
+// bound in the same scope already, a nested scope binding to around one of
+// the uses, or the binding's scope holding uses of to that refer to
+// something else.
+func Rename(src, name string, line, column int, to string) ([]Edit, error) {
+	if !isIdentifier(to) {
+		return nil, fmt.Errorf("%q is not a name that can be bound", to)
+	}
+	program, r, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := r.find(program, name, line, column)
+	if err != nil {
+		return nil, err
+	}
+	if name == to {
+		return nil, nil
+	}
+
+	if other, ok := b.scope.bindings[to]; ok {
+		sortIdentifiers(other.idents)
+		return nil, fmt.Errorf("%s is already bound in the same scope, at %s", to, position(other.idents[0].Token))
+	}
+	for _, ident := range b.idents {
+		for s := r.scope[ident]; s != b.scope; s = s.parent {
+			if other, ok := s.bindings[to]; ok {
+				sortIdentifiers(other.idents)
+				return nil, fmt.Errorf("%s at %s would refer to the %s bound at %s", name, position(ident.Token), to, position(other.idents[0].Token))
+			}
+		}
+	}
+	if ident := r.captured(b.scope, to); ident != nil {
+		return nil, fmt.Errorf("%s at %s would refer to the renamed %s", to, position(ident.Token), name)
+	}
+
+	toks, lines := tokens(src), lineStarts(src)
+	shorthands := shorthands(program)
+	sortIdentifiers(b.idents)
+	edits := make([]Edit, 0, len(b.idents))
+	for _, ident := range b.idents {
+		edit := Edit{Line: ident.Token.Line, Column: ident.Token.Column, Length: len(ident.Token.Literal), Text: to}
+		// {name} binds name to the field of the same name; renaming the
+		// binding but not the field spells the pair out.
+		if shorthands[ident] {
+			i, _ := at(toks, offset(lines, ident.Token))
+			if i == 0 || toks[i-1].Type != token.COLON {
+				edit.Text = name + ": " + to
+			}
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+// find returns the binding of name the identifier at line and column
+// belongs to, or the only binding of name when they are 0.
+func (r *resolution) find(program *ast.Program, name string, line, column int) (*binding, error) {
+	if line == 0 && column == 0 {
+		var found []*binding
+		seen := map[*binding]bool{}
+		ast.Inspect(program, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok && ident.Value == name {
+				if b := r.binding[ident]; b != nil && !seen[b] {
+					seen[b] = true
+					found = append(found, b)
+				}
+			}
+			return true
+		})
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("%s is not bound in the script", name)
+		case 1:
+			return found[0], nil
+		}
+		return nil, fmt.Errorf("%s is bound in %d places; give the position of the one to rename", name, len(found))
+	}
+
+	for ident, b := range r.binding {
+		tok := ident.Token
+		if ident.Value != name || tok.Line != line || column < tok.Column || column >= tok.Column+len(tok.Literal) {
+			continue
+		}
+		if b == nil {
+			return nil, fmt.Errorf("%s at %d:%d is not bound in the script", name, line, column)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("there is no %s at %d:%d", name, line, column)
+}
+
+// shorthands returns the identifiers of hash patterns that are bound to the
+// field of their own name, which are written either {name} or {name: name}.
+func shorthands(program *ast.Program) map[*ast.Identifier]bool {
+	found := map[*ast.Identifier]bool{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if pattern, ok := node.(*ast.HashPattern); ok {
+			for _, pair := range pattern.Pairs {
+				if ident, ok := pair.Value.(*ast.Identifier); ok && ident.Value == pair.Key {
+					found[ident] = true
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,233 @@
+package refactor
+
+import "github.com/fcidade/monkey-lang/ast"
+
+// scope is a place names are bound in: the program, a function literal, a
+// comprehension or an arm of a match.
+type scope struct {
+	parent   *scope
+	bindings map[string]*binding
+}
+
+// binding is a name bound in a scope, with every identifier that binds it
+// or refers to it.
+type binding struct {
+	name   string
+	scope  *scope
+	idents []*ast.Identifier
+}
+
+// resolution holds what every identifier of a program refers to. The rules
+// are those the evaluator resolves locals by: a function's parameters and
+// every let in its body, outside of nested functions, share one scope, as
+// do the pattern of a comprehension and the lets in its element and
+// condition, and the pattern of a match arm and the lets in its guard and
+// body. A name refers to the binding of the innermost scope binding it
+// anywhere, and the top-level lets, those in the blocks of top-level ifs
+// included, are the program's scope.
+type resolution struct {
+	top *scope
+
+	// binding is the binding each identifier refers to, nil for the names
+	// the program does not bind, such as builtins.
+	binding map[*ast.Identifier]*binding
+
+	// scope is the scope each identifier is in, and blocks the scope of the
+	// statements of each block.
+	scope  map[*ast.Identifier]*scope
+	blocks map[*ast.BlockStatement]*scope
+
+	// sites are the identifiers that bind a name rather than refer to one.
+	sites map[*ast.Identifier]bool
+}
+
+func resolve(program *ast.Program) *resolution {
+	r := &resolution{
+		binding: map[*ast.Identifier]*binding{},
+		scope:   map[*ast.Identifier]*scope{},
+		blocks:  map[*ast.BlockStatement]*scope{},
+		sites:   map[*ast.Identifier]bool{},
+	}
+	r.top = newScope(nil)
+	declareLets(r.top, program)
+	r.walk(program, r.top)
+	return r
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, bindings: map[string]*binding{}}
+}
+
+func (s *scope) declare(name string) {
+	if _, ok := s.bindings[name]; !ok {
+		s.bindings[name] = &binding{name: name, scope: s}
+	}
+}
+
+// lookup returns the binding name refers to in s, or nil when nothing
+// binds it.
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// within reports whether s is outer or a scope nested in it.
+func (s *scope) within(outer *scope) bool {
+	for ; s != nil; s = s.parent {
+		if s == outer {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resolution) walk(node ast.Node, s *scope) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			inner := newScope(s)
+			for _, param := range node.Parameters {
+				if param != nil {
+					inner.declare(param.Value)
+					r.sites[param] = true
+				}
+			}
+			declareLets(inner, node.Body)
+			for _, param := range node.Parameters {
+				r.walk(param, inner)
+			}
+			r.walk(node.Body, inner)
+			return false
+
+		case *ast.ArrayComprehension:
+			r.walkComprehension(node.Clause, s, node.Element)
+			return false
+
+		case *ast.HashComprehension:
+			r.walkComprehension(node.Clause, s, node.Key, node.Value)
+			return false
+
+		case *ast.MatchExpression:
+			r.walk(node.Subject, s)
+			for _, arm := range node.Arms {
+				inner := newScope(s)
+				r.bindPattern(inner, arm.Pattern)
+				declareLets(inner, arm.Guard)
+				declareLets(inner, arm.Body)
+				r.walk(arm.Pattern, inner)
+				r.walk(arm.Guard, inner)
+				r.walk(arm.Body, inner)
+			}
+			return false
+
+		case *ast.LetStatement:
+			if node.Name != nil {
+				r.sites[node.Name] = true
+			}
+			r.markSites(node.Pattern)
+
+		case *ast.BlockStatement:
+			r.blocks[node] = s
+
+		case *ast.Identifier:
+			b := s.lookup(node.Value)
+			if b != nil {
+				b.idents = append(b.idents, node)
+			}
+			r.binding[node] = b
+			r.scope[node] = s
+		}
+		return true
+	})
+}
+
+func (r *resolution) walkComprehension(clause *ast.ComprehensionClause, s *scope, body ...ast.Expression) {
+	if clause == nil {
+		return
+	}
+	r.walk(clause.Iterable, s)
+
+	inner := newScope(s)
+	r.bindPattern(inner, clause.Pattern)
+	declareLets(inner, clause.Condition)
+	for _, expr := range body {
+		declareLets(inner, expr)
+	}
+	r.walk(clause.Pattern, inner)
+	r.walk(clause.Condition, inner)
+	for _, expr := range body {
+		r.walk(expr, inner)
+	}
+}
+
+// bindPattern declares the names pattern binds in s, marking its
+// identifiers as binding them.
+func (r *resolution) bindPattern(s *scope, pattern ast.Pattern) {
+	declarePattern(s, pattern)
+	r.markSites(pattern)
+}
+
+func (r *resolution) markSites(pattern ast.Pattern) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			r.sites[ident] = true
+		}
+		return true
+	})
+}
+
+// declareLets declares in s the names bound by the lets in node, leaving out
+// the ones in nested scopes.
+func declareLets(s *scope, node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ArrayComprehension:
+			if node.Clause != nil {
+				declareLets(s, node.Clause.Iterable)
+			}
+			return false
+		case *ast.HashComprehension:
+			if node.Clause != nil {
+				declareLets(s, node.Clause.Iterable)
+			}
+			return false
+		case *ast.MatchExpression:
+			declareLets(s, node.Subject)
+			return false
+		case *ast.LetStatement:
+			if node.Name != nil {
+				s.declare(node.Name.Value)
+			}
+			declarePattern(s, node.Pattern)
+		}
+		return true
+	})
+}
+
+func declarePattern(s *scope, pattern ast.Pattern) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			s.declare(ident.Value)
+		}
+		return true
+	})
+}
+
+// captured returns an identifier named name that is in s, or a scope nested
+// in it, and refers to a binding outside of s, or to none, so that binding
+// name in s would change what it refers to. It returns nil when there is no
+// such identifier.
+func (r *resolution) captured(s *scope, name string) *ast.Identifier {
+	for ident, b := range r.binding {
+		if ident.Value == name && r.scope[ident].within(s) && (b == nil || !b.scope.within(s)) {
+			return ident
+		}
+	}
+	return nil
+}
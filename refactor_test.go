@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/refactor"
+)
+
+func TestRefactorFile(t *testing.T) {
+	rename := func(src string) ([]refactor.Edit, error) {
+		return refactor.Rename(src, "a", 0, 0, "total")
+	}
+	tests := []struct {
+		content    string
+		overwrite  bool
+		ok         bool
+		output     string
+		refactored string
+	}{
+		{"let a = 1 // one\nputs(a)\n", false, true, "let total = 1 // one\nputs(total)\n", "let a = 1 // one\nputs(a)\n"},
+		{"let a = 1 // one\nputs(a)\n", true, true, "", "let total = 1 // one\nputs(total)\n"},
+		{"let b = 1\n", true, false, "cannot refactor script.mk: a is not bound in the script\n", "let b = 1\n"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "script.mk")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if ok := refactorFile(path, tt.overwrite, &out, rename); ok != tt.ok {
+			t.Errorf("%q: expected ok=%t, got %t", tt.content, tt.ok, ok)
+		}
+		if got := string(bytes.ReplaceAll(out.Bytes(), []byte(dir+string(filepath.Separator)), nil)); got != tt.output {
+			t.Errorf("%q: expected output %q, got %q", tt.content, tt.output, got)
+		}
+		if content, _ := os.ReadFile(path); string(content) != tt.refactored {
+			t.Errorf("%q: expected the script to hold %q, got %q", tt.content, tt.refactored, content)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Terminals in bracketed paste mode wrap pasted text between pasteStart
+// and pasteEnd.
+const (
+	enableBracketedPaste  = "\x1b[?2004h"
+	disableBracketedPaste = "\x1b[?2004l"
+	pasteStart            = "\x1b[200~"
+	pasteEnd              = "\x1b[201~"
+)
+
+// incomplete reports whether source stops partway through, leaving a
+// bracket or a string open, so more lines are needed before it can parse.
+func incomplete(source string) bool {
+	l := lexer.New(source)
+	depth := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.ILLEGAL:
+			literal := strings.TrimPrefix(tok.Literal, "b")
+			if strings.HasPrefix(literal, `"`) || strings.HasPrefix(literal, "`") {
+				return true
+			}
+		}
+	}
+	return depth > 0
+}
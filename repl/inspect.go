@@ -0,0 +1,44 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// printTokens writes the tokens the lexer reads from code, one per line with
+// the position it starts at, up to and including the EOF token.
+func printTokens(out io.Writer, code string) {
+	l := lexer.New(code)
+	for {
+		tok := l.NextToken()
+		name := string(tok.Type)
+		if tok.Type == token.EOF {
+			name = "EOF"
+		}
+		fmt.Fprintf(out, "%d:%d %s %q\n", tok.Line, tok.Column, name, tok.Literal)
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+// printTree writes node and its children as an indented outline, each line
+// holding the kind of node and the source it stands for.
+func printTree(out io.Writer, node ast.Node, depth int) {
+	kind := reflect.TypeOf(node).Elem().Name()
+	fmt.Fprintf(out, "%s%s %s\n", strings.Repeat("  ", depth), kind, node.String())
+
+	ast.Inspect(node, func(child ast.Node) bool {
+		if child == node {
+			return true
+		}
+		printTree(out, child, depth+1)
+		return false
+	})
+}
@@ -2,50 +2,533 @@ package repl
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
 	"github.com/fcidade/monkey-lang/evaluator"
-	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/message"
 	"github.com/fcidade/monkey-lang/object"
-	"github.com/fcidade/monkey-lang/parser"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "
+	GOODBYE             = "Goodbye!\n"
+)
+
+// Config controls how the REPL presents itself. An empty Prompt, Banner,
+// ContinuationPrompt or Goodbye is not printed at all, Writer defaults to
+// os.Stdout and every line read is appended to HistoryFile when it is set.
+// BracketedPaste asks the terminal to mark pasted text, so a paste is
+// evaluated as a whole even when its lines would parse on their own.
+//
+// The files in Preload are evaluated, in order, before the first prompt, so
+// they can define helpers for the session. Color writes errors in red.
+//
+// A signal on Interrupts, such as the os.Interrupt a terminal sends for
+// Ctrl-C, cancels the evaluation in progress, or discards the input typed so
+// far when there is none.
+//
+// Inputs are evaluated in Environment when it is set, such as the one a
+// script failed in, instead of in a new environment with the prelude.
+type Config struct {
+	Prompt             string
+	ContinuationPrompt string
+	Banner             string
+	Goodbye            string
+	Writer             io.Writer
+	HistoryFile        string
+	BracketedPaste     bool
+	Color              bool
+	Preload            []string
+	Interrupts         <-chan os.Signal
+	Evaluator          evaluator.Config
+	Environment        *object.Environment
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Prompt:             PROMPT,
+		ContinuationPrompt: CONTINUATION_PROMPT,
+		Goodbye:            GOODBYE,
+		Writer:             os.Stdout,
+	}
+}
+
+// Start reads lines from in until EOF, evaluating each input and writing the
+// results to config.Writer. An input that leaves a bracket or a string open
+// continues on the next line, until it is closed or an empty line is read;
+// a bracketed paste is one input however many lines it has. At EOF, such as
+// Ctrl-D in a terminal, Start says goodbye and reports whether every input
+// was evaluated without parser or runtime errors.
+func Start(in io.Reader, config Config) bool {
+	out := config.Writer
+	if out == nil {
+		out = os.Stdout
+	}
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	var history io.Writer = io.Discard
+	if config.HistoryFile != "" {
+		f, err := os.OpenFile(config.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			fmt.Fprintln(out, message.Format(message.HistoryFailed, err))
+		} else {
+			defer f.Close()
+			history = f
+		}
+	}
+
+	if config.BracketedPaste {
+		io.WriteString(out, enableBracketedPaste)
+		defer io.WriteString(out, disableBracketedPaste)
+	}
 
+	io.WriteString(out, config.Banner)
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	s := newSession(config)
+	defer s.Close()
+	for _, path := range config.Preload {
+		s.preload(out, path)
+	}
+	ok := true
+
+	var input []string
+	pasting := false
 	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		switch {
+		case pasting:
+		case len(input) > 0:
+			io.WriteString(out, config.ContinuationPrompt)
+		default:
+			io.WriteString(out, config.Prompt)
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		var line string
+		select {
+		case <-config.Interrupts:
+			input = nil
+			pasting = false
+			io.WriteString(out, "\n")
+			continue
 
-		program := p.ParseProgram()
+		case scanned, more := <-lines:
+			if !more {
+				if len(input) > 0 && !s.run(out, strings.Join(input, "\n")) {
+					ok = false
+				}
+				if config.Goodbye != "" {
+					if config.Prompt != "" {
+						// The cursor is still after the prompt.
+						io.WriteString(out, "\n")
+					}
+					io.WriteString(out, config.Goodbye)
+				}
+				return ok
+			}
+			line = scanned
+		}
 
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		if line != "" {
+			fmt.Fprintln(history, line)
+		}
+
+		if strings.Contains(line, pasteStart) {
+			pasting = true
+			line = strings.Replace(line, pasteStart, "", 1)
+		}
+		if strings.Contains(line, pasteEnd) {
+			pasting = false
+			line = strings.Replace(line, pasteEnd, "", 1)
+		}
+
+		if len(input) == 0 && !pasting && strings.HasPrefix(line, ":") {
+			if !s.runCommand(out, line) {
+				ok = false
+			}
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		input = append(input, line)
+		source := strings.Join(input, "\n")
+		if pasting || line != "" && incomplete(source) {
+			continue
+		}
+		input = nil
+
+		if !s.run(out, source) {
+			ok = false
 		}
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
-	io.WriteString(out, " parser errors:\n")
+// run evaluates source, writing the result or the errors to out. It
+// reports whether there were none.
+func (s *Session) run(out io.Writer, source string) bool {
+	result, diagnostics := s.evalSource(source)
+	switch {
+	case result.Error != nil:
+		s.printError(out, result.Error.Inspect()+"\n")
+	case len(diagnostics) != 0:
+		errors := make([]string, len(diagnostics))
+		for i, d := range diagnostics {
+			errors[i] = d.Message
+		}
+		s.printParseErrors(out, errors)
+		return false
+	case result.Value != nil:
+		io.WriteString(out, result.Value.Inspect())
+		io.WriteString(out, "\n")
+	}
+	io.WriteString(out, result.Output)
+	return len(diagnostics) == 0
+}
+
+// preload evaluates the file at path into the REPL's environment before the
+// session starts, writing any errors to out.
+func (s *Session) preload(out io.Writer, path string) {
+	evaluated, parseErrors, err := loadSession(path, s.ev, s.env)
+	switch {
+	case err != nil:
+		s.printError(out, message.Format(message.PreloadFailed, path, err)+"\n")
+	case len(parseErrors) != 0:
+		s.printParseErrors(out, parseErrors)
+	case evaluated != nil && evaluated.Type() == object.ERROR_OBJ:
+		s.printError(out, message.Format(message.PreloadFailed, path, evaluated.(*object.Error).Message)+"\n")
+	}
+}
+
+// printError writes text to out, in red when color is on.
+func (s *Session) printError(out io.Writer, text string) {
+	if s.color {
+		text = "\x1b[31m" + strings.TrimSuffix(text, "\n") + "\x1b[0m\n"
+	}
+	io.WriteString(out, text)
+}
+
+func (s *Session) printParseErrors(out io.Writer, errors []string) {
+	var text strings.Builder
+	PrintParseErrors(&text, errors)
+	s.printError(out, text.String())
+}
+
+// Session is what a REPL keeps from one input to the next. Start drives one
+// from the lines it reads, and NewSession makes one for programs such as
+// editors, notebooks and tests to drive input by input with EvalLine.
+type Session struct {
+	ev  *evaluator.Evaluator
+	env *object.Environment
+
+	// interrupts cancel the evaluation eval is running.
+	interrupts <-chan os.Signal
+
+	// timing is toggled by :time, making eval measure every input.
+	timing bool
+
+	// color makes printError write errors in red.
+	color bool
+
+	// results counts the values bound by remember.
+	results int
+
+	// output holds what puts writes, for the Output of the result of EvalLine;
+	// it is nil when the evaluator writes elsewhere.
+	output *bytes.Buffer
+}
+
+// Result is what an input to a session gave.
+type Result struct {
+	// Value is the value the input evaluated to, nil when it has none, as
+	// for a let, a command or an input that failed.
+	Value object.Object
+
+	// Name is the name Value is bound to for the inputs after it, such as
+	// _3; it is empty when there is no value.
+	Name string
+
+	// Error is the runtime error that stopped the evaluation, if one did.
+	Error *object.Error
+
+	// Output is the text the input wrote: what it put, when the session
+	// keeps that, what a command replied and the report :time asks for.
+	Output string
+}
+
+// NewSession returns a session that evaluates with config.Evaluator and
+// whose evaluations a signal on config.Interrupts cancels; the rest of
+// config is how Start presents a session. Unless config.Evaluator.Output is
+// set, what the inputs put is kept in their results.
+func NewSession(config Config) *Session {
+	var output *bytes.Buffer
+	if config.Evaluator.Output == nil {
+		output = &bytes.Buffer{}
+		config.Evaluator.Output = output
+	}
+	s := newSession(config)
+	s.output = output
+	return s
+}
+
+func newSession(config Config) *Session {
+	ev := evaluator.New(config.Evaluator)
+	env := config.Environment
+	if env == nil {
+		env = ev.NewEnvironment()
+	}
+	return &Session{
+		ev:         ev,
+		env:        env,
+		interrupts: config.Interrupts,
+		color:      config.Color,
+	}
+}
+
+// EvalLine evaluates input, which may run over several lines, in the
+// session, or runs it when it is a command starting with a colon, and
+// returns the result with the errors that came up: those the parser found,
+// the runtime error that stopped the evaluation or the failure of the
+// command.
+func (s *Session) EvalLine(input string) (Result, []diagnostic.Diagnostic) {
+	if strings.HasPrefix(input, ":") {
+		var out strings.Builder
+		if !s.runCommand(&out, input) {
+			failure := diagnostic.Diagnostic{Code: diagnostic.CommandFailed, Message: strings.TrimSuffix(out.String(), "\n")}
+			return Result{Output: out.String()}, []diagnostic.Diagnostic{failure}
+		}
+		return Result{Output: out.String()}, nil
+	}
+	return s.Eval(input)
+}
+
+// Eval evaluates source in the session like EvalLine, but as Monkey even
+// when it starts with a colon, so callers that must not reach the commands,
+// which read and write files, can use it.
+func (s *Session) Eval(source string) (Result, []diagnostic.Diagnostic) {
+	if s.output != nil {
+		defer s.output.Reset()
+	}
+	result, diagnostics := s.evalSource(source)
+	if s.output != nil {
+		result.Output = s.output.String() + result.Output
+	}
+	return result, diagnostics
+}
+
+// Close closes the resources, such as connections and databases, that the
+// inputs of the session opened and left open.
+func (s *Session) Close() error {
+	return s.ev.CloseResources()
+}
+
+// Binding is a name bound in a session and its value.
+type Binding struct {
+	Name  string
+	Value object.Object
+}
+
+// Bindings returns what the inputs of the session bound, sorted by name,
+// including the names of their results such as _1 but not the prelude.
+func (s *Session) Bindings() []Binding {
+	names := s.env.Names()
+	bindings := make([]Binding, len(names))
+	for i, name := range names {
+		value, _ := s.env.Get(name)
+		bindings[i] = Binding{Name: name, Value: value}
+	}
+	return bindings
+}
+
+// evalSource parses and evaluates source, remembering its value. The
+// Output of the result is only the :time report, since puts writes to the
+// evaluator's output as it goes.
+func (s *Session) evalSource(source string) (Result, []diagnostic.Diagnostic) {
+	p := s.ev.NewParser(source)
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) != 0 {
+		return Result{}, diagnostics
+	}
+
+	var result Result
+	var diagnostics []diagnostic.Diagnostic
+	evaluated, report := s.eval(program)
+	switch evaluated := evaluated.(type) {
+	case nil:
+	case *object.Error:
+		result.Error = evaluated
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{Line: evaluated.Line, Column: evaluated.Column, Code: evaluated.Code, Message: evaluated.Message})
+	default:
+		result.Value, result.Name = evaluated, s.remember(evaluated)
+	}
+	if report != "" {
+		result.Output = report + "\n"
+	}
+	return result, diagnostics
+}
+
+// remember binds value to _, the latest result, and to _1, _2 and so on,
+// numbering every result from the start of the session, and returns the
+// numbered name.
+func (s *Session) remember(value object.Object) string {
+	s.results++
+	name := fmt.Sprintf("_%d", s.results)
+	s.env.Set("_", value)
+	s.env.Set(name, value)
+	return name
+}
+
+// isResultName reports whether name is one remember binds.
+func isResultName(name string) bool {
+	if name == "_" {
+		return true
+	}
+	if !strings.HasPrefix(name, "_") {
+		return false
+	}
+	_, err := strconv.Atoi(name[1:])
+	return err == nil
+}
+
+// eval evaluates program in the REPL's environment. With timing on it also
+// returns a line reporting how long that took, how many steps and values it
+// needed and how much memory it allocated.
+func (s *Session) eval(program *ast.Program) (object.Object, string) {
+	if s.interrupts != nil {
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-s.interrupts:
+				s.ev.Interrupt()
+			case <-done:
+			}
+		}()
+		defer func() {
+			close(done)
+			<-stopped
+			s.ev.ClearInterrupt()
+		}()
+	}
+
+	if !s.timing {
+		return s.ev.Eval(program, s.env), ""
+	}
+
+	stats := &evaluator.Stats{}
+	s.ev.SetStats(stats)
+	defer s.ev.SetStats(nil)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	evaluated := s.ev.Eval(program, s.env)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	values := 0
+	for _, n := range stats.Values {
+		values += n
+	}
+	report := message.Format(message.TimeReport, elapsed, stats.Steps, values, after.TotalAlloc-before.TotalAlloc)
+	return evaluated, report
+}
+
+// runCommand handles the REPL commands that start with a colon, reporting
+// whether the command succeeded.
+func (s *Session) runCommand(out io.Writer, line string) bool {
+	fields := strings.Fields(line)
+
+	switch fields[0] {
+	case ":save":
+		if len(fields) != 2 {
+			fmt.Fprintln(out, message.Format(message.SaveUsage))
+			return false
+		}
+		saved, skipped, err := saveSession(fields[1], s.env)
+		if err != nil {
+			fmt.Fprintln(out, message.Format(message.SaveFailed, err))
+			return false
+		}
+		fmt.Fprintln(out, message.Format(message.Saved, saved, fields[1]))
+		if len(skipped) > 0 {
+			fmt.Fprintln(out, message.Format(message.SaveSkipped, strings.Join(skipped, ", ")))
+		}
+
+	case ":load-session":
+		if len(fields) != 2 {
+			fmt.Fprintln(out, message.Format(message.LoadUsage))
+			return false
+		}
+		evaluated, parseErrors, err := loadSession(fields[1], s.ev, s.env)
+		if err != nil {
+			fmt.Fprintln(out, message.Format(message.LoadFailed, err))
+			return false
+		}
+		if len(parseErrors) != 0 {
+			PrintParseErrors(out, parseErrors)
+			return false
+		}
+		if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+			fmt.Fprintln(out, evaluated.Inspect())
+			return false
+		}
+		fmt.Fprintln(out, message.Format(message.Loaded, fields[1]))
+
+	case ":time":
+		s.timing = !s.timing
+		if s.timing {
+			fmt.Fprintln(out, message.Format(message.TimingOn))
+		} else {
+			fmt.Fprintln(out, message.Format(message.TimingOff))
+		}
+
+	case ":heap":
+		evaluator.WriteHeapReport(out, object.NewHeap(s.env))
+
+	case ":tokens", ":parse":
+		code := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		if code == "" {
+			fmt.Fprintln(out, message.Format(message.InspectUsage, fields[0]))
+			return false
+		}
+		if fields[0] == ":tokens" {
+			printTokens(out, code)
+			break
+		}
+		p := s.ev.NewParser(code)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			PrintParseErrors(out, p.Errors())
+			return false
+		}
+		printTree(out, program, 0)
+
+	default:
+		fmt.Fprintln(out, message.Format(message.UnknownCommand, fields[0]))
+		return false
+	}
+
+	return true
+}
+
+func PrintParseErrors(out io.Writer, errors []string) {
+	io.WriteString(out, message.Format(message.ParseErrorsHeader)+"\n")
 	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
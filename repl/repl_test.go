@@ -0,0 +1,458 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+)
+
+func TestStartNonInteractive(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"let a = 5;\na * 2\n", "10\n", true},
+		{"1 + 1\n\"foo\"\n", "2\nfoo\n", true},
+		{"5 + true\n1\n", "Error: type mismatch: INTEGER + BOOLEAN\n1\n", false},
+		{"1 + 1\nlet a = 5;\n_ * a\n_1 + _2\n5 + true\n_\n", "2\n10\n12\n" +
+			"Error: type mismatch: INTEGER + BOOLEAN\n12\n", false},
+		{"let x 5\n", "Woops! We ran into some monkey business here!\n" +
+			" parser errors:\n" +
+			"\texpected next token to be =, got INT instead\n", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ok := Start(strings.NewReader(tt.input), Config{Writer: &out})
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, out.String())
+		}
+		if ok != tt.ok {
+			t.Errorf("wrong status for %q. expected=%t, got=%t", tt.input, tt.ok, ok)
+		}
+	}
+}
+
+func TestSessionEvalLine(t *testing.T) {
+	tests := []struct {
+		input       string
+		value       string
+		name        string
+		output      string
+		diagnostics []diagnostic.Diagnostic
+	}{
+		{"let a = 5;", "", "", "", nil},
+		{"puts(a); a * 2", "10", "_1", "5\n", nil},
+		{"_ + 1", "11", "_2", "", nil},
+		{"let f = fn(x) {\n  x + true\n};\nf(1)", "", "", "", []diagnostic.Diagnostic{
+			{Line: 2, Column: 3, Code: diagnostic.TypeMismatch, Message: "type mismatch: INTEGER + BOOLEAN"},
+		}},
+		{"let x 5", "", "", "", []diagnostic.Diagnostic{
+			{Line: 1, Column: 7, Code: diagnostic.UnexpectedToken, Message: "expected next token to be =, got INT instead"},
+		}},
+		{":time", "", "", "timing is on\n", nil},
+		{":nope", "", "", "unknown command: :nope\n", []diagnostic.Diagnostic{
+			{Code: diagnostic.CommandFailed, Message: "unknown command: :nope"},
+		}},
+	}
+
+	s := NewSession(Config{})
+	for _, tt := range tests {
+		result, diagnostics := s.EvalLine(tt.input)
+
+		value := ""
+		if result.Value != nil {
+			value = result.Value.Inspect()
+		}
+		if value != tt.value || result.Name != tt.name {
+			t.Errorf("%q evaluated to %q bound to %q, want %q bound to %q", tt.input, value, result.Name, tt.value, tt.name)
+		}
+		if result.Output != tt.output {
+			t.Errorf("%q wrote %q, want %q", tt.input, result.Output, tt.output)
+		}
+		if !reflect.DeepEqual(diagnostics, tt.diagnostics) {
+			t.Errorf("%q gave diagnostics %+v, want %+v", tt.input, diagnostics, tt.diagnostics)
+		}
+		if (result.Error != nil) != (len(diagnostics) == 1 && diagnostics[0].Code == diagnostic.TypeMismatch) {
+			t.Errorf("%q gave the error %v with diagnostics %+v", tt.input, result.Error, diagnostics)
+		}
+	}
+}
+
+func TestSessionBindings(t *testing.T) {
+	s := NewSession(Config{})
+	s.Eval("let a = 1; let f = fn(x) { x };")
+	s.Eval("a + 1")
+	if _, diagnostics := s.Eval(":time"); len(diagnostics) == 0 || diagnostics[0].Code == diagnostic.CommandFailed {
+		t.Errorf("expected Eval to parse :time as Monkey, got %+v", diagnostics)
+	}
+
+	var got []string
+	for _, b := range s.Bindings() {
+		got = append(got, b.Name+" = "+b.Value.Inspect())
+	}
+	expected := []string{"_ = 2", "_1 = 2", "a = 1", "f = fn(x) {\nx\n}"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected bindings %q, got %q", expected, got)
+	}
+}
+
+func TestStartPrintsBannerAndPrompt(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1\n"), Config{Prompt: "monkey> ", Banner: "hi!\n", Writer: &out})
+
+	expected := "hi!\nmonkey> 1\nmonkey> "
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestStartWritesHistory(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+
+	var out bytes.Buffer
+	Start(strings.NewReader("let a = 1;\n\na\n"), Config{Writer: &out, HistoryFile: history})
+
+	content, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatalf("could not read history file: %s", err)
+	}
+	if string(content) != "let a = 1;\na\n" {
+		t.Errorf("wrong history. got=%q", content)
+	}
+}
+
+func TestSaveAndLoadSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mkenv")
+
+	input := `let n = 5;
+let s = "monkey";
+let ok = !false;
+let list = [1, "two", [3]];
+let h = {"a": 1, 2: true};
+let double = fn(x) { if (x > 0) { x * 2 } else { 0 } };
+let adder = fn(x) { fn(y) { x + y } };
+let addTwo = adder(2);
+let p = puts;
+n
+:save ` + path + "\n"
+
+	var out bytes.Buffer
+	if !Start(strings.NewReader(input), Config{Writer: &out}) {
+		t.Fatalf("saving session failed: %s", out.String())
+	}
+	expected := "saved 7 bindings to " + path + "\n" +
+		"skipped values that cannot be serialized: addTwo, p\n"
+	if !strings.HasSuffix(out.String(), expected) {
+		t.Errorf("wrong output. expected suffix %q, got=%q", expected, out.String())
+	}
+
+	input = ":load-session " + path + "\n" +
+		`[n, s, ok, list[2][0], h["a"], h[2], double(4), adder(1)(2)]` + "\n"
+
+	out.Reset()
+	if !Start(strings.NewReader(input), Config{Writer: &out}) {
+		t.Fatalf("loading session failed: %s", out.String())
+	}
+	expected = "loaded session from " + path + "\n" +
+		"[5, monkey, true, 3, 1, true, 8, 3]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	if Start(strings.NewReader(":nope\n"), Config{Writer: &out}) {
+		t.Errorf("unknown command should fail")
+	}
+	if out.String() != "unknown command: :nope\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestTokensAndParseCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{":tokens let a = 1;\n", "1:1 LET \"let\"\n" +
+			"1:5 IDENT \"a\"\n" +
+			"1:7 = \"=\"\n" +
+			"1:9 INT \"1\"\n" +
+			"1:10 ; \";\"\n" +
+			"1:11 EOF \"\"\n", true},
+		{":parse -a + 2\n", "Program ((-a) + 2)\n" +
+			"  ExpressionStatement ((-a) + 2)\n" +
+			"    InfixExpression ((-a) + 2)\n" +
+			"      PrefixExpression (-a)\n" +
+			"        Identifier a\n" +
+			"      IntegerLiteral 2\n", true},
+		{":parse let a = 1; a\n", "Program let a = 1;a\n" +
+			"  LetStatement let a = 1;\n" +
+			"    Identifier a\n" +
+			"    IntegerLiteral 1\n" +
+			"  ExpressionStatement a\n" +
+			"    Identifier a\n", true},
+		{":parse let 5\n", "Woops! We ran into some monkey business here!\n" +
+			" parser errors:\n" +
+			"\texpected next token to be IDENT, got INT instead\n", false},
+		{":tokens\n", "usage: :tokens <code>\n", false},
+		{":parse let a = 1;\na\n", "Program let a = 1;\n" +
+			"  LetStatement let a = 1;\n" +
+			"    Identifier a\n" +
+			"    IntegerLiteral 1\n" +
+			"Error: identifier not found: a\n", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ok := Start(strings.NewReader(tt.input), Config{Writer: &out})
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, out.String())
+		}
+		if ok != tt.ok {
+			t.Errorf("wrong status for %q. expected=%t, got=%t", tt.input, tt.ok, ok)
+		}
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	var out bytes.Buffer
+	input := ":time\nlet a = [1, 2];\n1 + a[0]\n:time\n3\n"
+	if !Start(strings.NewReader(input), Config{Writer: &out}) {
+		t.Fatalf("timing failed: %s", out.String())
+	}
+
+	expected := regexp.MustCompile(`^timing is on
+took \S+, 5 steps, 3 values, \d+ bytes allocated
+2
+took \S+, 7 steps, 7 values, \d+ bytes allocated
+timing is off
+3
+$`)
+	if !expected.MatchString(out.String()) {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestHeapCommand(t *testing.T) {
+	var out bytes.Buffer
+	input := "let words = [\"a\", \"b\", \"c\"];\nlet f = fn(x) { x };\n:heap\n"
+	if !Start(strings.NewReader(input), Config{Writer: &out, Evaluator: evaluator.Config{NoPrelude: true}}) {
+		t.Fatalf("the heap command failed: %s", out.String())
+	}
+
+	for _, expected := range []string{
+		`^6 objects and environments reachable, \d+ bytes\nby type:\n`,
+		`\n  STRING +3 +\d+ bytes\n`,
+		`\nlargest bindings, by the bytes only they keep alive:\n`,
+		`\n  words +ARRAY +\d+ bytes +3 references\n`,
+		`\n  f +FUNCTION +\d+ bytes +1 references\n`,
+	} {
+		if !regexp.MustCompile(expected).MatchString(out.String()) {
+			t.Errorf("expected the report to match %q, got %q", expected, out.String())
+		}
+	}
+}
+
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"let double = fn(x) {\n  x * 2\n};\ndouble(4)\n", "8\n", true},
+		{"[1,\n2,\n3]\n", "[1, 2, 3]\n", true},
+		{"let s = `a\nb`;\nlen(s)\n", "3\n", true},
+		{"[1,\n\n2\n", "Woops! We ran into some monkey business here!\n" +
+			" parser errors:\n" +
+			"\tno prefix parse function for  found\n" +
+			"\texpected next token to be ], got  instead\n" +
+			"2\n", false},
+		{"\x1b[200~let a = 1\na + 1\x1b[201~\n_ * 3\n", "2\n6\n", true},
+		{"\x1b[200~if (true) { 1 }\nelse { 2 }\x1b[201~\n", "1\n", true},
+		{"(1 +\n", "Woops! We ran into some monkey business here!\n" +
+			" parser errors:\n" +
+			"\tno prefix parse function for  found\n" +
+			"\texpected next token to be ), got  instead\n", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ok := Start(strings.NewReader(tt.input), Config{Writer: &out})
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q",
+				tt.input, tt.expected, out.String())
+		}
+		if ok != tt.ok {
+			t.Errorf("wrong status for %q. expected=%t, got=%t", tt.input, tt.ok, ok)
+		}
+	}
+}
+
+func TestContinuationPrompt(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("[1,\n2]\n"), Config{Prompt: "> ", ContinuationPrompt: ". ", Writer: &out})
+
+	expected := "> . [1, 2]\n> "
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestGoodbye(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1\n"), Config{Prompt: "> ", Goodbye: "bye\n", Writer: &out})
+
+	expected := "> 1\n> \nbye\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+// interruptingWriter sends an interrupt the first time it is written to.
+type interruptingWriter struct {
+	interrupts chan os.Signal
+	once       sync.Once
+}
+
+func (w *interruptingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { w.interrupts <- os.Interrupt })
+	return len(p), nil
+}
+
+func TestInterruptEvaluation(t *testing.T) {
+	interrupts := make(chan os.Signal, 1)
+	config := Config{Writer: &bytes.Buffer{}, Interrupts: interrupts}
+	config.Evaluator.Output = &interruptingWriter{interrupts: interrupts}
+
+	input := "let f = fn(n) { if (n > 0) { f(n - 1) + f(n - 1) } else { 0 } };\n" +
+		"puts(1); f(100)\n" +
+		"f(3)\n"
+	if Start(strings.NewReader(input), config) {
+		t.Errorf("an interrupted evaluation should fail")
+	}
+
+	expected := "Error: interrupted\n0\n"
+	if got := config.Writer.(*bytes.Buffer).String(); got != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer that can be read while Start writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) waitFor(t *testing.T, s string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if b.String() == s {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q, got=%q", s, b.String())
+}
+
+func TestInterruptInput(t *testing.T) {
+	in, input := io.Pipe()
+	interrupts := make(chan os.Signal)
+	out := &syncBuffer{}
+	done := make(chan bool)
+	go func() {
+		done <- Start(in, Config{Prompt: "> ", ContinuationPrompt: ".. ", Writer: out, Interrupts: interrupts})
+	}()
+
+	out.waitFor(t, "> ")
+	io.WriteString(input, "[1,\n")
+	out.waitFor(t, "> .. ")
+	interrupts <- os.Interrupt
+	out.waitFor(t, "> .. \n> ")
+	io.WriteString(input, "2\n")
+	input.Close()
+
+	if !<-done {
+		t.Errorf("discarded input should not fail")
+	}
+	expected := "> .. \n> 2\n> "
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestPreload(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"helpers.mk": "let double = fn(x) { x * 2 };",
+		"broken.mk":  "let y 1",
+		"failing.mk": "1 + true",
+		"rc.mk":      "let x = double(21);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var preload []string
+	for _, name := range []string{"helpers.mk", "broken.mk", "missing.mk", "failing.mk", "rc.mk"} {
+		preload = append(preload, filepath.Join(dir, name))
+	}
+
+	var out bytes.Buffer
+	if !Start(strings.NewReader("x\n"), Config{Writer: &out, Preload: preload}) {
+		t.Errorf("errors in preloaded files should not fail the session")
+	}
+
+	expected := "Woops! We ran into some monkey business here!\n" +
+		" parser errors:\n" +
+		"\texpected next token to be =, got INT instead\n" +
+		"could not load " + preload[2] + ": open " + preload[2] + ": no such file or directory\n" +
+		"could not load " + preload[3] + ": type mismatch: INTEGER + BOOLEAN\n" +
+		"42\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestColor(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1\n-true\n"), Config{Writer: &out, Color: true})
+
+	expected := "1\n\x1b[31mError: unknown operator: -BOOLEAN\x1b[0m\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/format"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+// saveSession writes the bindings of env to path as a series of let
+// statements, so restoring a session is just evaluating the file. It returns
+// the names that could not be serialized, such as builtins or closures that
+// captured a local environment. Results the REPL bound to _, _1 and so on
+// are left out, as they would be rebound by the next session.
+func saveSession(path string, env *object.Environment) (saved int, skipped []string, err error) {
+	var out bytes.Buffer
+
+	for _, name := range env.Names() {
+		if isResultName(name) {
+			continue
+		}
+		value, _ := env.Get(name)
+		source, ok := sourceOf(value, env)
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		fmt.Fprintf(&out, "let %s = %s;\n", name, source)
+		saved++
+	}
+
+	return saved, skipped, os.WriteFile(path, out.Bytes(), 0o644)
+}
+
+func loadSession(path string, ev *evaluator.Evaluator, env *object.Environment) (object.Object, []string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := ev.NewParser(string(content))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, p.Errors(), nil
+	}
+
+	return ev.Eval(program, env), nil, nil
+}
+
+func sourceOf(obj object.Object, env *object.Environment) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Boolean:
+		return obj.Inspect(), true
+
+	case *object.String:
+		if strings.Contains(obj.Value, `"`) {
+			return "", false
+		}
+		return `"` + obj.Value + `"`, true
+
+	case *object.Bytes:
+		return `hexDecode("` + hex.EncodeToString(obj.Value) + `")`, true
+
+	case *object.Array:
+		elements := []string{}
+		for _, el := range obj.Elements {
+			source, ok := sourceOf(el, env)
+			if !ok {
+				return "", false
+			}
+			elements = append(elements, source)
+		}
+		return "[" + strings.Join(elements, ", ") + "]", true
+
+	case *object.Tuple:
+		elements := []string{}
+		for _, el := range obj.Elements {
+			source, ok := sourceOf(el, env)
+			if !ok {
+				return "", false
+			}
+			elements = append(elements, source)
+		}
+		if len(elements) == 1 {
+			return "(" + elements[0] + ",)", true
+		}
+		return "(" + strings.Join(elements, ", ") + ")", true
+
+	case *object.Hash:
+		pairs := []string{}
+		for _, pair := range obj.Pairs {
+			key, ok := sourceOf(pair.Key, env)
+			if !ok {
+				return "", false
+			}
+			value, ok := sourceOf(pair.Value, env)
+			if !ok {
+				return "", false
+			}
+			pairs = append(pairs, key+": "+value)
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", true
+
+	case *object.Function:
+		if obj.Env != env {
+			return "", false
+		}
+		return format.Node(&ast.FunctionLiteral{
+			Parameters: obj.Parameters,
+			Body:       &obj.Body,
+		}), true
+	}
+
+	return "", false
+}
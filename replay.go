@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fcidade/monkey-lang/trace"
+)
+
+const replayHelp = `commands:
+  next [n], n     move forwards n steps, 1 by default; an empty line is next
+  back [n], b     move backwards n steps
+  goto <step>     move to step
+  start, end      move to the first or the last step
+  vars, v         list the variables visible at the step
+  stack, s        list the calls active at the step, innermost first
+  help, h         show this help
+  quit, q         stop replaying`
+
+func replayCommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey replay <trace.bin>")
+		fmt.Fprintln(flags.Output(), "steps through a trace monkey run -record wrote")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer file.Close()
+	t, err := trace.Read(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %s\n", flags.Arg(0), err)
+		return 1
+	}
+	replay(t, os.Stdin, os.Stdout)
+	return 0
+}
+
+// replay steps through t as the commands read from in say, writing where
+// each one leaves it to out.
+func replay(t *trace.Trace, in io.Reader, out io.Writer) {
+	r := trace.NewReplayer(t)
+	if r.Steps() == 0 {
+		fmt.Fprintln(out, "the trace has no steps")
+		return
+	}
+	fmt.Fprintf(out, "replaying %s, %d steps; help lists the commands\n", t.Script, r.Steps())
+	lines := strings.Split(t.Source, "\n")
+	showStep(out, r, lines)
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "replay> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		command := "next"
+		if len(fields) > 0 {
+			command = fields[0]
+		}
+		count := 1
+		if len(fields) > 1 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 {
+				fmt.Fprintf(out, "%s is not a count of steps\n", fields[1])
+				continue
+			}
+			count = n
+		}
+
+		step, _ := r.Step()
+		last := r.Steps() - 1
+		switch command {
+		case "next", "n":
+			if step == last {
+				fmt.Fprintln(out, "at the last step")
+				continue
+			}
+			if step += count; step > last {
+				step = last
+			}
+			r.Goto(step)
+		case "back", "b":
+			if step == 0 {
+				fmt.Fprintln(out, "at the first step")
+				continue
+			}
+			if step -= count; step < 0 {
+				step = 0
+			}
+			r.Goto(step)
+		case "goto":
+			if len(fields) != 2 || !r.Goto(count-1) {
+				fmt.Fprintf(out, "goto takes a step from 1 to %d\n", r.Steps())
+				continue
+			}
+		case "start":
+			r.Goto(0)
+		case "end":
+			r.Goto(last)
+		case "vars", "v":
+			for _, variable := range r.Variables() {
+				fmt.Fprintf(out, "%s = %s\n", variable.Name, variable.Value)
+			}
+			continue
+		case "stack", "s":
+			calls := r.Calls()
+			if len(calls) == 0 {
+				fmt.Fprintln(out, "at the top level")
+			}
+			for _, call := range calls {
+				fmt.Fprintf(out, "\tat %s\n", call)
+			}
+			continue
+		case "help", "h":
+			fmt.Fprintln(out, replayHelp)
+			continue
+		case "quit", "q":
+			return
+		default:
+			fmt.Fprintf(out, "unknown command %s; help lists the commands\n", command)
+			continue
+		}
+		showStep(out, r, lines)
+	}
+}
+
+// showStep writes the step r is at and the line of source it is on, marking
+// where its node starts.
+func showStep(out io.Writer, r *trace.Replayer, lines []string) {
+	step, event := r.Step()
+	where := "at the top level"
+	if calls := r.Calls(); len(calls) > 0 {
+		where = "in " + calls[0]
+	}
+	fmt.Fprintf(out, "step %d of %d, %d:%d %s, %s\n", step+1, r.Steps(), event.Line, event.Column, event.Node, where)
+	if event.Line > len(lines) {
+		return
+	}
+	line := lines[event.Line-1]
+	fmt.Fprintf(out, "%5d | %s\n", event.Line, line)
+	marker := []rune{}
+	for i := 0; i < event.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			marker = append(marker, '\t')
+		} else if line[i] < 0x80 || line[i] >= 0xc0 {
+			marker = append(marker, ' ')
+		}
+	}
+	fmt.Fprintf(out, "      | %s^\n", string(marker))
+}
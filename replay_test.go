@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/trace"
+)
+
+func TestReplay(t *testing.T) {
+	source := "let a = 1;\nlet b = a + 1;"
+	trace := &trace.Trace{
+		Header: trace.Header{Script: "script.mk", Source: source},
+		Events: []trace.Event{
+			{Kind: trace.Enter, Scope: 1},
+			{Kind: trace.Step, Line: 1, Column: 1, Node: "LetStatement", Scope: 1},
+			{Kind: trace.Bind, Scope: 1, Name: "a", Value: "1"},
+			{Kind: trace.Step, Line: 2, Column: 9, Node: "InfixExpression", Scope: 1},
+			{Kind: trace.Bind, Scope: 1, Name: "b", Value: "2"},
+		},
+	}
+
+	var out bytes.Buffer
+	replay(trace, strings.NewReader("\nvars\nn\nb 5\nvars\nb\nfly\n"), &out)
+	expected := "replaying script.mk, 2 steps; help lists the commands\n" +
+		"step 1 of 2, 1:1 LetStatement, at the top level\n" +
+		"    1 | let a = 1;\n" +
+		"      | ^\n" +
+		"replay> step 2 of 2, 2:9 InfixExpression, at the top level\n" +
+		"    2 | let b = a + 1;\n" +
+		"      |         ^\n" +
+		"replay> a = 1\n" +
+		"replay> at the last step\n" +
+		"replay> step 1 of 2, 1:1 LetStatement, at the top level\n" +
+		"    1 | let a = 1;\n" +
+		"      | ^\n" +
+		"replay> replay> at the first step\n" +
+		"replay> unknown command fly; help lists the commands\n" +
+		"replay> \n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/optimize"
+	"github.com/fcidade/monkey-lang/repl"
+	"github.com/fcidade/monkey-lang/trace"
+)
+
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	var config evaluator.Config
+	flags.IntVar(&config.MaxCallDepth, "max-call-depth", evaluator.DefaultMaxCallDepth, "how deeply functions may recurse")
+	allow := flags.String("allow", "", "run sandboxed, enabling only these comma-separated capabilities: output, dynamic, concurrency, fs, net, time, proc or packs")
+	logLevel := flags.String("log-level", "info", "least important messages the log builtins write: debug, info, warn or error")
+	flags.BoolVar(&config.LogJSON, "log-json", false, "write log messages as JSON objects")
+	flags.BoolVar(&config.StrictIndexing, "strict", false, "make out-of-range indexes and missing hash keys errors instead of null")
+	flags.BoolVar(&config.NoPrelude, "no-prelude", false, "leave out the prelude's functions, such as map, filter and reduce")
+	jsonDiagnostics := flags.Bool("json-diagnostics", false, "write errors to stdout as a JSON array of diagnostics with stable codes, and what the script puts to stderr")
+	optimization := flags.Int("O", optimize.None, "optimize before running: 1 folds constants and drops dead branches, 2 also propagates constants and removes unused lets")
+	flags.BoolVar(&config.Arena, "arena", false, "allocate the integers arithmetic produces in chunks, trading memory for fewer garbage collections")
+	plugins := flags.String("plugins", "", pluginsUsage)
+	flags.BoolVar(&config.PostMortem, "post-mortem", false, "when the script fails, start a REPL in the environment it failed in")
+	record := flags.String("record", "", "write a trace of every step of the run to this file, for monkey replay")
+	stats := flags.Bool("stats", false, "report evaluation steps, values by type, call depth and wall time after the run")
+	language := languageFlags(flags, &config.Language)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey run <script.mk>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := language(); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	level, err := evaluator.ParseLogLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return 2
+	}
+	config.LogLevel = level
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "allow" {
+			config.Sandboxed = true
+		}
+	})
+	for _, capability := range strings.Split(*allow, ",") {
+		if capability != "" {
+			config.Capabilities = append(config.Capabilities, evaluator.Capability(capability))
+		}
+	}
+	if *stats {
+		config.Stats = &evaluator.Stats{}
+	}
+	if err := loadPlugins(*plugins); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var recorder *trace.Recorder
+	if *record != "" {
+		recorder, err = startRecording(*record, flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		config.Hooks = recorder.Hooks()
+	}
+
+	start := time.Now()
+	run := runFile
+	if *jsonDiagnostics {
+		config.Output = os.Stderr
+		run = runFileDiagnostics
+	}
+	ev := evaluator.New(config)
+	defer ev.CloseResources()
+	env := ev.NewEnvironment()
+	if recorder != nil {
+		recorder.Observe(env)
+	}
+	ok := run(flags.Arg(0), ev, env, *optimization, os.Stdout)
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write the trace to %s: %s\n", *record, err)
+			ok = false
+		}
+	}
+	if *stats {
+		printStats(os.Stderr, config.Stats, time.Since(start))
+	}
+
+	if !ok {
+		if failure := ev.Failure(); failure != nil {
+			interrupts := make(chan os.Signal, 1)
+			signal.Notify(interrupts, os.Interrupt)
+			postMortem(flags.Arg(0), failure, config, os.Stdin, os.Stdout, interrupts)
+		}
+		return 1
+	}
+	return 0
+}
+
+// startRecording creates the file at path and returns a recorder writing a
+// trace of the script at script to it. Closing the recorder closes the file.
+func startRecording(path, script string) (*trace.Recorder, error) {
+	source, err := os.ReadFile(script)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", script, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return trace.NewRecorder(file, trace.Header{Script: script, Source: string(source)})
+}
+
+// printStats writes stats to out, listing the most common types of value
+// first.
+func printStats(out io.Writer, stats *evaluator.Stats, wall time.Duration) {
+	fmt.Fprintf(out, "steps: %d\n", stats.Steps)
+	fmt.Fprintf(out, "max call depth: %d\n", stats.MaxCallDepth)
+	fmt.Fprintf(out, "wall time: %s\n", wall)
+
+	types := make([]object.ObjectType, 0, len(stats.Values))
+	for t := range stats.Values {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if stats.Values[types[i]] != stats.Values[types[j]] {
+			return stats.Values[types[i]] > stats.Values[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	fmt.Fprintln(out, "values:")
+	for _, t := range types {
+		fmt.Fprintf(out, "  %s: %d\n", t, stats.Values[t])
+	}
+}
+
+// runFile evaluates the script at path in env, optimized at level, writing
+// parser and runtime errors to out. It reports whether the script ran without
+// errors.
+func runFile(path string, ev *evaluator.Evaluator, env *object.Environment, level int, out io.Writer) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	p := ev.NewParser(string(content))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
+	}
+
+	evaluated := ev.Eval(optimize.Program(program, level), env)
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Line != 0 {
+			fmt.Fprintf(out, "%s:%d:%d: ", path, err.Line, err.Column)
+		}
+		fmt.Fprintln(out, err.Inspect())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,258 @@
+// Package sessions manages many interpreter sessions at once, each with its
+// own environment, so a server can keep the bindings of many callers apart.
+// Sessions are found by random IDs, evaluate one input at a time within
+// the limits of their manager, and are dropped, with the resources they
+// left open, once left idle too long.
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+// ErrTooManySessions is what Create returns when the manager already holds
+// Limits.MaxSessions sessions.
+var ErrTooManySessions = errors.New("too many sessions are open")
+
+// Limits bound the sessions of a manager. A zero field sets no limit.
+type Limits struct {
+	// Timeout is how long one evaluation may run before it is interrupted.
+	Timeout time.Duration
+
+	// MaxOutput is the most bytes of what one evaluation puts that are kept.
+	MaxOutput int
+
+	// IdleTimeout is how long a session may go without evaluating before it
+	// is dropped.
+	IdleTimeout time.Duration
+
+	// MaxSessions is how many sessions may be open at once.
+	MaxSessions int
+}
+
+// Config is how the sessions of a manager evaluate and what bounds them.
+// What sessions put goes into their results, so Evaluator.Output is
+// ignored.
+type Config struct {
+	Evaluator evaluator.Config
+	Limits    Limits
+}
+
+// Result is what an evaluation in a session gave.
+type Result struct {
+	// Value is what the code evaluated to, nil when it has none or failed,
+	// and Name what the session bound it to, such as _1.
+	Value object.Object
+	Name  string
+
+	// Output is what the code put, and Truncated whether some of that was
+	// dropped for Limits.MaxOutput.
+	Output    string
+	Truncated bool
+
+	// Diagnostics are the errors the parser found or the runtime error that
+	// stopped the evaluation.
+	Diagnostics []diagnostic.Diagnostic
+}
+
+// Manager holds sessions. It is safe for concurrent use; evaluations in
+// different sessions run in parallel, and those in one session in turn.
+type Manager struct {
+	config Config
+
+	// now is the clock idle sessions are timed by.
+	now func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+type session struct {
+	mu         sync.Mutex
+	repl       *repl.Session
+	output     *limitedWriter
+	interrupts chan os.Signal
+
+	// used is when the session was last used, guarded by the manager's mu.
+	used time.Time
+}
+
+// NewManager returns a manager holding no sessions.
+func NewManager(config Config) *Manager {
+	return &Manager{config: config, now: time.Now, sessions: map[string]*session{}}
+}
+
+func (m *Manager) newSession() *session {
+	s := &session{output: &limitedWriter{max: m.config.Limits.MaxOutput}, interrupts: make(chan os.Signal)}
+	config := m.config.Evaluator
+	config.Output = s.output
+	s.repl = repl.NewSession(repl.Config{Evaluator: config, Interrupts: s.interrupts})
+	return s
+}
+
+// Create opens a session with nothing bound but the prelude and returns
+// its ID.
+func (m *Manager) Create() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	s := m.newSession()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	if max := m.config.Limits.MaxSessions; max > 0 && len(m.sessions) >= max {
+		return "", ErrTooManySessions
+	}
+	s.used = m.now()
+	m.sessions[id] = s
+	return id, nil
+}
+
+// get returns the session id, marking it used.
+func (m *Manager) get(id string) (*session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("there is no session %q", id)
+	}
+	s.used = m.now()
+	return s, nil
+}
+
+// expire drops the sessions idle for longer than the limit. Sessions are
+// only expired when the manager is used, which is when that matters. It is
+// called with mu held.
+func (m *Manager) expire() {
+	idle := m.config.Limits.IdleTimeout
+	if idle <= 0 {
+		return
+	}
+	now := m.now()
+	for id, s := range m.sessions {
+		if now.Sub(s.used) > idle {
+			delete(m.sessions, id)
+			s.repl.Close()
+		}
+	}
+}
+
+// Eval evaluates code in the session id.
+func (m *Manager) Eval(id, code string) (Result, error) {
+	s, err := m.get(id)
+	if err != nil {
+		return Result{}, err
+	}
+	return m.eval(s, code), nil
+}
+
+// Run evaluates code in a session of its own, which is not kept.
+func (m *Manager) Run(code string) Result {
+	s := m.newSession()
+	defer s.repl.Close()
+	return m.eval(s, code)
+}
+
+func (m *Manager) eval(s *session, code string) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timeout := m.config.Limits.Timeout; timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			select {
+			case s.interrupts <- os.Interrupt:
+			default:
+				// The evaluation has finished.
+			}
+		})
+		defer timer.Stop()
+	}
+	evaluated, diagnostics := s.repl.Eval(code)
+
+	result := Result{
+		Value:       evaluated.Value,
+		Name:        evaluated.Name,
+		Output:      s.output.buf.String() + evaluated.Output,
+		Truncated:   s.output.truncated,
+		Diagnostics: diagnostics,
+	}
+	s.output.reset()
+	for i, d := range result.Diagnostics {
+		// Nothing but the time limit interrupts sessions.
+		if d.Code == diagnostic.Interrupted {
+			result.Diagnostics[i].Message = fmt.Sprintf("stopped after the time limit of %s", m.config.Limits.Timeout)
+		}
+	}
+	return result
+}
+
+// Bindings returns what the evaluations in the session id bound, sorted by
+// name.
+func (m *Manager) Bindings(id string) ([]repl.Binding, error) {
+	s, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repl.Bindings(), nil
+}
+
+// Destroy drops the session id, closing the resources its evaluations left
+// open. An evaluation running in it finishes, but the ID no longer finds it.
+func (m *Manager) Destroy(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return fmt.Errorf("there is no session %q", id)
+	}
+	delete(m.sessions, id)
+	s.repl.Close()
+	return nil
+}
+
+// Len returns how many sessions are open.
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	return len(m.sessions)
+}
+
+// limitedWriter keeps the first max bytes written to it, or all of them
+// when max is zero, and drops the rest.
+type limitedWriter struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if room := w.max - w.buf.Len(); w.max > 0 && len(p) > room {
+		w.buf.Write(p[:room])
+		w.truncated = true
+		return len(p), nil
+	}
+	return w.buf.Write(p)
+}
+
+func (w *limitedWriter) reset() {
+	w.buf.Reset()
+	w.truncated = false
+}
@@ -0,0 +1,147 @@
+package sessions
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func TestIsolation(t *testing.T) {
+	m := NewManager(Config{})
+	a, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := m.Create()
+
+	if result, _ := m.Eval(a, `let x = 1; puts("a"); x + 1`); result.Output != "a\n" || result.Value.Inspect() != "2" || result.Name != "_1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result, _ := m.Eval(b, "x"); len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostic.IdentifierNotFound {
+		t.Errorf("expected x to be unbound in another session, got %+v", result)
+	}
+	if result := m.Run("x"); len(result.Diagnostics) != 1 {
+		t.Errorf("expected x to be unbound in a session of its own, got %+v", result)
+	}
+	if result, _ := m.Eval(a, "x"); result.Output != "" || result.Value.Inspect() != "1" {
+		t.Errorf("expected the session to keep x and drop what it put, got %+v", result)
+	}
+
+	bindings, err := m.Bindings(a)
+	if err != nil || len(bindings) != 4 || bindings[3].Name != "x" {
+		t.Errorf("unexpected bindings %+v (%v)", bindings, err)
+	}
+
+	if err := m.Destroy(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Eval(a, "x"); err == nil {
+		t.Errorf("expected the destroyed session to be gone")
+	}
+	if err := m.Destroy(a); err == nil {
+		t.Errorf("expected destroying a session twice to fail")
+	}
+}
+
+func TestDestroyClosesResources(t *testing.T) {
+	m := NewManager(Config{})
+	id, _ := m.Create()
+	result, _ := m.Eval(id, "let listener = tcpListen(0); listener")
+	listener, ok := result.Value.(*object.Listener)
+	if !ok {
+		t.Fatalf("expected a listener, got %+v", result)
+	}
+	if err := m.Destroy(id); err != nil {
+		t.Fatal(err)
+	}
+	if !listener.Closed() {
+		t.Errorf("expected destroying the session to close its listener")
+	}
+
+	result = m.Run("tcpListen(0)")
+	if listener, ok := result.Value.(*object.Listener); !ok || !listener.Closed() {
+		t.Errorf("expected the listener of a run to be closed after it, got %+v", result)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	m := NewManager(Config{Limits: Limits{Timeout: 50 * time.Millisecond, MaxOutput: 6, MaxSessions: 1}})
+	id, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create(); err != ErrTooManySessions {
+		t.Errorf("expected ErrTooManySessions, got %v", err)
+	}
+
+	result, _ := m.Eval(id, `puts("abcd"); puts("efgh"); 1`)
+	if result.Output != "abcd\ne" || !result.Truncated || result.Value.Inspect() != "1" {
+		t.Errorf("expected the output to be cut at 6 bytes, got %+v", result)
+	}
+
+	result, _ = m.Eval(id, "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(50)")
+	expected := []diagnostic.Diagnostic{{Code: diagnostic.Interrupted, Message: "stopped after the time limit of 50ms"}}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != expected[0].Code || result.Diagnostics[0].Message != expected[0].Message {
+		t.Errorf("expected %+v, got %+v", expected, result.Diagnostics)
+	}
+
+	// The session still works after being interrupted.
+	if result, _ := m.Eval(id, "fib(5)"); result.Value == nil || result.Value.Inspect() != "5" || result.Truncated {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestIdleExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewManager(Config{Limits: Limits{IdleTimeout: time.Minute}})
+	m.now = func() time.Time { return now }
+
+	idle, _ := m.Create()
+	busy, _ := m.Create()
+	now = now.Add(40 * time.Second)
+	m.Eval(busy, "1")
+	now = now.Add(40 * time.Second)
+
+	if _, err := m.Eval(idle, "1"); err == nil {
+		t.Errorf("expected the idle session to have expired")
+	}
+	if _, err := m.Eval(busy, "1"); err != nil {
+		t.Errorf("expected the session used 40s ago to be kept, got %v", err)
+	}
+	if m.Len() != 1 {
+		t.Errorf("expected 1 session, got %d", m.Len())
+	}
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	m := NewManager(Config{})
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := m.Create()
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < 20; j++ {
+				code := fmt.Sprintf("let v = %d; puts(v); v", i)
+				if result, err := m.Eval(id, code); err != nil || result.Output != fmt.Sprintf("%d\n", i) {
+					errs <- fmt.Errorf("session %d: %+v (%v)", i, result, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
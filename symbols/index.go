@@ -0,0 +1,174 @@
+// Package symbols keeps an index of where the names the scripts of a
+// project bind are defined, so that an editor can go from an identifier to
+// its definition or search the definitions of the whole project.
+//
+// Names are resolved within each script as the evaluator resolves them:
+// import only reaches the standard library, so no script refers to the
+// bindings of another, and the index spans the project only in that every
+// script's definitions can be searched at once.
+package symbols
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/parser"
+	"github.com/fcidade/monkey-lang/refactor"
+)
+
+// Symbol is the definition of a name: the identifier in the script at Path
+// that first binds it.
+type Symbol struct {
+	Name   string
+	Path   string
+	Line   int
+	Column int
+
+	// TopLevel is set for the names the script binds in its own scope
+	// rather than in that of a function, comprehension or match arm.
+	TopLevel bool
+}
+
+func (s Symbol) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", s.Path, s.Line, s.Column, s.Name)
+}
+
+// Index holds the definitions of a set of scripts. Scripts are added and
+// brought up to date with Update as they change; each is parsed again
+// incrementally, from the statements the change touched.
+type Index struct {
+	files map[string]*file
+}
+
+type file struct {
+	parser      *parser.Incremental
+	src         string
+	diagnostics []diagnostic.Diagnostic
+
+	// definitions are the script's symbols in the order they appear, and
+	// references every identifier that refers to one of them, or binds it,
+	// sorted by position.
+	definitions []Symbol
+	references  []reference
+}
+
+type reference struct {
+	line, column, length int
+	definition           Symbol
+}
+
+// New returns an index holding no scripts.
+func New() *Index {
+	return &Index{files: map[string]*file{}}
+}
+
+// Load adds every script under dir, those whose names end in .mk, to ix.
+func (ix *Index) Load(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".mk" {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		ix.Update(path, string(content))
+		return nil
+	})
+}
+
+// Update indexes src as the content of the script at path, replacing what
+// ix held for it, and returns the errors parsing it found. A script with
+// errors is indexed as far as it parsed.
+func (ix *Index) Update(path, src string) []diagnostic.Diagnostic {
+	f, ok := ix.files[path]
+	if !ok {
+		f = &file{parser: parser.NewIncremental(parser.Config{})}
+		ix.files[path] = f
+	} else if f.src == src {
+		return f.diagnostics
+	}
+
+	program, diagnostics := f.parser.Parse(src)
+	f.src, f.diagnostics = src, diagnostics
+	f.definitions, f.references = nil, nil
+	for _, b := range refactor.Bindings(program) {
+		def := Symbol{
+			Name:     b.Name,
+			Path:     path,
+			Line:     b.Definition.Token.Line,
+			Column:   b.Definition.Token.Column,
+			TopLevel: b.TopLevel,
+		}
+		f.definitions = append(f.definitions, def)
+		for _, ident := range b.Identifiers {
+			f.references = append(f.references, reference{ident.Token.Line, ident.Token.Column, len(ident.Token.Literal), def})
+		}
+	}
+	sort.Slice(f.references, func(i, j int) bool {
+		a, b := f.references[i], f.references[j]
+		return a.line < b.line || a.line == b.line && a.column < b.column
+	})
+	return diagnostics
+}
+
+// Remove drops the script at path from ix.
+func (ix *Index) Remove(path string) {
+	delete(ix.files, path)
+}
+
+// Definition returns the definition of the identifier at line and column
+// of the script at path, reporting whether there is one: there is none for
+// what is not an identifier, nor for the names a script leaves for the
+// evaluator to provide, such as builtins.
+func (ix *Index) Definition(path string, line, column int) (Symbol, bool) {
+	f, ok := ix.files[path]
+	if !ok {
+		return Symbol{}, false
+	}
+	i := sort.Search(len(f.references), func(i int) bool {
+		r := f.references[i]
+		return r.line > line || r.line == line && r.column+r.length > column
+	})
+	if i == len(f.references) {
+		return Symbol{}, false
+	}
+	if r := f.references[i]; r.line == line && r.column <= column {
+		return r.definition, true
+	}
+	return Symbol{}, false
+}
+
+// Lookup returns every definition of name, in any scope of any script.
+func (ix *Index) Lookup(name string) []Symbol {
+	return ix.symbols(func(s Symbol) bool { return s.Name == name })
+}
+
+// Symbols returns the top-level definitions of every script.
+func (ix *Index) Symbols() []Symbol {
+	return ix.symbols(func(s Symbol) bool { return s.TopLevel })
+}
+
+// symbols returns the definitions keep selects, ordered by script and then
+// by where they appear in it.
+func (ix *Index) symbols(keep func(Symbol) bool) []Symbol {
+	paths := make([]string, 0, len(ix.files))
+	for path := range ix.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var symbols []Symbol
+	for _, path := range paths {
+		for _, s := range ix.files[path].definitions {
+			if keep(s) {
+				symbols = append(symbols, s)
+			}
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,96 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefinition(t *testing.T) {
+	ix := New()
+	ix.Update("a.mk", "let x = 1;\nlet f = fn(x) {\n  x + len(x)\n};\nf(x)")
+
+	tests := []struct {
+		line, column int
+		expected     Symbol
+		ok           bool
+	}{
+		{5, 3, Symbol{Name: "x", Path: "a.mk", Line: 1, Column: 5, TopLevel: true}, true},
+		{5, 1, Symbol{Name: "f", Path: "a.mk", Line: 2, Column: 5, TopLevel: true}, true},
+		{3, 3, Symbol{Name: "x", Path: "a.mk", Line: 2, Column: 12}, true},
+		{3, 11, Symbol{Name: "x", Path: "a.mk", Line: 2, Column: 12}, true},
+		{2, 12, Symbol{Name: "x", Path: "a.mk", Line: 2, Column: 12}, true},
+		{3, 7, Symbol{}, false},
+		{3, 8, Symbol{}, false},
+		{9, 1, Symbol{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ix.Definition("a.mk", tt.line, tt.column)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("Definition(%d, %d) = %v, %t, want %v, %t", tt.line, tt.column, got, ok, tt.expected, tt.ok)
+		}
+	}
+	if _, ok := ix.Definition("b.mk", 1, 5); ok {
+		t.Errorf("found a definition in a script that is not indexed")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ix := New()
+	ix.Update("a.mk", "let a = 1;\nlet b = a;\n")
+	if diagnostics := ix.Update("a.mk", "let a = 1;\nlet c = 2;\nlet b = a;\nlet d = ;\n"); len(diagnostics) != 1 {
+		t.Errorf("expected one error, got %v", diagnostics)
+	}
+
+	want := []Symbol{
+		{Name: "b", Path: "a.mk", Line: 3, Column: 5, TopLevel: true},
+	}
+	if got := ix.Lookup("b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup(b) = %v, want %v", got, want)
+	}
+	if got, _ := ix.Definition("a.mk", 3, 9); got.Line != 1 {
+		t.Errorf("a at 3:9 is defined at %v, want line 1", got)
+	}
+
+	ix.Remove("a.mk")
+	if got := ix.Symbols(); len(got) != 0 {
+		t.Errorf("expected no symbols once the script is removed, got %v", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.mk":      "let main = fn() { let local = 1; local };\nmain()",
+		"lib/util.mk":  "let double = fn(n) { n * 2 };",
+		"lib/notes.md": "let ignored = 1;",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ix := New()
+	if err := ix.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	util, main := filepath.Join(dir, "lib", "util.mk"), filepath.Join(dir, "main.mk")
+	want := []Symbol{
+		{Name: "double", Path: util, Line: 1, Column: 5, TopLevel: true},
+		{Name: "main", Path: main, Line: 1, Column: 5, TopLevel: true},
+	}
+	if got := ix.Symbols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols() = %v, want %v", got, want)
+	}
+	if got := ix.Lookup("local"); len(got) != 1 || got[0].Path != main || got[0].TopLevel {
+		t.Errorf("Lookup(local) = %v, want the local of main", got)
+	}
+}
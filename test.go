@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/repl"
+)
+
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	watching := flags.Bool("watch", false, "keep running the tests again as they change")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often the tests are checked for changes with -watch")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey test [flags] [dir]")
+		fmt.Fprintln(flags.Output(), "runs the tests under dir, the scripts named *_test.mk; a test passes when it runs")
+		fmt.Fprintln(flags.Output(), "without errors and, when name_test.out sits beside it, evaluates to what that holds")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	color := isTerminal(os.Stdout)
+	if *watching {
+		watchTests(dir, *interval, color, os.Stdout, nil)
+		return 0
+	}
+
+	paths, err := testFiles(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not find the tests in %s: %s\n", dir, err)
+		return 1
+	}
+	if !runTests(paths, color, os.Stdout) {
+		return 1
+	}
+	return 0
+}
+
+// testFiles returns the tests under dir, sorted.
+func testFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && strings.HasSuffix(path, "_test.mk") {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+// runTests runs the tests at paths, writing a line to out for each and then
+// a summary, in green and red with color set. What a failing test put and
+// why it failed follow its line. It reports whether every test passed.
+func runTests(paths []string, color bool, out io.Writer) bool {
+	failed := 0
+	for _, path := range paths {
+		var details bytes.Buffer
+		if runTest(path, &details) {
+			fmt.Fprintf(out, "%s %s\n", paint("PASS", "32", color), path)
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "%s %s\n", paint("FAIL", "31", color), path)
+		for _, line := range strings.Split(strings.TrimSuffix(details.String(), "\n"), "\n") {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+	}
+
+	switch {
+	case len(paths) == 0:
+		fmt.Fprintln(out, "no tests")
+	case failed == 0:
+		fmt.Fprintln(out, paint(fmt.Sprintf("ok: %d passed", len(paths)), "32", color))
+	default:
+		fmt.Fprintln(out, paint(fmt.Sprintf("FAIL: %d failed, %d passed", failed, len(paths)-failed), "31", color))
+	}
+	return failed == 0
+}
+
+// runTest runs the test at path, writing what it puts and why it failed to
+// out, and reports whether it passed.
+func runTest(path string, out io.Writer) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", path, err)
+		return false
+	}
+
+	ev := evaluator.New(evaluator.Config{Output: out, MaxCallDepth: evaluator.DefaultMaxCallDepth})
+	p := ev.NewParser(string(content))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParseErrors(out, p.Errors())
+		return false
+	}
+
+	evaluated := ev.Eval(program, ev.NewEnvironment())
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Line != 0 {
+			fmt.Fprintf(out, "%s:%d:%d: ", path, err.Line, err.Column)
+		}
+		fmt.Fprintln(out, err.Inspect())
+		return false
+	}
+
+	expected, err := os.ReadFile(expectedPath(path))
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		fmt.Fprintf(out, "could not read %s: %s\n", expectedPath(path), err)
+		return false
+	}
+	if want, got := strings.TrimSuffix(string(expected), "\n"), inspect(evaluated); got != want {
+		fmt.Fprintf(out, "evaluated to %s, want %s\n", got, want)
+		return false
+	}
+	return true
+}
+
+// expectedPath is where the value the test at path is expected to have is
+// kept: name_test.out for name_test.mk.
+func expectedPath(path string) string {
+	return strings.TrimSuffix(path, ".mk") + ".out"
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "null"
+	}
+	return obj.Inspect()
+}
+
+// watchTests runs the tests under dir and then, until stop is closed, runs
+// again every test that is added or changes, or whose expected value does.
+// The scripts import nothing but the standard library, so a test depends on
+// no other script and only the tests that changed are run.
+func watchTests(dir string, interval time.Duration, color bool, out io.Writer, stop <-chan struct{}) {
+	seen := map[string]string{}
+	failing := ""
+	for first := true; ; first = false {
+		paths, err := testFiles(dir)
+		if err != nil {
+			if msg := err.Error(); msg != failing {
+				fmt.Fprintf(out, "could not find the tests in %s: %s\n", dir, err)
+				failing = msg
+			}
+		} else {
+			failing = ""
+			current := map[string]string{}
+			var changed []string
+			for _, path := range paths {
+				current[path] = testVersion(path)
+				if seen[path] != current[path] {
+					changed = append(changed, path)
+				}
+			}
+			seen = current
+
+			if first || len(changed) > 0 {
+				if !first {
+					fmt.Fprintf(out, "--- %s changed, running again ---\n", strings.Join(changed, ", "))
+				}
+				runTests(changed, color, out)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// testVersion tells apart the versions of the test at path and its expected
+// value by their modification times and sizes.
+func testVersion(path string) string {
+	var version strings.Builder
+	for _, p := range []string{path, expectedPath(path)} {
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&version, "%d/%d;", info.ModTime().UnixNano(), info.Size())
+		} else {
+			version.WriteString("-;")
+		}
+	}
+	return version.String()
+}
+
+// paint wraps text in the terminal escape for the color code when color is
+// set.
+func paint(text, code string, color bool) string {
+	if !color {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"math_test.mk":     "1 + 2",
+		"math_test.out":    "3\n",
+		"wrong_test.mk":    "puts(\"checking\"); 1 + 1",
+		"wrong_test.out":   "3\n",
+		"broken_test.mk":   "let a = 1; a + true",
+		"lib/util_test.mk": "let f = fn(x) { x };",
+		"helper.mk":        "b",
+	}
+	for name, content := range scripts {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeScript(t, path, content, time.Now())
+	}
+
+	paths, err := testFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if runTests(paths, false, &out) {
+		t.Errorf("expected the tests to fail")
+	}
+
+	broken := filepath.Join(dir, "broken_test.mk")
+	expected := "FAIL " + broken + "\n" +
+		"    " + broken + ":1:12: Error: type mismatch: INTEGER + BOOLEAN\n" +
+		"PASS " + filepath.Join(dir, "lib", "util_test.mk") + "\n" +
+		"PASS " + filepath.Join(dir, "math_test.mk") + "\n" +
+		"FAIL " + filepath.Join(dir, "wrong_test.mk") + "\n" +
+		"    checking\n" +
+		"    evaluated to 2, want 3\n" +
+		"FAIL: 2 failed, 2 passed\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestWatchTests(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a_test.mk"), filepath.Join(dir, "b_test.mk")
+	writeScript(t, a, "1", time.Now().Add(-time.Minute))
+	writeScript(t, b, "2", time.Now().Add(-time.Minute))
+
+	var out syncBuffer
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watchTests(dir, time.Millisecond, false, &out, stop)
+		close(done)
+	}()
+
+	waitFor(t, &out, "PASS "+a+"\nPASS "+b+"\nok: 2 passed\n")
+	writeScript(t, filepath.Join(dir, "b_test.out"), "3\n", time.Now())
+	waitFor(t, &out, "--- "+b+" changed, running again ---\nFAIL "+b+"\n    evaluated to 2, want 3\nFAIL: 1 failed, 0 passed\n")
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,100 @@
+// Package differential runs a shared corpus of programs on every way the
+// interpreter has of running them, and checks that they all write the same
+// output and evaluate to the same value or error, so that none of them can
+// drift from the others. The corpus is the end-to-end programs plus those
+// in testdata here.
+package differential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/optimize"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+// engine prepares a parsed program to be evaluated, and evaluates it with
+// integers allocated from an arena or not.
+type engine struct {
+	name    string
+	prepare func(*ast.Program) *ast.Program
+	arena   bool
+}
+
+// engines are compared with the first, which evaluates programs as parsed.
+var engines = []engine{
+	{"evaluator", unchanged, false},
+	{"optimize basic", func(program *ast.Program) *ast.Program { return optimize.Program(program, optimize.Basic) }, false},
+	{"optimize full", func(program *ast.Program) *ast.Program { return optimize.Program(program, optimize.Full) }, false},
+	{"arena", unchanged, true},
+}
+
+func unchanged(program *ast.Program) *ast.Program { return program }
+
+func TestEngines(t *testing.T) {
+	var paths []string
+	for _, dir := range []string{"testdata", filepath.Join("..", "e2e", "testdata")} {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.mk"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no programs in the corpus")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".mk")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := run(string(source), engines[0])
+			for _, engine := range engines[1:] {
+				if got := run(string(source), engine); got != want {
+					t.Errorf("%s runs %s differently from %s.\n--- %s\n%s--- %s\n%s",
+						engine.name, path, engines[0].name, engines[0].name, want, engine.name, got)
+				}
+			}
+		})
+	}
+}
+
+// run evaluates source on engine and returns what it printed, followed by a
+// line with the value it evaluated to, or by its parser errors.
+func run(source string, engine engine) string {
+	var out bytes.Buffer
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(&out, "parser error: %s\n", msg)
+		}
+		return out.String()
+	}
+
+	ev := evaluator.New(evaluator.Config{
+		Output:              &out,
+		LogOutput:           &out,
+		DeterministicRandom: true,
+		Arena:               engine.arena,
+	})
+	result := ev.Eval(engine.prepare(program), object.NewEnvironment())
+	if result == nil {
+		result = evaluator.NULL
+	}
+	fmt.Fprintf(&out, "=> %s\n", result.Inspect())
+	return out.String()
+}
@@ -0,0 +1,18 @@
+let pick = fn(n) {
+	if (true) { let doubled = n * 2; }
+	if (false) { return "false branch"; }
+	if (1) { doubled } else { "never" }
+};
+puts(pick(4));
+
+let early = fn() {
+	return "early";
+	puts("unreachable");
+};
+puts(early());
+
+let maybe = if (1 > 2) { "yes" };
+puts(maybe ?? "null");
+
+let sign = fn(x) { if (x < 0) { -1 } else { if (x > 0) { 1 } else { 0 } } };
+[sign(-3), sign(0), sign(3), if (0 == 0) { "zero" } else { "other" }]
@@ -0,0 +1,15 @@
+let width = 6;
+let height = 7;
+let area = width * height;
+let label = "area: " + toString(area);
+puts(label);
+
+let big = 9223372036854775807;
+puts(big + 1, -(0 - 5) * 3 % 4, 1 < 2 < 3, "ab" * 2);
+
+let unused = fn() { puts("never") };
+let shadowed = 1;
+let shadowed = shadowed + 1;
+puts(shadowed);
+
+10 / (width - 6)
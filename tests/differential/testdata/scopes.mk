@@ -0,0 +1,19 @@
+let n = 3;
+let f = fn(n) { n + 1 };
+puts(f(10));
+
+let later = fn() { total };
+let total = 5;
+puts(later());
+
+let items = [x * n for x in 1..n if x > 1];
+puts(items);
+
+let result = match (n, "three") {
+	(3, name) => name,
+	_ => "other",
+};
+puts(result);
+
+let code = "1 + 2";
+eval(code) + n
@@ -0,0 +1,90 @@
+// Package e2e runs the programs in testdata and compares what each one
+// writes, and what it evaluates to, with the golden file next to it. Run
+//
+//	go test ./tests/e2e -update
+//
+// to write the golden files from the current output after a deliberate
+// change in behavior, and review the difference before committing it.
+package e2e
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+func TestPrograms(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no programs in testdata")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".mk")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := run(string(source))
+
+			golden := strings.TrimSuffix(path, ".mk") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s, run with -update to create it", err)
+			}
+			if got != string(want) {
+				t.Errorf("output of %s differs from %s.\n--- want\n%s--- got\n%s", path, golden, want, got)
+			}
+		})
+	}
+}
+
+// run evaluates source and returns what it printed, followed by a line with
+// the value it evaluated to, or by its parser errors. Runs are
+// deterministic, so they can be compared with earlier ones.
+func run(source string) string {
+	var out bytes.Buffer
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(&out, "parser error: %s\n", msg)
+		}
+		return out.String()
+	}
+
+	ev := evaluator.New(evaluator.Config{
+		Output:              &out,
+		LogOutput:           &out,
+		DeterministicRandom: true,
+	})
+	result := ev.Eval(program, object.NewEnvironment())
+	if result == nil {
+		result = evaluator.NULL
+	}
+	fmt.Fprintf(&out, "=> %s\n", result.Inspect())
+	return out.String()
+}
@@ -0,0 +1,13 @@
+puts(len("hello"), len([1, 2]), len(b"abc"));
+puts(toString(42) + "!", bytes("hi"), hexEncode(b"hi"));
+puts(sort([3, 1, 2]), sort(["b", "a"]));
+puts(slice("monkey", 1, 4), slice([1, 2, 3], 1));
+puts(urlEncode("a b&c"), urlDecode("a+b%26c"));
+seedRandom(7);
+let first = [random(100), random(100)];
+seedRandom(7);
+puts(first == [random(100), random(100)]);
+logInfo("done", {"count": 3});
+let s = builder();
+append(s, "a", 1, true);
+toString(s)
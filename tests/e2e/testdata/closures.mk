@@ -0,0 +1,17 @@
+let counter = fn() {
+	let count = [0];
+	fn() { pushMut(count, len(count)); len(count) - 1 }
+};
+
+let next = counter();
+next();
+next();
+puts(next());
+
+let compose = fn(f, g) { fn(x) { f(g(x)) } };
+let double = |x| x * 2;
+let inc = |x| x + 1;
+puts(compose(double, inc)(5), compose(inc, double)(5));
+
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+fib(15)
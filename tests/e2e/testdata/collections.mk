@@ -0,0 +1,13 @@
+let people = [
+	{"name": "ada", "age": 36, "langs": ["ml", "go"]},
+	{"name": "bob", "age": 17, "langs": []},
+	{"name": "cy", "age": 52, "langs": ["c"]}
+];
+
+puts([p.name for p in people if p.age > 18]);
+puts({p.name: len(p.langs) for p in people});
+puts(sortBy(people, |p| p.age)[0].name);
+puts(people[5]?.name ?? "nobody");
+puts(rest([1, 2, 3]), push([1], 2), first([]), last([1, 2]));
+puts({3: "c", 1: "a", 2: "b"});
+(len(people), [1, 2] * 2, "ab" * 3, b"ab" + b"cd")
@@ -0,0 +1,4 @@
+let total = 0;
+let price = 10;
+let tax = price @ 2;
+let name = "oops;
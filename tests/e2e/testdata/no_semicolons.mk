@@ -0,0 +1,12 @@
+let numbers = [3, 1, 2]
+let total = fn(xs) {
+  if (len(xs) == 0) {
+    return 0
+  }
+  first(xs) + total(rest(xs))
+}
+puts(total(numbers))
+let sum = total(numbers)
+-1
+puts(sum)
+[first(numbers), last(numbers)]
@@ -0,0 +1,8 @@
+puts(1 + 2 * 3 - 4 / 2);
+puts(-7 % 3, 7 % -3);
+puts(1 < 2 < 3, 3 > 2 > 2, !true == false);
+puts(0..5);
+puts("a" == "a", [1, [2]] == [1, [2]], {"a": 1} != {"a": 2});
+puts(200ms + 1s, 1h30m);
+puts({}["missing"] ?? 1);
+[1, 2] + 3
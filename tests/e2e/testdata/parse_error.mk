@@ -0,0 +1,3 @@
+let x = ;
+let = 5;
+puts(x)
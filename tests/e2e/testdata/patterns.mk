@@ -0,0 +1,25 @@
+let describe = fn(value) {
+	match value {
+		0 => "zero",
+		[] => "empty",
+		[x] => "one: " + toString(x),
+		[x, ...rest] if len(rest) > 1 => "many, starting with " + toString(x),
+		[x, y] => "two",
+		{name: n} => "named " + n,
+		(a, b) => "pair",
+		_ => "something else"
+	}
+};
+
+puts(describe(0));
+puts(describe([]));
+puts(describe([7]));
+puts(describe([1, 2]));
+puts(describe([1, 2, 3]));
+puts(describe({"name": "ada"}));
+puts(describe((1, 2)));
+puts(describe(true));
+
+let [a, {b, c: [d, ...e]}] = [1, {"b": 2, "c": [3, 4, 5]}];
+let x, y = (a + b, d);
+[a, b, d, e, x, y]
@@ -0,0 +1,5 @@
+let divide = fn(a, b) { a / b };
+let average = fn(xs) { divide(len(xs), len(xs) - 3) };
+puts("before");
+average([1, 2, 3]);
+puts("never printed");
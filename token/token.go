@@ -5,6 +5,34 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Line and Column are where the token starts in the input, counting
+	// from 1. Columns count bytes.
+	Line   int
+	Column int
+
+	// Trivia is what a lexer keeping it found around the token besides
+	// whitespace. It is nil otherwise, and for tokens with nothing around.
+	Trivia *Trivia
+}
+
+// Trivia holds the comments and blank lines around a token.
+type Trivia struct {
+	// Leading are the comments on the lines before the token, and Blank
+	// counts the blank lines between the last of them, or the token before
+	// when there are none, and the token.
+	Leading []Comment
+	Blank   int
+
+	// Trailing is the comment after the token on its line, if any.
+	Trailing string
+}
+
+// Comment is a comment, with its //, and the number of blank lines before
+// it.
+type Comment struct {
+	Text  string
+	Blank int
 }
 
 const (
@@ -13,7 +41,10 @@ const (
 
 	IDENTIFIER = "IDENT"
 	INT        = "INT"
+	DURATION   = "DURATION"
 	STRING     = "STRING"
+	BYTES      = "BYTES"
+	CHAR       = "CHAR"
 
 	BANG     = "!"
 	ASSIGN   = "="
@@ -23,9 +54,19 @@ const (
 	MINUS    = "-"
 	SLASH    = "/"
 	ASTERISK = "*"
+	PERCENT  = "%"
+	PIPE     = "|"
 
 	EQ     = "=="
 	NOT_EQ = "!="
+	ARROW  = "=>"
+
+	NULLISH      = "??"
+	DOT          = "."
+	QUESTION_DOT = "?."
+
+	ELLIPSIS = "..."
+	DOT_DOT  = ".."
 
 	COMMA     = ","
 	COLON     = ":"
@@ -45,6 +86,9 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	FOR      = "FOR"
+	IN       = "IN"
+	MATCH    = "MATCH"
 )
 
 var keywords = map[string]TokenType{
@@ -55,6 +99,16 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"for":    FOR,
+	"in":     IN,
+	"match":  MATCH,
+}
+
+// IsKeyword reports whether ident is a reserved word, and so cannot be used
+// as a name.
+func IsKeyword(ident string) bool {
+	_, ok := keywords[ident]
+	return ok
 }
 
 func LookupIdentifier(ident string) TokenType {
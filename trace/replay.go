@@ -0,0 +1,141 @@
+package trace
+
+import "sort"
+
+// Replayer steps through a trace, forwards and backwards, keeping the
+// variables and calls there were at the step it is at. Going back undoes
+// the events after the step instead of replaying the trace from its start.
+type Replayer struct {
+	trace *Trace
+
+	// steps holds the indexes of the Step events, and step the one of them
+	// the replayer is at. Events up to and including it are applied.
+	steps   []int
+	step    int
+	applied int
+
+	outer  map[int]int
+	values map[int]map[string]string
+	calls  []string
+}
+
+// Variable is a variable visible at a step, with its inspected value.
+type Variable struct {
+	Name, Value string
+}
+
+// NewReplayer returns a replayer at the first step of t.
+func NewReplayer(t *Trace) *Replayer {
+	r := &Replayer{trace: t, outer: map[int]int{}, values: map[int]map[string]string{}}
+	for i, event := range t.Events {
+		switch event.Kind {
+		case Step:
+			r.steps = append(r.steps, i)
+		case Enter:
+			r.outer[event.Scope] = event.Outer
+		}
+	}
+	r.Goto(0)
+	return r
+}
+
+// Steps returns how many steps the trace has.
+func (r *Replayer) Steps() int {
+	return len(r.steps)
+}
+
+// Step returns the index of the step the replayer is at, and its event.
+func (r *Replayer) Step() (int, Event) {
+	if len(r.steps) == 0 {
+		return 0, Event{}
+	}
+	return r.step, r.trace.Events[r.steps[r.step]]
+}
+
+// Forward moves to the next step, reporting whether there was one.
+func (r *Replayer) Forward() bool {
+	return r.Goto(r.step + 1)
+}
+
+// Back moves to the previous step, reporting whether there was one.
+func (r *Replayer) Back() bool {
+	return r.Goto(r.step - 1)
+}
+
+// Goto moves to step, reporting whether the trace has it.
+func (r *Replayer) Goto(step int) bool {
+	if step < 0 || step >= len(r.steps) {
+		return false
+	}
+	target := r.steps[step] + 1
+	for r.applied < target {
+		r.apply(r.trace.Events[r.applied])
+		r.applied++
+	}
+	for r.applied > target {
+		r.applied--
+		r.undo(r.trace.Events[r.applied])
+	}
+	r.step = step
+	return true
+}
+
+// Variables returns the variables visible at the step, sorted by name, with
+// those of inner scopes hiding those of outer ones.
+func (r *Replayer) Variables() []Variable {
+	_, event := r.Step()
+	seen := map[string]bool{}
+	var variables []Variable
+	for scope := event.Scope; scope != 0; scope = r.outer[scope] {
+		for name, value := range r.values[scope] {
+			if !seen[name] {
+				seen[name] = true
+				variables = append(variables, Variable{name, value})
+			}
+		}
+	}
+	sort.Slice(variables, func(i, j int) bool { return variables[i].Name < variables[j].Name })
+	return variables
+}
+
+// Calls returns the calls active at the step, innermost first.
+func (r *Replayer) Calls() []string {
+	calls := make([]string, len(r.calls))
+	for i, call := range r.calls {
+		calls[len(calls)-1-i] = call
+	}
+	return calls
+}
+
+func (r *Replayer) apply(event Event) {
+	switch event.Kind {
+	case Bind:
+		if r.values[event.Scope] == nil {
+			r.values[event.Scope] = map[string]string{}
+		}
+		r.values[event.Scope][event.Name] = event.Value
+	case Call:
+		r.calls = append(r.calls, event.Name)
+	case Return:
+		if len(r.calls) > 0 {
+			r.calls = r.calls[:len(r.calls)-1]
+		}
+	}
+}
+
+func (r *Replayer) undo(event Event) {
+	switch event.Kind {
+	case Bind:
+		if event.Rebound {
+			r.values[event.Scope][event.Name] = event.Previous
+		} else {
+			delete(r.values[event.Scope], event.Name)
+		}
+	case Call:
+		if len(r.calls) > 0 {
+			r.calls = r.calls[:len(r.calls)-1]
+		}
+	case Return:
+		r.calls = append(r.calls, event.Name)
+	}
+}
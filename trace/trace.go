@@ -0,0 +1,261 @@
+// Package trace records what an evaluation does, node by node, so it can be
+// replayed afterwards: stepped through forwards and backwards, with the
+// variables and calls there were at each step. A trace is a gob stream of a
+// Header followed by its events.
+package trace
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Version is the version of the format traces are written in.
+const Version = 1
+
+// maxScopes is how many of the environments it has entered a recorder
+// remembers. It forgets them all past that, entering them again when they
+// are next seen, so that recording a long run takes bounded memory.
+const maxScopes = 1 << 12
+
+// maxValueLength is how many bytes of the inspected values a trace keeps.
+const maxValueLength = 200
+
+// Header starts a trace, saying what was evaluated.
+type Header struct {
+	Version int
+
+	// Script is the path of the script, and Source what it held.
+	Script string
+	Source string
+}
+
+// Kind is what an Event records.
+type Kind uint8
+
+const (
+	// Step is a node about to be evaluated, one of the positions a replay
+	// stops at.
+	Step Kind = iota
+
+	// Enter is the sight of an environment, Scope, enclosed in Outer. It
+	// comes before the first event in Scope, and may come again later.
+	Enter
+
+	// Bind is Name bound to Value in Scope, where it was bound to Previous
+	// before when Rebound is set.
+	Bind
+
+	// Call is a call of Name, and Return the call returning Value.
+	Call
+	Return
+)
+
+// Event is one thing the evaluation did. Which fields are set depends on
+// its Kind.
+type Event struct {
+	Kind Kind
+
+	// Line and Column are where the node of a Step starts, and Node what
+	// kind of node it is, such as InfixExpression.
+	Line, Column int
+	Node         string
+
+	// Scope identifies the environment of a Step, an Enter or a Bind, by
+	// its object.Environment ID. Outer is that of its enclosing environment,
+	// 0 for an outermost one.
+	Scope, Outer int
+
+	Name            string
+	Value, Previous string
+	Rebound         bool
+}
+
+// Trace is a trace read back.
+type Trace struct {
+	Header
+	Events []Event
+}
+
+// Read reads a trace written by a Recorder.
+func Read(r io.Reader) (*Trace, error) {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	t := &Trace{}
+	if err := dec.Decode(&t.Header); err != nil {
+		return nil, fmt.Errorf("not a trace: %s", err)
+	}
+	if t.Version != Version {
+		return nil, fmt.Errorf("the trace is of version %d, only version %d is supported", t.Version, Version)
+	}
+	for {
+		var event Event
+		err := dec.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return t, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the trace is corrupt after %d events: %s", len(t.Events), err)
+		}
+		t.Events = append(t.Events, event)
+	}
+}
+
+// Recorder writes a trace of the evaluations it hooks into. It is safe for
+// concurrent use, so scripts using parallel builtins can be recorded, though
+// the events of their goroutines are interleaved.
+type Recorder struct {
+	mu  sync.Mutex
+	out io.Writer
+	w   *bufio.Writer
+	enc *gob.Encoder
+	err error
+
+	// entered holds the IDs of the environments entered in the trace, by ID
+	// rather than by pointer so the environments can still be collected.
+	entered map[uint64]struct{}
+}
+
+// NewRecorder writes header to w and returns a recorder writing the rest of
+// the trace after it. It must be closed to write out the events it holds.
+func NewRecorder(w io.Writer, header Header) (*Recorder, error) {
+	r := &Recorder{out: w, w: bufio.NewWriter(w), entered: map[uint64]struct{}{}}
+	r.enc = gob.NewEncoder(r.w)
+	header.Version = Version
+	if err := r.enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Hooks returns the hooks recording the nodes and calls of an evaluation,
+// for its evaluator.Config.
+func (r *Recorder) Hooks() *evaluator.Hooks {
+	return &evaluator.Hooks{
+		Node: func(node ast.Node, env *object.Environment) {
+			tok, ok := nodeToken(node)
+			if !ok || tok.Line == 0 {
+				return
+			}
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.write(Event{
+				Kind:   Step,
+				Line:   tok.Line,
+				Column: tok.Column,
+				Node:   reflect.TypeOf(node).Elem().Name(),
+				Scope:  r.scope(env),
+			})
+		},
+		Call: func(name string, fn object.Object, args []object.Object) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.write(Event{Kind: Call, Name: name})
+		},
+		Return: func(name string, fn object.Object, result object.Object) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.write(Event{Kind: Return, Name: name, Value: inspect(result)})
+		},
+	}
+}
+
+// Observe records the bindings made in env and the environments enclosed in
+// it from then on. Bindings made before are not in the trace.
+func (r *Recorder) Observe(env *object.Environment) {
+	env.Observe(func(env *object.Environment, name string, value, previous object.Object) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		event := Event{Kind: Bind, Scope: r.scope(env), Name: name, Value: inspect(value)}
+		if previous != nil {
+			event.Previous, event.Rebound = inspect(previous), true
+		}
+		r.write(event)
+	})
+}
+
+// Close writes out the events the recorder holds and closes the writer it
+// was created with when that is an io.Closer, returning the first error
+// writing the trace failed with.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if closer, ok := r.out.(io.Closer); ok {
+		if err := closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	if r.err == nil {
+		r.err = errors.New("the recorder is closed")
+		return nil
+	}
+	return r.err
+}
+
+func (r *Recorder) write(event Event) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(event)
+}
+
+// scope returns the number of env, entering it and the environments
+// enclosing it when the recorder does not remember having entered them.
+func (r *Recorder) scope(env *object.Environment) int {
+	if env == nil {
+		return 0
+	}
+	id := env.ID()
+	if _, ok := r.entered[id]; ok {
+		return int(id)
+	}
+	outer := r.scope(env.Outer())
+	if len(r.entered) >= maxScopes {
+		r.entered = map[uint64]struct{}{}
+	}
+	r.entered[id] = struct{}{}
+	r.write(Event{Kind: Enter, Scope: int(id), Outer: outer})
+	return int(id)
+}
+
+// nodeToken returns the token node starts with. Nodes keep it in their Token
+// field, all but the program, which has none.
+func nodeToken(node ast.Node) (token.Token, bool) {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return token.Token{}, false
+	}
+	field := v.Elem().FieldByName("Token")
+	if !field.IsValid() {
+		return token.Token{}, false
+	}
+	tok, ok := field.Interface().(token.Token)
+	return tok, ok
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return ""
+	}
+	s := obj.Inspect()
+	if len(s) > maxValueLength {
+		n := maxValueLength
+		for !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,159 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+)
+
+func record(t *testing.T, source string) *Trace {
+	t.Helper()
+	var buf bytes.Buffer
+	recorder, err := NewRecorder(&buf, Header{Script: "script.mk", Source: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := evaluator.New(evaluator.Config{Hooks: recorder.Hooks()})
+	env := e.NewEnvironment()
+	recorder.Observe(env)
+	e.Eval(e.NewParser(source).ParseProgram(), env)
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Script != "script.mk" || trace.Source != source {
+		t.Errorf("unexpected header %+v", trace.Header)
+	}
+	return trace
+}
+
+func variables(r *Replayer) string {
+	var vars []string
+	for _, v := range r.Variables() {
+		vars = append(vars, v.Name+"="+v.Value)
+	}
+	return strings.Join(vars, " ")
+}
+
+func TestReplay(t *testing.T) {
+	trace := record(t, "let a = 1;\nlet f = fn(x) { let y = x * 2; y };\nlet a = f(map([a], fn(v) { v + 1 })[0]);\na")
+	r := NewReplayer(trace)
+
+	var steps []string
+	for {
+		step, event := r.Step()
+		if step != len(steps) {
+			t.Fatalf("expected step %d, got %d", len(steps), step)
+		}
+		// The steps of map, from the prelude, have no position to stop at.
+		if event.Line == 0 {
+			t.Errorf("unexpected step %s without a position", event.Node)
+		}
+		steps = append(steps, event.Node)
+		if !r.Forward() {
+			break
+		}
+	}
+	if steps[0] != "LetStatement" || steps[1] != "IntegerLiteral" || steps[len(steps)-1] != "Identifier" {
+		t.Errorf("expected the steps to start with the first let and end with a, got %v", steps)
+	}
+	if got := variables(r); !strings.HasPrefix(got, "a=4 f=fn(x)") {
+		t.Errorf("expected a to be bound again at the end, got %q", got)
+	}
+
+	// Back to the multiplication in f, seeing x and the a bound first.
+	for {
+		if !r.Back() {
+			t.Fatal("the multiplication was never reached going back")
+		}
+		if _, event := r.Step(); event.Node == "InfixExpression" && event.Line == 2 {
+			break
+		}
+	}
+	if got := variables(r); !strings.HasPrefix(got, "a=1 f=fn(x)") || !strings.HasSuffix(got, "x=2") {
+		t.Errorf("unexpected variables in f %q", got)
+	}
+	if calls := r.Calls(); strings.Join(calls, " ") != "f" {
+		t.Errorf("expected to be in f, got %v", calls)
+	}
+
+	r.Goto(0)
+	if got := variables(r); got != "" {
+		t.Errorf("expected no variables at the start, got %q", got)
+	}
+	if r.Back() || r.Goto(r.Steps()) {
+		t.Errorf("expected to stay within the steps")
+	}
+}
+
+func TestRecorderConcurrency(t *testing.T) {
+	trace := record(t, "pmap([1, 2, 3, 4], fn(x) { let y = x * x; y })")
+	calls := 0
+	for _, event := range trace.Events {
+		if event.Kind == Call && event.Name == "<anonymous fn>" {
+			calls++
+		}
+	}
+	if calls != 4 {
+		t.Errorf("expected the 4 calls of pmap to be recorded, got %d", calls)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	if _, err := Read(strings.NewReader("not a trace")); err == nil {
+		t.Errorf("expected garbage not to read as a trace")
+	}
+	var buf bytes.Buffer
+	recorder, _ := NewRecorder(&buf, Header{})
+	recorder.Observe(object.NewEnvironment())
+	recorder.Close()
+	trace, err := Read(&buf)
+	if err != nil || len(trace.Events) != 0 {
+		t.Errorf("expected an empty trace, got %v, %v", trace, err)
+	}
+}
+
+func TestRecorderForgetsScopes(t *testing.T) {
+	var buf bytes.Buffer
+	recorder, err := NewRecorder(&buf, Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := evaluator.New(evaluator.Config{Hooks: recorder.Hooks()})
+	env := e.NewEnvironment()
+	recorder.Observe(env)
+	source := "let add = fn(x) { let y = x + 1; y };\nlet total = sum(map(0..1999, fn(x) { add(x) + add(x) + add(x) }));\ntotal"
+	e.Eval(e.NewParser(source).ParseProgram(), env)
+	if len(recorder.entered) > maxScopes {
+		t.Errorf("expected at most %d scopes remembered, got %d", maxScopes, len(recorder.entered))
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes := map[int]bool{}
+	for _, event := range trace.Events {
+		if event.Kind == Enter {
+			scopes[event.Scope] = true
+		}
+	}
+	if len(scopes) <= maxScopes {
+		t.Fatalf("expected more than %d scopes to be entered, got %d", maxScopes, len(scopes))
+	}
+	r := NewReplayer(trace)
+	r.Goto(r.Steps() - 1)
+	if got := variables(r); !strings.HasPrefix(got, "add=fn(x)") || !strings.Contains(got, "total=5997000") {
+		t.Errorf("expected the globals to be replayed past the forgotten scopes, got %q", got)
+	}
+}
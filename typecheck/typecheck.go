@@ -0,0 +1,551 @@
+// Package typecheck checks the type annotations of a program without running
+// it. Types are inferred from literals, operators and annotations; anything
+// else, such as a parameter without an annotation or the result of indexing,
+// is of type any and matches every type, so code without annotations always
+// passes.
+package typecheck
+
+import (
+	"reflect"
+
+	"github.com/fcidade/monkey-lang/ast"
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/message"
+	"github.com/fcidade/monkey-lang/token"
+)
+
+// Type is the name of a type as written in annotations.
+type Type string
+
+const (
+	Any    Type = "any"
+	Int    Type = "int"
+	String Type = "string"
+	Bool   Type = "bool"
+	Bytes  Type = "bytes"
+	Char   Type = "char"
+	Array  Type = "array"
+	Hash   Type = "hash"
+	Tuple  Type = "tuple"
+	Fn     Type = "fn"
+	Null   Type = "null"
+	Time   Type = "time"
+
+	// never is the type of a statement that does not complete, such as a
+	// return, so it does not count towards the type of its block.
+	never Type = "never"
+)
+
+var types = map[string]Type{
+	"any": Any, "int": Int, "string": String, "bool": Bool, "bytes": Bytes, "char": Char,
+	"array": Array, "hash": Hash, "tuple": Tuple, "fn": Fn, "null": Null, "time": Time,
+}
+
+// builtinResults are the types the builtins with a fixed result type return.
+var builtinResults = map[string]Type{
+	"len":          Int,
+	"rest":         Array,
+	"push":         Array,
+	"pushMut":      Array,
+	"toString":     String,
+	"puts":         Null,
+	"bytes":        Bytes,
+	"sort":         Array,
+	"sortBy":       Array,
+	"isFrozen":     Bool,
+	"random":       Int,
+	"randomBytes":  Bytes,
+	"exec":         Hash,
+	"shell":        Hash,
+	"joinPath":     String,
+	"basename":     String,
+	"dirname":      String,
+	"ext":          String,
+	"absPath":      String,
+	"glob":         Array,
+	"csvParse":     Array,
+	"csvEncode":    String,
+	"yamlEncode":   String,
+	"tomlDecode":   Hash,
+	"tomlEncode":   String,
+	"template":     String,
+	"import":       Hash,
+	"version":      Hash,
+	"ord":          Int,
+	"parseInt":     Int,
+	"padLeft":      String,
+	"padRight":     String,
+	"center":       String,
+	"formatNumber": String,
+	"formatTable":  String,
+	"printTable":   Null,
+	"chr":          Char,
+	"port":         Int,
+	"write":        Int,
+	"close":        Null,
+	"query":        Array,
+	"logDebug":     Null,
+	"logInfo":      Null,
+	"logWarn":      Null,
+	"logError":     Null,
+	"now":          Time,
+	"parseTime":    Time,
+	"fromUnix":     Time,
+	"addDuration":  Time,
+	"inZone":       Time,
+	"formatTime":   String,
+	"diff":         Int,
+	"unix":         Int,
+	"year":         Int,
+	"month":        Int,
+	"day":          Int,
+}
+
+// argumentResult returns the type the builtin name returns when its result
+// depends on the types of its arguments, args, reporting whether it is such
+// a builtin.
+func argumentResult(name string, args []Type) (Type, bool) {
+	switch name {
+	case "repeat":
+		// Strings and arrays repeat into their own type, anything else into
+		// an array.
+		if len(args) == 0 || args[0] == Any {
+			return Any, true
+		}
+		if args[0] == String || args[0] == Array {
+			return args[0], true
+		}
+		return Array, true
+	}
+	return Any, false
+}
+
+// Check returns a diagnostic for each type error in program, at the node
+// the error is in.
+func Check(program *ast.Program) []diagnostic.Diagnostic {
+	c := &checker{scope: newScope(nil)}
+	c.statements(program.Statements)
+	return c.errors
+}
+
+type binding struct {
+	typ Type
+	// fn is the literal a let bound, so calls through the name can be
+	// checked against its parameters.
+	fn *ast.FunctionLiteral
+}
+
+type scope struct {
+	outer    *scope
+	bindings map[string]binding
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, bindings: make(map[string]binding)}
+}
+
+func (s *scope) lookup(name string) (binding, bool) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+// function is what the checker knows about the function literal it is in.
+type function struct {
+	name   string
+	result Type
+}
+
+type checker struct {
+	scope    *scope
+	function *function
+	errors   []diagnostic.Diagnostic
+}
+
+// errorf records an error at node.
+func (c *checker) errorf(node ast.Node, id message.ID, a ...interface{}) {
+	tok := nodeToken(node)
+	c.errors = append(c.errors, diagnostic.Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Code:    message.Code(id),
+		Message: message.Format(id, a...),
+	})
+}
+
+// nodeToken returns the token node starts with, which nodes keep in their
+// Token field.
+func nodeToken(node ast.Node) token.Token {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return token.Token{}
+	}
+	field := v.Elem().FieldByName("Token")
+	if !field.IsValid() {
+		return token.Token{}
+	}
+	tok, _ := field.Interface().(token.Token)
+	return tok
+}
+
+// expect reports an error at node unless got can be used where want is
+// required.
+func (c *checker) expect(node ast.Node, want, got Type, what string) {
+	if want == Any || got == Any || got == never || want == got {
+		return
+	}
+	c.errorf(node, message.TypeExpected, what, want, got)
+}
+
+// annotation returns the type an annotation names, which is any when there
+// is no annotation.
+func (c *checker) annotation(annotation *ast.TypeAnnotation) Type {
+	if annotation == nil {
+		return Any
+	}
+	t, ok := types[annotation.Name]
+	if !ok {
+		c.errorf(annotation, message.UnknownType, annotation.Name)
+		return Any
+	}
+	return t
+}
+
+// statements checks stmts and returns the type of the last one, which is
+// what a block evaluates to.
+func (c *checker) statements(stmts []ast.Statement) Type {
+	result := Null
+	for _, stmt := range stmts {
+		result = c.statement(stmt)
+	}
+	return result
+}
+
+func (c *checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt)
+		return Any
+
+	case *ast.ReturnStatement:
+		t := c.expr(stmt.ReturnValue)
+		if c.function != nil {
+			c.expect(stmt, c.function.result, t, message.Format(message.TypeOfResult, c.function.name))
+		}
+		return never
+
+	case *ast.ExpressionStatement:
+		return c.expr(stmt.Expression)
+
+	case *ast.BlockStatement:
+		return c.statements(stmt.Statements)
+	}
+	return Any
+}
+
+func (c *checker) let(let *ast.LetStatement) {
+	if let.Pattern != nil {
+		c.expr(let.Value)
+		c.declarePattern(let.Pattern)
+		return
+	}
+
+	name := let.Name.Value
+	want := c.annotation(let.Name.Type)
+
+	// A function is declared before its body is checked, so it can call
+	// itself.
+	fn, _ := let.Value.(*ast.FunctionLiteral)
+	if fn != nil {
+		c.expect(let, want, Fn, message.Format(message.TypeOfLet, name))
+		c.scope.bindings[name] = binding{typ: Fn, fn: fn}
+		c.functionLiteral(fn, name)
+		return
+	}
+
+	got := c.expr(let.Value)
+	c.expect(let, want, got, message.Format(message.TypeOfLet, name))
+	if want == Any {
+		want = got
+	}
+	c.scope.bindings[name] = binding{typ: want}
+}
+
+// declarePattern declares what pattern binds, whose types are not known.
+func (c *checker) declarePattern(pattern ast.Pattern) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			c.scope.bindings[ident.Value] = binding{typ: Any}
+		}
+		return true
+	})
+}
+
+func (c *checker) withScope(f func()) {
+	c.scope = newScope(c.scope)
+	f()
+	c.scope = c.scope.outer
+}
+
+func (c *checker) functionLiteral(fn *ast.FunctionLiteral, name string) {
+	outer := c.function
+	c.function = &function{name: name, result: c.annotation(fn.ReturnType)}
+
+	c.withScope(func() {
+		for _, param := range fn.Parameters {
+			c.scope.bindings[param.Value] = binding{typ: c.annotation(param.Type)}
+		}
+		result := c.statements(fn.Body.Statements)
+		c.expect(fn, c.function.result, result, message.Format(message.TypeOfResult, name))
+	})
+
+	c.function = outer
+}
+
+func (c *checker) expr(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.BytesLiteral:
+		return Bytes
+	case *ast.CharLiteral:
+		return Char
+
+	case *ast.Identifier:
+		if b, ok := c.scope.lookup(exp.Value); ok {
+			return b.typ
+		}
+		if _, ok := builtinResults[exp.Value]; ok {
+			return Fn
+		}
+		if _, ok := argumentResult(exp.Value, nil); ok {
+			return Fn
+		}
+		return Any
+
+	case *ast.PrefixExpression:
+		right := c.expr(exp.Right)
+		if exp.Operator == "!" {
+			return Bool
+		}
+		if right != Any && right != Int {
+			c.errorf(exp, message.PrefixNotDefined, exp.Operator, right)
+		}
+		return Int
+
+	case *ast.InfixExpression:
+		return c.infix(exp, exp.Operator, c.expr(exp.Left), c.expr(exp.Right))
+
+	case *ast.ComparisonChain:
+		operands := make([]Type, len(exp.Operands))
+		for i, operand := range exp.Operands {
+			operands[i] = c.expr(operand)
+		}
+		for i, operator := range exp.Operators {
+			c.infix(exp, operator, operands[i], operands[i+1])
+		}
+		return Bool
+
+	case *ast.IfExpression:
+		c.expr(exp.Condition)
+		consequence := c.statement(exp.Consequence)
+		alternative := Null
+		if exp.Alternative != nil {
+			alternative = c.statement(exp.Alternative)
+		}
+		return join(consequence, alternative)
+
+	case *ast.FunctionLiteral:
+		c.functionLiteral(exp, "fn")
+		return Fn
+
+	case *ast.CallExpression:
+		return c.call(exp)
+
+	case *ast.ArrayLiteral:
+		c.exprs(exp.Elements)
+		return Array
+
+	case *ast.TupleLiteral:
+		c.exprs(exp.Elements)
+		return Tuple
+
+	case *ast.HashLiteral:
+		for key, value := range exp.Pairs {
+			c.expr(key)
+			c.expr(value)
+		}
+		return Hash
+
+	case *ast.IndexExpression:
+		c.expr(exp.Left)
+		c.expr(exp.Index)
+		return Any
+
+	case *ast.FieldExpression:
+		c.expr(exp.Left)
+		return Any
+
+	case *ast.ArrayComprehension:
+		c.comprehension(exp.Clause, exp.Element)
+		return Array
+
+	case *ast.HashComprehension:
+		c.comprehension(exp.Clause, exp.Key, exp.Value)
+		return Hash
+
+	case *ast.MatchExpression:
+		c.expr(exp.Subject)
+		result := never
+		for _, arm := range exp.Arms {
+			c.withScope(func() {
+				c.declarePattern(arm.Pattern)
+				if arm.Guard != nil {
+					c.expr(arm.Guard)
+				}
+				result = join(result, c.statement(arm.Body))
+			})
+		}
+		return result
+	}
+	return Any
+}
+
+func (c *checker) exprs(exps []ast.Expression) []Type {
+	result := make([]Type, len(exps))
+	for i, exp := range exps {
+		result[i] = c.expr(exp)
+	}
+	return result
+}
+
+func (c *checker) comprehension(clause *ast.ComprehensionClause, body ...ast.Expression) {
+	c.expr(clause.Iterable)
+	c.withScope(func() {
+		c.declarePattern(clause.Pattern)
+		if clause.Condition != nil {
+			c.expr(clause.Condition)
+		}
+		c.exprs(body)
+	})
+}
+
+func (c *checker) call(call *ast.CallExpression) Type {
+	callee := c.expr(call.Function)
+	args := c.exprs(call.Arguments)
+
+	name := "fn"
+	var fn *ast.FunctionLiteral
+	switch function := call.Function.(type) {
+	case *ast.Identifier:
+		name = function.Value
+		b, ok := c.scope.lookup(name)
+		if !ok {
+			if result, ok := builtinResults[name]; ok {
+				return result
+			}
+			result, _ := argumentResult(name, args)
+			return result
+		}
+		fn = b.fn
+	case *ast.FunctionLiteral:
+		fn = function
+	}
+
+	if callee != Any && callee != Fn {
+		c.errorf(call, message.NotCallable, name, callee)
+		return Any
+	}
+	if fn == nil {
+		return Any
+	}
+
+	if len(args) < len(fn.Parameters) {
+		c.errorf(call, message.TypeArgumentCount, name, len(fn.Parameters), len(args))
+	}
+	for i, param := range fn.Parameters {
+		if i < len(args) {
+			c.expect(call.Arguments[i], typeOf(param.Type), args[i], message.Format(message.TypeOfArgument, i+1, name))
+		}
+	}
+	return typeOf(fn.ReturnType)
+}
+
+// typeOf is like annotation but silent about unknown types, which were
+// reported where the annotation was checked.
+func typeOf(annotation *ast.TypeAnnotation) Type {
+	if annotation == nil {
+		return Any
+	}
+	if t, ok := types[annotation.Name]; ok {
+		return t
+	}
+	return Any
+}
+
+// infix returns the type of applying operator to left and right in node,
+// reporting the combinations that fail at runtime. Hashes may define operators of
+// their own, so their results are not known.
+func (c *checker) infix(node ast.Node, operator string, left, right Type) Type {
+	switch operator {
+	case "??":
+		return join(left, right)
+	case "==", "!=":
+		if left != Any && right != Any && left != right {
+			c.errorf(node, message.InfixNotDefined, operator, left, right)
+		}
+		return Bool
+	}
+
+	if left == Any || right == Any || left == Hash {
+		switch operator {
+		case "<", ">":
+			return Bool
+		case "..":
+			return Array
+		case "-", "/", "%":
+			return Int
+		}
+		return Any
+	}
+
+	switch {
+	case left == right && (left == Time || left == Char) && (operator == "<" || operator == ">"):
+		return Bool
+	case left == Int && right == Int:
+		switch operator {
+		case "<", ">":
+			return Bool
+		case "..":
+			return Array
+		}
+		return Int
+	case operator == "+" && left == right && (left == String || left == Bytes):
+		return left
+	case operator == "*" && left == Int && (right == String || right == Array):
+		return right
+	case operator == "*" && right == Int && (left == String || left == Array):
+		return left
+	}
+
+	c.errorf(node, message.InfixNotDefined, operator, left, right)
+	return Any
+}
+
+// join is the type of a value that is either a or b.
+func join(a, b Type) Type {
+	switch {
+	case a == never:
+		return b
+	case b == never || a == b:
+		return a
+	}
+	return Any
+}
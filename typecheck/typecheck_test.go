@@ -0,0 +1,111 @@
+package typecheck
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fcidade/monkey-lang/diagnostic"
+	"github.com/fcidade/monkey-lang/lexer"
+	"github.com/fcidade/monkey-lang/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5; x + 1", nil},
+		{"let x: int = 5", nil},
+		{`let x: int = "five"`, []string{"let x: expected int, got string"}},
+		{`let x: string = 1 + 2 * 3`, []string{"let x: expected string, got int"}},
+		{"let x: number = 5", []string{"unknown type number"}},
+		{`5 + "five"`, []string{"operator + not defined for int and string"}},
+		{`"a" + "b" == 1`, []string{"operator == not defined for string and int"}},
+		{`-"a"`, []string{"operator - not defined for string"}},
+		{`"ab" * 2; [1] * 3; b"a" + b"b"`, nil},
+		{`let s = "a"; s < "b"`, []string{"operator < not defined for string and string"}},
+		{"let c: char = 'a'; c < 'b'; let n: int = ord(c); let d: char = chr(n)", nil},
+		{"'a' + 1", []string{"operator + not defined for char and int"}},
+		{"let f = fn(a, b) { a + b }; f(1, true)", nil},
+		{"x + true; let h = {}; h + 1", nil},
+		{
+			"let add = fn(a: int, b: int): int { a + b }; add(1, 2); add(1)",
+			[]string{"add takes 2 arguments, got 1"},
+		},
+		{
+			`let add = fn(a: int, b: int): int { a + b }; add(1, "2")`,
+			[]string{"argument 2 to add: expected int, got string"},
+		},
+		{
+			`let add = fn(a: int, b: int): int { a + b }; let s: string = add(1, 2)`,
+			[]string{"let s: expected string, got int"},
+		},
+		{
+			`let greet = fn(name: string): int { "hello " + name }`,
+			[]string{"result of greet: expected int, got string"},
+		},
+		{
+			`let f = fn(n: int): string { if (n > 0) { return "pos"; } return 1; }`,
+			[]string{"result of f: expected string, got int"},
+		},
+		{
+			"let fact = fn(n: int): int { if (n < 2) { return 1; } n * fact(n - 1) }",
+			nil,
+		},
+		{
+			"let f = fn(n: int): bool { if (n < 2) { true } else { n } }",
+			nil,
+		},
+		{"let n: int = len([1, 2]); let s: string = toString(n)", nil},
+		{`let a: string = repeat("ab", 2); let b: array = repeat([1], 2); let c: array = repeat(0, 3)`, nil},
+		{`let a: int = repeat("ab", 2)`, []string{"let a: expected int, got string"}},
+		{"let t: time = now(); t < addDuration(t, 5); t + 1", []string{"operator + not defined for time and int"}},
+		{`let s: int = toString(1)`, []string{"let s: expected int, got string"}},
+		{"let x = 5; x(1)", []string{"cannot call x of type int"}},
+		{
+			"let x = 5; let f = fn(x: string) { x + 1 }",
+			[]string{"operator + not defined for string and int"},
+		},
+		{"let f = |x: int| x + 1; f(1)", nil},
+		{"[x + 1 for x in 0..3]; match 1 { [a] => a + 1, _ => 0 }", nil},
+		{`let x: int = match 1 { 1 => "one", _ => "many" }`, []string{"let x: expected int, got string"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		var errors []string
+		for _, d := range Check(program) {
+			if d.Code != diagnostic.TypeError {
+				t.Errorf("expected %q to have the code %s, got %s", d.Message, diagnostic.TypeError, d.Code)
+			}
+			errors = append(errors, d.Message)
+		}
+		if !reflect.DeepEqual(errors, tt.expected) {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestCheckPositions(t *testing.T) {
+	input := "let a = 1;\nlet s: string = a;\nlet f = fn(n: int) { n };\nf(true) + 1;\n  5 + \"five\""
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	var got []string
+	for _, d := range Check(program) {
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Line, d.Column, d.Message))
+	}
+	expected := []string{
+		"2:1 let s: expected string, got int",
+		"4:3 argument 1 to f: expected int, got bool",
+		"5:5 operator + not defined for int and string",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong positions. expected=%q, got=%q", expected, got)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fcidade/monkey-lang/evaluator"
+	"github.com/fcidade/monkey-lang/object"
+	"github.com/fcidade/monkey-lang/optimize"
+)
+
+func watchCommand(args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	preserve := flags.Bool("preserve", false, "keep the bindings of previous runs instead of starting fresh")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often the script is checked for changes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey watch [flags] <script.mk>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	watch(flags.Arg(0), *preserve, *interval, os.Stdout, nil)
+	return 0
+}
+
+// watch runs the script at path and then runs it again every time its
+// modification time or size changes, until stop is closed. With preserve set
+// every run shares one environment, so bindings survive between edits.
+func watch(path string, preserve bool, interval time.Duration, out io.Writer, stop <-chan struct{}) {
+	var env *object.Environment
+	var last os.FileInfo
+	// failing is set once the script could not be stat'ed, so the error is
+	// printed once rather than on every check.
+	failing := false
+
+	for {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			if !failing {
+				fmt.Fprintf(out, "could not stat %s: %s\n", path, err)
+			}
+			failing = true
+			last = nil
+
+		case last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size():
+			failing = false
+			if last != nil {
+				fmt.Fprintf(out, "--- %s changed, running again ---\n", path)
+			}
+			last = info
+			ev := evaluator.New(evaluator.Config{})
+			if env == nil || !preserve {
+				env = ev.NewEnvironment()
+			}
+			runFile(path, ev, env, optimize.None, out)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		preserve bool
+		expected string
+	}{
+		{false, ":1:1: Error: identifier not found: a\n"},
+		{true, ":1:1: Error: type mismatch: INTEGER + BOOLEAN\n"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "script.mk")
+		writeScript(t, path, "let a = 5; b;", time.Now().Add(-time.Minute))
+
+		var out syncBuffer
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			watch(path, tt.preserve, time.Millisecond, &out, stop)
+			close(done)
+		}()
+
+		waitFor(t, &out, path+":1:12: Error: identifier not found: b\n")
+		writeScript(t, path, "a + true;", time.Now())
+		waitFor(t, &out, "--- "+path+" changed, running again ---\n"+path+tt.expected)
+
+		close(stop)
+		<-done
+	}
+}
+
+func TestWatchMissingScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+
+	var out syncBuffer
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watch(path, false, time.Millisecond, &out, stop)
+		close(done)
+	}()
+
+	waitFor(t, &out, "could not stat "+path)
+	time.Sleep(20 * time.Millisecond)
+	if n := strings.Count(out.String(), "could not stat"); n != 1 {
+		t.Errorf("expected the error to be printed once, got it %d times", n)
+	}
+	writeScript(t, path, "b;", time.Now())
+	waitFor(t, &out, path+":1:1: Error: identifier not found: b\n")
+
+	close(stop)
+	<-done
+}
+
+func writeScript(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, out *syncBuffer, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), substr) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q. got=%q", substr, out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}